@@ -0,0 +1,257 @@
+package auralis
+
+import (
+	"slices"
+)
+
+// Accumulator computes aggregate over values added one by one,
+// null values are passed to accumulator and ignored by all built-in ones
+type Accumulator interface {
+	add(value any)
+	result() any
+}
+
+var aggregateFunctions = map[string]func() Accumulator{
+	"count": func() Accumulator { return &countAccumulator{} },
+	"sum":   func() Accumulator { return &sumAccumulator{} },
+	"avg":   func() Accumulator { return &avgAccumulator{} },
+	"min":   func() Accumulator { return &extremumAccumulator{sign: -1} },
+	"max":   func() Accumulator { return &extremumAccumulator{sign: 1} },
+}
+
+func isAggregateFunction(name string) bool {
+	_, ok := aggregateFunctions[name]
+	return ok
+}
+
+type countAccumulator struct {
+	count int64
+}
+
+func (a *countAccumulator) add(value any) {
+	if value != nil {
+		a.count++
+	}
+}
+
+func (a *countAccumulator) result() any {
+	return a.count
+}
+
+type sumAccumulator struct {
+	sum      int64
+	fraction float64
+	double   bool
+	count    int
+}
+
+func (a *sumAccumulator) add(value any) {
+	if value == nil {
+		return
+	}
+
+	a.count++
+	if isInteger(value) {
+		a.sum += toInt64(value)
+		return
+	}
+
+	a.double = true
+	a.fraction += toFloat64(value)
+}
+
+func (a *sumAccumulator) result() any {
+	if a.count == 0 {
+		return nil
+	}
+
+	if a.double {
+		return float64(a.sum) + a.fraction
+	}
+
+	return a.sum
+}
+
+type avgAccumulator struct {
+	sum   float64
+	count int
+}
+
+func (a *avgAccumulator) add(value any) {
+	if value == nil {
+		return
+	}
+
+	a.sum += toFloat64(value)
+	a.count++
+}
+
+func (a *avgAccumulator) result() any {
+	if a.count == 0 {
+		return nil
+	}
+
+	return a.sum / float64(a.count)
+}
+
+// extremumAccumulator keeps minimum for negative sign and maximum for positive one
+type extremumAccumulator struct {
+	sign  int
+	value any
+}
+
+func (a *extremumAccumulator) add(value any) {
+	if value == nil {
+		return
+	}
+
+	if a.value == nil || compareValues(value, a.value)*a.sign > 0 {
+		a.value = value
+	}
+}
+
+func (a *extremumAccumulator) result() any {
+	return a.value
+}
+
+// accumulate computes aggregate function call over given rows
+func accumulate(call FunctionCall, columns []Column, rows []Row) (any, error) {
+	acc := aggregateFunctions[call.name]()
+	for _, row := range rows {
+		if call.star {
+			acc.add(true)
+			continue
+		}
+
+		if len(call.args) != 1 {
+			return nil, AuraError{
+				Code:    "INVALID_ARGUMENTS",
+				Message: "aggregate function " + call.name + " expects single argument"}
+		}
+
+		value, err := evaluateExpression(call.args[0], columns, row)
+		if err != nil {
+			return nil, err
+		}
+
+		acc.add(value)
+	}
+
+	return acc.result(), nil
+}
+
+// planAggregate returns aggregate functions used in projections and order by and
+// columns of grouped rows, which contain grouping keys followed by aggregate
+// results. Expressions are rewritten to reference these columns
+func planAggregate(columns []Column, groupBy []Expression, projections []Expression,
+	orderBy []OrderByItem) ([]FunctionCall, []Column, []Expression, []OrderByItem, error) {
+	aggregates := collectFunctionCalls(slices.Concat(projections, orderByExpressions(orderBy)),
+		func(call FunctionCall) bool {
+			return call.over == nil && isAggregateFunction(call.name)
+		})
+
+	grouped := []Column{}
+	for i, expr := range groupBy {
+		grouped = append(grouped, Column{
+			name:     hiddenColumnName("group", i),
+			dataType: expressionDataType(expr, columns),
+		})
+	}
+
+	for i, call := range aggregates {
+		grouped = append(grouped, Column{
+			name:     hiddenColumnName("aggregate", i),
+			dataType: expressionDataType(call, columns),
+		})
+	}
+
+	replace := func(expr Expression) (Expression, bool, error) {
+		for i, group := range groupBy {
+			if sameExpression(expr, group, columns) {
+				return ColumnReference{name: hiddenColumnName("group", i)}, true, nil
+			}
+		}
+
+		if call, ok := expr.(FunctionCall); ok && call.over == nil && isAggregateFunction(call.name) {
+			i := slices.IndexFunc(aggregates, func(c FunctionCall) bool { return sameExpression(c, call, nil) })
+			return ColumnReference{name: hiddenColumnName("aggregate", i)}, true, nil
+		}
+
+		if _, ok := expr.(ColumnReference); ok {
+			return nil, false, ErrGroupingRequired
+		}
+
+		return nil, false, nil
+	}
+
+	rewritten := make([]Expression, 0, len(projections))
+	for _, expr := range projections {
+		expr, err := rewriteExpression(expr, replace)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		rewritten = append(rewritten, expr)
+	}
+
+	rewrittenOrderBy := make([]OrderByItem, 0, len(orderBy))
+	for _, item := range orderBy {
+		expr, err := rewriteExpression(item.expression, replace)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		rewrittenOrderBy = append(rewrittenOrderBy, OrderByItem{expression: expr, descending: item.descending})
+	}
+
+	return aggregates, grouped, rewritten, rewrittenOrderBy, nil
+}
+
+// groupRows groups rows and computes aggregates of each group, returned rows
+// contain grouping keys followed by aggregate results
+func groupRows(columns []Column, groupBy []Expression, aggregates []FunctionCall, rows []Row) ([]Row, error) {
+	// groups preserve order of first occurrence
+	keys := []string{}
+	groups := map[string][]Row{}
+	values := map[string][]any{}
+	for _, row := range rows {
+		groupValues := make([]any, 0, len(groupBy))
+		for _, expr := range groupBy {
+			value, err := evaluateExpression(expr, columns, row)
+			if err != nil {
+				return nil, err
+			}
+
+			groupValues = append(groupValues, value)
+		}
+
+		key := Row{cells: groupValues}.key()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			values[key] = groupValues
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	// aggregate without grouping always produces single row
+	if len(groupBy) == 0 && len(keys) == 0 {
+		keys = append(keys, "")
+	}
+
+	grouped := []Row{}
+	for _, key := range keys {
+		cells := slices.Clone(values[key])
+		for _, call := range aggregates {
+			value, err := accumulate(call, columns, groups[key])
+			if err != nil {
+				return nil, err
+			}
+
+			cells = append(cells, value)
+		}
+
+		grouped = append(grouped, Row{cells: cells})
+	}
+
+	return grouped, nil
+}
@@ -0,0 +1,289 @@
+package auralis
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Vector holds values of one column of batch decoded into slice of column type,
+// nulls are marked separately and hold zero value
+type Vector interface {
+	append(data []byte)
+	appendNull()
+	value(i int) any
+	// filter keeps positions of selection whose values satisfy condition
+	filter(condition Condition, selection []int) []int
+	reset()
+}
+
+type typedVector[T any] struct {
+	values  []T
+	nulls   []bool
+	decode  func(data []byte) T
+	compare func(a, b T) int
+}
+
+func newVector(dataType DataType) (Vector, error) {
+	switch dataType {
+	case smallint:
+		return &typedVector[int16]{
+			decode:  func(data []byte) int16 { return int16(binary.BigEndian.Uint16(data)) },
+			compare: cmp.Compare[int16],
+		}, nil
+	case integer:
+		return &typedVector[int32]{
+			decode:  func(data []byte) int32 { return int32(binary.BigEndian.Uint32(data)) },
+			compare: cmp.Compare[int32],
+		}, nil
+	case bigint:
+		return &typedVector[int64]{
+			decode:  func(data []byte) int64 { return int64(binary.BigEndian.Uint64(data)) },
+			compare: cmp.Compare[int64],
+		}, nil
+	case varchar:
+		return &typedVector[string]{
+			decode:  func(data []byte) string { return string(bytes.TrimRight(data, "\x00")) },
+			compare: strings.Compare,
+		}, nil
+	case uniqueidentifier:
+		return &typedVector[uuid.UUID]{
+			decode:  func(data []byte) uuid.UUID { return uuid.UUID(data) },
+			compare: func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) },
+		}, nil
+	case timestamp:
+		return &typedVector[time.Time]{
+			decode:  func(data []byte) time.Time { return time.UnixMicro(int64(binary.BigEndian.Uint64(data))).UTC() },
+			compare: time.Time.Compare,
+		}, nil
+	case boolean:
+		return &typedVector[bool]{
+			decode:  func(data []byte) bool { return data[0] == 1 },
+			compare: func(a, b bool) int { return compareValues(a, b) },
+		}, nil
+	default:
+		return nil, errors.New("unhandled type")
+	}
+}
+
+func (v *typedVector[T]) append(data []byte) {
+	v.values = append(v.values, v.decode(data))
+	v.nulls = append(v.nulls, false)
+}
+
+func (v *typedVector[T]) appendNull() {
+	var zero T
+	v.values = append(v.values, zero)
+	v.nulls = append(v.nulls, true)
+}
+
+func (v *typedVector[T]) value(i int) any {
+	if v.nulls[i] {
+		return nil
+	}
+
+	return v.values[i]
+}
+
+func (v *typedVector[T]) reset() {
+	v.values, v.nulls = v.values[:0], v.nulls[:0]
+}
+
+func (v *typedVector[T]) filter(condition Condition, selection []int) []int {
+	sign, negated := baseSign(condition.sign)
+	if sign == "is null" {
+		kept := selection[:0]
+		for _, i := range selection {
+			if v.nulls[i] != negated {
+				kept = append(kept, i)
+			}
+		}
+
+		return kept
+	}
+
+	match := v.predicate(sign, condition.value)
+	if match == nil {
+		// patterns and values of other types are evaluated value by value
+		condition.sign = sign
+		match = func(value T) bool { return EvaluateCondition(condition, value) }
+	}
+
+	// comparison with null is never satisfied, also when negated
+	kept := selection[:0]
+	for _, i := range selection {
+		if !v.nulls[i] && match(v.values[i]) != negated {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
+
+// predicate compares values with condition value of the vector type, nil
+// when the value is of other type
+func (v *typedVector[T]) predicate(sign string, value any) func(T) bool {
+	switch sign {
+	case "in", "between":
+		values := []T{}
+		for _, item := range value.([]any) {
+			typed, ok := item.(T)
+			if !ok {
+				return nil
+			}
+			values = append(values, typed)
+		}
+
+		if sign == "in" {
+			return func(x T) bool {
+				return slices.ContainsFunc(values, func(y T) bool { return v.compare(x, y) == 0 })
+			}
+		}
+
+		return func(x T) bool { return v.compare(x, values[0]) >= 0 && v.compare(x, values[1]) <= 0 }
+	}
+
+	other, ok := value.(T)
+	if !ok {
+		return nil
+	}
+
+	switch sign {
+	case "=":
+		return func(x T) bool { return v.compare(x, other) == 0 }
+	case "!=":
+		return func(x T) bool { return v.compare(x, other) != 0 }
+	case ">":
+		return func(x T) bool { return v.compare(x, other) > 0 }
+	case ">=":
+		return func(x T) bool { return v.compare(x, other) >= 0 }
+	case "<":
+		return func(x T) bool { return v.compare(x, other) < 0 }
+	case "<=":
+		return func(x T) bool { return v.compare(x, other) <= 0 }
+	default:
+		return nil
+	}
+}
+
+// Batch holds columns of visible tuples of a page decoded into vectors, rows
+// are created only for positions left in selection by filters
+type Batch struct {
+	columns   []Column
+	positions []int // positions of decoded columns within table, bits of null bitmap
+	offsets   []int // offsets of decoded columns within tuple
+	vectors   []Vector
+	ids       []RowID
+	selection []int
+}
+
+// newBatch returns batch decoding given columns of table, columns are kept
+// in table order like by decodeRow
+func newBatch(table Table, names []string) (*Batch, error) {
+	batch := &Batch{}
+	offset := valuesOffset(table)
+	for i, cd := range table.columns {
+		if slices.Contains(names, cd.name) {
+			vector, err := newVector(cd.dataType)
+			if err != nil {
+				return nil, err
+			}
+
+			batch.columns = append(batch.columns, cd)
+			batch.positions = append(batch.positions, i)
+			batch.offsets = append(batch.offsets, offset)
+			batch.vectors = append(batch.vectors, vector)
+		}
+		offset += getDataTypeByteSize(cd.dataType)
+	}
+
+	return batch, nil
+}
+
+// decodePage replaces content of batch by tuples of page visible to
+// transaction, all of them are selected
+func (b *Batch) decodePage(tx *Transaction, page *Page) {
+	for _, vector := range b.vectors {
+		vector.reset()
+	}
+	b.ids, b.selection = b.ids[:0], b.selection[:0]
+
+	for slot := range page.slotCount() {
+		tuple := page.tuple(slot)
+		if tuple == nil || !tx.sees(tuple) {
+			continue
+		}
+
+		for i, vector := range b.vectors {
+			if isNull(tuple, b.positions[i]) {
+				vector.appendNull()
+				continue
+			}
+
+			vector.append(tuple[b.offsets[i] : b.offsets[i]+getDataTypeByteSize(b.columns[i].dataType)])
+		}
+
+		b.selection = append(b.selection, len(b.ids))
+		b.ids = append(b.ids, RowID{page: page.id, slot: slot})
+	}
+}
+
+// filter narrows selection to rows satisfying conditions converted into
+// column types, conditions of columns which weren't decoded are ignored
+func (b *Batch) filter(conditions []Condition) {
+	for i, cd := range b.columns {
+		for _, condition := range GetMatchingCondition(conditions, cd.name) {
+			b.selection = b.vectors[i].filter(condition, b.selection)
+		}
+	}
+}
+
+// row returns values of row at position of batch
+func (b *Batch) row(i int) Row {
+	row := Row{cells: make([]any, len(b.vectors))}
+	for j, vector := range b.vectors {
+		row.cells[j] = vector.value(i)
+	}
+
+	return row
+}
+
+// scanBatches calls fn with filtered batch of each table page
+func scanBatches(tx *Transaction, table Table, names []string, conditions []Condition, fn func(batch *Batch) error) error {
+	path := getTableDiskPath(table.schemaTable)
+	tx.read(path)
+
+	batch, err := newBatch(table, names)
+	if err != nil {
+		return err
+	}
+
+	count, err := bufferPool.numberOfPages(path)
+	if err != nil {
+		return err
+	}
+
+	for number := range count {
+		page, err := bufferPool.fetchPage(PageID{file: path, number: number})
+		if err != nil {
+			return err
+		}
+
+		// vectors hold copies of values so the page isn't needed anymore
+		batch.decodePage(tx, page)
+		bufferPool.unpinPage(page.id, false)
+
+		batch.filter(conditions)
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package auralis
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestVectorFilter(t *testing.T) {
+	testCases := map[string]struct {
+		dataType  DataType
+		values    any
+		nulls     []int // positions of null values
+		condition Condition
+		selection []int
+		expected  []int
+	}{
+		"comparison": {
+			dataType:  integer,
+			values:    []int32{5, 1, 7, 3},
+			condition: Condition{sign: ">=", value: int32(3)},
+			selection: []int{0, 1, 2, 3},
+			expected:  []int{0, 2, 3},
+		},
+		"only selected positions": {
+			dataType:  integer,
+			values:    []int32{5, 1, 7, 3},
+			condition: Condition{sign: ">=", value: int32(3)},
+			selection: []int{1, 2},
+			expected:  []int{2},
+		},
+		"negated between": {
+			dataType:  smallint,
+			values:    []int16{5, 1, 7, 3},
+			condition: Condition{sign: "not between", value: []any{int16(2), int16(5)}},
+			selection: []int{0, 1, 2, 3},
+			expected:  []int{1, 2},
+		},
+		"in": {
+			dataType:  varchar,
+			values:    []string{"a", "b", "c"},
+			condition: Condition{sign: "in", value: []any{"c", "a"}},
+			selection: []int{0, 1, 2},
+			expected:  []int{0, 2},
+		},
+		"pattern": {
+			dataType:  varchar,
+			values:    []string{"apple", "banana", "avocado"},
+			condition: Condition{sign: "not like", value: "'a%'"},
+			selection: []int{0, 1, 2},
+			expected:  []int{1},
+		},
+		"null is not matched by negated sign": {
+			dataType:  integer,
+			values:    []int32{5, 0, 7},
+			nulls:     []int{1},
+			condition: Condition{sign: "!=", value: int32(7)},
+			selection: []int{0, 1, 2},
+			expected:  []int{0},
+		},
+		"value of other integer type": {
+			dataType:  bigint,
+			values:    []int64{10, 20},
+			condition: Condition{sign: "=", value: int32(20)},
+			selection: []int{0, 1},
+			expected:  []int{1},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			vector, err := newVector(tC.dataType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nulls := make([]bool, reflect.ValueOf(tC.values).Len())
+			for _, i := range tC.nulls {
+				nulls[i] = true
+			}
+
+			switch values := tC.values.(type) {
+			case []int16:
+				vector.(*typedVector[int16]).values, vector.(*typedVector[int16]).nulls = values, nulls
+			case []int32:
+				vector.(*typedVector[int32]).values, vector.(*typedVector[int32]).nulls = values, nulls
+			case []int64:
+				vector.(*typedVector[int64]).values, vector.(*typedVector[int64]).nulls = values, nulls
+			case []string:
+				vector.(*typedVector[string]).values, vector.(*typedVector[string]).nulls = values, nulls
+			}
+
+			if selection := vector.filter(tC.condition, tC.selection); !reflect.DeepEqual(selection, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, selection)
+			}
+		})
+	}
+}
+
+func TestScanBatches(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id integer PRIMARY KEY, name varchar, price integer)",
+		"INSERT INTO items (id, name, price) VALUES (1, 'a', 10), (2, 'b', 20), (3, 'c', 30)",
+		"DELETE FROM items WHERE id = 2",
+	)
+
+	rows := []map[string]any{}
+	err := autocommit(func(tx *Transaction) error {
+		table, err := getTable(tx, SchemaTable[string, string]{defaultScheme, "items"})
+		if err != nil {
+			return err
+		}
+
+		conditions := []Condition{{target: "price", sign: "!=", value: int32(30)}}
+		return scanBatches(tx, table, []string{"name", "price"}, conditions, func(batch *Batch) error {
+			// columns are decoded in table order
+			for _, i := range batch.selection {
+				row := map[string]any{}
+				for j, value := range batch.row(i).cells {
+					row[batch.columns[j].name] = value
+				}
+				rows = append(rows, row)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deleted row isn't visible
+	expected := []map[string]any{{"name": "a", "price": int32(10)}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
+
+// BenchmarkScan compares decoding and filtering of rows one by one with batches
+// of vectors, about tenth of rows matches the condition
+func BenchmarkScan(b *testing.B) {
+	setupTestDatabase(b, "CREATE TABLE items (id integer PRIMARY KEY, name varchar, price integer)")
+
+	var table Table
+	err := autocommit(func(tx *Transaction) error {
+		var err error
+		if table, err = getTable(tx, SchemaTable[string, string]{defaultScheme, "items"}); err != nil {
+			return err
+		}
+
+		rows := []Row{}
+		for i := range 20000 {
+			row := Row{}
+			for _, cd := range table.columns {
+				switch cd.name {
+				case "id":
+					row.cells = append(row.cells, int32(i))
+				case "name":
+					row.cells = append(row.cells, fmt.Sprintf("item %d", i))
+				default:
+					row.cells = append(row.cells, int32(i%100))
+				}
+			}
+			rows = append(rows, row)
+		}
+
+		return writeIntoTable(tx, table, DataSet{columns: table.columns, rows: rows})
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	names := []string{"id", "name", "price"}
+	conditions := []Condition{{target: "price", sign: "<", value: int32(10)}}
+
+	b.Run("rows", func(b *testing.B) {
+		for b.Loop() {
+			count := 0
+			err := autocommit(func(tx *Transaction) error {
+				return scanTable(tx, table, func(id RowID, tuple []byte) error {
+					row, err := decodeRow(table, tuple, names)
+					if err != nil {
+						return err
+					}
+
+					if matchesRow(table.columns, row, conditions) {
+						count++
+					}
+
+					return nil
+				})
+			})
+			if err != nil || count != 2000 {
+				b.Fatal(count, err)
+			}
+		}
+	})
+
+	b.Run("batches", func(b *testing.B) {
+		for b.Loop() {
+			count := 0
+			err := autocommit(func(tx *Transaction) error {
+				return scanBatches(tx, table, names, conditions, func(batch *Batch) error {
+					for _, i := range batch.selection {
+						_ = batch.row(i)
+						count++
+					}
+
+					return nil
+				})
+			})
+			if err != nil || count != 2000 {
+				b.Fatal(count, err)
+			}
+		}
+	})
+}
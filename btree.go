@@ -0,0 +1,508 @@
+package auralis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"sort"
+)
+
+// B+tree node is stored after page header, page 0 of index file is always the root.
+// Node layout: leaf flag (1B), keys count (2B), next leaf (8B) followed by keys
+// prefixed by their length (2B). Internal node stores child page (8B) before
+// each key and one after the last key. Leaves are linked for range scans,
+// next leaf zero ends the chain as the root is never a sibling
+const nodeHeaderSize = 11
+
+// Node is decoded B+tree page, all keys of child i are smaller than key i and
+// at least as large as key i-1
+type Node struct {
+	leaf     bool
+	keys     [][]byte
+	children []int64
+	next     int64
+}
+
+func decodeNode(page *Page) Node {
+	data := page.data[pageHeaderSize:]
+	n := Node{leaf: data[0] == 1, next: int64(binary.BigEndian.Uint64(data[3:11]))}
+	count := int(binary.BigEndian.Uint16(data[1:3]))
+
+	offset := nodeHeaderSize
+	for i := 0; i <= count; i++ {
+		if !n.leaf {
+			n.children = append(n.children, int64(binary.BigEndian.Uint64(data[offset:offset+8])))
+			offset += 8
+		}
+
+		if i == count {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset : offset+2]))
+		n.keys = append(n.keys, slices.Clone(data[offset+2:offset+2+length]))
+		offset += 2 + length
+	}
+
+	return n
+}
+
+func (n Node) encode() []byte {
+	data := []byte{0}
+	if n.leaf {
+		data[0] = 1
+	}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(n.keys)))
+	data = binary.BigEndian.AppendUint64(data, uint64(n.next))
+
+	for i := 0; i <= len(n.keys); i++ {
+		if !n.leaf {
+			data = binary.BigEndian.AppendUint64(data, uint64(n.children[i]))
+		}
+
+		if i == len(n.keys) {
+			break
+		}
+
+		data = binary.BigEndian.AppendUint16(data, uint16(len(n.keys[i])))
+		data = append(data, n.keys[i]...)
+	}
+
+	return data
+}
+
+func (n Node) fits() bool {
+	return len(n.encode()) <= pageSize-pageHeaderSize
+}
+
+// split moves upper half of node into a new right node, separator is the
+// smallest key of right node
+func (n *Node) split() (Node, []byte) {
+	mid := len(n.keys) / 2
+	if n.leaf {
+		right := Node{leaf: true, keys: slices.Clone(n.keys[mid:]), next: n.next}
+		n.keys = n.keys[:mid]
+		return right, right.keys[0]
+	}
+
+	// separator of internal node moves to parent
+	separator := n.keys[mid]
+	right := Node{keys: slices.Clone(n.keys[mid+1:]), children: slices.Clone(n.children[mid+1:])}
+	n.keys, n.children = n.keys[:mid], n.children[:mid+1]
+	return right, separator
+}
+
+// child returns position of child which may contain key
+func (n Node) child(key []byte) int {
+	return sort.Search(len(n.keys), func(i int) bool { return bytes.Compare(n.keys[i], key) > 0 })
+}
+
+// writeNodePage stores node into page memory, page header is kept
+func writeNodePage(page *Page, image []byte) {
+	clear(page.data[pageHeaderSize:])
+	copy(page.data[pageHeaderSize:], image)
+}
+
+// BTree is B+tree stored in index file, changes are logged by images of changed
+// nodes and are never undone, entries pointing to removed rows are removed by vacuum
+type BTree struct {
+	file string
+}
+
+// nodeChanges collects changed nodes which are logged by a single record so
+// recovery never sees split applied only partially. Pages of index which
+// aren't nodes are changed by their images
+type nodeChanges struct {
+	file   string
+	nodes  map[int64]Node
+	images map[int64][]byte
+	order  []int64
+}
+
+func newNodeChanges(file string) *nodeChanges {
+	return &nodeChanges{file: file, nodes: map[int64]Node{}, images: map[int64][]byte{}}
+}
+
+func (c *nodeChanges) set(number int64, n Node) {
+	c.touch(number)
+	c.nodes[number] = n
+}
+
+func (c *nodeChanges) setImage(number int64, image []byte) {
+	c.touch(number)
+	c.images[number] = image
+}
+
+func (c *nodeChanges) touch(number int64) {
+	if !slices.Contains(c.order, number) {
+		c.order = append(c.order, number)
+	}
+}
+
+// allocate reserves a new page at the end of index file
+func (c *nodeChanges) allocate() (int64, error) {
+	page, err := bufferPool.newPage(c.file)
+	if err != nil {
+		return 0, err
+	}
+	bufferPool.unpinPage(page.id, true)
+
+	return page.id.number, nil
+}
+
+// apply logs changed nodes and writes them into pages
+func (c *nodeChanges) apply(tx *Transaction) error {
+	if len(c.order) == 0 {
+		return nil
+	}
+
+	pages := []*Page{}
+	defer func() {
+		for _, page := range pages {
+			bufferPool.unpinPage(page.id, true)
+		}
+	}()
+
+	images := []byte{}
+	encoded := [][]byte{}
+	for _, number := range c.order {
+		page, err := bufferPool.fetchPage(PageID{file: c.file, number: number})
+		if err != nil {
+			return err
+		}
+		pages = append(pages, page)
+
+		image := c.images[number]
+		if n, ok := c.nodes[number]; ok {
+			image = n.encode()
+		}
+		images = appendNodeImage(images, number, image)
+		encoded = append(encoded, image)
+	}
+
+	lsn := tx.log(LogRecord{kind: indexRecord, page: PageID{file: c.file}, tuple: images})
+	for i, page := range pages {
+		writeNodePage(page, encoded[i])
+		page.setLSN(lsn)
+	}
+
+	return nil
+}
+
+// appendNodeImage encodes node image of index record as page number (8B),
+// image length (2B) and the image
+func appendNodeImage(data []byte, number int64, image []byte) []byte {
+	data = binary.BigEndian.AppendUint64(data, uint64(number))
+	data = binary.BigEndian.AppendUint16(data, uint16(len(image)))
+	return append(data, image...)
+}
+
+func decodeNodeImages(data []byte) map[int64][]byte {
+	images := map[int64][]byte{}
+	for offset := 0; offset+10 <= len(data); {
+		number := int64(binary.BigEndian.Uint64(data[offset : offset+8]))
+		length := int(binary.BigEndian.Uint16(data[offset+8 : offset+10]))
+		images[number] = data[offset+10 : offset+10+length]
+		offset += 10 + length
+	}
+
+	return images
+}
+
+func (t BTree) node(number int64) (Node, error) {
+	page, err := bufferPool.fetchPage(PageID{file: t.file, number: number})
+	if err != nil {
+		return Node{}, err
+	}
+	defer bufferPool.unpinPage(page.id, false)
+
+	return decodeNode(page), nil
+}
+
+// insert adds key into tree, existing key is kept
+func (t BTree) insert(tx *Transaction, key []byte) error {
+	changes := newNodeChanges(t.file)
+	root, err := t.node(0)
+	if err != nil {
+		return err
+	}
+
+	split, err := t.insertInto(changes, 0, root, key)
+	if err != nil {
+		return err
+	}
+
+	// root stays at page 0, its halves move to new pages
+	if split != nil {
+		left, err := changes.allocate()
+		if err != nil {
+			return err
+		}
+
+		right, err := changes.allocate()
+		if err != nil {
+			return err
+		}
+
+		leftNode := changes.nodes[0]
+		if leftNode.leaf {
+			leftNode.next = right
+		}
+
+		changes.set(left, leftNode)
+		changes.set(right, split.node)
+		changes.set(0, Node{keys: [][]byte{split.separator}, children: []int64{left, right}})
+	}
+
+	return changes.apply(tx)
+}
+
+// nodeSplit is the right half of split node with its separator
+type nodeSplit struct {
+	node      Node
+	separator []byte
+}
+
+// insertInto inserts key into subtree, split of the root of subtree is
+// returned so parent can link the new node. Root split is left to the caller
+func (t BTree) insertInto(changes *nodeChanges, number int64, n Node, key []byte) (*nodeSplit, error) {
+	if n.leaf {
+		i, found := slices.BinarySearchFunc(n.keys, key, bytes.Compare)
+		if found {
+			return nil, nil
+		}
+		n.keys = slices.Insert(n.keys, i, key)
+	} else {
+		i := n.child(key)
+		child, err := t.node(n.children[i])
+		if err != nil {
+			return nil, err
+		}
+
+		split, err := t.insertInto(changes, n.children[i], child, key)
+		if err != nil || split == nil {
+			return nil, err
+		}
+
+		right, err := t.placeSplit(changes, n.children[i], split)
+		if err != nil {
+			return nil, err
+		}
+
+		n.keys = slices.Insert(n.keys, i, split.separator)
+		n.children = slices.Insert(n.children, i+1, right)
+	}
+
+	if n.fits() {
+		changes.set(number, n)
+		return nil, nil
+	}
+
+	right, separator := n.split()
+	changes.set(number, n)
+	return &nodeSplit{node: right, separator: separator}, nil
+}
+
+// placeSplit allocates page for right half of split child, leaf chain is
+// extended by the new leaf
+func (t BTree) placeSplit(changes *nodeChanges, left int64, split *nodeSplit) (int64, error) {
+	right, err := changes.allocate()
+	if err != nil {
+		return 0, err
+	}
+
+	changes.set(right, split.node)
+	if split.node.leaf {
+		leftNode := changes.nodes[left]
+		leftNode.next = right
+		changes.set(left, leftNode)
+	}
+
+	return right, nil
+}
+
+// KeyBound limits range scan by key prefix
+type KeyBound struct {
+	key       []byte
+	inclusive bool
+}
+
+// scan calls fn with keys between bounds in ascending order, nil bound is unlimited
+func (t BTree) scan(low *KeyBound, high *KeyBound, fn func(key []byte) error) error {
+	number := int64(0)
+	n, err := t.node(number)
+	if err != nil {
+		return err
+	}
+
+	for !n.leaf {
+		i := 0
+		if low != nil {
+			i = n.child(low.key)
+		}
+
+		number = n.children[i]
+		if n, err = t.node(number); err != nil {
+			return err
+		}
+	}
+
+	for {
+		for _, key := range n.keys {
+			if low != nil && !low.below(key) {
+				continue
+			}
+
+			if high != nil && !high.above(key) {
+				return nil
+			}
+
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+
+		if n.next == 0 {
+			return nil
+		}
+
+		if n, err = t.node(n.next); err != nil {
+			return err
+		}
+	}
+}
+
+// below reports whether key is within range starting at bound
+func (b KeyBound) below(key []byte) bool {
+	c := bytes.Compare(key[:min(len(key), len(b.key))], b.key)
+	return c > 0 || (c == 0 && b.inclusive)
+}
+
+// above reports whether key is within range ending at bound
+func (b KeyBound) above(key []byte) bool {
+	c := bytes.Compare(key[:min(len(key), len(b.key))], b.key)
+	return c < 0 || (c == 0 && b.inclusive)
+}
+
+// removeKeys removes keys rejected by keep from all leaves, leaves are
+// not merged
+func (t BTree) removeKeys(tx *Transaction, keep func(key []byte) (bool, error)) (int64, error) {
+	number := int64(0)
+	n, err := t.node(number)
+	if err != nil {
+		return 0, err
+	}
+
+	for !n.leaf {
+		number = n.children[0]
+		if n, err = t.node(number); err != nil {
+			return 0, err
+		}
+	}
+
+	removed := int64(0)
+	for {
+		kept := [][]byte{}
+		for _, key := range n.keys {
+			ok, err := keep(key)
+			if err != nil {
+				return 0, err
+			}
+
+			if ok {
+				kept = append(kept, key)
+			}
+		}
+
+		if len(kept) < len(n.keys) {
+			removed += int64(len(n.keys) - len(kept))
+			n.keys = kept
+
+			changes := newNodeChanges(t.file)
+			changes.set(number, n)
+			if err := changes.apply(tx); err != nil {
+				return 0, err
+			}
+		}
+
+		if n.next == 0 {
+			return removed, nil
+		}
+
+		number = n.next
+		if n, err = t.node(number); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// buildTree packs sorted keys into pages of a new tree, leaves are filled
+// first and the root is placed at page 0
+func buildTree(file string, keys [][]byte) []*Page {
+	level := []Node{{leaf: true}}
+	for _, key := range keys {
+		last := &level[len(level)-1]
+		last.keys = append(last.keys, key)
+		if !last.fits() {
+			last.keys = last.keys[:len(last.keys)-1]
+			level = append(level, Node{leaf: true, keys: [][]byte{key}})
+		}
+	}
+
+	// pages are numbered from 1, the last level is moved to page 0
+	nodes := []Node{}
+	first := [][]byte{}
+	for i := range level {
+		first = append(first, firstKey(level[i]))
+	}
+
+	for len(level) > 1 {
+		numbers := []int64{}
+		for i := range level {
+			numbers = append(numbers, int64(len(nodes)+1+i))
+		}
+
+		for i := range level {
+			if level[i].leaf && i+1 < len(level) {
+				level[i].next = numbers[i+1]
+			}
+		}
+		nodes = append(nodes, level...)
+
+		parents := []Node{{children: []int64{numbers[0]}}}
+		parentFirst := [][]byte{first[0]}
+		for i := 1; i < len(level); i++ {
+			last := &parents[len(parents)-1]
+			last.keys = append(last.keys, first[i])
+			last.children = append(last.children, numbers[i])
+			if !last.fits() {
+				last.keys, last.children = last.keys[:len(last.keys)-1], last.children[:len(last.children)-1]
+				parents = append(parents, Node{children: []int64{numbers[i]}})
+				parentFirst = append(parentFirst, first[i])
+			}
+		}
+
+		level, first = parents, parentFirst
+	}
+
+	nodes = append([]Node{level[0]}, nodes...)
+	pages := []*Page{}
+	for number, n := range nodes {
+		page := &Page{id: PageID{file: file, number: int64(number)}}
+		page.init()
+		writeNodePage(page, n.encode())
+		pages = append(pages, page)
+	}
+
+	return pages
+}
+
+// firstKey returns the smallest key of subtree, node of built level keeps
+// its smallest key as the first one
+func firstKey(n Node) []byte {
+	if len(n.keys) == 0 {
+		return nil
+	}
+
+	return n.keys[0]
+}
@@ -0,0 +1,126 @@
+package auralis
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// treeKeys returns keys large enough to split nodes on a few levels
+func treeKeys(count int) [][]byte {
+	keys := [][]byte{}
+	for i := range count {
+		keys = append(keys, append([]byte(fmt.Sprintf("%06d", i)), make([]byte, 300)...))
+	}
+
+	return keys
+}
+
+func createTree(t *testing.T, keys [][]byte) BTree {
+	t.Helper()
+
+	tree := BTree{file: dataPath + "/dbo.test.index"}
+	err := autocommit(func(tx *Transaction) error {
+		return replaceFiles(tx, map[string][]*Page{tree.file: buildTree(tree.file, keys)})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tree
+}
+
+func scanKeys(t *testing.T, tree BTree, low *KeyBound, high *KeyBound) [][]byte {
+	t.Helper()
+
+	keys := [][]byte{}
+	err := tree.scan(low, high, func(key []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keys
+}
+
+func TestBTree(t *testing.T) {
+	keys := treeKeys(2000)
+
+	testCases := map[string]struct {
+		build func(t *testing.T) BTree
+	}{
+		"keys inserted in random order": {
+			build: func(t *testing.T) BTree {
+				tree := createTree(t, nil)
+				err := autocommit(func(tx *Transaction) error {
+					for _, i := range rand.Perm(len(keys)) {
+						if err := tree.insert(tx, keys[i]); err != nil {
+							return err
+						}
+					}
+
+					// existing key is kept once
+					return tree.insert(tx, keys[0])
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return tree
+			},
+		},
+		"keys loaded in bulk": {
+			build: func(t *testing.T) BTree {
+				return createTree(t, keys)
+			},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t)
+			tree := tC.build(t)
+
+			root, err := tree.node(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if child, err := tree.node(root.children[0]); err != nil || child.leaf {
+				t.Fatalf("\nexp tree of three levels\ngot root %+v child %+v err %v", root.leaf, child.leaf, err)
+			}
+
+			if scanned := scanKeys(t, tree, nil, nil); !reflect.DeepEqual(scanned, keys) {
+				t.Fatalf("\nexp %+v keys\ngot %+v keys", len(keys), len(scanned))
+			}
+
+			scanned := scanKeys(t, tree, &KeyBound{key: []byte("000100")}, &KeyBound{key: []byte("000200"), inclusive: true})
+			if !reflect.DeepEqual(scanned, keys[101:201]) {
+				t.Fatalf("\nexp %+v keys\ngot %+v keys", 100, len(scanned))
+			}
+
+			err = autocommit(func(tx *Transaction) error {
+				removed, err := tree.removeKeys(tx, func(key []byte) (bool, error) {
+					return key[5]%2 == 0, nil
+				})
+				if removed != 1000 {
+					t.Errorf("\nexp %+v\ngot %+v", 1000, removed)
+				}
+
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := slices.DeleteFunc(slices.Clone(keys), func(key []byte) bool { return key[5]%2 == 1 })
+			if scanned := scanKeys(t, tree, nil, nil); !slices.EqualFunc(scanned, expected, bytes.Equal) {
+				t.Fatalf("\nexp %+v keys\ngot %+v keys", len(expected), len(scanned))
+			}
+		})
+	}
+}
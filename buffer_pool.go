@@ -0,0 +1,345 @@
+package auralis
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+const defaultBufferPoolSize = 256 // pages, 2 MiB
+
+var (
+	ErrBufferPoolFull = AuraError{Code: "BUFFER_POOL_FULL", Message: "all buffer pool pages are pinned"}
+)
+
+// frame holds single page cached within buffer pool
+type frame struct {
+	page       Page
+	valid      bool
+	pinCount   int
+	dirty      bool
+	referenced bool // second chance bit of clock eviction
+}
+
+// BufferPool caches table pages in memory, pages are evicted with clock algorithm
+// approximating LRU. Pinned pages are never evicted and dirty pages are written
+// back before eviction
+type BufferPool struct {
+	mu        sync.Mutex
+	frames    []frame
+	pageTable map[PageID]int // page to frame index
+	hand      int
+	files     map[string]*os.File
+	pageCount map[string]int64 // includes pages allocated but not written yet
+}
+
+var bufferPool = newBufferPool(defaultBufferPoolSize)
+
+func newBufferPool(size int) *BufferPool {
+	return &BufferPool{
+		frames:    make([]frame, size),
+		pageTable: map[PageID]int{},
+		files:     map[string]*os.File{},
+		pageCount: map[string]int64{},
+	}
+}
+
+// fetchPage returns pinned page, page has to be unpinned when no longer used
+func (bp *BufferPool) fetchPage(id PageID) (*Page, error) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	if i, ok := bp.pageTable[id]; ok {
+		bp.frames[i].pinCount++
+		bp.frames[i].referenced = true
+		return &bp.frames[i].page, nil
+	}
+
+	i, err := bp.victim()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := bp.file(id.file)
+	if err != nil {
+		return nil, err
+	}
+
+	fr := &bp.frames[i]
+	fr.page.id = id
+	clear(fr.page.data[:])
+	if _, err := f.ReadAt(fr.page.data[:], id.number*pageSize); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// page allocated but never written is read as zeros
+	if !fr.page.initialized() {
+		fr.page.init()
+	}
+
+	// page beyond end of file is allocated eg. by recovery
+	if count, err := bp.countPages(id.file); err == nil && id.number >= count {
+		bp.pageCount[id.file] = id.number + 1
+	}
+
+	bp.place(i, id)
+	return &fr.page, nil
+}
+
+// newPage allocates empty pinned page at the end of file
+func (bp *BufferPool) newPage(file string) (*Page, error) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	count, err := bp.countPages(file)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := bp.victim()
+	if err != nil {
+		return nil, err
+	}
+
+	id := PageID{file: file, number: count}
+	fr := &bp.frames[i]
+	fr.page.id = id
+	fr.page.init()
+
+	bp.place(i, id)
+	fr.dirty = true
+	bp.pageCount[file] = count + 1
+
+	return &fr.page, nil
+}
+
+// unpinPage releases page pin, dirty marks page as modified by the caller
+func (bp *BufferPool) unpinPage(id PageID, dirty bool) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	i, ok := bp.pageTable[id]
+	if !ok {
+		return
+	}
+
+	fr := &bp.frames[i]
+	if fr.pinCount > 0 {
+		fr.pinCount--
+	}
+	fr.dirty = fr.dirty || dirty
+}
+
+// numberOfPages returns number of pages of file
+func (bp *BufferPool) numberOfPages(file string) (int64, error) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	return bp.countPages(file)
+}
+
+// dropFile discards cached pages of file without writing them and closes the file
+func (bp *BufferPool) dropFile(file string) error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	for i := range bp.frames {
+		if bp.frames[i].valid && bp.frames[i].page.id.file == file {
+			delete(bp.pageTable, bp.frames[i].page.id)
+			bp.frames[i] = frame{}
+		}
+	}
+	delete(bp.pageCount, file)
+
+	if f, ok := bp.files[file]; ok {
+		delete(bp.files, file)
+		return f.Close()
+	}
+
+	return nil
+}
+
+// truncateFile discards cached pages from count on without writing them and
+// shortens file to count pages, longer file is not extended
+func (bp *BufferPool) truncateFile(file string, count int64) error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	current, err := bp.countPages(file)
+	if err != nil || count >= current {
+		return err
+	}
+
+	for i := range bp.frames {
+		id := bp.frames[i].page.id
+		if bp.frames[i].valid && id.file == file && id.number >= count {
+			delete(bp.pageTable, id)
+			bp.frames[i] = frame{}
+		}
+	}
+
+	f, err := bp.file(file)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Truncate(count * pageSize); err != nil {
+		return err
+	}
+
+	bp.pageCount[file] = count
+	return nil
+}
+
+// flushAll writes all dirty pages to disk
+func (bp *BufferPool) flushAll() error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	for i := range bp.frames {
+		if err := bp.writeBack(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sync writes all dirty pages and syncs files to disk
+func (bp *BufferPool) sync() error {
+	if err := bp.flushAll(); err != nil {
+		return err
+	}
+
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	for _, f := range bp.files {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close flushes dirty pages and closes all files, files are closed even when
+// pages can't be written
+func (bp *BufferPool) close() error {
+	errs := []error{bp.flushAll()}
+
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	for name, f := range bp.files {
+		errs = append(errs, f.Close())
+		delete(bp.files, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// victim returns index of free frame or evicts unpinned page, referenced pages
+// get a second chance and are evicted only when the hand visits them again
+func (bp *BufferPool) victim() (int, error) {
+	// two full sweeps clear all reference bits
+	for range 2*len(bp.frames) + 1 {
+		i := bp.hand
+		bp.hand = (bp.hand + 1) % len(bp.frames)
+
+		fr := &bp.frames[i]
+		if !fr.valid {
+			return i, nil
+		}
+
+		if fr.pinCount > 0 {
+			continue
+		}
+
+		if fr.referenced {
+			fr.referenced = false
+			continue
+		}
+
+		if err := bp.writeBack(i); err != nil {
+			return 0, err
+		}
+
+		delete(bp.pageTable, fr.page.id)
+		fr.valid = false
+		return i, nil
+	}
+
+	return 0, ErrBufferPoolFull
+}
+
+func (bp *BufferPool) place(i int, id PageID) {
+	bp.frames[i] = frame{page: bp.frames[i].page, valid: true, pinCount: 1, referenced: true}
+	bp.pageTable[id] = i
+}
+
+func (bp *BufferPool) writeBack(i int) error {
+	fr := &bp.frames[i]
+	if !fr.valid || !fr.dirty {
+		return nil
+	}
+
+	// log records describing page changes have to be on disk first
+	if writeAheadLog != nil {
+		if err := writeAheadLog.flush(fr.page.lsn()); err != nil {
+			return err
+		}
+	}
+
+	f, err := bp.file(fr.page.id.file)
+	if err != nil {
+		return err
+	}
+
+	if err := physicalWrite(f, "page", fr.page.data[:], fr.page.id.number*pageSize); err != nil {
+		return err
+	}
+
+	fr.dirty = false
+	return nil
+}
+
+// file returns open file, files stay open across statements
+func (bp *BufferPool) file(name string) (*os.File, error) {
+	if f, ok := bp.files[name]; ok {
+		return f, nil
+	}
+
+	f, err := os.OpenFile(name, os.O_RDWR, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrTableNotFound
+		}
+
+		return nil, err
+	}
+
+	bp.files[name] = f
+	return f, nil
+}
+
+func (bp *BufferPool) countPages(file string) (int64, error) {
+	if count, ok := bp.pageCount[file]; ok {
+		return count, nil
+	}
+
+	f, err := bp.file(file)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	count := (info.Size() + pageSize - 1) / pageSize
+	bp.pageCount[file] = count
+	return count, nil
+}
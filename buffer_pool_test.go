@@ -0,0 +1,187 @@
+package auralis
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func createPagesFile(t *testing.T, pages int) string {
+	t.Helper()
+
+	path := t.TempDir() + "/pages"
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i := range pages {
+		page := Page{}
+		page.init()
+		page.insertTuple([]byte{byte(i)})
+		if _, err := f.Write(page.data[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+func TestBufferPoolEviction(t *testing.T) {
+	path := createPagesFile(t, 4)
+	bp := newBufferPool(2)
+	defer bp.close()
+
+	for i := range int64(4) {
+		page, err := bp.fetchPage(PageID{file: path, number: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if page.tuple(0)[0] != byte(i) {
+			t.Errorf("\nexp %+v\ngot %+v", i, page.tuple(0)[0])
+		}
+		bp.unpinPage(page.id, false)
+	}
+
+	if len(bp.pageTable) != 2 {
+		t.Errorf("\nexp %+v\ngot %+v", 2, len(bp.pageTable))
+	}
+
+	// recently used page stays cached
+	bp.fetchPage(PageID{file: path, number: 3})
+	bp.unpinPage(PageID{file: path, number: 3}, false)
+	bp.fetchPage(PageID{file: path, number: 0})
+	bp.unpinPage(PageID{file: path, number: 0}, false)
+	if _, ok := bp.pageTable[PageID{file: path, number: 3}]; !ok {
+		t.Errorf("recently used page evicted")
+	}
+}
+
+func TestBufferPoolPinnedPages(t *testing.T) {
+	path := createPagesFile(t, 3)
+	bp := newBufferPool(2)
+	defer bp.close()
+
+	first := PageID{file: path, number: 0}
+	if _, err := bp.fetchPage(first); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bp.fetchPage(PageID{file: path, number: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bp.fetchPage(PageID{file: path, number: 2}); err != ErrBufferPoolFull {
+		t.Fatalf("\nexp %+v\ngot %+v", ErrBufferPoolFull, err)
+	}
+
+	bp.unpinPage(first, false)
+	page, err := bp.fetchPage(PageID{file: path, number: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := bp.pageTable[first]; ok || page.tuple(0)[0] != 2 {
+		t.Errorf("expected unpinned page to be replaced")
+	}
+}
+
+func TestBufferPoolDirtyPages(t *testing.T) {
+	path := createPagesFile(t, 2)
+	bp := newBufferPool(1)
+	defer bp.close()
+
+	page, err := bp.fetchPage(PageID{file: path, number: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page.insertTuple([]byte("modified"))
+	bp.unpinPage(page.id, true)
+
+	// eviction writes modified page back
+	page, err = bp.fetchPage(PageID{file: path, number: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp.unpinPage(page.id, false)
+
+	page, err = bp.fetchPage(PageID{file: path, number: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(page.tuple(1)) != "modified" {
+		t.Errorf("\nexp %+v\ngot %+v", "modified", string(page.tuple(1)))
+	}
+	bp.unpinPage(page.id, false)
+
+	allocated, err := bp.newPage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp.unpinPage(allocated.id, true)
+
+	if count, _ := bp.numberOfPages(path); allocated.id.number != 2 || count != 3 {
+		t.Errorf("unexpected allocated page %d of %d pages", allocated.id.number, count)
+	}
+}
+
+func TestTableSpanningPages(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+
+	var table Table
+	err := autocommit(func(tx *Transaction) (err error) {
+		table, err = getTable(tx, SchemaTable[string, string]{defaultScheme, "items"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []Row{}
+	for i := range 1000 {
+		row := Row{}
+		for _, cd := range table.columns {
+			if cd.dataType == smallint {
+				row.cells = append(row.cells, int16(i))
+			} else {
+				row.cells = append(row.cells, "item")
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	err = autocommit(func(tx *Transaction) error {
+		return writeIntoTable(tx, table, DataSet{columns: table.columns, rows: rows})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pages are read back from disk through pool smaller than the table
+	if err := bufferPool.close(); err != nil {
+		t.Fatal(err)
+	}
+	bufferPool = newBufferPool(2)
+
+	count, err := bufferPool.numberOfPages(getTableDiskPath(table.schemaTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1000 rows of 18B with 16B headers and 4B slots
+	if count != 5 {
+		t.Errorf("\nexp %+v\ngot %+v", 5, count)
+	}
+
+	dataSet, err := ExecuteQuery("SELECT count(*), max(id) FROM items WHERE id >= 100")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{int64(900), int16(999)}}
+	if !reflect.DeepEqual(resultCells(dataSet), expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, resultCells(dataSet))
+	}
+}
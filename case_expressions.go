@@ -0,0 +1,218 @@
+package auralis
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CaseExpression is a searched case when operand is nil, otherwise simple case
+// comparing operand with value of each branch
+type CaseExpression struct {
+	operand  Expression
+	branches []CaseBranch
+	fallback Expression // result of else clause, nil when omitted
+}
+
+type CaseBranch struct {
+	conditions []Condition // searched case conditions joined with and
+	value      Expression  // simple case value compared with operand
+	result     Expression
+}
+
+var ErrDataTypeMismatch = AuraError{Code: "DATATYPE_MISMATCH", Message: "case result types cannot be matched"}
+
+func (c CaseExpression) String() string {
+	return "case"
+}
+
+// evaluateCaseExpression returns result of the first matching branch converted to
+// the unified result type, without matching branch and else clause result is null
+func evaluateCaseExpression(expr CaseExpression, columns []Column, row Row) (any, error) {
+	var operand any
+	if expr.operand != nil {
+		v, err := evaluateExpression(expr.operand, columns, row)
+		if err != nil {
+			return nil, err
+		}
+
+		operand = v
+	}
+
+	result := expr.fallback
+	for _, branch := range expr.branches {
+		var matches bool
+		if expr.operand != nil {
+			value, err := evaluateExpression(branch.value, columns, row)
+			if err != nil {
+				return nil, err
+			}
+
+			matches = operand != nil && value != nil && compareValues(operand, value) == 0
+		} else {
+			ok, err := matchesConditions(columns, row, branch.conditions)
+			if err != nil {
+				return nil, err
+			}
+
+			matches = ok
+		}
+
+		if matches {
+			result = branch.result
+			break
+		}
+	}
+
+	if result == nil {
+		return nil, nil
+	}
+
+	value, err := evaluateExpression(result, columns, row)
+	if err != nil {
+		return nil, err
+	}
+
+	dataType, err := caseDataType(expr, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToDataType(value, dataType), nil
+}
+
+// caseDataType unifies types of all branch results, numeric types are widened
+// to the widest one, other types have to be equal
+func caseDataType(expr CaseExpression, columns []Column) (DataType, error) {
+	results := []Expression{}
+	for _, branch := range expr.branches {
+		results = append(results, branch.result)
+	}
+
+	if expr.fallback != nil {
+		results = append(results, expr.fallback)
+	}
+
+	unified := unknown
+	for _, result := range results {
+		dataType := expressionDataType(result, columns)
+		switch {
+		case dataType == unknown || dataType == unified:
+		case unified == unknown:
+			unified = dataType
+		case isNumericType(unified) && isNumericType(dataType):
+			if slices.Index(numericTypes, dataType) > slices.Index(numericTypes, unified) {
+				unified = dataType
+			}
+		default:
+			return unknown, AuraError{
+				Code:    ErrDataTypeMismatch.Code,
+				Message: fmt.Sprintf("case types %s and %s cannot be matched", unified, dataType)}
+		}
+	}
+
+	return unified, nil
+}
+
+// numericTypes are ordered from the narrowest
+var numericTypes = []DataType{smallint, integer, bigint, double}
+
+func isNumericType(dataType DataType) bool {
+	return slices.Contains(numericTypes, dataType)
+}
+
+// convertToDataType widens numeric value to given numeric type
+func convertToDataType(value any, dataType DataType) any {
+	if !isNumber(value) {
+		return value
+	}
+
+	switch dataType {
+	case integer:
+		return int32(toInt64(value))
+	case bigint:
+		return toInt64(value)
+	case double:
+		return toFloat64(value)
+	default:
+		return value
+	}
+}
+
+// caseSubexpressions returns all expressions case consists of
+func caseSubexpressions(expr CaseExpression) []Expression {
+	exprs := []Expression{}
+	if expr.operand != nil {
+		exprs = append(exprs, expr.operand)
+	}
+
+	for _, branch := range expr.branches {
+		for _, condition := range branch.conditions {
+			exprs = append(exprs, condition.expr)
+			if value, ok := condition.value.(Expression); ok {
+				exprs = append(exprs, value)
+			}
+		}
+
+		if branch.value != nil {
+			exprs = append(exprs, branch.value)
+		}
+		exprs = append(exprs, branch.result)
+	}
+
+	if expr.fallback != nil {
+		exprs = append(exprs, expr.fallback)
+	}
+
+	return exprs
+}
+
+func rewriteCaseExpression(expr CaseExpression,
+	replace func(Expression) (Expression, bool, error)) (Expression, error) {
+	rewrite := func(e Expression) (Expression, error) {
+		if e == nil {
+			return nil, nil
+		}
+
+		return rewriteExpression(e, replace)
+	}
+
+	operand, err := rewrite(expr.operand)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback, err := rewrite(expr.fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	rewritten := CaseExpression{operand: operand, fallback: fallback}
+	for _, branch := range expr.branches {
+		b := CaseBranch{}
+		for _, condition := range branch.conditions {
+			if condition.expr, err = rewrite(condition.expr); err != nil {
+				return nil, err
+			}
+
+			if value, ok := condition.value.(Expression); ok {
+				if condition.value, err = rewrite(value); err != nil {
+					return nil, err
+				}
+			}
+
+			b.conditions = append(b.conditions, condition)
+		}
+
+		if b.value, err = rewrite(branch.value); err != nil {
+			return nil, err
+		}
+
+		if b.result, err = rewrite(branch.result); err != nil {
+			return nil, err
+		}
+
+		rewritten.branches = append(rewritten.branches, b)
+	}
+
+	return rewritten, nil
+}
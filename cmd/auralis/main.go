@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/font3r/auralis"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+func main() {
+	args := os.Args
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s QUERY\n", filepath.Base(args[0]))
+		os.Exit(2)
+	}
+
+	db, err := auralis.Open(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	dataSet, err := db.Exec(context.Background(), args[1])
+	if err != nil {
+		db.Close()
+		log.Fatal(err)
+	}
+
+	fmt.Println()
+	switch {
+	case dataSet != nil && dataSet.Columns() != nil:
+		displayDataSet(dataSet)
+	case dataSet != nil:
+		fmt.Printf("%d rows affected\n", dataSet.RowsAffected())
+	default:
+		log.Printf("NO RESULT\n")
+	}
+}
+
+func displayDataSet(dataSet *auralis.DataSet) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAutoIndex(true)
+
+	style := table.StyleDefault
+	style.Format.Header = text.FormatDefault
+	t.SetStyle(style)
+
+	tableHeader := table.Row{}
+	for _, cd := range dataSet.Columns() {
+		tableHeader = append(tableHeader, cd.Name())
+	}
+	t.AppendHeader(tableHeader)
+
+	for _, dataRow := range dataSet.Rows() {
+		tableRow := table.Row{}
+		for _, dataCell := range dataRow {
+			if dataCell == nil {
+				tableRow = append(tableRow, "NULL")
+				continue
+			}
+
+			if t, ok := dataCell.(time.Time); ok {
+				tableRow = append(tableRow, t.Format("2006-01-02 15:04:05.999999"))
+				continue
+			}
+
+			tableRow = append(tableRow, fmt.Sprintf("%v", dataCell))
+		}
+		t.AppendRow(tableRow)
+	}
+
+	t.Render()
+}
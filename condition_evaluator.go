@@ -1,15 +1,36 @@
-package main
+package auralis
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Condition struct {
 	target string
+	expr   Expression // compared expression when target is not a plain column
 	sign   string
-	value  any
+	value  any    // raw literal, column name or expression
+	escape string // escape character of like patterns, backslash when empty
 }
 
+var (
+	ErrInvalidPattern       = AuraError{Code: "INVALID_PATTERN", Message: "invalid pattern"}
+	ErrInvalidConditionType = AuraError{
+		Code:    "INVALID_CONDITION",
+		Message: "pattern matching is supported only for character types"}
+)
+
 func ConvertConditionType(td Table, cond *Condition) error {
 	for _, cd := range td.columns {
 		if cond.target == cd.name {
-			value, err := ConvertToConcreteType(cd.dataType, cond.value)
+			value, err := convertConditionValue(cd.dataType, *cond)
 			if err != nil {
 				return err
 			}
@@ -21,13 +42,201 @@ func ConvertConditionType(td Table, cond *Condition) error {
 	return nil
 }
 
+// convertConditionValue converts raw condition value into value comparable with
+// column of given type, patterns are compiled into regular expressions
+func convertConditionValue(dataType DataType, cond Condition) (any, error) {
+	sign, _ := baseSign(cond.sign)
+
+	switch sign {
+	case "is null":
+		return nil, nil
+	case "in", "between":
+		values := []any{}
+		for _, v := range cond.value.([]any) {
+			value, err := ConvertToConcreteType(dataType, v)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		return values, nil
+	case "like", "ilike", "~", "~*":
+		if dataType != varchar {
+			return nil, ErrInvalidConditionType
+		}
+
+		pattern := unquote(cond.value.(string))
+		if sign == "like" || sign == "ilike" {
+			return likePattern(pattern, cond.escape, sign == "ilike")
+		}
+
+		if sign == "~*" {
+			pattern = "(?i)" + pattern
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, ErrInvalidPattern
+		}
+
+		return re, nil
+	default:
+		return ConvertToConcreteType(dataType, cond.value)
+	}
+}
+
+// likePattern translates like pattern where % matches any sequence of characters
+// and _ matches single character into anchored regular expression
+func likePattern(pattern string, escape string, caseInsensitive bool) (*regexp.Regexp, error) {
+	escapeChar := '\\'
+	if escape != "" {
+		runes := []rune(escape)
+		if len(runes) != 1 {
+			return nil, AuraError{Code: "INVALID_PATTERN", Message: "escape string must be a single character"}
+		}
+		escapeChar = runes[0]
+	}
+
+	var b strings.Builder
+	b.WriteString("(?s)")
+	if caseInsensitive {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == escapeChar:
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	if escaped {
+		return nil, AuraError{Code: "INVALID_PATTERN", Message: "like pattern must not end with escape character"}
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// baseSign strips negation of condition sign, eg. not like -> like, !~ -> ~,
+// is not null -> is null
+func baseSign(sign string) (string, bool) {
+	if s, ok := strings.CutPrefix(sign, "not "); ok {
+		return s, true
+	}
+
+	if sign == "is not null" {
+		return "is null", true
+	}
+
+	if strings.HasPrefix(sign, "!~") {
+		return sign[1:], true
+	}
+
+	return sign, false
+}
+
 func GetMatchingCondition(conditions []Condition, target string) []Condition {
 	return filter(conditions, func(c Condition) bool {
 		return c.target == target
 	})
 }
 
+// isNullCondition reports whether condition tests value for null, it has no
+// value to compare with
+func isNullCondition(cond Condition) bool {
+	sign, _ := baseSign(cond.sign)
+	return sign == "is null"
+}
+
+// EvaluateCondition checks value of any supported type against condition
+func EvaluateCondition(cond Condition, value any) bool {
+	sign, negated := baseSign(cond.sign)
+	if sign == "is null" {
+		return (value == nil) != negated
+	}
+
+	// comparison with null is never satisfied, also when negated
+	if value == nil {
+		return false
+	}
+
+	if negated {
+		cond.sign = sign
+		return !EvaluateCondition(cond, value)
+	}
+
+	switch sign {
+	case "in":
+		return slices.ContainsFunc(cond.value.([]any), func(v any) bool {
+			return compareValues(value, v) == 0
+		})
+	case "between":
+		bounds := cond.value.([]any)
+		return compareValues(value, bounds[0]) >= 0 && compareValues(value, bounds[1]) <= 0
+	}
+
+	switch value := value.(type) {
+	case int16:
+		return EvaluateIntCondition(cond, value)
+	case int32:
+		return EvaluateIntCondition(cond, value)
+	case int64:
+		return EvaluateIntCondition(cond, value)
+	case string:
+		return EvaluateStringCondition(cond, value)
+	case uuid.UUID:
+		return EvaluateUUIDCondition(cond, value)
+	case time.Time:
+		return EvaluateTimestampCondition(cond, value)
+	case bool:
+		return EvaluateBooleanCondition(cond, value)
+	default:
+		panic("invalid condition value type")
+	}
+}
+
 func EvaluateIntCondition[V int16 | int32 | int64](cond Condition, value V) bool {
+	// compare on widest type, condition value may come from column of other integer type
+	l, r := int64(value), toInt64(cond.value)
+
+	switch cond.sign {
+	case "=":
+		return l == r
+	case "!=":
+		return l != r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	default:
+		panic("invalid condition sign")
+	}
+}
+
+func EvaluateStringCondition[V string](cond Condition, value V) bool {
+	if sign, negated := baseSign(cond.sign); negated {
+		cond.sign = sign
+		return !EvaluateStringCondition(cond, value)
+	}
+
 	switch cond.sign {
 	case "=":
 		return value == cond.value.(V)
@@ -41,22 +250,171 @@ func EvaluateIntCondition[V int16 | int32 | int64](cond Condition, value V) bool
 		return value < cond.value.(V)
 	case "<=":
 		return value <= cond.value.(V)
+	case "like", "ilike", "~", "~*":
+		re, ok := cond.value.(*regexp.Regexp)
+		if !ok {
+			// pattern was not converted upfront
+			v, err := convertConditionValue(varchar, cond)
+			if err != nil {
+				return false
+			}
+			re = v.(*regexp.Regexp)
+		}
+
+		return re.MatchString(string(value))
 	default:
 		panic("invalid condition sign")
 	}
 }
 
-func EvaluateStringCondition[V string](cond Condition, value V) bool {
+func EvaluateUUIDCondition(cond Condition, value uuid.UUID) bool {
 	switch cond.sign {
 	case "=":
-		return value == cond.value.(V)
+		return value == cond.value.(uuid.UUID)
 	case "!=":
-		return value != cond.value.(V)
+		return value != cond.value.(uuid.UUID)
+	default:
+		panic("invalid condition sign")
+	}
+}
+
+func EvaluateTimestampCondition(cond Condition, value time.Time) bool {
+	other, ok := cond.value.(time.Time)
+	if !ok {
+		return false
+	}
+
+	switch cond.sign {
+	case "=":
+		return value.Equal(other)
+	case "!=":
+		return !value.Equal(other)
+	case ">":
+		return value.After(other)
+	case ">=":
+		return !value.Before(other)
+	case "<":
+		return value.Before(other)
+	case "<=":
+		return !value.After(other)
+	default:
+		panic("invalid condition sign")
+	}
+}
+
+// EvaluateBooleanCondition compares booleans, false is ordered before true
+func EvaluateBooleanCondition(cond Condition, value bool) bool {
+	other, ok := cond.value.(bool)
+	if !ok {
+		return false
+	}
+
+	c := compareValues(value, other)
+	switch cond.sign {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
 	default:
 		panic("invalid condition sign")
 	}
 }
 
+func toInt64(value any) int64 {
+	switch value := value.(type) {
+	case int:
+		return int64(value)
+	case int16:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	default:
+		panic("invalid integer value")
+	}
+}
+
+// compareValues orders values of supported types, null is ordered after any value
+func compareValues(a any, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		default:
+			return -1
+		}
+	}
+
+	if isInteger(a) && isInteger(b) {
+		return cmp.Compare(toInt64(a), toInt64(b))
+	}
+
+	if isNumber(a) && isNumber(b) {
+		return cmp.Compare(toFloat64(a), toFloat64(b))
+	}
+
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	case uuid.UUID:
+		if b, ok := b.(uuid.UUID); ok {
+			return bytes.Compare(a[:], b[:])
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0
+			case !a:
+				return -1
+			default:
+				return 1
+			}
+		}
+	}
+
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+func isInteger(value any) bool {
+	switch value.(type) {
+	case int, int16, int32, int64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNumber(value any) bool {
+	_, ok := value.(float64)
+	return ok || isInteger(value)
+}
+
+func toFloat64(value any) float64 {
+	if v, ok := value.(float64); ok {
+		return v
+	}
+
+	return float64(toInt64(value))
+}
+
 func filter[T any](items []T, predicate func(T) bool) []T {
 	var result []T
 	for _, item := range items {
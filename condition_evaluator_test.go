@@ -1,4 +1,4 @@
-package main
+package auralis
 
 import "testing"
 
@@ -76,3 +76,137 @@ func TestEvaluateIntCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateStringCondition(t *testing.T) {
+	testCases := map[string]struct {
+		condition Condition
+		value     string
+
+		expected bool
+	}{
+		"abc < abd": {
+			condition: Condition{sign: "<", value: "abd"}, value: "abc",
+			expected: true,
+		},
+		"b <= a": {
+			condition: Condition{sign: "<=", value: "a"}, value: "b",
+			expected: false,
+		},
+		"b > a": {
+			condition: Condition{sign: ">", value: "a"}, value: "b",
+			expected: true,
+		},
+		"a >= a": {
+			condition: Condition{sign: ">=", value: "a"}, value: "a",
+			expected: true,
+		},
+		"like with percent": {
+			condition: Condition{sign: "like", value: "'ali%'"}, value: "alice",
+			expected: true,
+		},
+		"like with underscore": {
+			condition: Condition{sign: "like", value: "'b_b'"}, value: "bob",
+			expected: true,
+		},
+		"like is case sensitive": {
+			condition: Condition{sign: "like", value: "'ALI%'"}, value: "alice",
+			expected: false,
+		},
+		"like matches whole value": {
+			condition: Condition{sign: "like", value: "'li'"}, value: "alice",
+			expected: false,
+		},
+		"like with regular expression characters": {
+			condition: Condition{sign: "like", value: "'a.c'"}, value: "abc",
+			expected: false,
+		},
+		"like with default escape": {
+			condition: Condition{sign: "like", value: "'100\\%'"}, value: "100%",
+			expected: true,
+		},
+		"like with custom escape": {
+			condition: Condition{sign: "like", value: "'a!_c'", escape: "!"}, value: "abc",
+			expected: false,
+		},
+		"not like": {
+			condition: Condition{sign: "not like", value: "'a%'"}, value: "bob",
+			expected: true,
+		},
+		"ilike": {
+			condition: Condition{sign: "ilike", value: "'ALI%'"}, value: "Alice",
+			expected: true,
+		},
+		"regex match": {
+			condition: Condition{sign: "~", value: "'^[a-c]+[0-9]$'"}, value: "abc1",
+			expected: true,
+		},
+		"regex is case sensitive": {
+			condition: Condition{sign: "~", value: "'^A'"}, value: "abc",
+			expected: false,
+		},
+		"case insensitive regex match": {
+			condition: Condition{sign: "~*", value: "'^A'"}, value: "abc",
+			expected: true,
+		},
+		"negated regex match": {
+			condition: Condition{sign: "!~", value: "'z'"}, value: "abc",
+			expected: true,
+		},
+	}
+
+	for test, tC := range testCases {
+		res := EvaluateStringCondition(tC.condition, tC.value)
+		t.Run(test, func(t *testing.T) {
+			if res != tC.expected {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, res)
+			}
+		})
+	}
+}
+
+func TestEvaluateCondition(t *testing.T) {
+	testCases := map[string]struct {
+		condition Condition
+		value     any
+
+		expected bool
+	}{
+		"string in list": {
+			condition: Condition{sign: "in", value: []any{"a", "b"}}, value: "b",
+			expected: true,
+		},
+		"string not in list": {
+			condition: Condition{sign: "not in", value: []any{"a", "b"}}, value: "c",
+			expected: true,
+		},
+		"string between": {
+			condition: Condition{sign: "between", value: []any{"b", "d"}}, value: "c",
+			expected: true,
+		},
+		"string not between": {
+			condition: Condition{sign: "not between", value: []any{"b", "d"}}, value: "d",
+			expected: false,
+		},
+		"int in list": {
+			condition: Condition{sign: "in", value: []any{int16(1), int16(3)}}, value: int16(2),
+			expected: false,
+		},
+		"int between inclusive": {
+			condition: Condition{sign: "between", value: []any{int16(1), int16(3)}}, value: int16(3),
+			expected: true,
+		},
+		"null never matches": {
+			condition: Condition{sign: "not in", value: []any{"a"}}, value: nil,
+			expected: false,
+		},
+	}
+
+	for test, tC := range testCases {
+		res := EvaluateCondition(tC.condition, tC.value)
+		t.Run(test, func(t *testing.T) {
+			if res != tC.expected {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, res)
+			}
+		})
+	}
+}
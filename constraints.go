@@ -0,0 +1,419 @@
+package auralis
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const constraints string = "constraints"
+
+// constraint types as stored in catalog
+const (
+	primaryKeyConstraint = "PRIMARY KEY"
+	uniqueConstraint     = "UNIQUE"
+	checkConstraint      = "CHECK"
+	notNullConstraint    = "NOT NULL"
+	foreignKeyConstraint = "FOREIGN KEY"
+)
+
+// auralisConstraints lists constraints of tables, each column of constraint is
+// stored in own row along with referenced column of foreign key. Clause of check
+// constraint doesn't fit varchar so it is split into chunks stored in rows
+// ordered by position
+var auralisConstraints = Table{
+	schemaTable: SchemaTable[string, string]{internalSchema, constraints},
+	columns: []Column{
+		{
+			name:     "table_schema",
+			dataType: varchar,
+			position: 1,
+		},
+		{
+			name:     "table_name",
+			dataType: varchar,
+			position: 2,
+		},
+		{
+			name:     "constraint_name",
+			dataType: varchar,
+			position: 3,
+		},
+		{
+			name:     "constraint_type",
+			dataType: varchar,
+			position: 4,
+		},
+		{
+			name:     "column_name",
+			dataType: varchar,
+			position: 5,
+		},
+		{
+			name:     "position",
+			dataType: smallint,
+			position: 6,
+		},
+		{
+			name:     "check_clause",
+			dataType: varchar,
+			position: 7,
+		},
+		{
+			name:     "ref_schema",
+			dataType: varchar,
+			position: 8,
+		},
+		{
+			name:     "ref_table",
+			dataType: varchar,
+			position: 9,
+		},
+		{
+			name:     "ref_column",
+			dataType: varchar,
+			position: 10,
+		},
+		{
+			name:     "update_rule",
+			dataType: varchar,
+			position: 11,
+		},
+		{
+			name:     "delete_rule",
+			dataType: varchar,
+			position: 12,
+		},
+	},
+}
+
+var (
+	ErrNotNullViolation = AuraError{Code: "NOT_NULL_VIOLATION", Message: "null value violates not-null constraint"}
+	ErrCheckViolation   = AuraError{Code: "CHECK_VIOLATION", Message: "row violates check constraint"}
+
+	ErrMultiplePrimaryKeys = AuraError{Code: "INVALID_TABLE_DEFINITION", Message: "multiple primary keys are not allowed"}
+	ErrConstraintExists    = AuraError{Code: "DUPLICATE_OBJECT", Message: "constraint already exists"}
+)
+
+// Constraint restricts values of table rows. Primary key and unique constraints
+// are enforced by unique index of the same name, primary key columns can't be null
+type Constraint struct {
+	source     SchemaTable[string, string] // table of constraint, set when read from catalog
+	name       string
+	kind       string
+	columns    []string
+	conditions []Condition // conditions of check constraint
+	clause     string      // text of check conditions
+
+	references        SchemaTable[string, string] // referenced table of foreign key
+	referencedColumns []string
+	onDelete          string // referential actions of foreign key
+	onUpdate          string
+}
+
+// constraintName generates name of constraint from table and column names,
+// name is shortened to fit varchar and numbered when already used
+func constraintName(table string, columns []string, suffix string, used func(name string) (bool, error)) (string, error) {
+	base := strings.Join(append([]string{table}, columns...), "_")
+	for n := 0; ; n++ {
+		end := "_" + suffix
+		if n > 0 {
+			end += strconv.Itoa(n)
+		}
+
+		name := base
+		if size := getDataTypeByteSize(varchar) - len(end); len(name) > size {
+			name = name[:max(size, 0)]
+		}
+		name += end
+
+		exists, err := used(name)
+		if err != nil || !exists {
+			return name, err
+		}
+	}
+}
+
+// addConstraints names constraints of new table, creates indexes of primary key
+// and unique constraints and registers constraints in catalog. Foreign keys are
+// added last so they can reference unique constraints of the table itself
+func addConstraints(tx *Transaction, table Table) error {
+	names := []string{}
+	used := func(name string) (bool, error) {
+		if slices.Contains(names, name) {
+			return true, nil
+		}
+
+		return schemaIndexExists(tx, table.schemaTable.schema, name)
+	}
+
+	isForeignKey := func(constraint Constraint) bool { return constraint.kind == foreignKeyConstraint }
+	ordered := append(
+		slices.DeleteFunc(slices.Clone(table.constraints), isForeignKey),
+		slices.DeleteFunc(slices.Clone(table.constraints), func(constraint Constraint) bool { return !isForeignKey(constraint) })...)
+
+	rows := []Row{}
+	for _, constraint := range ordered {
+		if constraint.name == "" {
+			suffix := map[string]string{
+				primaryKeyConstraint: "pkey",
+				uniqueConstraint:     "key",
+				checkConstraint:      "check",
+				notNullConstraint:    "not_null",
+				foreignKeyConstraint: "fkey",
+			}[constraint.kind]
+
+			columns := constraint.columns
+			if constraint.kind == primaryKeyConstraint {
+				columns = nil
+			}
+
+			var err error
+			if constraint.name, err = constraintName(table.schemaTable.name, columns, suffix, used); err != nil {
+				return err
+			}
+		} else if slices.Contains(names, constraint.name) {
+			return ErrConstraintExists
+		}
+		names = append(names, constraint.name)
+
+		if constraint.kind == primaryKeyConstraint || constraint.kind == uniqueConstraint {
+			index := Index{name: constraint.name, unique: true, method: btreeIndex}
+			for _, name := range constraint.columns {
+				index.columns = append(index.columns, table.columns[slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == name })])
+			}
+
+			if err := createIndex(tx, table, index); err != nil {
+				return err
+			}
+		}
+
+		if constraint.kind == foreignKeyConstraint {
+			if err := resolveForeignKey(tx, table, &constraint); err != nil {
+				return err
+			}
+		}
+
+		row := func(column string, position int, clause string, referenced string) Row {
+			return Row{cells: []any{
+				table.schemaTable.schema, table.schemaTable.name, constraint.name, constraint.kind,
+				column, int16(position), clause,
+				constraint.references.schema, constraint.references.name, referenced,
+				constraint.onUpdate, constraint.onDelete,
+			}}
+		}
+
+		if constraint.kind == checkConstraint {
+			for i, chunk := range clauseChunks(constraint.clause) {
+				rows = append(rows, row("", i+1, chunk, ""))
+			}
+			continue
+		}
+
+		for i, name := range constraint.columns {
+			referenced := ""
+			if constraint.kind == foreignKeyConstraint {
+				referenced = constraint.referencedColumns[i]
+			}
+			rows = append(rows, row(name, i+1, "", referenced))
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return writeIntoTable(tx, auralisConstraints, DataSet{columns: auralisConstraints.columns, rows: rows})
+}
+
+// clauseChunks splits text of expression into chunks fitting varchar
+func clauseChunks(clause string) []string {
+	chunks := []string{}
+	size := getDataTypeByteSize(varchar)
+	for i := 0; i < len(clause); i += size {
+		chunks = append(chunks, clause[i:min(i+size, len(clause))])
+	}
+
+	return chunks
+}
+
+// validateConstraints checks that constraints reference columns of table and
+// the table has at most one primary key
+func validateConstraints(table Table) error {
+	primaryKeys := 0
+	for _, constraint := range table.constraints {
+		if constraint.kind == primaryKeyConstraint {
+			primaryKeys++
+		}
+
+		for i, name := range constraint.columns {
+			if !slices.ContainsFunc(table.columns, func(cd Column) bool { return cd.name == name }) {
+				return ErrColumnNotFound
+			}
+
+			if slices.Contains(constraint.columns[:i], name) {
+				return ErrDuplicateIndexColumn
+			}
+		}
+
+		for _, condition := range constraint.conditions {
+			if err := validateCondition(condition, table.columns); err != nil {
+				return err
+			}
+		}
+	}
+
+	if primaryKeys > 1 {
+		return ErrMultiplePrimaryKeys
+	}
+
+	return nil
+}
+
+// validateCondition checks that columns compared by condition exist
+func validateCondition(condition Condition, columns []Column) error {
+	if condition.expr != nil {
+		if err := validateExpression(condition.expr, columns); err != nil {
+			return err
+		}
+	} else if _, err := resolveColumn(columns, condition.target); err != nil {
+		return err
+	}
+
+	switch value := condition.value.(type) {
+	case Expression:
+		return validateExpression(value, columns)
+	case string:
+		if isColumnReference(value) {
+			_, err := resolveColumn(columns, value)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tableConstraints returns constraints of table registered in catalog
+func tableConstraints(tx *Transaction, table Table) ([]Constraint, error) {
+	return readConstraints(tx, []Condition{
+		{target: "table_schema", sign: "=", value: table.schemaTable.schema},
+		{target: "table_name", sign: "=", value: table.schemaTable.name},
+	})
+}
+
+// readConstraints returns constraints registered in catalog matching conditions,
+// check clauses are parsed again from their chunks
+func readConstraints(tx *Transaction, conditions []Condition) ([]Constraint, error) {
+	names := []string{}
+	for _, cd := range auralisConstraints.columns {
+		names = append(names, cd.name)
+	}
+
+	dataSet, err := readFromTable(tx, auralisConstraints, SelectQuery{
+		source:      auralisConstraints.schemaTable,
+		dataColumns: names,
+		conditions:  conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// constraint names are unique only within table
+	found := map[string]*Constraint{}
+	parts := map[string]map[int16][]string{}
+	keys := []string{}
+	for _, row := range dataSet.rows {
+		source := SchemaTable[string, string]{row.cells[0].(string), row.cells[1].(string)}
+		key := fmt.Sprintf("%s.%s.%s", source.schema, source.name, row.cells[2])
+		if _, ok := found[key]; !ok {
+			found[key] = &Constraint{
+				source:     source,
+				name:       row.cells[2].(string),
+				kind:       row.cells[3].(string),
+				references: SchemaTable[string, string]{row.cells[7].(string), row.cells[8].(string)},
+				onUpdate:   row.cells[10].(string),
+				onDelete:   row.cells[11].(string),
+			}
+			parts[key] = map[int16][]string{}
+			keys = append(keys, key)
+		}
+
+		// column, check clause chunk and referenced column
+		parts[key][row.cells[5].(int16)] = []string{row.cells[4].(string), row.cells[6].(string), row.cells[9].(string)}
+	}
+
+	slices.Sort(keys)
+	result := []Constraint{}
+	for _, key := range keys {
+		constraint := *found[key]
+		for position := int16(1); position <= int16(len(parts[key])); position++ {
+			part := parts[key][position]
+			switch constraint.kind {
+			case checkConstraint:
+				constraint.clause += part[1]
+			case foreignKeyConstraint:
+				constraint.columns = append(constraint.columns, part[0])
+				constraint.referencedColumns = append(constraint.referencedColumns, part[2])
+			default:
+				constraint.columns = append(constraint.columns, part[0])
+			}
+		}
+
+		if constraint.kind == checkConstraint {
+			if constraint.conditions, err = parseCheckClause(constraint.clause); err != nil {
+				return nil, fmt.Errorf("check constraint %s: %w", constraint.name, err)
+			}
+		}
+
+		result = append(result, constraint)
+	}
+
+	return result, nil
+}
+
+// checkConstraints verifies row against not-null and check constraints of
+// table, unique values are verified by indexes
+func checkConstraints(table Table, row Row) error {
+	for _, constraint := range table.constraints {
+		if constraint.kind != notNullConstraint && constraint.kind != primaryKeyConstraint {
+			continue
+		}
+
+		for _, name := range constraint.columns {
+			i := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == name })
+			if row.cells[i] == nil {
+				return AuraError{
+					Code:    ErrNotNullViolation.Code,
+					Message: fmt.Sprintf("null value in column %s violates not-null constraint %s", name, constraint.name)}
+			}
+		}
+	}
+
+	for _, constraint := range table.constraints {
+		if constraint.kind != checkConstraint {
+			continue
+		}
+
+		// comparison of null value is unknown, which satisfies the constraint
+		conditions := slices.DeleteFunc(slices.Clone(constraint.conditions), func(c Condition) bool {
+			return !isNullCondition(c) && slices.ContainsFunc(expressionColumns(conditionExpressions(c)), func(name string) bool {
+				i, err := resolveColumn(table.columns, name)
+				return err == nil && row.cells[i] == nil
+			})
+		})
+
+		ok, err := matchesConditions(table.columns, row, conditions)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return AuraError{
+				Code:    ErrCheckViolation.Code,
+				Message: fmt.Sprintf("row violates check constraint %s", constraint.name)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package auralis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConstraints(t *testing.T) {
+	testCases := map[string]struct {
+		create  string
+		queries []string
+		err     string // code of expected error
+	}{
+		"primary key rejects duplicate": {
+			create: "CREATE TABLE items (id smallint PRIMARY KEY, name varchar)",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('1', 'b')",
+			},
+			err: ErrUniqueViolation.Code,
+		},
+		"primary key of table rejects duplicate update": {
+			create: "CREATE TABLE items (id smallint, name varchar, PRIMARY KEY (id, name))",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('1', 'b')",
+				"UPDATE items SET name = 'a' WHERE name = 'b'",
+			},
+			err: ErrUniqueViolation.Code,
+		},
+		"unique column rejects duplicate": {
+			create: "CREATE TABLE items (id smallint, name varchar UNIQUE)",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('2', 'a')",
+			},
+			err: ErrUniqueViolation.Code,
+		},
+		"unique column accepts other value": {
+			create: "CREATE TABLE items (id smallint, name varchar CONSTRAINT items_name UNIQUE)",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('2', 'b')",
+			},
+		},
+		"check of column rejects insert": {
+			create: "CREATE TABLE items (id smallint CHECK (id > 0), name varchar)",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('0', 'b')",
+			},
+			err: ErrCheckViolation.Code,
+		},
+		"check of table rejects update": {
+			create: "CREATE TABLE items (id smallint, name varchar, CHECK (id between 1 and 100 and name <> 'forbidden value'))",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"UPDATE items SET name = 'forbidden value' WHERE id = 1",
+			},
+			err: ErrCheckViolation.Code,
+		},
+		"check accepts matching row": {
+			create: "CREATE TABLE items (id smallint, name varchar, CONSTRAINT items_range CHECK (id >= 1 and id <= 5))",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('5', 'b')",
+				"UPDATE items SET id = 2 WHERE id = 5",
+			},
+		},
+		"unique column accepts repeated null": {
+			create: "CREATE TABLE items (id smallint, name varchar UNIQUE)",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', NULL)",
+				"INSERT INTO items (id, name) VALUES ('2', NULL)",
+			},
+		},
+		"check of null is unknown": {
+			create: "CREATE TABLE items (id smallint CHECK (id > 0), name varchar)",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES (NULL, 'a')",
+			},
+		},
+		"check of other column rejects row with null": {
+			create: "CREATE TABLE items (id smallint, name varchar, CHECK (id > 0 and name <> 'forbidden value'))",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES (NULL, 'forbidden value')",
+			},
+			err: ErrCheckViolation.Code,
+		},
+		"check of is not null rejects null": {
+			create: "CREATE TABLE items (id smallint, name varchar, CHECK (id > 0 and name IS NOT NULL))",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES (NULL, 'a')",
+				"INSERT INTO items (id, name) VALUES (1, NULL)",
+			},
+			err: ErrCheckViolation.Code,
+		},
+		"not null rejects null": {
+			create: "CREATE TABLE items (id smallint, name varchar NOT NULL)",
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"UPDATE items SET name = NULL",
+			},
+			err: ErrNotNullViolation.Code,
+		},
+		"unknown column": {
+			create: "CREATE TABLE others (id smallint, UNIQUE (name))",
+			err:    ErrColumnNotFound.Code,
+		},
+		"unknown column of check": {
+			create: "CREATE TABLE others (id smallint CHECK (price > 0))",
+			err:    ErrColumnNotFound.Code,
+		},
+		"multiple primary keys": {
+			create: "CREATE TABLE others (id smallint PRIMARY KEY, name varchar, PRIMARY KEY (name))",
+			err:    ErrMultiplePrimaryKeys.Code,
+		},
+		"constraint name is used": {
+			create: "CREATE TABLE others (id smallint CONSTRAINT others_id CHECK (id > 0), CONSTRAINT others_id UNIQUE (id))",
+			err:    ErrConstraintExists.Code,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t)
+
+			var err error
+			for _, query := range append([]string{tC.create}, tC.queries...) {
+				if _, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			code := ""
+			var auraErr AuraError
+			if errors.As(err, &auraErr) {
+				code = auraErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+		})
+	}
+}
+
+func TestNotNull(t *testing.T) {
+	testCases := map[string]struct {
+		queries  []string
+		err      string  // code of expected error
+		expected [][]any // rows of items
+	}{
+		"null in nullable column": {
+			queries:  []string{"INSERT INTO items (id, name, note) VALUES (1, 'a', NULL)"},
+			expected: [][]any{{int16(1), "a", nil}},
+		},
+		"omitted nullable column": {
+			queries:  []string{"INSERT INTO items (id, name) VALUES (1, 'a')"},
+			expected: [][]any{{int16(1), "a", nil}},
+		},
+		"nullable column updated to null": {
+			queries: []string{
+				"INSERT INTO items (id, name, note) VALUES (1, 'a', 'x'), (2, 'b', 'y')",
+				"UPDATE items SET note = NULL WHERE id = 2",
+			},
+			expected: [][]any{{int16(1), "a", "x"}, {int16(2), "b", nil}},
+		},
+		"null in not null column": {
+			queries: []string{"INSERT INTO items (id, name, note) VALUES (1, NULL, 'x')"},
+			err:     ErrNotNullViolation.Code,
+		},
+		"omitted not null column": {
+			queries: []string{"INSERT INTO items (id, note) VALUES (1, 'x')"},
+			err:     ErrNotNullViolation.Code,
+		},
+		"not null column updated to null": {
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES (1, 'a')",
+				"UPDATE items SET name = NULL",
+			},
+			err: ErrNotNullViolation.Code,
+		},
+		"null in primary key": {
+			queries: []string{"INSERT INTO items (id, name) VALUES (NULL, 'a')"},
+			err:     ErrNotNullViolation.Code,
+		},
+		"null selected into not null column": {
+			queries: []string{
+				"CREATE TABLE others (id smallint, note varchar)",
+				"INSERT INTO others (id) VALUES (1)",
+				"INSERT INTO items (id, name) SELECT id, note FROM others",
+			},
+			err: ErrNotNullViolation.Code,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE items (id smallint PRIMARY KEY, name varchar NOT NULL, note varchar)")
+
+			var err error
+			for _, query := range tC.queries {
+				if _, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			code := ""
+			var auraErr AuraError
+			if errors.As(err, &auraErr) {
+				code = auraErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+
+			if tC.err != "" {
+				return
+			}
+
+			if rows := queryCells(t, "SELECT id, name, note FROM items ORDER BY id"); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestConstraintsCatalog(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id smallint PRIMARY KEY, name varchar NOT NULL UNIQUE, CHECK (id > 0 and name <> 'none'))",
+	)
+
+	expected := [][]any{
+		{"items_check", "CHECK", "", int16(1), "id > 0 and name "},
+		{"items_check", "CHECK", "", int16(2), "!= 'none'"},
+		{"items_n_not_null", "NOT NULL", "name", int16(1), ""},
+		{"items_name_key", "UNIQUE", "name", int16(1), ""},
+		{"items_pkey", "PRIMARY KEY", "id", int16(1), ""},
+	}
+	query := "SELECT constraint_name, constraint_type, column_name, position, check_clause FROM auralis.constraints " +
+		"WHERE table_name = 'items' ORDER BY constraint_name, position"
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+
+	// unique constraints are backed by indexes
+	expected = [][]any{{"items_name_key"}, {"items_pkey"}}
+	if rows := queryCells(t, "SELECT index_name FROM auralis.indexes WHERE table_name = 'items' ORDER BY index_name"); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
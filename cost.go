@@ -0,0 +1,318 @@
+package auralis
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// costs are in units of sequential page read
+const (
+	seqPageCost     = 1.0
+	randomPageCost  = 4.0
+	cpuTupleCost    = 0.01
+	cpuOperatorCost = 0.0025
+
+	// defaultGroups is number of groups assumed for grouping keys
+	defaultGroups = 200
+)
+
+// Estimate is planner guess of rows produced by plan node, startup cost is
+// spent before the first row is returned
+type Estimate struct {
+	rows    float64
+	startup float64
+	total   float64
+}
+
+// estimatePlan estimates plan node executed by operator from estimates of
+// its inputs
+func estimatePlan(plan LogicalPlan, operator Operator, inputs []Estimate) Estimate {
+	switch p := plan.(type) {
+	case ScanPlan:
+		return estimateScan(p, operator)
+	case FilterPlan:
+		input := inputs[0]
+		return Estimate{
+			rows:    clampRows(input.rows * selectivity(p, p.conditions)),
+			startup: input.startup,
+			total:   input.total + input.rows*float64(len(p.conditions))*cpuOperatorCost,
+		}
+	case JoinPlan:
+		left, right := inputs[0], inputs[1]
+		pairs := left.rows * right.rows
+		return Estimate{
+			rows:    clampRows(pairs * selectivity(p, p.conditions)),
+			startup: left.startup + right.total,
+			total:   left.total + right.total + pairs*(cpuTupleCost+float64(len(p.conditions))*cpuOperatorCost),
+		}
+	case AggregatePlan:
+		input := inputs[0]
+		rows := 1.0
+		if len(p.groupBy) > 0 {
+			rows = math.Min(input.rows, defaultGroups)
+		}
+
+		startup := input.total + input.rows*float64(len(p.groupBy)+len(p.aggregates))*cpuOperatorCost
+		return Estimate{rows: rows, startup: startup, total: startup + rows*cpuTupleCost}
+	case WindowPlan:
+		input := inputs[0]
+		startup := input.total + input.rows*float64(len(p.calls))*cpuOperatorCost
+		return Estimate{rows: input.rows, startup: startup, total: startup + input.rows*cpuTupleCost}
+	case SortPlan:
+		input := inputs[0]
+		startup := input.total + 2*cpuOperatorCost*input.rows*math.Log2(math.Max(input.rows, 2))
+		return Estimate{rows: input.rows, startup: startup, total: startup + input.rows*cpuOperatorCost}
+	case ProjectPlan:
+		input := inputs[0]
+		return Estimate{
+			rows:    input.rows,
+			startup: input.startup,
+			total:   input.total + input.rows*float64(len(p.projections))*cpuOperatorCost,
+		}
+	case LimitPlan:
+		input := inputs[0]
+		rows := math.Max(input.rows-float64(p.offset), 0)
+		if p.limit != nil {
+			rows = math.Min(rows, float64(*p.limit))
+		}
+
+		// only fraction of input is pulled
+		fraction := 1.0
+		if input.rows > 0 {
+			fraction = math.Min((rows+float64(p.offset))/input.rows, 1)
+		}
+
+		return Estimate{rows: rows, startup: input.startup, total: input.startup + (input.total-input.startup)*fraction}
+	case UnionPlan:
+		left, right := inputs[0], inputs[1]
+		rows := left.rows + right.rows
+		total := left.total + right.total
+		if !p.all {
+			total += rows * cpuOperatorCost
+		}
+
+		return Estimate{rows: rows, startup: left.startup, total: total}
+	default:
+		panic("unsupported plan")
+	}
+}
+
+// estimateScan estimates scan of rows of source or table, index scan reads
+// page of each row found by index
+func estimateScan(plan ScanPlan, operator Operator) Estimate {
+	if plan.table == nil {
+		rows := float64(len(plan.rows))
+		return Estimate{
+			rows:  clampRows(rows * selectivity(plan, plan.conditions)),
+			total: rows * (cpuTupleCost + float64(len(plan.conditions))*cpuOperatorCost),
+		}
+	}
+
+	if index, ok := operator.(*indexScanOperator); ok {
+		return estimateIndexScan(plan, index.scan)
+	}
+
+	return estimateSeqScan(plan)
+}
+
+func estimateSeqScan(plan ScanPlan) Estimate {
+	pages, tuples := tableTuples(plan)
+	return Estimate{
+		rows:  clampRows(tuples * selectivity(plan, plan.conditions)),
+		total: pages*seqPageCost + tuples*(cpuTupleCost+float64(len(plan.conditions))*cpuOperatorCost),
+	}
+}
+
+// estimateIndexScan counts rows found by conditions on restricted index
+// columns, the other conditions filter rows which were read
+func estimateIndexScan(plan ScanPlan, scan IndexScan) Estimate {
+	_, tuples := tableTuples(plan)
+	restricted := scan.index.columns[:scan.columns]
+	indexed := filter(plan.conditions, func(condition Condition) bool {
+		return slices.ContainsFunc(restricted, func(cd Column) bool { return cd.name == unqualifiedName(condition.target) })
+	})
+
+	fetched := clampRows(tuples * selectivity(plan, indexed))
+	return Estimate{
+		rows:    clampRows(tuples * selectivity(plan, plan.conditions)),
+		startup: randomPageCost,
+		total:   randomPageCost + fetched*(randomPageCost+cpuTupleCost+float64(len(plan.conditions))*cpuOperatorCost),
+	}
+}
+
+// tableTuples returns number of pages of scanned table and estimated number
+// of its tuples. Tuples of analyzed table are scaled by growth of the table,
+// otherwise pages are assumed to be full of live tuples
+func tableTuples(plan ScanPlan) (float64, float64) {
+	count, err := bufferPool.numberOfPages(getTableDiskPath(plan.table.schemaTable))
+	if err != nil {
+		count = 0
+	}
+
+	pages := float64(count)
+	if stats := plan.statistics; stats != nil {
+		if stats.pages > 0 {
+			return pages, float64(stats.rows) * pages / float64(stats.pages)
+		}
+
+		if count == 0 {
+			return pages, float64(stats.rows)
+		}
+	}
+
+	perPage := float64((pageSize - pageHeaderSize) / (valuesOffset(*plan.table) + calculateRowSize(*plan.table) + slotSize))
+	return pages, pages * perPage
+}
+
+// selectivity is fraction of rows of plan expected to satisfy all conditions,
+// conditions on columns of analyzed tables are estimated from statistics
+func selectivity(plan LogicalPlan, conditions []Condition) float64 {
+	fraction := 1.0
+	for _, condition := range conditions {
+		s, ok := statisticsSelectivity(plan, condition)
+		if !ok {
+			s = defaultSelectivity(condition)
+		}
+		fraction *= s
+	}
+
+	return fraction
+}
+
+// defaultSelectivity is guess of selectivity of condition operator
+func defaultSelectivity(condition Condition) float64 {
+	sign, negated := baseSign(condition.sign)
+	var s float64
+	switch {
+	case sign == "=" || sign == "between" || sign == "is null":
+		s = 0.005
+	case sign == "!=":
+		s = 0.995
+	case sign == "in":
+		values, _ := condition.value.([]any)
+		s = math.Min(0.005*float64(max(len(values), 1)), 1)
+	case strings.Contains(sign, "<") || strings.Contains(sign, ">"):
+		s = 1.0 / 3
+	default:
+		s = 0.005 // pattern matching
+	}
+
+	if negated {
+		s = 1 - s
+	}
+
+	return s
+}
+
+// statisticsSelectivity estimates condition comparing column with literal,
+// or equality of columns, from statistics of compared columns
+func statisticsSelectivity(plan LogicalPlan, condition Condition) (float64, bool) {
+	stats := planStatistics(plan, condition.target)
+	if condition.expr != nil || stats == nil || stats.dataType == boolean {
+		return 0, false
+	}
+
+	sign, negated := baseSign(condition.sign)
+	if sign == "is null" {
+		if negated {
+			return 1 - stats.nullFraction(), true
+		}
+
+		return stats.nullFraction(), true
+	}
+
+	if isColumnReference(condition.value) {
+		other := planStatistics(plan, condition.value.(string))
+		if sign != "=" || negated || other == nil {
+			return 0, false
+		}
+
+		// values of the column with fewer distinct values are assumed to be
+		// among the values of the other one
+		distinct := math.Max(float64(max(stats.distinct, other.distinct)), 1)
+		return (1 - stats.nullFraction()) * (1 - other.nullFraction()) / distinct, true
+	}
+
+	var value any
+	var err error
+	switch v := condition.value.(type) {
+	case Literal:
+		value, err = castValue(stats.dataType, v.value)
+	case string:
+		value, err = convertConditionValue(stats.dataType, condition)
+	case []any:
+		if slices.ContainsFunc(v, func(v any) bool { _, ok := v.(string); return !ok }) {
+			return 0, false
+		}
+		value, err = convertConditionValue(stats.dataType, condition)
+	default:
+		return 0, false
+	}
+	if err != nil {
+		return 0, false
+	}
+
+	var s float64
+	switch sign {
+	case "=":
+		s = stats.equalFraction(value)
+	case "!=":
+		s = 1 - stats.equalFraction(value) - stats.nullFraction()
+	case "<":
+		s = stats.lessFraction(value, false)
+	case "<=":
+		s = stats.lessFraction(value, true)
+	case ">":
+		s = 1 - stats.lessFraction(value, true) - stats.nullFraction()
+	case ">=":
+		s = 1 - stats.lessFraction(value, false) - stats.nullFraction()
+	case "in":
+		for _, v := range value.([]any) {
+			s += stats.equalFraction(v)
+		}
+	case "between":
+		bounds := value.([]any)
+		s = stats.lessFraction(bounds[1], true) - stats.lessFraction(bounds[0], false)
+	default:
+		return 0, false // patterns
+	}
+
+	s = math.Min(math.Max(s, 0), 1)
+	if negated {
+		s = math.Max(1-s-stats.nullFraction(), 0)
+	}
+
+	return s, true
+}
+
+// planStatistics returns statistics of column of plan output which is read
+// from analyzed table, nil when column isn't known
+func planStatistics(plan LogicalPlan, name string) *ColumnStatistics {
+	switch p := plan.(type) {
+	case ScanPlan:
+		if p.statistics == nil {
+			return nil
+		}
+
+		i, err := resolveColumn(p.output, name)
+		if err != nil {
+			return nil
+		}
+
+		return p.statistics.columns[p.output[i].name]
+	case FilterPlan, JoinPlan, SortPlan, LimitPlan:
+		for _, input := range plan.inputs() {
+			if stats := planStatistics(input, name); stats != nil {
+				return stats
+			}
+		}
+	}
+
+	return nil
+}
+
+// clampRows rounds estimate of rows, at least one row is expected
+func clampRows(rows float64) float64 {
+	return math.Max(math.Round(rows), 1)
+}
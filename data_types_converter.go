@@ -1,8 +1,11 @@
-package main
+package auralis
 
 import (
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -16,46 +19,175 @@ const (
 	varchar          DataType = "varchar"          // fixed 16 for now
 	uniqueidentifier DataType = "uniqueidentifier" // 16
 	boolean          DataType = "boolean"          // 1
+	double           DataType = "double"           // 8, only computed values for now
+	timestamp        DataType = "timestamp"        // 8, microseconds since unix epoch in UTC
+	unknown          DataType = "unknown"          // type of null literal
 )
 
+// columnTypes are types whose values can be stored in table columns
+var columnTypes = []DataType{smallint, integer, bigint, varchar, uniqueidentifier, boolean, timestamp}
+
 var (
+	ErrUnsupportedColumnType  = AuraError{Code: "UNDEFINED_DATA_TYPE", Message: "type is not supported for columns"}
 	ErrSmallintTypeConversion = AuraError{
 		Message: "type smallint conversion error",
 		Code:    "TYPE_CONV_ERROR",
 	}
+	ErrIntegerTypeConversion = AuraError{
+		Message: "type integer conversion error",
+		Code:    "TYPE_CONV_ERROR",
+	}
+	ErrDoubleTypeConversion = AuraError{
+		Message: "type double conversion error",
+		Code:    "TYPE_CONV_ERROR",
+	}
 	ErrUUIDTypeConversion = AuraError{
 		Message: "type UUID conversion error",
 		Code:    "TYPE_CONV_ERROR",
 	}
+	ErrTimestampTypeConversion = AuraError{
+		Message: "type timestamp conversion error",
+		Code:    "TYPE_CONV_ERROR",
+	}
+	ErrBooleanTypeConversion = AuraError{
+		Message: "type boolean conversion error",
+		Code:    "TYPE_CONV_ERROR",
+	}
 )
 
+// timestampLayouts are accepted formats of timestamp literals, the first one
+// is used to display timestamps
+var timestampLayouts = []string{"2006-01-02 15:04:05.999999", time.RFC3339Nano, "2006-01-02"}
+
 func ConvertToConcreteType(sourceType DataType, value any) (any, error) {
 	switch sourceType {
 	case smallint:
 		{
-			v, err := strconv.ParseInt(value.(string), 10, 16)
+			v, err := strconv.ParseInt(unquote(value.(string)), 10, 16)
 			if err != nil {
 				return nil, ErrSmallintTypeConversion
 			}
 
 			return int16(v), nil
 		}
+	case integer:
+		{
+			v, err := strconv.ParseInt(unquote(value.(string)), 10, 32)
+			if err != nil {
+				return nil, ErrIntegerTypeConversion
+			}
+
+			return int32(v), nil
+		}
+	case bigint:
+		{
+			v, err := strconv.ParseInt(unquote(value.(string)), 10, 64)
+			if err != nil {
+				return nil, ErrIntegerTypeConversion
+			}
+
+			return v, nil
+		}
+	case double:
+		{
+			v, err := strconv.ParseFloat(unquote(value.(string)), 64)
+			if err != nil {
+				return nil, ErrDoubleTypeConversion
+			}
+
+			return v, nil
+		}
 	case varchar:
 		{
-			return strings.Trim(value.(string), "'"), nil
+			return unquote(value.(string)), nil
 		}
 	case uniqueidentifier:
 		{
-			v, err := uuid.Parse(value.(string))
+			v, err := uuid.Parse(unquote(value.(string)))
 			if err != nil {
 				return nil, ErrUUIDTypeConversion
 			}
 
 			return v, nil
 		}
+	case timestamp:
+		{
+			for _, layout := range timestampLayouts {
+				if v, err := time.Parse(layout, unquote(value.(string))); err == nil {
+					return v.UTC().Truncate(time.Microsecond), nil
+				}
+			}
+
+			return nil, ErrTimestampTypeConversion
+		}
+	case boolean:
+		{
+			switch strings.ToLower(unquote(value.(string))) {
+			case "true", "t":
+				return true, nil
+			case "false", "f":
+				return false, nil
+			}
+
+			return nil, ErrBooleanTypeConversion
+		}
 	default:
-		panic("invalid source type")
+		return nil, AuraError{
+			Code:    "TYPE_CONV_ERROR",
+			Message: fmt.Sprintf("values of type %s can't be converted", sourceType)}
+	}
+}
+
+// castValue converts computed value into value of column type
+func castValue(dataType DataType, value any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if dataType == varchar {
+			return v, nil
+		}
+
+		return ConvertToConcreteType(dataType, v)
+	case uuid.UUID:
+		if dataType == uniqueidentifier {
+			return v, nil
+		}
+	case float64:
+		if dataType == double {
+			return v, nil
+		}
+	case bool:
+		if dataType == boolean {
+			return v, nil
+		}
+	case time.Time:
+		if dataType == timestamp {
+			return v.UTC().Truncate(time.Microsecond), nil
+		}
 	}
+
+	if isInteger(value) {
+		n := toInt64(value)
+		switch {
+		case dataType == smallint && n >= math.MinInt16 && n <= math.MaxInt16:
+			return int16(n), nil
+		case dataType == smallint:
+			return nil, ErrSmallintTypeConversion
+		case dataType == integer && n >= math.MinInt32 && n <= math.MaxInt32:
+			return int32(n), nil
+		case dataType == integer:
+			return nil, ErrIntegerTypeConversion
+		case dataType == bigint:
+			return n, nil
+		case dataType == double:
+			return float64(n), nil
+		}
+	}
+
+	return nil, AuraError{
+		Code:    "TYPE_CONV_ERROR",
+		Message: fmt.Sprintf("value of type %s can't be converted to %s", valueDataType(value), dataType)}
 }
 
 func getDataTypeByteSize(dataType DataType) int {
@@ -72,7 +204,18 @@ func getDataTypeByteSize(dataType DataType) int {
 		return 16
 	case boolean:
 		return 1
+	case double, timestamp:
+		return 8
 	default:
 		panic("unhandled type")
 	}
 }
+
+// unquote strips single quotes of sql literal and unescapes doubled quotes within it
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+
+	return value
+}
@@ -1,7 +1,8 @@
-package main
+package auralis
 
 import (
 	"testing"
+	"time"
 )
 
 func TestConvertToConcreteType(t *testing.T) {
@@ -27,6 +28,43 @@ func TestConvertToConcreteType(t *testing.T) {
 			value:       string("-32768"),
 			expectedRes: int16(-32768),
 		},
+		"valid timestamp conversion": {
+			sourceType:  timestamp,
+			value:       string("'2024-02-29 13:04:05.25'"),
+			expectedRes: time.Date(2024, 2, 29, 13, 4, 5, 250000000, time.UTC),
+		},
+		"valid timestamp conversion of date": {
+			sourceType:  timestamp,
+			value:       string("2024-02-29"),
+			expectedRes: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		"invalid timestamp conversion": {
+			sourceType:  timestamp,
+			value:       string("2024-02-30"),
+			expectedErr: ErrTimestampTypeConversion,
+		},
+		"valid boolean conversion": {
+			sourceType:  boolean,
+			value:       string("'TRUE'"),
+			expectedRes: true,
+		},
+		"valid boolean conversion of unquoted literal": {
+			sourceType:  boolean,
+			value:       string("false"),
+			expectedRes: false,
+		},
+		"invalid boolean conversion": {
+			sourceType:  boolean,
+			value:       string("'yes please'"),
+			expectedErr: ErrBooleanTypeConversion,
+		},
+		"unknown type": {
+			sourceType: DataType("foo"),
+			value:      string("1"),
+			expectedErr: AuraError{
+				Code:    "TYPE_CONV_ERROR",
+				Message: "values of type foo can't be converted"},
+		},
 	}
 	for test, tC := range testCases {
 		val, err := ConvertToConcreteType(tC.sourceType, tC.value)
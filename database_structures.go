@@ -1,30 +1,42 @@
-package main
+package auralis
 
 import (
+	"errors"
 	"os"
 )
 
 const (
-	dataPath string = "./data"
-
-	internalSchema string = "auralis"
-	tables         string = "tables"
-	columns        string = "columns"
-
-	tablesPath  string = dataPath + "/" + internalSchema + "." + tables
-	columnsPath string = dataPath + "/" + internalSchema + "." + columns
+	internalDatabase string = "auralis"       // database of catalog tables
+	userDatabase     string = "test-database" // database of user tables
+	internalSchema   string = "auralis"
+	tables           string = "tables"
+	columns          string = "columns"
 )
 
+// dataPath is data directory of open database, tests run on isolated directories
+var dataPath string = "./data"
+
 type Table struct {
 	schemaTable SchemaTable[string, string]
 	columns     []Column // describes table schema
+	indexes     []Index
+	constraints []Constraint
+	defaults    []ColumnDefault
 }
 
 type Column struct {
 	name     string
 	dataType DataType
 	position int16
-	// attributes eg. PK
+	table    string // table name or alias qualifying column within data set
+}
+
+func (c Column) Name() string {
+	return c.name
+}
+
+func (c Column) Type() DataType {
+	return c.dataType
 }
 
 var auralisTables = Table{
@@ -74,41 +86,63 @@ var auralisColumnsTable = Table{
 	},
 }
 
-func initDatabaseInternalStructure() {
-	if _, err := os.Stat(dataPath); !os.IsNotExist(err) {
-		return
+// initDatabaseInternalStructure creates data directory with internal tables,
+// existing database is recovered from write-ahead log
+func initDatabaseInternalStructure() error {
+	_, err := os.Stat(dataPath)
+	exists := !os.IsNotExist(err)
+	if !exists {
+		if err := os.MkdirAll(dataPath, os.ModePerm); err != nil {
+			return err
+		}
 	}
 
-	err := os.Mkdir(dataPath, os.ModePerm)
+	wal, records, err := openWAL(getWALDiskPath())
 	if err != nil {
-		panic(err)
+		return err
 	}
+	writeAheadLog = wal
 
-	schemaF, err := os.Create(tablesPath)
-	if err != nil {
-		panic(err)
-	}
-	defer schemaF.Close()
+	// locks and transactions don't survive restart
+	lockManager = newLockManager()
+	serializableTransactions = serializableTransactions[:0]
+	sequenceStates.states = map[string]*sequenceState{}
 
-	schemaF, err = os.Create(columnsPath)
-	if err != nil {
-		panic(err)
+	if exists {
+		return recoverDatabase(records)
 	}
-	defer schemaF.Close()
 
-	err = addAuralisInternalTables()
-	if err != nil {
-		panic(err)
+	for _, table := range []Table{auralisTables, auralisColumnsTable, auralisVacuumStatistics, auralisStatistics, auralisIndexes, auralisConstraints, auralisSequences, auralisDefaults} {
+		f, err := os.Create(getTableDiskPath(table.schemaTable))
+		if err != nil {
+			return err
+		}
+		f.Close()
 	}
+
+	return addAuralisInternalTables()
+}
+
+// shutdownDatabase rolls back unfinished transaction of default session and writes
+// all changes into table files so the next start doesn't have to replay log.
+// Transactions of other sessions have to be finished before
+func shutdownDatabase() error {
+	return errors.Join(defaultSession.close(), writeAheadLog.checkpoint(), bufferPool.close(), writeAheadLog.close())
 }
 
 func addAuralisInternalTables() error {
-	err := writeIntoTable(auralisTables,
+	return autocommit(func(tx *Transaction) error {
+		return writeInternalTables(tx)
+	})
+}
+
+func writeInternalTables(tx *Transaction) error {
+	err := writeIntoTable(tx, auralisTables,
 		DataSet{
 			columns: auralisTables.columns,
 			rows: []Row{
 				{
-					cells: []any{"auralis", internalSchema, tables},
+					cells: []any{internalDatabase, internalSchema, tables},
 				},
 			},
 		})
@@ -117,12 +151,12 @@ func addAuralisInternalTables() error {
 		return err
 	}
 
-	err = writeIntoTable(auralisTables,
+	err = writeIntoTable(tx, auralisTables,
 		DataSet{
 			columns: auralisTables.columns,
 			rows: []Row{
 				{
-					cells: []any{"auralis", internalSchema, columns},
+					cells: []any{internalDatabase, internalSchema, columns},
 				},
 			},
 		})
@@ -131,17 +165,17 @@ func addAuralisInternalTables() error {
 		return err
 	}
 
-	err = writeIntoTable(auralisColumnsTable, DataSet{
+	err = writeIntoTable(tx, auralisColumnsTable, DataSet{
 		columns: auralisColumnsTable.columns,
 		rows: []Row{
 			{
-				cells: []any{"auralis", "tables", "database_name", string(varchar), int16(1)},
+				cells: []any{internalDatabase, "tables", "database_name", string(varchar), int16(1)},
 			},
 			{
-				cells: []any{"auralis", "tables", "table_schema", string(varchar), int16(2)},
+				cells: []any{internalDatabase, "tables", "table_schema", string(varchar), int16(2)},
 			},
 			{
-				cells: []any{"auralis", "tables", "table_name", string(varchar), int16(3)},
+				cells: []any{internalDatabase, "tables", "table_name", string(varchar), int16(3)},
 			},
 		},
 	})
@@ -150,23 +184,23 @@ func addAuralisInternalTables() error {
 		return err
 	}
 
-	err = writeIntoTable(auralisColumnsTable, DataSet{
+	err = writeIntoTable(tx, auralisColumnsTable, DataSet{
 		columns: auralisColumnsTable.columns,
 		rows: []Row{
 			{
-				cells: []any{"auralis", "columns", "table_schema", string(varchar), int16(1)},
+				cells: []any{internalDatabase, "columns", "table_schema", string(varchar), int16(1)},
 			},
 			{
-				cells: []any{"auralis", "columns", "table_name", string(varchar), int16(2)},
+				cells: []any{internalDatabase, "columns", "table_name", string(varchar), int16(2)},
 			},
 			{
-				cells: []any{"auralis", "columns", "column_name", string(varchar), int16(3)},
+				cells: []any{internalDatabase, "columns", "column_name", string(varchar), int16(3)},
 			},
 			{
-				cells: []any{"auralis", "columns", "data_type", string(varchar), int16(4)},
+				cells: []any{internalDatabase, "columns", "data_type", string(varchar), int16(4)},
 			},
 			{
-				cells: []any{"auralis", "columns", "position", string(smallint), int16(5)},
+				cells: []any{internalDatabase, "columns", "position", string(smallint), int16(5)},
 			},
 		},
 	})
@@ -175,11 +209,31 @@ func addAuralisInternalTables() error {
 		return err
 	}
 
-	return nil
+	if err := addTable(tx, internalDatabase, auralisVacuumStatistics); err != nil {
+		return err
+	}
+
+	if err := addTable(tx, internalDatabase, auralisStatistics); err != nil {
+		return err
+	}
+
+	if err := addTable(tx, internalDatabase, auralisIndexes); err != nil {
+		return err
+	}
+
+	if err := addTable(tx, internalDatabase, auralisConstraints); err != nil {
+		return err
+	}
+
+	if err := addTable(tx, internalDatabase, auralisSequences); err != nil {
+		return err
+	}
+
+	return addTable(tx, internalDatabase, auralisDefaults)
 }
 
-func getTable(source SchemaTable[string, string]) (Table, error) {
-	dataSet, err := readFromTable(auralisColumnsTable, SelectQuery{
+func getTable(tx *Transaction, source SchemaTable[string, string]) (Table, error) {
+	dataSet, err := readFromTable(tx, auralisColumnsTable, SelectQuery{
 		source:      SchemaTable[string, string]{internalSchema, tables},
 		dataColumns: []string{"table_schema", "table_name", "column_name", "data_type", "position"},
 		conditions: []Condition{
@@ -211,24 +265,52 @@ func getTable(source SchemaTable[string, string]) (Table, error) {
 		sourceColumns = append(sourceColumns, sourceColumn)
 	}
 
-	return Table{
+	// table file may outlive catalog rows of rolled back create
+	if len(sourceColumns) == 0 {
+		return Table{}, ErrTableNotFound
+	}
+
+	table := Table{
 		schemaTable: source,
 		columns:     sourceColumns,
-	}, nil
+	}
+
+	table.indexes, err = tableIndexes(tx, table)
+	if err != nil {
+		return Table{}, err
+	}
+
+	table.constraints, err = tableConstraints(tx, table)
+	if err != nil {
+		return Table{}, err
+	}
+
+	table.defaults, err = tableDefaults(tx, table)
+	if err != nil {
+		return Table{}, err
+	}
+
+	return table, nil
 }
 
-func addTable(table Table) error {
-	writeIntoTable(auralisTables,
-		DataSet{
-			columns: auralisTables.columns,
-			rows: []Row{
-				{
-					cells: []any{"test-database", table.schemaTable.schema,
-						table.schemaTable.name},
-				},
-			},
-		})
+// tableExists reports whether table is registered in catalog by transaction
+// or by committed one
+func tableExists(tx *Transaction, source SchemaTable[string, string]) (bool, error) {
+	snapshot := writeAheadLog.latestSnapshot()
+	dataSet, err := readFromTable(&Transaction{id: tx.id, snapshot: &snapshot}, auralisColumnsTable, SelectQuery{
+		source:      SchemaTable[string, string]{internalSchema, tables},
+		dataColumns: []string{"table_schema", "table_name"},
+		conditions: []Condition{
+			{target: "table_schema", sign: "=", value: source.schema},
+			{target: "table_name", sign: "=", value: source.name},
+		},
+	})
+
+	return len(dataSet.rows) > 0, err
+}
 
+// addTable registers table of database and its columns in catalog
+func addTable(tx *Transaction, database string, table Table) error {
 	rows := []Row{}
 	for _, cd := range table.columns {
 		rows = append(rows, Row{
@@ -239,10 +321,17 @@ func addTable(table Table) error {
 		})
 	}
 
-	writeIntoTable(auralisColumnsTable, DataSet{
-		columns: auralisColumnsTable.columns,
-		rows:    rows,
+	// catalog is locked as its rows are moved by vacuum full
+	err := writeIntoTable(tx, auralisTables, DataSet{
+		rows: []Row{
+			{
+				cells: []any{database, table.schemaTable.schema, table.schemaTable.name},
+			},
+		},
 	})
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return writeIntoTable(tx, auralisColumnsTable, DataSet{rows: rows})
 }
@@ -0,0 +1,328 @@
+package auralis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrDatabaseOpen   = AuraError{Code: "OBJECT_IN_USE", Message: "database is already open"}
+	ErrDatabaseClosed = AuraError{Code: "CONNECTION_DOES_NOT_EXIST", Message: "database is closed"}
+)
+
+// DB is a handle of database stored in data directory of dir. Storage, locks
+// and settings are shared by the process, so only one database can be open at
+// a time. Each call of DB runs in its own session so DB can be used by several
+// goroutines, transaction spanning several calls is started by Begin
+type DB struct {
+	statements     *statementCache
+	transactions   map[*Tx]struct{} // open transactions, guarded by openLock
+	stopAutovacuum func()
+}
+
+// logger receives informational and error messages of database
+var logger atomic.Pointer[log.Logger]
+
+func init() {
+	logger.Store(log.Default())
+}
+
+// SetLogger directs informational and error messages of database into l,
+// messages are discarded when l is nil. Standard logger is used by default
+func SetLogger(l *log.Logger) {
+	if l == nil {
+		l = log.New(io.Discard, "", 0)
+	}
+
+	logger.Store(l)
+}
+
+// openLock guards openDatabase
+var openLock sync.Mutex
+
+// openDatabase is the database open in the process
+var openDatabase *DB
+
+// Open creates or recovers database in dir and starts autovacuum. Only one
+// database can be open in a process at a time: data directory, buffer pool,
+// write-ahead log, locks and settings are package level state, so Open fails
+// with ErrDatabaseOpen until the open database is closed. ExecuteQuery works
+// on the same database
+func Open(dir string) (*DB, error) {
+	openLock.Lock()
+	defer openLock.Unlock()
+
+	// storage initialized by other means is live as well
+	if openDatabase != nil || writeAheadLog != nil {
+		return nil, ErrDatabaseOpen
+	}
+
+	dataPath = filepath.Join(dir, "data")
+	bufferPool = newBufferPool(defaultBufferPoolSize)
+	if err := initDatabaseInternalStructure(); err != nil {
+		if writeAheadLog != nil {
+			writeAheadLog.close()
+			writeAheadLog = nil
+		}
+		return nil, err
+	}
+
+	openDatabase = &DB{
+		statements:     newStatementCache(),
+		transactions:   map[*Tx]struct{}{},
+		stopAutovacuum: startAutovacuum(),
+	}
+	return openDatabase, nil
+}
+
+// Close stops autovacuum, rolls back open transactions and writes all changes
+// into table files. Calls running concurrently have to finish before
+func (db *DB) Close() error {
+	openLock.Lock()
+	defer openLock.Unlock()
+
+	if openDatabase != db {
+		return ErrDatabaseClosed
+	}
+
+	db.stopAutovacuum()
+	openDatabase = nil
+	defer func() { writeAheadLog = nil }()
+
+	errs := []error{}
+	for tx := range db.transactions {
+		// transaction ended concurrently is already finished
+		if err := tx.finish("ROLLBACK"); err != ErrNoActiveTransaction {
+			errs = append(errs, err)
+		}
+	}
+	clear(db.transactions)
+
+	return errors.Join(append(errs, shutdownDatabase())...)
+}
+
+// Exec executes semicolon separated statements and returns result of the last
+// one, nil for statements without result. Arguments are bound to $n
+// placeholders of single statement. Transaction begun by statements has to end
+// by them, it's rolled back otherwise
+func (db *DB) Exec(ctx context.Context, query string, args ...any) (*DataSet, error) {
+	return db.execute(func(s *Session) (*DataSet, error) {
+		return s.executeContext(ctx, query, args...)
+	})
+}
+
+// Query executes statements like Exec and returns rows of the last one
+func (db *DB) Query(ctx context.Context, query string, args ...any) (*Rows, error) {
+	dataSet, err := db.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRows(ctx, dataSet), nil
+}
+
+// Prepare lexes and parses statement once, its arguments are bound by each
+// execution
+func (db *DB) Prepare(query string) (*Statement, error) {
+	p, err := prepareStatement(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{db: db, statement: p}, nil
+}
+
+// Begin starts transaction in its own session, statements of transaction are
+// executed one at a time until Commit or Rollback
+func (db *DB) Begin(ctx context.Context) (*Tx, error) {
+	tx := &Tx{db: db, session: db.newSession()}
+
+	openLock.Lock()
+	if openDatabase != db {
+		openLock.Unlock()
+		return nil, ErrDatabaseClosed
+	}
+	db.transactions[tx] = struct{}{}
+	openLock.Unlock()
+
+	if _, err := tx.session.executeContext(ctx, "BEGIN"); err != nil {
+		tx.forget()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// execute runs fn in a new session closed afterwards
+func (db *DB) execute(fn func(*Session) (*DataSet, error)) (*DataSet, error) {
+	if err := db.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	s := db.newSession()
+	dataSet, err := fn(s)
+	if closeErr := s.close(); err == nil && closeErr != nil {
+		return nil, closeErr
+	}
+
+	return dataSet, err
+}
+
+// newSession returns session sharing statement cache of DB
+func (db *DB) newSession() *Session {
+	s := newSession()
+	s.statements = db.statements
+	return s
+}
+
+// storageOpen reports whether storage of database is initialized
+func storageOpen() bool {
+	openLock.Lock()
+	defer openLock.Unlock()
+
+	return writeAheadLog != nil
+}
+
+func (db *DB) checkOpen() error {
+	openLock.Lock()
+	defer openLock.Unlock()
+
+	if openDatabase != db {
+		return ErrDatabaseClosed
+	}
+
+	return nil
+}
+
+// Tx is a transaction of DB running in its own session, it can be used by
+// several goroutines whose calls are executed one after another
+type Tx struct {
+	db      *DB
+	session *Session
+	lock    sync.Mutex // guards done
+	done    bool
+}
+
+// Exec executes statements within transaction like DB.Exec
+func (tx *Tx) Exec(ctx context.Context, query string, args ...any) (*DataSet, error) {
+	return tx.execute(func(s *Session) (*DataSet, error) {
+		return s.executeContext(ctx, query, args...)
+	})
+}
+
+// Query executes statements within transaction and returns rows of the last one
+func (tx *Tx) Query(ctx context.Context, query string, args ...any) (*Rows, error) {
+	dataSet, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRows(ctx, dataSet), nil
+}
+
+// Stmt returns statement prepared by DB executed within transaction
+func (tx *Tx) Stmt(statement *Statement) *Statement {
+	return &Statement{db: tx.db, tx: tx, statement: statement.statement}
+}
+
+// Commit makes changes of transaction visible to others
+func (tx *Tx) Commit() error {
+	if err := tx.db.checkOpen(); err != nil {
+		return err
+	}
+
+	defer tx.forget()
+	return tx.finish("COMMIT")
+}
+
+// Rollback discards changes of transaction
+func (tx *Tx) Rollback() error {
+	if err := tx.db.checkOpen(); err != nil {
+		return err
+	}
+
+	defer tx.forget()
+	return tx.finish("ROLLBACK")
+}
+
+// execute runs fn in session of transaction, transaction ended by statements
+// of fn can't be used anymore
+func (tx *Tx) execute(fn func(*Session) (*DataSet, error)) (*DataSet, error) {
+	if err := tx.db.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	tx.lock.Lock()
+	if tx.done {
+		tx.lock.Unlock()
+		return nil, ErrNoActiveTransaction
+	}
+
+	dataSet, err := fn(tx.session)
+	ended := tx.session.transaction == nil
+	tx.done = tx.done || ended
+	tx.lock.Unlock()
+
+	if ended {
+		tx.forget()
+	}
+
+	return dataSet, err
+}
+
+// finish ends transaction by command and closes its session
+func (tx *Tx) finish(command string) error {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.done {
+		return ErrNoActiveTransaction
+	}
+
+	tx.done = true
+	_, err := tx.session.executeQuery(command)
+	return errors.Join(err, tx.session.close())
+}
+
+// forget removes transaction from open transactions of DB
+func (tx *Tx) forget() {
+	openLock.Lock()
+	defer openLock.Unlock()
+
+	delete(tx.db.transactions, tx)
+}
+
+// Statement is a statement prepared by DB with $n placeholders
+type Statement struct {
+	db        *DB
+	tx        *Tx // transaction of statement, each execution runs in new session without it
+	statement *PreparedStatement
+}
+
+// Exec binds arguments and returns result of statement
+func (s *Statement) Exec(ctx context.Context, args ...any) (*DataSet, error) {
+	run := func(session *Session) (*DataSet, error) {
+		return session.executePrepared(ctx, s.statement, args)
+	}
+
+	if s.tx != nil {
+		return s.tx.execute(run)
+	}
+
+	return s.db.execute(run)
+}
+
+// Query binds arguments and returns rows of statement
+func (s *Statement) Query(ctx context.Context, args ...any) (*Rows, error) {
+	dataSet, err := s.Exec(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRows(ctx, dataSet), nil
+}
@@ -0,0 +1,441 @@
+package auralis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// openTestDatabase opens database in temporary directory, it's closed at the end of test
+func openTestDatabase(t *testing.T, dir string) *DB {
+	t.Helper()
+
+	previous, previousPool, previousLog := dataPath, bufferPool, writeAheadLog
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		dataPath, bufferPool, writeAheadLog = previous, previousPool, previousLog
+	})
+
+	return db
+}
+
+func TestDB(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	db := openTestDatabase(t, dir)
+
+	// second database doesn't replace storage of the open one
+	path := dataPath
+	for _, other := range []string{dir, t.TempDir()} {
+		if _, err := Open(other); err != ErrDatabaseOpen {
+			t.Fatalf("\nexp %+v\ngot %+v", ErrDatabaseOpen, err)
+		}
+	}
+
+	if dataPath != path {
+		t.Fatalf("\nexp %+v\ngot %+v", path, dataPath)
+	}
+
+	if _, err := db.Exec(ctx, "CREATE TABLE events (id integer, kind varchar, created timestamp)"); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	dataSet, err := db.Exec(ctx, "INSERT INTO events (id, kind, created) VALUES ($1, $2, $3), ($4, $2, $3)", 1, "login", created, 2)
+	if err != nil || dataSet.RowsAffected() != 2 {
+		t.Fatal(dataSet, err)
+	}
+
+	// data are recovered by the next open
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(ctx, "SELECT id FROM events"); err != ErrDatabaseClosed {
+		t.Fatalf("\nexp %+v\ngot %+v", ErrDatabaseClosed, err)
+	}
+
+	if _, err := ExecuteQuery("SELECT id FROM events"); err != ErrDatabaseClosed {
+		t.Fatalf("\nexp %+v\ngot %+v", ErrDatabaseClosed, err)
+	}
+
+	db = openTestDatabase(t, dir)
+	rows, err := db.Query(ctx, "SELECT id, kind, created FROM events WHERE id >= $1 ORDER BY id DESC", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	type event struct {
+		id      int64
+		kind    string
+		created time.Time
+	}
+
+	events := []event{}
+	for rows.Next() {
+		e := event{}
+		if err := rows.Scan(&e.id, &e.kind, &e.created); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []event{{2, "login", created}, {1, "login", created}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, events)
+	}
+
+	if names := []string{rows.Columns()[0].Name(), rows.Columns()[1].Name()}; !reflect.DeepEqual(names, []string{"id", "kind"}) {
+		t.Errorf("\nexp %+v\ngot %+v", []string{"id", "kind"}, names)
+	}
+}
+
+func TestOpenWithLiveStorage(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE items (id integer)")
+
+	if _, err := Open(t.TempDir()); err != ErrDatabaseOpen {
+		t.Fatalf("\nexp %+v\ngot %+v", ErrDatabaseOpen, err)
+	}
+
+	if _, err := ExecuteQuery("SELECT id FROM items"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreparedStatement(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDatabase(t, t.TempDir())
+
+	if _, err := db.Exec(ctx, "CREATE TABLE items (id integer, name varchar)"); err != nil {
+		t.Fatal(err)
+	}
+
+	insert, err := db.Prepare("INSERT INTO items (id, name) VALUES ($1, $2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range []string{"a", "b", "c"} {
+		if _, err := insert.Exec(ctx, i, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := db.Prepare("SELECT count(*) FROM items WHERE id > $1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := count.Query(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if !rows.Next() || rows.Scan(&n) != nil || n != 2 {
+		t.Errorf("\nexp %+v\ngot %+v", 2, n)
+	}
+}
+
+func TestTx(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDatabase(t, t.TempDir())
+
+	if _, err := db.Exec(ctx, "CREATE TABLE items (id integer, name varchar)"); err != nil {
+		t.Fatal(err)
+	}
+
+	insert, err := db.Prepare("INSERT INTO items (id, name) VALUES ($1, $2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int {
+		t.Helper()
+
+		rows, err := db.Query(ctx, "SELECT count(*) FROM items")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var n int
+		if !rows.Next() || rows.Scan(&n) != nil {
+			t.Fatal(rows.Err())
+		}
+		return n
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.Stmt(insert).Exec(ctx, 1, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes are visible to other sessions after commit
+	if n := count(); n != 0 {
+		t.Errorf("\nexp %+v\ngot %+v", 0, n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := count(); n != 1 {
+		t.Errorf("\nexp %+v\ngot %+v", 1, n)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT id FROM items"); err != ErrNoActiveTransaction {
+		t.Errorf("\nexp %+v\ngot %+v", ErrNoActiveTransaction, err)
+	}
+
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.Exec(ctx, "INSERT INTO items (id, name) VALUES ($1, $2)", 2, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// transaction left open by statements of Exec is rolled back
+	if _, err := db.Exec(ctx, "BEGIN; INSERT INTO items (id, name) VALUES (3, 'c')"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := count(); n != 1 {
+		t.Errorf("\nexp %+v\ngot %+v", 1, n)
+	}
+
+	// open transaction is rolled back by close
+	if tx, err = db.Begin(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.Exec(ctx, "INSERT INTO items (id, name) VALUES (4, 'd')"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := dataPath
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != ErrDatabaseClosed {
+		t.Errorf("\nexp %+v\ngot %+v", ErrDatabaseClosed, err)
+	}
+
+	db = openTestDatabase(t, strings.TrimSuffix(dir, "/data"))
+	if n := count(); n != 1 {
+		t.Errorf("\nexp %+v\ngot %+v", 1, n)
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDatabase(t, t.TempDir())
+
+	if _, err := db.Exec(ctx, "CREATE TABLE items (id integer, name varchar)"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := range 8 {
+		wg.Add(2)
+
+		// each transaction sees only its own uncommitted rows
+		go func() {
+			defer wg.Done()
+
+			tx, err := db.Begin(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			for j := range 5 {
+				if _, err := tx.Exec(ctx, "INSERT INTO items (id, name) VALUES ($1, 'tx')", i*10+j); err != nil {
+					errs <- err
+					return
+				}
+			}
+
+			var n int
+			rows, err := tx.Query(ctx, "SELECT count(*) FROM items WHERE id >= $1 AND id < $2", i*10, i*10+10)
+			if err == nil && rows.Next() {
+				err = rows.Scan(&n)
+			}
+			if err == nil && n != 5 {
+				err = fmt.Errorf("transaction %d sees %d rows", i, n)
+			}
+			if err != nil {
+				tx.Rollback()
+				errs <- err
+				return
+			}
+
+			errs <- tx.Commit()
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			_, err := db.Exec(ctx, "INSERT INTO items (id, name) VALUES ($1, 'exec')", 100+i)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := [][]any{{"exec", int64(8)}, {"tx", int64(40)}}
+	rows := queryCells(t, "SELECT name, count(*) FROM items GROUP BY name ORDER BY name")
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	db := openTestDatabase(t, t.TempDir())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := db.Exec(canceled, "CREATE TABLE items (id integer, name varchar)"); err != context.Canceled {
+		t.Fatalf("\nexp %+v\ngot %+v", context.Canceled, err)
+	}
+
+	if _, err := db.Exec(context.Background(), "CREATE TABLE items (id integer, name varchar)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting for lock of other session stops at deadline of context
+	locker := newSession()
+	if _, err := locker.executeQuery("BEGIN; LOCK TABLE items IN EXCLUSIVE MODE"); err != nil {
+		t.Fatal(err)
+	}
+	defer locker.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := db.Query(ctx, "SELECT id FROM items"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("\nexp %+v\ngot %+v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	SetLogger(log.New(&buffer, "", 0))
+	t.Cleanup(func() { SetLogger(log.Default()) })
+
+	ctx := context.Background()
+	db := openTestDatabase(t, t.TempDir())
+	if _, err := db.Exec(ctx, "CREATE TABLE items (id integer, name varchar)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(ctx, "SELECT id FROM items"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buffer.String(), "INFO: scanning table dbo.items") {
+		t.Errorf("\nexp %+v\ngot %+v", "INFO: scanning table dbo.items", buffer.String())
+	}
+
+	// messages are discarded without logger
+	SetLogger(nil)
+	buffer.Reset()
+	if _, err := db.Exec(ctx, "SELECT id FROM items"); err != nil || buffer.Len() != 0 {
+		t.Errorf("\nexp %+v\ngot %+v %v", "", buffer.String(), err)
+	}
+}
+
+func TestScanValue(t *testing.T) {
+	id := uuid.New()
+	testCases := map[string]struct {
+		value       any
+		dest        any
+		expected    any
+		expectedErr bool
+	}{
+		"widened integer": {
+			value:    int16(7),
+			dest:     new(int64),
+			expected: int64(7),
+		},
+		"integer out of range": {
+			value:       int64(1 << 40),
+			dest:        new(int32),
+			expectedErr: true,
+		},
+		"integer into double": {
+			value:    int32(3),
+			dest:     new(float64),
+			expected: float64(3),
+		},
+		"uuid": {
+			value:    id,
+			dest:     new(uuid.UUID),
+			expected: id,
+		},
+		"null into any": {
+			value:    nil,
+			dest:     new(any),
+			expected: nil,
+		},
+		"null into string": {
+			value:       nil,
+			dest:        new(string),
+			expectedErr: true,
+		},
+		"number into string": {
+			value:       int32(1),
+			dest:        new(string),
+			expectedErr: true,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			err := scanValue(tC.dest, tC.value)
+			if (err != nil) != tC.expectedErr {
+				t.Fatalf("\nexp error %+v\ngot %+v", tC.expectedErr, err)
+			}
+
+			if got := reflect.ValueOf(tC.dest).Elem().Interface(); err == nil && !reflect.DeepEqual(got, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package auralis
+
+import (
+	"fmt"
+	"slices"
+)
+
+const defaults string = "defaults"
+
+// kinds of identity columns
+const (
+	identityAlways    = "ALWAYS"
+	identityByDefault = "BY DEFAULT"
+)
+
+// auralisDefaults lists default expressions of columns, like check clauses
+// they are split into chunks stored in rows ordered by position
+var auralisDefaults = Table{
+	schemaTable: SchemaTable[string, string]{internalSchema, defaults},
+	columns: []Column{
+		{
+			name:     "table_schema",
+			dataType: varchar,
+			position: 1,
+		},
+		{
+			name:     "table_name",
+			dataType: varchar,
+			position: 2,
+		},
+		{
+			name:     "column_name",
+			dataType: varchar,
+			position: 3,
+		},
+		{
+			name:     "position",
+			dataType: smallint,
+			position: 4,
+		},
+		{
+			name:     "default_clause",
+			dataType: varchar,
+			position: 5,
+		},
+		{
+			name:     "identity",
+			dataType: varchar,
+			position: 6,
+		},
+	},
+}
+
+var (
+	ErrGeneratedAlways  = AuraError{Code: "GENERATED_ALWAYS", Message: "value of identity column generated always can't be specified"}
+	ErrInvalidIdentity  = AuraError{Code: "INVALID_TABLE_DEFINITION", Message: "identity column must be of type smallint, integer or bigint"}
+	ErrMultipleDefaults = AuraError{Code: "INVALID_TABLE_DEFINITION", Message: "multiple default values specified for column"}
+)
+
+// serialTypes are integer types of serial columns
+var serialTypes = map[string]DataType{
+	"smallserial": smallint,
+	"serial":      integer,
+	"bigserial":   bigint,
+}
+
+// ColumnDefault is value of column omitted by insert. Identity and serial
+// columns take values of sequence created along with the column
+type ColumnDefault struct {
+	column   string
+	expr     Expression
+	clause   string           // text of default expression
+	identity string           // always or by default for identity columns
+	sequence bool             // default is the next value of sequence of column
+	options  map[string]int64 // options of sequence of column
+}
+
+// validateDefaults checks that defaults belong to distinct columns of table and
+// that sequence columns are of integer type
+func validateDefaults(table Table) error {
+	for i, d := range table.defaults {
+		j := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == d.column })
+		if j == -1 {
+			return ErrColumnNotFound
+		}
+
+		if slices.ContainsFunc(table.defaults[:i], func(other ColumnDefault) bool { return other.column == d.column }) {
+			return AuraError{
+				Code:    ErrMultipleDefaults.Code,
+				Message: fmt.Sprintf("multiple default values specified for column %s", d.column)}
+		}
+
+		if d.sequence {
+			if _, ok := sequenceLimits[table.columns[j].dataType]; !ok {
+				return ErrInvalidIdentity
+			}
+			continue
+		}
+
+		// defaults can't reference columns
+		if err := validateExpression(d.expr, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addDefaults creates sequences of identity and serial columns and registers
+// defaults of new table in catalog
+func addDefaults(tx *Transaction, table Table) error {
+	rows := []Row{}
+	for _, d := range table.defaults {
+		if d.sequence {
+			i := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == d.column })
+			name, err := constraintName(table.schemaTable.name, []string{d.column}, "seq", func(name string) (bool, error) {
+				_, err := getSequence(tx, SchemaTable[string, string]{table.schemaTable.schema, name})
+				if err == ErrSequenceNotFound {
+					return false, nil
+				}
+
+				return err == nil, err
+			})
+			if err != nil {
+				return err
+			}
+
+			sequence, err := newSequence(SchemaTable[string, string]{table.schemaTable.schema, name}, table.columns[i].dataType, d.options)
+			if err != nil {
+				return err
+			}
+
+			if err := createSequence(tx, sequence); err != nil {
+				return err
+			}
+			d.clause = fmt.Sprintf("nextval('%s.%s')", table.schemaTable.schema, name)
+		}
+
+		for i, chunk := range clauseChunks(d.clause) {
+			rows = append(rows, Row{cells: []any{
+				table.schemaTable.schema, table.schemaTable.name, d.column, int16(i + 1), chunk, d.identity,
+			}})
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return writeIntoTable(tx, auralisDefaults, DataSet{columns: auralisDefaults.columns, rows: rows})
+}
+
+// tableDefaults returns defaults of table registered in catalog, expressions
+// are parsed again from their chunks
+func tableDefaults(tx *Transaction, table Table) ([]ColumnDefault, error) {
+	names := []string{}
+	for _, cd := range auralisDefaults.columns {
+		names = append(names, cd.name)
+	}
+
+	dataSet, err := readFromTable(tx, auralisDefaults, SelectQuery{
+		source:      auralisDefaults.schemaTable,
+		dataColumns: names,
+		conditions: []Condition{
+			{target: "table_schema", sign: "=", value: table.schemaTable.schema},
+			{target: "table_name", sign: "=", value: table.schemaTable.name},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ColumnDefault{}
+	chunks := map[string]map[int16]string{}
+	for _, row := range dataSet.rows {
+		column := row.cells[2].(string)
+		if _, ok := chunks[column]; !ok {
+			chunks[column] = map[int16]string{}
+			result = append(result, ColumnDefault{column: column, identity: row.cells[5].(string)})
+		}
+		chunks[column][row.cells[3].(int16)] = row.cells[4].(string)
+	}
+
+	for i, d := range result {
+		for position := int16(1); position <= int16(len(chunks[d.column])); position++ {
+			d.clause += chunks[d.column][position]
+		}
+
+		if d.expr, err = parseDefaultClause(d.clause); err != nil {
+			return nil, fmt.Errorf("default of column %s: %w", d.column, err)
+		}
+		result[i] = d
+	}
+
+	return result, nil
+}
+
+// columnDefault returns default of column when it has one
+func columnDefault(table Table, column string) (ColumnDefault, bool) {
+	i := slices.IndexFunc(table.defaults, func(d ColumnDefault) bool { return d.column == column })
+	if i == -1 {
+		return ColumnDefault{}, false
+	}
+
+	return table.defaults[i], true
+}
+
+// defaultValue evaluates default of column, columns without default are null
+func defaultValue(table Table, cd Column) (any, error) {
+	d, ok := columnDefault(table, cd.name)
+	if !ok {
+		return nil, nil
+	}
+
+	value, err := evaluateExpression(d.expr, nil, Row{})
+	if err != nil {
+		return nil, err
+	}
+
+	return castValue(cd.dataType, value)
+}
+
+// checkGeneratedAlways rejects value specified for identity column generated always
+func checkGeneratedAlways(table Table, column string) error {
+	if d, ok := columnDefault(table, column); ok && d.identity == identityAlways {
+		return AuraError{
+			Code:    ErrGeneratedAlways.Code,
+			Message: fmt.Sprintf("value of column %s can't be specified, it is generated always", column)}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package auralis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestColumnDefaults(t *testing.T) {
+	testCases := map[string]struct {
+		create   string
+		queries  []string
+		expected [][]any // id and name of items
+		err      string  // code of expected error
+	}{
+		"omitted column gets default": {
+			create:   "CREATE TABLE items (id smallint, name varchar DEFAULT 'none')",
+			queries:  []string{"INSERT INTO items (id) VALUES ('1')"},
+			expected: [][]any{{int16(1), "none"}},
+		},
+		"default keyword in values": {
+			create:   "CREATE TABLE items (id smallint DEFAULT 7, name varchar)",
+			queries:  []string{"INSERT INTO items (id, name) VALUES (DEFAULT, 'a')"},
+			expected: [][]any{{int16(7), "a"}},
+		},
+		"omitted column without default is null": {
+			create:  "CREATE TABLE items (id smallint, name varchar NOT NULL)",
+			queries: []string{"INSERT INTO items (id) VALUES ('1')"},
+			err:     ErrNotNullViolation.Code,
+		},
+		"default of other type": {
+			create:  "CREATE TABLE items (id smallint DEFAULT 'none', name varchar)",
+			queries: []string{"INSERT INTO items (name) VALUES ('a')"},
+			err:     ErrSmallintTypeConversion.Code,
+		},
+		"serial column": {
+			create: "CREATE TABLE items (id serial PRIMARY KEY, name varchar)",
+			queries: []string{
+				"INSERT INTO items (name) VALUES ('a')",
+				"INSERT INTO items (name) VALUES ('b')",
+			},
+			expected: [][]any{{int32(1), "a"}, {int32(2), "b"}},
+		},
+		"identity generated by default": {
+			create: "CREATE TABLE items (id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 10 INCREMENT BY 10), name varchar)",
+			queries: []string{
+				"INSERT INTO items (name) VALUES ('a')",
+				"INSERT INTO items (id, name) VALUES ('5', 'b')",
+				"INSERT INTO items (id, name) VALUES (DEFAULT, 'c')",
+			},
+			expected: [][]any{{int64(5), "b"}, {int64(10), "a"}, {int64(20), "c"}},
+		},
+		"identity generated always rejects value": {
+			create:  "CREATE TABLE items (id integer GENERATED ALWAYS AS IDENTITY, name varchar)",
+			queries: []string{"INSERT INTO items (id, name) VALUES ('5', 'b')"},
+			err:     ErrGeneratedAlways.Code,
+		},
+		"identity generated always rejects update": {
+			create: "CREATE TABLE items (id integer GENERATED ALWAYS AS IDENTITY, name varchar)",
+			queries: []string{
+				"INSERT INTO items (name) VALUES ('a')",
+				"UPDATE items SET id = 5",
+			},
+			err: ErrGeneratedAlways.Code,
+		},
+		"identity of text column": {
+			create: "CREATE TABLE items (id varchar GENERATED ALWAYS AS IDENTITY, name varchar)",
+			err:    ErrInvalidIdentity.Code,
+		},
+		"default referencing column": {
+			create: "CREATE TABLE items (id smallint, name varchar DEFAULT id)",
+			err:    ErrColumnNotFound.Code,
+		},
+		"multiple defaults": {
+			create: "CREATE TABLE items (id smallint DEFAULT 1 DEFAULT 2, name varchar)",
+			err:    ErrMultipleDefaults.Code,
+		},
+		"unknown column of insert": {
+			create:  "CREATE TABLE items (id smallint, name varchar)",
+			queries: []string{"INSERT INTO items (price) VALUES ('1')"},
+			err:     ErrColumnNotFound.Code,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t)
+
+			var err error
+			for _, query := range append([]string{tC.create}, tC.queries...) {
+				if _, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			code := ""
+			var auraErr AuraError
+			if errors.As(err, &auraErr) {
+				code = auraErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+
+			if tC.err != "" {
+				return
+			}
+
+			if rows := queryCells(t, "SELECT id, name FROM items ORDER BY id"); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestColumnDefaultsCatalog(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id smallserial, name varchar DEFAULT 'unknown item name', created timestamp DEFAULT now())",
+	)
+
+	expected := [][]any{
+		{"created", int16(1), "now ( )", ""},
+		{"id", int16(1), "nextval('dbo.ite", ""},
+		{"id", int16(2), "ms_id_seq')", ""},
+		{"name", int16(1), "'unknown item na", ""},
+		{"name", int16(2), "me'", ""},
+	}
+	query := "SELECT column_name, position, default_clause, identity FROM auralis.defaults " +
+		"WHERE table_name = 'items' ORDER BY column_name, position"
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+
+	// sequence of serial column is registered like other sequences
+	expected = [][]any{{"items_id_seq", "smallint", int64(1), int64(32767)}}
+	query = "SELECT sequence_name, data_type, min_value, max_value FROM auralis.sequences"
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+
+	before := time.Now().UTC().Truncate(time.Microsecond)
+	if _, err := ExecuteQuery("INSERT INTO items (name) VALUES (DEFAULT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := queryCells(t, "SELECT id, name, created FROM items")
+	if len(rows) != 1 || rows[0][0] != int16(1) || rows[0][1] != "unknown item nam" {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+
+	if created := rows[0][2].(time.Time); created.Before(before) || created.After(time.Now()) {
+		t.Errorf("creation time %v is not current", created)
+	}
+}
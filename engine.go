@@ -1,133 +1,405 @@
-package main
+package auralis
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
+	"slices"
 )
 
 const defaultScheme = "dbo"
 
-func ExecuteQuery(raw string) (*DataSet, error) {
-	tokens := Analyze(raw)
-	if len(tokens) <= 0 {
-		return &DataSet{}, AuraError{
-			Code:    "INVALID_QUERY",
-			Message: "missing query tokens"}
+// ExecuteQuery executes semicolon separated statements within default session
+// of the open database and returns result of the last one. Query with
+// arguments must be a single statement, arguments are bound to its $n
+// placeholders
+func ExecuteQuery(raw string, args ...any) (*DataSet, error) {
+	if !storageOpen() {
+		return nil, ErrDatabaseClosed
 	}
 
-	log.Printf("INFO: lexer tokens %v\n", tokens)
+	return defaultSession.executeContext(context.Background(), raw, args...)
+}
 
-	query, err := ParseTokens(tokens)
-	if err != nil {
-		return &DataSet{}, err
+func splitStatements(tokens []TokenLiteral) [][]TokenLiteral {
+	statements := [][]TokenLiteral{}
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && tokens[i].kind != semicolon {
+			continue
+		}
+
+		if i > start {
+			statements = append(statements, tokens[start:i])
+		}
+		start = i + 1
 	}
 
-	log.Printf("INFO: parsed query %+v\n", query)
+	return statements
+}
 
+// executeQuery executes data query or statement within transaction
+func executeQuery(tx *Transaction, query any) (*DataSet, error) {
 	switch query := query.(type) {
 	case SelectQuery:
-		return handleSelectQuery(query)
+		return handleSelectQuery(tx, query)
 	case InsertQuery:
-		return handleInsertQuery(query)
+		return handleInsertQuery(tx, query)
 	case CreateTableQuery:
-		return handleCreateTableQuery(query)
+		return handleCreateTableQuery(tx, query)
+	case CreateIndexQuery:
+		return nil, handleCreateIndexQuery(tx, query)
+	case CreateSequenceQuery:
+		return nil, handleCreateSequenceQuery(tx, query)
+	case UpdateQuery:
+		return handleUpdateQuery(tx, query)
+	case DeleteQuery:
+		return handleDeleteQuery(tx, query)
+	case LockTableQuery:
+		return nil, handleLockTableQuery(tx, query)
+	case AnalyzeQuery:
+		return nil, handleAnalyzeQuery(tx, query)
+	case ExplainQuery:
+		return handleExplainQuery(tx, query)
 	default:
 		panic("unsupported query")
 	}
 }
 
-func handleSelectQuery(query SelectQuery) (*DataSet, error) {
-	table, err := getTable(query.source)
+func handleSelectQuery(tx *Transaction, query SelectQuery) (*DataSet, error) {
+	return executeSelect(tx, query, map[string]*DataSet{})
+}
+
+func handleInsertQuery(tx *Transaction, query InsertQuery) (*DataSet, error) {
+	table, err := getTable(tx, query.source)
 	if err != nil {
 		return &DataSet{}, err
 	}
 
-	if len(query.dataColumns) == 1 && query.dataColumns[0] == "*" {
-		query.dataColumns = make([]string, 0)
-		for _, cd := range table.columns {
-			query.dataColumns = append(query.dataColumns, cd.name)
+	if err := validateReturning(table, query.returning); err != nil {
+		return &DataSet{}, err
+	}
+
+	var onConflict OnConflictClause
+	var arbiters []Index
+	if query.onConflict != nil {
+		if onConflict, arbiters, err = resolveOnConflict(tx, table, *query.onConflict); err != nil {
+			return &DataSet{}, err
+		}
+	}
+
+	for _, name := range query.dataColumns {
+		if !slices.ContainsFunc(table.columns, func(cd Column) bool { return cd.name == name }) {
+			return &DataSet{}, ErrColumnNotFound
 		}
 	}
 
-	// TODO: validate conditions, eg. data types
-	if len(query.conditions) > 0 {
-		for i := range query.conditions {
-			err := ConvertConditionType(table, &query.conditions[i])
+	// values are stored in table columns order, when columns are specified
+	// value positions have to be mapped into that order. Columns which aren't
+	// specified get their default values
+	positions := make([]int, len(table.columns))
+	count := len(table.columns)
+	for i := range table.columns {
+		positions[i] = i
+		if len(query.dataColumns) == 0 {
+			continue
+		}
+
+		positions[i] = slices.Index(query.dataColumns, table.columns[i].name)
+		count = len(query.dataColumns)
+	}
+
+	// rows of query are already typed, values are literals
+	values, convert := query.values, ConvertToConcreteType
+	if query.query != nil {
+		dataSet, err := executeSelect(tx, *query.query, map[string]*DataSet{})
+		if err != nil {
+			return &DataSet{}, err
+		}
+
+		values, convert = make([][]any, 0, len(dataSet.rows)), castValue
+		for _, row := range dataSet.rows {
+			values = append(values, row.cells)
+		}
+
+		if len(dataSet.columns) != count {
+			return &DataSet{}, AuraError{
+				Code:    "INVALID_QUERY",
+				Message: "query columns count does not match table columns"}
+		}
+	}
+
+	rows := []Row{}
+	for _, valueRow := range values {
+		if len(valueRow) != count {
+			return &DataSet{}, AuraError{
+				Code:    "INVALID_QUERY",
+				Message: "values count does not match table columns"}
+		}
+
+		row := Row{cells: make([]any, 0, len(table.columns))}
+		for i, cd := range table.columns {
+			var value any
+			if positions[i] == -1 || (query.query == nil && valueRow[positions[i]] == "default") {
+				value, err = defaultValue(table, cd)
+			} else if query.query == nil && valueRow[positions[i]] == "null" {
+				err = checkGeneratedAlways(table, cd.name)
+			} else if err = checkGeneratedAlways(table, cd.name); err == nil {
+				value, err = convert(cd.dataType, valueRow[positions[i]])
+			}
 			if err != nil {
 				return &DataSet{}, err
 			}
+
+			row.cells = append(row.cells, value)
+		}
+
+		rows = append(rows, row)
+	}
+
+	if query.onConflict != nil {
+		rows, err = upsertRows(tx, table, onConflict, arbiters, rows)
+		if err != nil {
+			return &DataSet{}, err
 		}
+
+		return returningDataSet(table, query.returning, rows)
 	}
 
-	dataSet, err := readFromTable(table, query)
+	err = writeIntoTable(tx, table, DataSet{
+		columns: table.columns,
+		rows:    rows,
+	})
 	if err != nil {
 		return &DataSet{}, err
 	}
 
-	return dataSet, nil
+	return returningDataSet(table, query.returning, rows)
 }
 
-func handleInsertQuery(query InsertQuery) (*DataSet, error) {
-	table, err := getTable(query.source)
+func handleUpdateQuery(tx *Transaction, query UpdateQuery) (*DataSet, error) {
+	table, err := getTable(tx, query.source)
 	if err != nil {
 		return &DataSet{}, err
 	}
 
-	// TODO: validate provided data against table and cast datatype
-	// TODO: handle default values in case of non-null columns (that are also not supported)
-	rows := []Row{}
-	for _, valueRow := range query.values {
-		row := Row{cells: make([]any, 0, len(valueRow))}
-		for i, valueCell := range valueRow {
-			value, err := ConvertToConcreteType(table.columns[i].dataType, valueCell)
+	if err := validateReturning(table, query.returning); err != nil {
+		return &DataSet{}, err
+	}
+
+	columns := qualifiedColumns(table)
+	positions := []int{}
+	for _, assignment := range query.assignments {
+		i := slices.IndexFunc(columns, func(cd Column) bool { return cd.name == assignment.column })
+		if i == -1 {
+			return &DataSet{}, ErrColumnNotFound
+		}
+
+		if err := checkGeneratedAlways(table, assignment.column); err != nil {
+			return &DataSet{}, err
+		}
+
+		if err := validateExpression(assignment.value, columns); err != nil {
+			return &DataSet{}, err
+		}
+
+		positions = append(positions, i)
+	}
+
+	rows, err := updateTable(tx, table, query.conditions, func(row Row) (Row, error) {
+		updated := Row{cells: slices.Clone(row.cells)}
+		for i, assignment := range query.assignments {
+			value, err := evaluateExpression(assignment.value, columns, row)
 			if err != nil {
-				return &DataSet{}, err
+				return Row{}, err
 			}
 
-			row.cells = append(row.cells, value)
+			value, err = castValue(columns[positions[i]].dataType, value)
+			if err != nil {
+				return Row{}, err
+			}
+
+			updated.cells[positions[i]] = value
 		}
 
-		rows = append(rows, row)
+		return updated, nil
+	})
+	if err != nil {
+		return &DataSet{}, err
 	}
 
-	err = writeIntoTable(table, DataSet{
-		columns: table.columns,
-		rows:    rows,
-	})
+	return returningDataSet(table, query.returning, rows)
+}
+
+func handleDeleteQuery(tx *Transaction, query DeleteQuery) (*DataSet, error) {
+	table, err := getTable(tx, query.source)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	if err := validateReturning(table, query.returning); err != nil {
+		return &DataSet{}, err
+	}
+
+	rows, err := deleteFromTable(tx, table, query.conditions)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	return returningDataSet(table, query.returning, rows)
+}
+
+// qualifiedColumns returns columns of table qualified by its name so they can
+// be referenced as table.column
+func qualifiedColumns(table Table) []Column {
+	columns := slices.Clone(table.columns)
+	for i := range columns {
+		columns[i].table = table.schemaTable.name
+	}
+
+	return columns
+}
+
+// validateReturning checks that expressions of returning clause reference
+// columns of table
+func validateReturning(table Table, returning ReturningClause) error {
+	columns := qualifiedColumns(table)
+	for _, expr := range returning.projections {
+		if err := validateExpression(expr, columns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// returningDataSet computes returning clause of data modifying statement from
+// changed rows, statement without the clause returns only number of the rows
+func returningDataSet(table Table, returning ReturningClause, rows []Row) (*DataSet, error) {
+	if len(returning.projections) == 0 {
+		return &DataSet{affected: len(rows)}, nil
+	}
+
+	dataSet, err := projectDataSet(&DataSet{columns: qualifiedColumns(table), rows: rows}, returning.names, returning.projections)
+	if err != nil {
+		return &DataSet{}, err
+	}
+	dataSet.affected = len(rows)
+
+	return dataSet, nil
+}
+
+func handleLockTableQuery(tx *Transaction, query LockTableQuery) error {
+	for _, source := range query.sources {
+		table, err := getTable(tx, source)
+		if err != nil {
+			return err
+		}
 
-	return nil, err
+		if err := lockManager.lock(tx, tableLockTag(table), query.mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func handleCreateTableQuery(query CreateTableQuery) (*DataSet, error) {
+func handleCreateTableQuery(tx *Transaction, query CreateTableQuery) (*DataSet, error) {
 	cds := []Column{}
 	var i int16 = 1
-	for name, attributes := range query.columns {
+	for _, definition := range query.columns {
+		name, attributes := definition.name, definition.attributes
 		if len(attributes) == 0 {
 			return nil, errors.New("missing data type for columns")
 		}
 
-		// TODO: validate data type
-		// if attributes[0] != string(uniqueidentifier) {
+		// serial columns are integers taking values of their own sequence
+		dataType := DataType(attributes[0])
+		if serialType, ok := serialTypes[attributes[0]]; ok {
+			dataType = serialType
+			query.defaults = append(query.defaults, ColumnDefault{column: name, sequence: true})
+		}
 
-		// }
+		if !slices.Contains(columnTypes, dataType) {
+			return nil, AuraError{
+				Code:    ErrUnsupportedColumnType.Code,
+				Message: fmt.Sprintf("type %s of column %s is not supported", attributes[0], name)}
+		}
 
 		cds = append(cds, Column{
 			name:     name,
-			dataType: DataType(attributes[0]),
+			dataType: dataType,
 			position: i,
 		})
 
 		i++
 	}
 
-	err := cretateTable(Table{
+	table := Table{
 		schemaTable: query.source,
 		columns:     cds,
-	})
+		constraints: query.constraints,
+		defaults:    query.defaults,
+	}
+	if err := validateConstraints(table); err != nil {
+		return nil, err
+	}
+
+	if err := validateDefaults(table); err != nil {
+		return nil, err
+	}
+
+	if err := cretateTable(tx, table); err != nil {
+		return nil, err
+	}
+
+	if err := addDefaults(tx, table); err != nil {
+		return nil, err
+	}
+
+	return nil, addConstraints(tx, table)
+}
 
+func handleCreateIndexQuery(tx *Transaction, query CreateIndexQuery) error {
+	table, err := getTable(tx, query.source)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	// catalog is written without maintaining indexes
+	if table.schemaTable.schema == internalSchema {
+		return ErrInternalIndex
+	}
+
+	index := Index{name: query.name, unique: query.unique, method: query.method}
+	if index.method == "" {
+		index.method = btreeIndex
+	}
+
+	for _, name := range query.columns {
+		i := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == name })
+		if i == -1 {
+			return ErrColumnNotFound
+		}
+
+		if slices.ContainsFunc(index.columns, func(cd Column) bool { return cd.name == name }) {
+			return ErrDuplicateIndexColumn
+		}
+
+		index.columns = append(index.columns, table.columns[i])
+	}
+
+	return createIndex(tx, table, index)
+}
+
+func handleShowQuery(query ShowQuery) (*DataSet, error) {
+	value, err := getSetting(query.name)
+	if err != nil {
+		return &DataSet{}, err
 	}
 
-	return nil, nil
+	return &DataSet{
+		columns: []Column{{name: query.name, dataType: varchar}},
+		rows:    []Row{{cells: []any{value}}},
+	}, nil
 }
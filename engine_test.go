@@ -0,0 +1,836 @@
+package auralis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// setupTestDatabase initializes database structure in temporary directory
+func setupTestDatabase(t testing.TB, queries ...string) {
+	t.Helper()
+
+	previous, previousPool, previousLog := dataPath, bufferPool, writeAheadLog
+	dataPath = t.TempDir() + "/data"
+	bufferPool = newBufferPool(defaultBufferPoolSize)
+	t.Cleanup(func() {
+		shutdownDatabase()
+		dataPath, bufferPool, writeAheadLog = previous, previousPool, previousLog
+	})
+
+	if err := initDatabaseInternalStructure(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range queries {
+		if _, err := ExecuteQuery(query); err != nil {
+			t.Fatalf("setup query %s failed: %v", query, err)
+		}
+	}
+}
+
+func resultCells(dataSet *DataSet) [][]any {
+	cells := [][]any{}
+	for _, row := range dataSet.rows {
+		cells = append(cells, row.cells)
+	}
+
+	return cells
+}
+
+func TestCommonTableExpressions(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE employees (id smallint, manager smallint, name varchar)",
+		"INSERT INTO employees (id, manager, name) VALUES ('1', '0', 'ceo')",
+		"INSERT INTO employees (id, manager, name) VALUES ('2', '1', 'cto')",
+		"INSERT INTO employees (id, manager, name) VALUES ('3', '2', 'dev')",
+		"INSERT INTO employees (id, manager, name) VALUES ('4', '1', 'cfo')",
+		"INSERT INTO employees (id, manager, name) VALUES ('5', '3', 'intern')",
+	)
+
+	testCases := map[string]struct {
+		query string
+
+		expected    [][]any
+		expectedErr error
+	}{
+		"simple common table expression": {
+			query:    "WITH managers AS (SELECT id, name FROM employees WHERE manager = 1) SELECT name FROM managers",
+			expected: [][]any{{"cto"}, {"cfo"}},
+		},
+		"common table expression referenced multiple times": {
+			query: "WITH e AS (SELECT id, manager FROM employees) " +
+				"SELECT a.id, b.id FROM e a JOIN e b ON a.manager = b.id WHERE b.id = 2",
+			expected: [][]any{{int16(3), int16(2)}},
+		},
+		"common table expression with column names": {
+			query:    "WITH e (employee_id) AS (SELECT id FROM employees WHERE id < 3) SELECT employee_id FROM e",
+			expected: [][]any{{int16(1)}, {int16(2)}},
+		},
+		"union removes duplicates": {
+			query: "WITH e AS (SELECT manager FROM employees UNION SELECT manager FROM employees) " +
+				"SELECT manager FROM e",
+			expected: [][]any{{int16(0)}, {int16(1)}, {int16(2)}, {int16(3)}},
+		},
+		"recursive subordinates": {
+			query: "WITH RECURSIVE subordinates AS (" +
+				"SELECT id, name FROM employees WHERE id = 2 " +
+				"UNION ALL " +
+				"SELECT e.id, e.name FROM employees e JOIN subordinates s ON e.manager = s.id" +
+				") SELECT name FROM subordinates",
+			expected: [][]any{{"cto"}, {"dev"}, {"intern"}},
+		},
+		"recursive managers chain": {
+			query: "WITH RECURSIVE chain AS (" +
+				"SELECT id, manager FROM employees WHERE id = 5 " +
+				"UNION " +
+				"SELECT e.id, e.manager FROM chain c INNER JOIN employees e ON e.id = c.manager" +
+				") SELECT id FROM chain",
+			expected: [][]any{{int16(5)}, {int16(3)}, {int16(2)}, {int16(1)}},
+		},
+		"recursion limit": {
+			query: "SET max_recursive_iterations = 2; WITH RECURSIVE s AS (" +
+				"SELECT id FROM employees WHERE id = 1 " +
+				"UNION ALL " +
+				"SELECT e.id FROM employees e JOIN s ON e.manager = s.id" +
+				") SELECT id FROM s",
+			expectedErr: ErrRecursionLimitExceeded,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			defer setSetting("max_recursive_iterations", "1000")
+
+			dataSet, err := ExecuteQuery(tC.query)
+			if err != tC.expectedErr {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			}
+
+			if tC.expectedErr == nil && !reflect.DeepEqual(resultCells(dataSet), tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, resultCells(dataSet))
+			}
+		})
+	}
+}
+
+func TestSettings(t *testing.T) {
+	setupTestDatabase(t)
+	defer setSetting("max_recursive_iterations", "1000")
+
+	dataSet, err := ExecuteQuery("SET max_recursive_iterations TO 10; SHOW max_recursive_iterations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(resultCells(dataSet), [][]any{{"10"}}) {
+		t.Errorf("\nexp %+v\ngot %+v", [][]any{{"10"}}, resultCells(dataSet))
+	}
+
+	if _, err := ExecuteQuery("SET max_recursive_iterations = 0"); err == nil {
+		t.Errorf("expected invalid value error")
+	}
+
+	if _, err := ExecuteQuery("SHOW unknown_setting"); err != ErrUnknownSetting {
+		t.Errorf("\nexp %+v\ngot %+v", ErrUnknownSetting, err)
+	}
+}
+
+func TestWindowFunctions(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE sales (id smallint, region varchar, amount smallint)",
+		"INSERT INTO sales (id, region, amount) VALUES ('1', 'north', '10')",
+		"INSERT INTO sales (id, region, amount) VALUES ('2', 'north', '20')",
+		"INSERT INTO sales (id, region, amount) VALUES ('3', 'north', '20')",
+		"INSERT INTO sales (id, region, amount) VALUES ('4', 'south', '5')",
+		"INSERT INTO sales (id, region, amount) VALUES ('5', 'south', '15')",
+	)
+
+	testCases := map[string]struct {
+		query string
+
+		expected [][]any
+	}{
+		"row number over whole set": {
+			query:    "SELECT id, row_number() OVER (ORDER BY id DESC) FROM sales",
+			expected: [][]any{{int16(5), int64(1)}, {int16(4), int64(2)}, {int16(3), int64(3)}, {int16(2), int64(4)}, {int16(1), int64(5)}},
+		},
+		"rank and dense rank within partitions": {
+			query: "SELECT id, rank() OVER (PARTITION BY region ORDER BY amount DESC), " +
+				"dense_rank() OVER (PARTITION BY region ORDER BY amount) FROM sales ORDER BY id",
+			expected: [][]any{
+				{int16(1), int64(3), int64(1)},
+				{int16(2), int64(1), int64(2)},
+				{int16(3), int64(1), int64(2)},
+				{int16(4), int64(2), int64(1)},
+				{int16(5), int64(1), int64(2)},
+			},
+		},
+		"lag and lead with offset and default": {
+			query: "SELECT id, lag(amount) OVER (ORDER BY id), lead(amount, 2, 0) OVER (ORDER BY id) " +
+				"FROM sales WHERE region = 'north'",
+			expected: [][]any{
+				{int16(1), nil, int16(20)},
+				{int16(2), int16(10), int64(0)},
+				{int16(3), int16(20), int64(0)},
+			},
+		},
+		"first value per partition": {
+			query:    "SELECT id, first_value(id) OVER (PARTITION BY region ORDER BY amount DESC) AS top FROM sales ORDER BY id",
+			expected: [][]any{{int16(1), int16(2)}, {int16(2), int16(2)}, {int16(3), int16(2)}, {int16(4), int16(5)}, {int16(5), int16(5)}},
+		},
+		"running sum with default frame includes peers": {
+			query:    "SELECT id, sum(amount) OVER (ORDER BY amount) FROM sales WHERE region = 'north'",
+			expected: [][]any{{int16(1), int64(10)}, {int16(2), int64(50)}, {int16(3), int64(50)}},
+		},
+		"moving aggregates with rows frame": {
+			query: "SELECT id, sum(amount) OVER (ORDER BY id ROWS BETWEEN 1 PRECEDING AND CURRENT ROW), " +
+				"count(*) OVER (ORDER BY id ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) FROM sales",
+			expected: [][]any{
+				{int16(1), int64(10), int64(5)},
+				{int16(2), int64(30), int64(4)},
+				{int16(3), int64(40), int64(3)},
+				{int16(4), int64(25), int64(2)},
+				{int16(5), int64(20), int64(1)},
+			},
+		},
+		"aggregate over partition without order": {
+			query:    "SELECT id, max(amount) OVER (PARTITION BY region), avg(amount) OVER (PARTITION BY region) FROM sales WHERE id > 3",
+			expected: [][]any{{int16(4), int16(15), float64(10)}, {int16(5), int16(15), float64(10)}},
+		},
+		"group by with aggregates": {
+			query:    "SELECT region, count(*), sum(amount) AS total FROM sales GROUP BY region ORDER BY total DESC",
+			expected: [][]any{{"north", int64(3), int64(50)}, {"south", int64(2), int64(20)}},
+		},
+		"window over aggregates": {
+			query:    "SELECT region, rank() OVER (ORDER BY sum(amount)) FROM sales GROUP BY region",
+			expected: [][]any{{"south", int64(1)}, {"north", int64(2)}},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			dataSet, err := ExecuteQuery(tC.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(resultCells(dataSet), tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, resultCells(dataSet))
+			}
+		})
+	}
+}
+
+func TestStringConditions(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE users (id smallint, name varchar)",
+		"INSERT INTO users (id, name) VALUES ('1', 'Alice Smith')",
+		"INSERT INTO users (id, name) VALUES ('2', 'bob')",
+		"INSERT INTO users (id, name) VALUES ('3', 'carol_1')",
+		"INSERT INTO users (id, name) VALUES ('4', 'dave')",
+	)
+
+	testCases := map[string]struct {
+		query string
+
+		expected    [][]any
+		expectedErr error
+	}{
+		"lexicographic comparison": {
+			query:    "SELECT id FROM users WHERE name >= 'bob' AND name < 'd'",
+			expected: [][]any{{int16(2)}, {int16(3)}},
+		},
+		"like keeps letter case of literal": {
+			query:    "SELECT name FROM users WHERE name LIKE 'Alice %'",
+			expected: [][]any{{"Alice Smith"}},
+		},
+		"ilike": {
+			query:    "SELECT id FROM users WHERE name ILIKE 'a%'",
+			expected: [][]any{{int16(1)}},
+		},
+		"like with escape": {
+			query:    "SELECT id FROM users WHERE name LIKE '%!_%' ESCAPE '!'",
+			expected: [][]any{{int16(3)}},
+		},
+		"in list": {
+			query:    "SELECT id FROM users WHERE name IN ('bob', 'dave', 'eve')",
+			expected: [][]any{{int16(2)}, {int16(4)}},
+		},
+		"not between": {
+			query:    "SELECT id FROM users WHERE name NOT BETWEEN 'b' AND 'd'",
+			expected: [][]any{{int16(1)}, {int16(4)}},
+		},
+		"regex": {
+			query:    "SELECT id FROM users WHERE name ~ '_[0-9]$'",
+			expected: [][]any{{int16(3)}},
+		},
+		"integer between": {
+			query:    "SELECT id FROM users WHERE id BETWEEN 2 AND 3",
+			expected: [][]any{{int16(2)}, {int16(3)}},
+		},
+		"pattern on non character column": {
+			query:       "SELECT id FROM users WHERE id LIKE '1%'",
+			expectedErr: ErrInvalidConditionType,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			dataSet, err := ExecuteQuery(tC.query)
+			if err != tC.expectedErr {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			}
+
+			if tC.expectedErr == nil && !reflect.DeepEqual(resultCells(dataSet), tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, resultCells(dataSet))
+			}
+		})
+	}
+}
+
+func TestScalarFunctions(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE users (id smallint, name varchar)",
+		"INSERT INTO users (id, name) VALUES ('1', 'Alice')",
+		"INSERT INTO users (id, name) VALUES ('2', 'BOB')",
+		"INSERT INTO users (id, name) VALUES ('3', 'carol')",
+	)
+
+	testCases := map[string]struct {
+		query string
+
+		expected    [][]any
+		expectedErr string // expected error code
+	}{
+		"functions in select list": {
+			query:    "SELECT upper(name), length(name) AS len, mod(id, 2) FROM users WHERE id = 1",
+			expected: [][]any{{"ALICE", int64(5), int64(1)}},
+		},
+		"nested functions": {
+			query:    "SELECT concat(lower(name), '-', id) FROM users WHERE id = 2",
+			expected: [][]any{{"bob-2"}},
+		},
+		"function in where": {
+			query:    "SELECT id FROM users WHERE lower(name) = 'bob'",
+			expected: [][]any{{int16(2)}},
+		},
+		"function compared with function": {
+			query:    "SELECT id FROM users WHERE length(name) > abs(-4)",
+			expected: [][]any{{int16(1)}, {int16(3)}},
+		},
+		"function in order by": {
+			query:    "SELECT name FROM users ORDER BY lower(name) DESC",
+			expected: [][]any{{"carol"}, {"BOB"}, {"Alice"}},
+		},
+		"coalesce with null": {
+			query:    "SELECT coalesce(null, name) FROM users WHERE id = 3",
+			expected: [][]any{{"carol"}},
+		},
+		"invalid argument type": {
+			query:       "SELECT lower(id) FROM users",
+			expectedErr: "INVALID_ARGUMENT_TYPE",
+		},
+		"invalid argument type in where": {
+			query:       "SELECT id FROM users WHERE abs(name) > 1",
+			expectedErr: "INVALID_ARGUMENT_TYPE",
+		},
+		"unknown function": {
+			query:       "SELECT reverse(name) FROM users",
+			expectedErr: "FUNCTION_NOT_FOUND",
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			dataSet, err := ExecuteQuery(tC.query)
+			if tC.expectedErr != "" {
+				if auraErr, ok := err.(AuraError); !ok || auraErr.Code != tC.expectedErr {
+					t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(resultCells(dataSet), tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, resultCells(dataSet))
+			}
+		})
+	}
+
+	dataSet, err := ExecuteQuery("SELECT gen_random_uuid() FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dataSet.rows) != 3 || dataSet.rows[0].cells[0] == dataSet.rows[1].cells[0] {
+		t.Errorf("expected distinct uuid per row, got %+v", resultCells(dataSet))
+	}
+}
+
+func TestCaseExpressions(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE sales (id smallint, region varchar, amount smallint)",
+		"INSERT INTO sales (id, region, amount) VALUES ('1', 'north', '10')",
+		"INSERT INTO sales (id, region, amount) VALUES ('2', 'north', '20')",
+		"INSERT INTO sales (id, region, amount) VALUES ('3', 'south', '5')",
+		"INSERT INTO sales (id, region, amount) VALUES ('4', 'east', '15')",
+	)
+
+	testCases := map[string]struct {
+		query string
+
+		expected    [][]any
+		expectedErr string // expected error code
+	}{
+		"searched case": {
+			query: "SELECT id, CASE WHEN amount >= 15 THEN 'high' WHEN amount >= 10 THEN 'medium' ELSE 'low' END AS size " +
+				"FROM sales",
+			expected: [][]any{{int16(1), "medium"}, {int16(2), "high"}, {int16(3), "low"}, {int16(4), "high"}},
+		},
+		"simple case without else": {
+			query:    "SELECT id, CASE region WHEN 'north' THEN 'N' WHEN 'south' THEN 'S' END FROM sales",
+			expected: [][]any{{int16(1), "N"}, {int16(2), "N"}, {int16(3), "S"}, {int16(4), nil}},
+		},
+		"numeric result types are widened": {
+			query:    "SELECT CASE WHEN id = 1 THEN amount ELSE 100 END FROM sales WHERE id < 3",
+			expected: [][]any{{int64(10)}, {int64(100)}},
+		},
+		"case in where": {
+			query:    "SELECT id FROM sales WHERE CASE WHEN region = 'north' THEN amount ELSE 0 END > 15",
+			expected: [][]any{{int16(2)}},
+		},
+		"case in order by": {
+			query:    "SELECT id FROM sales ORDER BY CASE region WHEN 'east' THEN 0 ELSE 1 END, id DESC",
+			expected: [][]any{{int16(4)}, {int16(3)}, {int16(2)}, {int16(1)}},
+		},
+		"case within aggregate": {
+			query:    "SELECT sum(CASE WHEN region = 'north' THEN amount ELSE 0 END), count(*) FROM sales",
+			expected: [][]any{{int64(30), int64(4)}},
+		},
+		"aggregate within case": {
+			query: "SELECT region, CASE WHEN count(*) > 1 THEN 'many' ELSE region END FROM sales " +
+				"GROUP BY region ORDER BY region",
+			expected: [][]any{{"east", "east"}, {"north", "many"}, {"south", "south"}},
+		},
+		"mismatched result types": {
+			query:       "SELECT CASE WHEN id = 1 THEN 'one' ELSE amount END FROM sales",
+			expectedErr: "DATATYPE_MISMATCH",
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			dataSet, err := ExecuteQuery(tC.query)
+			if tC.expectedErr != "" {
+				if auraErr, ok := err.(AuraError); !ok || auraErr.Code != tC.expectedErr {
+					t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(resultCells(dataSet), tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, resultCells(dataSet))
+			}
+		})
+	}
+}
+
+func TestNullConditions(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE users (id smallint, name varchar, manager smallint)",
+		"INSERT INTO users (id, name, manager) VALUES (1, 'alice', NULL), (2, NULL, 1), (3, 'carol', 1)",
+	)
+
+	testCases := map[string]struct {
+		query    string
+		expected [][]any
+	}{
+		"is null": {
+			query:    "SELECT id FROM users WHERE name IS NULL",
+			expected: [][]any{{int16(2)}},
+		},
+		"is not null": {
+			query:    "SELECT id FROM users WHERE manager IS NOT NULL AND name IS NOT NULL",
+			expected: [][]any{{int16(3)}},
+		},
+		"comparison with null is not satisfied": {
+			query:    "SELECT id FROM users WHERE name != 'alice' ORDER BY id",
+			expected: [][]any{{int16(3)}},
+		},
+		"case": {
+			query:    "SELECT id, CASE WHEN manager IS NULL THEN 'top' ELSE 'report' END FROM users ORDER BY id",
+			expected: [][]any{{int16(1), "top"}, {int16(2), "report"}, {int16(3), "report"}},
+		},
+		"join": {
+			query:    "SELECT u.id, m.name FROM users u JOIN users m ON u.manager = m.id WHERE u.name IS NULL",
+			expected: [][]any{{int16(2), "alice"}},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			if rows := queryCells(t, tC.query); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+
+	// rows are found by index as well as by scan
+	if _, err := ExecuteQuery("CREATE INDEX users_manager ON users (manager)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ExecuteQuery("UPDATE users SET name = 'bob' WHERE name IS NULL AND manager = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ExecuteQuery("DELETE FROM users WHERE manager IS NULL"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{int16(2), "bob"}, {int16(3), "carol"}}
+	if rows := queryCells(t, "SELECT id, name FROM users ORDER BY id"); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	testCases := map[string]struct {
+		queries []string
+		failing string // statement failing within transaction
+
+		expected    [][]any
+		expectedErr error
+	}{
+		"rollback discards all statements": {
+			queries: []string{
+				"BEGIN",
+				"INSERT INTO items (id, name) VALUES ('3', 'c')",
+				"UPDATE items SET name = 'z'",
+				"DELETE FROM items WHERE id = 1",
+				"ROLLBACK",
+			},
+			expected: [][]any{{int16(1), "a"}, {int16(2), "b"}},
+		},
+		"commit applies all statements": {
+			queries: []string{
+				"BEGIN; INSERT INTO items (id, name) VALUES ('3', 'c'); DELETE FROM items WHERE id = 1",
+				"UPDATE items SET name = upper(name) WHERE id >= 2",
+				"COMMIT",
+			},
+			expected: [][]any{{int16(2), "B"}, {int16(3), "C"}},
+		},
+		"rollback to savepoint": {
+			queries: []string{
+				"BEGIN",
+				"INSERT INTO items (id, name) VALUES ('3', 'c')",
+				"SAVEPOINT s1",
+				"DELETE FROM items",
+				"SAVEPOINT s2",
+				"INSERT INTO items (id, name) VALUES ('4', 'd')",
+				"ROLLBACK TO SAVEPOINT s1",
+				"INSERT INTO items (id, name) VALUES ('5', 'e')",
+				"COMMIT",
+			},
+			expected: [][]any{{int16(1), "a"}, {int16(2), "b"}, {int16(3), "c"}, {int16(5), "e"}},
+		},
+		"released savepoint": {
+			queries: []string{
+				"BEGIN",
+				"SAVEPOINT s1",
+				"RELEASE SAVEPOINT s1",
+				"ROLLBACK TO s1",
+			},
+			expectedErr: ErrSavepointNotFound,
+		},
+		"failed statement is rolled back within transaction": {
+			queries: []string{
+				"BEGIN",
+				"DELETE FROM items WHERE id = 1",
+				"UPDATE items SET id = nullif(id, 2)",
+				"COMMIT",
+			},
+			failing:  "UPDATE items SET id = nullif(id, 2)",
+			expected: [][]any{{int16(2), "b"}},
+		},
+		"update outside of transaction": {
+			queries:  []string{"UPDATE items SET id = id, name = concat(name, id) WHERE name = 'b'"},
+			expected: [][]any{{int16(1), "a"}, {int16(2), "b2"}},
+		},
+		"commit without transaction": {
+			queries:     []string{"COMMIT"},
+			expectedErr: ErrNoActiveTransaction,
+		},
+		"nested begin": {
+			queries:     []string{"BEGIN", "BEGIN"},
+			expectedErr: ErrActiveTransaction,
+		},
+		"created table is rolled back": {
+			queries: []string{
+				"BEGIN",
+				"CREATE TABLE others (id smallint)",
+				"ROLLBACK",
+				"SELECT id FROM others",
+			},
+			expectedErr: ErrTableNotFound,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id smallint NOT NULL, name varchar)",
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('2', 'b')",
+			)
+
+			var err error
+			for _, query := range tC.queries {
+				_, err = ExecuteQuery(query)
+				if query == tC.failing {
+					var auraErr AuraError
+					if !errors.As(err, &auraErr) || auraErr.Code != ErrNotNullViolation.Code {
+						t.Fatalf("\nexp %+v\ngot %+v", ErrNotNullViolation, err)
+					}
+					err = nil
+				}
+			}
+
+			if err != tC.expectedErr {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			}
+
+			if tC.expectedErr != nil {
+				return
+			}
+
+			dataSet, err := ExecuteQuery("SELECT id, name FROM items ORDER BY id")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(resultCells(dataSet), tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, resultCells(dataSet))
+			}
+		})
+	}
+}
+
+func TestCreateExistingTable(t *testing.T) {
+	testCases := map[string][]string{
+		"outside of transaction": {"CREATE TABLE items (id smallint)"},
+		"rolled back transaction": {
+			"BEGIN",
+			"CREATE TABLE items (id smallint)",
+			"ROLLBACK",
+		},
+	}
+	for test, queries := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id smallint, name varchar)",
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+			)
+
+			for _, query := range queries {
+				_, err := ExecuteQuery(query)
+				if query == "CREATE TABLE items (id smallint)" && err != ErrTableExists {
+					t.Fatalf("\nexp %+v\ngot %+v", ErrTableExists, err)
+				}
+			}
+
+			// rows and catalog of existing table are kept
+			expected := [][]any{{int16(1), "a"}}
+			if rows := queryCells(t, "SELECT id, name FROM items"); !reflect.DeepEqual(rows, expected) {
+				t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+			}
+
+			expected = [][]any{{"id"}, {"name"}}
+			query := "SELECT column_name FROM auralis.columns WHERE table_name = 'items' ORDER BY position"
+			if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+				t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+			}
+		})
+	}
+}
+
+func TestTablesCatalog(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE items (id integer)")
+
+	query := "SELECT database_name, table_name FROM auralis.tables WHERE table_name IN ('tables', 'sequences', 'items')"
+	expected := [][]any{{"auralis", "tables"}, {"auralis", "sequences"}, {"test-database", "items"}}
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	testCases := map[string]struct {
+		create string
+		err    string // code of expected error
+	}{
+		"storable types": {
+			create: "CREATE TABLE items (a smallint, b integer, c bigint, d varchar, e uniqueidentifier, f boolean, g timestamp)",
+		},
+		"serial type": {
+			create: "CREATE TABLE items (id serial)",
+		},
+		"computed type": {
+			create: "CREATE TABLE items (id smallint, price double)",
+			err:    ErrUnsupportedColumnType.Code,
+		},
+		"unknown type": {
+			create: "CREATE TABLE items (id smallint, price foo)",
+			err:    ErrUnsupportedColumnType.Code,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t)
+
+			_, err := ExecuteQuery(tC.create)
+			code := ""
+			var auraErr AuraError
+			if errors.As(err, &auraErr) {
+				code = auraErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+
+			// rejected table is not created
+			if _, err := ExecuteQuery("SELECT * FROM items"); (err == ErrTableNotFound) != (tC.err != "") {
+				t.Errorf("\nexp table found %+v\ngot %+v", tC.err == "", err)
+			}
+		})
+	}
+}
+
+func TestBooleanColumn(t *testing.T) {
+	testCases := map[string]struct {
+		queries     []string
+		expected    [][]any
+		expectedErr error
+	}{
+		"quoted and unquoted literals": {
+			queries:  []string{"INSERT INTO flags (id, enabled) VALUES (1, 'true'), (2, false), (3, TRUE)"},
+			expected: [][]any{{int16(1), true}, {int16(2), false}, {int16(3), true}},
+		},
+		"update to literal": {
+			queries: []string{
+				"INSERT INTO flags (id, enabled) VALUES (1, true), (2, true)",
+				"UPDATE flags SET enabled = false WHERE id = 2",
+			},
+			expected: [][]any{{int16(1), true}, {int16(2), false}},
+		},
+		"condition on literal": {
+			queries: []string{
+				"INSERT INTO flags (id, enabled) VALUES (1, true), (2, false)",
+				"DELETE FROM flags WHERE enabled = false",
+			},
+			expected: [][]any{{int16(1), true}},
+		},
+		"invalid literal": {
+			queries:     []string{"INSERT INTO flags (id, enabled) VALUES (1, 'maybe')"},
+			expectedErr: ErrBooleanTypeConversion,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE flags (id smallint, enabled boolean)")
+
+			var err error
+			for _, query := range tC.queries {
+				if _, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			if err != tC.expectedErr {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			}
+
+			if tC.expectedErr != nil {
+				return
+			}
+
+			if rows := queryCells(t, "SELECT id, enabled FROM flags ORDER BY id"); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestReturning(t *testing.T) {
+	testCases := map[string]struct {
+		query string
+
+		expected    [][]any
+		affected    int
+		expectedErr error
+	}{
+		"insert returning generated column": {
+			query:    "INSERT INTO items (name) VALUES ('c') RETURNING id, upper(name) AS title",
+			expected: [][]any{{int32(3), "C"}},
+			affected: 1,
+		},
+		"update returning new values": {
+			query:    "UPDATE items SET name = concat(name, id) RETURNING *",
+			expected: [][]any{{int32(1), "a1"}, {int32(2), "b2"}},
+			affected: 2,
+		},
+		"delete returning removed rows": {
+			query:    "DELETE FROM items WHERE id = 2 RETURNING items.name",
+			expected: [][]any{{"b"}},
+			affected: 1,
+		},
+		"delete without matching rows": {
+			query:    "DELETE FROM items WHERE id = 5 RETURNING id",
+			expected: [][]any{},
+		},
+		"insert without returning": {
+			query:    "INSERT INTO items (name) VALUES ('c')",
+			expected: [][]any{},
+			affected: 1,
+		},
+		"update without returning": {
+			query:    "UPDATE items SET name = 'c'",
+			expected: [][]any{},
+			affected: 2,
+		},
+		"returning unknown column": {
+			query:       "DELETE FROM items RETURNING price",
+			expectedErr: ErrColumnNotFound,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id serial, name varchar)",
+				"INSERT INTO items (name) VALUES ('a')",
+				"INSERT INTO items (name) VALUES ('b')",
+			)
+
+			dataSet, err := ExecuteQuery(tC.query)
+			if err != tC.expectedErr {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			}
+
+			if tC.expectedErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(resultCells(dataSet), tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, resultCells(dataSet))
+			}
+
+			if dataSet.affected != tC.affected {
+				t.Errorf("\nexp %+v\ngot %+v", tC.affected, dataSet.affected)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package auralis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ExplainNode describes operator of executed plan, actual values are
+// present only when the plan was analyzed
+type ExplainNode struct {
+	NodeType    string   `json:"Node Type"`
+	Relation    string   `json:"Relation Name,omitempty"`
+	Alias       string   `json:"Alias,omitempty"`
+	Index       string   `json:"Index Name,omitempty"`
+	StartupCost float64  `json:"Startup Cost"`
+	TotalCost   float64  `json:"Total Cost"`
+	PlanRows    int64    `json:"Plan Rows"`
+	Filter      string   `json:"Filter,omitempty"`
+	JoinFilter  string   `json:"Join Filter,omitempty"`
+	GroupKey    []string `json:"Group Key,omitempty"`
+	SortKey     []string `json:"Sort Key,omitempty"`
+	*ExplainActual
+	Plans []*ExplainNode `json:"Plans,omitempty"`
+
+	stats *OperatorStats
+}
+
+// ExplainActual are values measured by explain analyze, times are in
+// milliseconds and include time spent by inputs
+type ExplainActual struct {
+	StartupTime float64 `json:"Actual Startup Time"`
+	TotalTime   float64 `json:"Actual Total Time"`
+	Rows        int64   `json:"Actual Rows"`
+	Loops       int64   `json:"Actual Loops"`
+	PagesRead   int64   `json:"Pages Read"`
+}
+
+type ExplainResult struct {
+	Plan          *ExplainNode `json:"Plan"`
+	PlanningTime  *float64     `json:"Planning Time,omitempty"`
+	ExecutionTime *float64     `json:"Execution Time,omitempty"`
+}
+
+// OperatorStats are counters of instrumented operator
+type OperatorStats struct {
+	rows    int64
+	loops   int64
+	pages   int64
+	startup time.Duration // time until the first row
+	total   time.Duration
+}
+
+// pageReader is operator reading table pages
+type pageReader interface {
+	pagesRead() int64
+}
+
+// instrumentedOperator measures rows and time of operator
+type instrumentedOperator struct {
+	operator Operator
+	stats    *OperatorStats
+	elapsed  time.Duration // time spent by current loop
+}
+
+func (o *instrumentedOperator) open() error {
+	o.stats.loops++
+	o.elapsed = 0
+
+	start := time.Now()
+	err := o.operator.open()
+	o.elapsed += time.Since(start)
+
+	return err
+}
+
+func (o *instrumentedOperator) next() (Row, bool, error) {
+	start := time.Now()
+	row, ok, err := o.operator.next()
+	o.elapsed += time.Since(start)
+
+	if ok {
+		o.stats.rows++
+		if o.stats.rows == 1 {
+			o.stats.startup = o.elapsed
+		}
+	}
+
+	return row, ok, err
+}
+
+func (o *instrumentedOperator) close() error {
+	start := time.Now()
+	err := o.operator.close()
+	o.elapsed += time.Since(start)
+
+	o.stats.total += o.elapsed
+	if reader, ok := o.operator.(pageReader); ok {
+		o.stats.pages = reader.pagesRead()
+	}
+
+	return err
+}
+
+func handleExplainQuery(tx *Transaction, query ExplainQuery) (*DataSet, error) {
+	start := time.Now()
+	plan, err := planSelect(tx, query.query, map[string]*DataSet{})
+	if err != nil {
+		return &DataSet{}, err
+	}
+	plan = optimizePlan(plan)
+
+	// operators are built bottom up, each node takes its inputs from stack
+	nodes := []*ExplainNode{}
+	estimates := []Estimate{}
+	root, err := buildOperator(tx, plan, func(plan LogicalPlan, operator Operator) Operator {
+		count := len(plan.inputs())
+		inputs := estimates[len(estimates)-count:]
+		estimate := estimatePlan(plan, operator, inputs)
+
+		node := describeOperator(plan, operator)
+		node.StartupCost, node.TotalCost = roundCost(estimate.startup), roundCost(estimate.total)
+		node.PlanRows = int64(estimate.rows)
+		node.Plans = append(node.Plans, nodes[len(nodes)-count:]...)
+		node.stats = &OperatorStats{}
+
+		nodes = append(nodes[:len(nodes)-count], node)
+		estimates = append(estimates[:len(estimates)-count], estimate)
+		return &instrumentedOperator{operator: operator, stats: node.stats}
+	})
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	result := ExplainResult{Plan: nodes[0]}
+	if query.analyze {
+		planning := time.Since(start)
+
+		start = time.Now()
+		if _, err := runOperator(root, plan.columns()); err != nil {
+			return &DataSet{}, err
+		}
+		execution := time.Since(start)
+
+		addActualValues(result.Plan)
+		planningTime, executionTime := milliseconds(planning), milliseconds(execution)
+		result.PlanningTime, result.ExecutionTime = &planningTime, &executionTime
+	}
+
+	dataSet := &DataSet{columns: []Column{{name: "QUERY PLAN", dataType: varchar}}}
+	if query.format == "json" {
+		data, err := json.MarshalIndent([]ExplainResult{result}, "", "  ")
+		if err != nil {
+			return &DataSet{}, err
+		}
+
+		dataSet.rows = []Row{{cells: []any{string(data)}}}
+		return dataSet, nil
+	}
+
+	for _, line := range formatExplainResult(result) {
+		dataSet.rows = append(dataSet.rows, Row{cells: []any{line}})
+	}
+
+	return dataSet, nil
+}
+
+// describeOperator returns node naming operator and its conditions and keys,
+// expressions referencing results of aggregates and windows are shown as
+// the calls computing them
+func describeOperator(plan LogicalPlan, operator Operator) *ExplainNode {
+	node := &ExplainNode{}
+	computed := computedExpressions(plan)
+
+	switch p := plan.(type) {
+	case ScanPlan:
+		switch op := operator.(type) {
+		case *seqScanOperator:
+			node.NodeType = "Seq Scan"
+		case *indexScanOperator:
+			node.NodeType, node.Index = "Index Scan", op.scan.index.name
+		default:
+			node.NodeType = "Result"
+			if p.source != (SchemaTable[string, string]{}) {
+				node.NodeType = "CTE Scan"
+			}
+		}
+
+		if p.source != (SchemaTable[string, string]{}) {
+			node.Relation, node.Alias = p.source.name, p.qualifier
+		}
+		node.Filter = formatConditions(p.conditions, computed)
+	case FilterPlan:
+		node.NodeType, node.Filter = "Filter", formatConditions(p.conditions, computed)
+	case JoinPlan:
+		node.NodeType, node.JoinFilter = "Nested Loop", formatConditions(p.conditions, computed)
+	case AggregatePlan:
+		node.NodeType = "Aggregate"
+		for _, expr := range p.groupBy {
+			node.GroupKey = append(node.GroupKey, formatExpression(expr, computed))
+		}
+	case WindowPlan:
+		node.NodeType = "WindowAgg"
+	case SortPlan:
+		node.NodeType = "Sort"
+		for _, item := range p.orderBy {
+			key := formatExpression(item.expression, computed)
+			if item.descending {
+				key += " DESC"
+			}
+
+			node.SortKey = append(node.SortKey, key)
+		}
+	case ProjectPlan:
+		node.NodeType = "Project"
+	case LimitPlan:
+		node.NodeType = "Limit"
+	case UnionPlan:
+		node.NodeType = "Union"
+		if p.all {
+			node.NodeType = "Union All"
+		}
+	}
+
+	return node
+}
+
+// computedExpressions maps hidden columns produced by aggregate and window
+// nodes below plan to expressions computing them
+func computedExpressions(plan LogicalPlan) map[string]Expression {
+	computed := map[string]Expression{}
+	for _, input := range plan.inputs() {
+		for name, expr := range computedExpressions(input) {
+			computed[name] = expr
+		}
+	}
+
+	switch p := plan.(type) {
+	case AggregatePlan:
+		for i, expr := range p.groupBy {
+			computed[hiddenColumnName("group", i)] = expr
+		}
+
+		for i, call := range p.aggregates {
+			computed[hiddenColumnName("aggregate", i)] = call
+		}
+	case WindowPlan:
+		for i, call := range p.calls {
+			computed[hiddenColumnName("window", i)] = call
+		}
+	}
+
+	return computed
+}
+
+func addActualValues(node *ExplainNode) {
+	node.ExplainActual = &ExplainActual{
+		StartupTime: milliseconds(node.stats.startup),
+		TotalTime:   milliseconds(node.stats.total),
+		Rows:        node.stats.rows,
+		Loops:       node.stats.loops,
+		PagesRead:   node.stats.pages,
+	}
+
+	for _, input := range node.Plans {
+		addActualValues(input)
+	}
+}
+
+// formatExplainResult renders plan as indented tree, inputs of node are
+// prefixed with arrow
+func formatExplainResult(result ExplainResult) []string {
+	lines := []string{}
+	var format func(node *ExplainNode, depth int)
+	format = func(node *ExplainNode, depth int) {
+		indent, details := "", "  "
+		if depth > 0 {
+			indent = strings.Repeat(" ", 6*depth-4) + "->  "
+			details = strings.Repeat(" ", 6*depth+2)
+		}
+
+		label := node.NodeType
+		if node.Index != "" {
+			label += " using " + node.Index
+		}
+
+		if node.Relation != "" {
+			label += " on " + node.Relation
+			if node.Alias != node.Relation {
+				label += " " + node.Alias
+			}
+		}
+
+		line := fmt.Sprintf("%s%s  (cost=%.2f..%.2f rows=%d)", indent, label, node.StartupCost, node.TotalCost, node.PlanRows)
+		switch {
+		case node.ExplainActual == nil:
+		case node.Loops == 0:
+			line += " (never executed)"
+		default:
+			line += fmt.Sprintf(" (actual time=%.3f..%.3f rows=%d loops=%d)",
+				node.StartupTime, node.TotalTime, node.Rows, node.Loops)
+		}
+		lines = append(lines, line)
+
+		if node.Filter != "" {
+			lines = append(lines, details+"Filter: "+node.Filter)
+		}
+
+		if node.JoinFilter != "" {
+			lines = append(lines, details+"Join Filter: "+node.JoinFilter)
+		}
+
+		if len(node.GroupKey) > 0 {
+			lines = append(lines, details+"Group Key: "+strings.Join(node.GroupKey, ", "))
+		}
+
+		if len(node.SortKey) > 0 {
+			lines = append(lines, details+"Sort Key: "+strings.Join(node.SortKey, ", "))
+		}
+
+		if node.ExplainActual != nil && node.PagesRead > 0 {
+			lines = append(lines, fmt.Sprintf("%sPages Read: %d", details, node.PagesRead))
+		}
+
+		for _, input := range node.Plans {
+			format(input, depth+1)
+		}
+	}
+	format(result.Plan, 0)
+
+	if result.PlanningTime != nil {
+		lines = append(lines, fmt.Sprintf("Planning Time: %.3f ms", *result.PlanningTime))
+	}
+
+	if result.ExecutionTime != nil {
+		lines = append(lines, fmt.Sprintf("Execution Time: %.3f ms", *result.ExecutionTime))
+	}
+
+	return lines
+}
+
+func formatConditions(conditions []Condition, computed map[string]Expression) string {
+	formatted := []string{}
+	for _, condition := range conditions {
+		formatted = append(formatted, formatCondition(condition, computed))
+	}
+
+	return strings.Join(formatted, " AND ")
+}
+
+func formatCondition(condition Condition, computed map[string]Expression) string {
+	target := condition.target
+	if condition.expr != nil {
+		target = formatExpression(condition.expr, computed)
+	}
+
+	sign := strings.ToUpper(condition.sign)
+	if isNullCondition(condition) {
+		return fmt.Sprintf("%s %s", target, sign)
+	}
+
+	switch value := condition.value.(type) {
+	case Expression:
+		return fmt.Sprintf("%s %s %s", target, sign, formatExpression(value, computed))
+	case []any:
+		values := []string{}
+		for _, v := range value {
+			values = append(values, fmt.Sprintf("%v", v))
+		}
+
+		if base, _ := baseSign(condition.sign); base == "between" {
+			return fmt.Sprintf("%s %s %s", target, sign, strings.Join(values, " AND "))
+		}
+
+		return fmt.Sprintf("%s %s (%s)", target, sign, strings.Join(values, ", "))
+	default:
+		return fmt.Sprintf("%s %s %v", target, sign, value)
+	}
+}
+
+// formatExpression renders expression as sql text
+func formatExpression(expr Expression, computed map[string]Expression) string {
+	switch e := expr.(type) {
+	case ColumnReference:
+		if c, ok := computed[e.name]; ok {
+			return formatExpression(c, computed)
+		}
+
+		return e.name
+	case Literal:
+		switch v := e.value.(type) {
+		case nil:
+			return "NULL"
+		case string:
+			return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		default:
+			return fmt.Sprintf("%v", v)
+		}
+	case FunctionCall:
+		args := []string{}
+		for _, arg := range e.args {
+			args = append(args, formatExpression(arg, computed))
+		}
+		if e.star {
+			args = []string{"*"}
+		}
+
+		call := fmt.Sprintf("%s(%s)", e.name, strings.Join(args, ", "))
+		if e.over != nil {
+			call += " OVER (...)"
+		}
+
+		return call
+	case CaseExpression:
+		return "CASE ... END"
+	default:
+		return expr.String()
+	}
+}
+
+func roundCost(cost float64) float64 {
+	return math.Round(cost*100) / 100
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
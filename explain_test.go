@@ -0,0 +1,132 @@
+package auralis
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// explainNumbers matches estimates and measurements which vary between runs
+var explainNumbers = regexp.MustCompile(`\(cost=[^)]*\)|\(actual [^)]*\)|\d+\.\d+ ms`)
+
+func TestExplain(t *testing.T) {
+	testCases := map[string]struct {
+		query    string
+		expected []string
+	}{
+		"scan with pushed condition": {
+			query: "EXPLAIN SELECT name FROM users WHERE age > 18",
+			expected: []string{
+				"Project  #",
+				"  ->  Seq Scan on users  #",
+				"        Filter: age > 18",
+			},
+		},
+		"index scan": {
+			query: "EXPLAIN SELECT name FROM users u WHERE u.id = 2",
+			expected: []string{
+				"Project  #",
+				"  ->  Index Scan using users_pkey on users u  #",
+				"        Filter: u.id = 2",
+			},
+		},
+		"join with aggregate": {
+			query: "EXPLAIN SELECT u.name, count(o.id) AS orders FROM users u JOIN orders o ON u.id = o.user_id " +
+				"GROUP BY u.name ORDER BY count(o.id) DESC LIMIT 1",
+			expected: []string{
+				"Limit  #",
+				"  ->  Project  #",
+				"        ->  Sort  #",
+				"              Sort Key: count(o.id) DESC",
+				"              ->  Aggregate  #",
+				"                    Group Key: u.name",
+				"                    ->  Nested Loop  #",
+				"                          Join Filter: u.id = o.user_id",
+				"                          ->  Seq Scan on users u  #",
+				"                          ->  Seq Scan on orders o  #",
+			},
+		},
+		"analyze": {
+			query: "EXPLAIN ANALYZE SELECT name FROM users WHERE name IN ('a', 'b')",
+			expected: []string{
+				"Project  # #",
+				"  ->  Seq Scan on users  # #",
+				"        Filter: name IN ('a', 'b')",
+				"        Pages Read: 1",
+				"Planning Time: #",
+				"Execution Time: #",
+			},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE users (id integer PRIMARY KEY, name varchar, age integer)",
+				"CREATE TABLE orders (id integer PRIMARY KEY, user_id integer)",
+				"INSERT INTO users (id, name, age) VALUES (1, 'a', 20), (2, 'b', 30), (3, 'c', 15)",
+				"INSERT INTO orders (id, user_id) VALUES (1, 1), (2, 1), (3, 2)",
+			)
+
+			dataSet, err := ExecuteQuery(tC.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := []string{}
+			for _, row := range dataSet.rows {
+				lines = append(lines, explainNumbers.ReplaceAllString(row.cells[0].(string), "#"))
+			}
+
+			if !reflect.DeepEqual(lines, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, lines)
+			}
+		})
+	}
+}
+
+func TestExplainAnalyzeJSON(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE users (id integer PRIMARY KEY, name varchar)",
+		"INSERT INTO users (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd')",
+	)
+
+	dataSet, err := ExecuteQuery("EXPLAIN (ANALYZE, FORMAT JSON) SELECT id FROM users WHERE id > 1 LIMIT 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results []ExplainResult
+	if err := json.Unmarshal([]byte(dataSet.rows[0].cells[0].(string)), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].PlanningTime == nil || results[0].ExecutionTime == nil {
+		t.Fatalf("unexpected result %+v", results)
+	}
+
+	// scan stops when limit is reached
+	type actual struct {
+		node  string
+		rows  int64
+		loops int64
+		pages int64
+	}
+	expected := []actual{{"Limit", 2, 1, 0}, {"Project", 2, 1, 0}, {"Index Scan", 2, 1, 2}}
+	got := []actual{}
+	for node := results[0].Plan; node != nil; {
+		got = append(got, actual{node.NodeType, node.Rows, node.Loops, node.PagesRead})
+		if node.PlanRows < 1 || node.TotalCost < node.StartupCost {
+			t.Errorf("invalid estimate of %s %+v", node.NodeType, node)
+		}
+
+		if len(node.Plans) == 0 {
+			break
+		}
+		node = node.Plans[0]
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, got)
+	}
+}
@@ -0,0 +1,398 @@
+package auralis
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Expression interface {
+	// String returns label used as column name in query results
+	String() string
+}
+
+type ColumnReference struct {
+	name string // optionally qualified with table name or alias
+}
+
+type Literal struct {
+	value any
+}
+
+// Parameter is $n placeholder of prepared statement, it's replaced by bound
+// value before statement is executed
+type Parameter struct {
+	n int
+}
+
+type FunctionCall struct {
+	name string
+	args []Expression
+	star bool              // function called with *, eg. count(*)
+	over *WindowDefinition // not nil for window function calls
+}
+
+type WindowDefinition struct {
+	partitionBy []Expression
+	orderBy     []OrderByItem
+	frame       *WindowFrame // nil for default frame
+}
+
+type OrderByItem struct {
+	expression Expression
+	descending bool
+}
+
+type FrameBoundKind int
+
+const (
+	unboundedPreceding FrameBoundKind = iota
+	offsetPreceding
+	currentRow
+	offsetFollowing
+	unboundedFollowing
+)
+
+// WindowFrame describes rows frame relative to the current row
+type WindowFrame struct {
+	start FrameBound
+	end   FrameBound
+}
+
+type FrameBound struct {
+	kind   FrameBoundKind
+	offset int
+}
+
+var (
+	ErrFunctionNotFound = AuraError{Code: "FUNCTION_NOT_FOUND", Message: "function does not exist"}
+	ErrGroupingRequired = AuraError{
+		Code:    "GROUPING_ERROR",
+		Message: "column must appear in the group by clause or be used in an aggregate function"}
+	ErrMisplacedFunction = AuraError{
+		Code:    "GROUPING_ERROR",
+		Message: "aggregate and window functions are not allowed here"}
+)
+
+func (c ColumnReference) String() string {
+	return unqualifiedName(c.name)
+}
+
+func (l Literal) String() string {
+	return "?column?"
+}
+
+func (p Parameter) String() string {
+	return "?column?"
+}
+
+func (f FunctionCall) String() string {
+	return f.name
+}
+
+// parseOperand converts symbol token value into literal or column reference
+func parseOperand(value string) Expression {
+	if strings.HasPrefix(value, "'") {
+		return Literal{value: unquote(value)}
+	}
+
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return Literal{value: v}
+	}
+
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return Literal{value: v}
+	}
+
+	if value == "null" {
+		return Literal{value: nil}
+	}
+
+	if isBooleanLiteral(value) {
+		return Literal{value: value == "true"}
+	}
+
+	if n, ok := parameterIndex(value); ok {
+		return Parameter{n: n}
+	}
+
+	return ColumnReference{name: value}
+}
+
+// evaluateExpression computes expression value for row described by columns
+func evaluateExpression(expr Expression, columns []Column, row Row) (any, error) {
+	switch expr := expr.(type) {
+	case ColumnReference:
+		i, err := resolveColumn(columns, expr.name)
+		if err != nil {
+			return nil, err
+		}
+
+		return row.cells[i], nil
+	case Literal:
+		return expr.value, nil
+	case FunctionCall:
+		if expr.over != nil || isAggregateFunction(expr.name) {
+			return nil, ErrMisplacedFunction
+		}
+
+		fn, ok := scalarFunctions[expr.name]
+		if !ok {
+			return nil, ErrFunctionNotFound
+		}
+
+		args := make([]any, 0, len(expr.args))
+		for _, arg := range expr.args {
+			value, err := evaluateExpression(arg, columns, row)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, value)
+		}
+
+		return callScalarFunction(expr.name, fn, args)
+	case CaseExpression:
+		return evaluateCaseExpression(expr, columns, row)
+	default:
+		panic("unhandled expression")
+	}
+}
+
+// validateExpression checks that referenced columns and functions exist and
+// functions are called with arguments of expected types
+func validateExpression(expr Expression, columns []Column) error {
+	switch expr := expr.(type) {
+	case ColumnReference:
+		if expr.name == "*" {
+			return nil
+		}
+
+		_, err := resolveColumn(columns, expr.name)
+		return err
+	case FunctionCall:
+		argTypes := make([]DataType, 0, len(expr.args))
+		for _, arg := range expr.args {
+			if err := validateExpression(arg, columns); err != nil {
+				return err
+			}
+
+			argTypes = append(argTypes, expressionDataType(arg, columns))
+		}
+
+		if expr.over != nil {
+			for _, e := range slices.Concat(expr.over.partitionBy, orderByExpressions(expr.over.orderBy)) {
+				if err := validateExpression(e, columns); err != nil {
+					return err
+				}
+			}
+		}
+
+		if isAggregateFunction(expr.name) || (expr.over != nil && isWindowFunction(expr.name)) {
+			return nil
+		}
+
+		fn, ok := scalarFunctions[expr.name]
+		if !ok {
+			return AuraError{Code: ErrFunctionNotFound.Code, Message: fmt.Sprintf("function %s does not exist", expr.name)}
+		}
+
+		return checkFunctionArguments(expr.name, fn, argTypes)
+	case CaseExpression:
+		for _, e := range caseSubexpressions(expr) {
+			if err := validateExpression(e, columns); err != nil {
+				return err
+			}
+		}
+
+		_, err := caseDataType(expr, columns)
+		return err
+	default:
+		return nil
+	}
+}
+
+// expressionDataType infers type of values produced by expression
+func expressionDataType(expr Expression, columns []Column) DataType {
+	switch expr := expr.(type) {
+	case ColumnReference:
+		i, err := resolveColumn(columns, expr.name)
+		if err != nil {
+			return varchar
+		}
+
+		return columns[i].dataType
+	case Literal:
+		return valueDataType(expr.value)
+	case FunctionCall:
+		switch expr.name {
+		case "row_number", "rank", "dense_rank", "count", "sum":
+			return bigint
+		case "avg":
+			return double
+		}
+
+		if fn, ok := scalarFunctions[expr.name]; ok && expr.over == nil {
+			argTypes := make([]DataType, 0, len(expr.args))
+			for _, arg := range expr.args {
+				argTypes = append(argTypes, expressionDataType(arg, columns))
+			}
+
+			return fn.returnType(argTypes)
+		}
+
+		if len(expr.args) > 0 {
+			return expressionDataType(expr.args[0], columns)
+		}
+
+		return varchar
+	case CaseExpression:
+		dataType, err := caseDataType(expr, columns)
+		if err != nil {
+			return varchar
+		}
+
+		return dataType
+	default:
+		panic("unhandled expression")
+	}
+}
+
+func valueDataType(value any) DataType {
+	switch value.(type) {
+	case nil:
+		return unknown
+	case uuid.UUID:
+		return uniqueidentifier
+	case int16:
+		return smallint
+	case int32:
+		return integer
+	case int64:
+		return bigint
+	case float64:
+		return double
+	case time.Time:
+		return timestamp
+	case bool:
+		return boolean
+	default:
+		return varchar
+	}
+}
+
+// rewriteExpression replaces expression nodes for which replace returns true,
+// remaining nodes are traversed
+func rewriteExpression(expr Expression, replace func(Expression) (Expression, bool, error)) (Expression, error) {
+	replaced, ok, err := replace(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		return replaced, nil
+	}
+
+	if c, ok := expr.(CaseExpression); ok {
+		return rewriteCaseExpression(c, replace)
+	}
+
+	call, ok := expr.(FunctionCall)
+	if !ok {
+		return expr, nil
+	}
+
+	args := make([]Expression, 0, len(call.args))
+	for _, arg := range call.args {
+		arg, err := rewriteExpression(arg, replace)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+	}
+	call.args = args
+
+	if call.over != nil {
+		over := *call.over
+		over.partitionBy = nil
+		for _, partition := range call.over.partitionBy {
+			partition, err := rewriteExpression(partition, replace)
+			if err != nil {
+				return nil, err
+			}
+
+			over.partitionBy = append(over.partitionBy, partition)
+		}
+
+		over.orderBy = nil
+		for _, item := range call.over.orderBy {
+			e, err := rewriteExpression(item.expression, replace)
+			if err != nil {
+				return nil, err
+			}
+
+			over.orderBy = append(over.orderBy, OrderByItem{expression: e, descending: item.descending})
+		}
+		call.over = &over
+	}
+
+	return call, nil
+}
+
+// collectFunctionCalls returns distinct function calls matching predicate,
+// nested calls of matching ones are not traversed
+func collectFunctionCalls(exprs []Expression, predicate func(FunctionCall) bool) []FunctionCall {
+	calls := []FunctionCall{}
+	for _, expr := range exprs {
+		rewriteExpression(expr, func(e Expression) (Expression, bool, error) {
+			call, ok := e.(FunctionCall)
+			if !ok || !predicate(call) {
+				return nil, false, nil
+			}
+
+			for _, c := range calls {
+				if reflect.DeepEqual(c, call) {
+					return e, true, nil
+				}
+			}
+
+			calls = append(calls, call)
+			return e, true, nil
+		})
+	}
+
+	return calls
+}
+
+// sameExpression compares expressions, column references are compared by resolved column
+func sameExpression(a Expression, b Expression, columns []Column) bool {
+	ac, aok := a.(ColumnReference)
+	bc, bok := b.(ColumnReference)
+	if aok && bok {
+		ai, aerr := resolveColumn(columns, ac.name)
+		bi, berr := resolveColumn(columns, bc.name)
+		return aerr == nil && berr == nil && ai == bi
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func orderByExpressions(items []OrderByItem) []Expression {
+	exprs := make([]Expression, 0, len(items))
+	for _, item := range items {
+		exprs = append(exprs, item.expression)
+	}
+
+	return exprs
+}
+
+func hiddenColumnName(kind string, i int) string {
+	// # can't be a part of identifier so it won't clash with table columns
+	return fmt.Sprintf("#%s%d", kind, i)
+}
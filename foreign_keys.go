@@ -0,0 +1,300 @@
+package auralis
+
+import (
+	"fmt"
+	"slices"
+)
+
+// referential actions of foreign keys
+const (
+	noAction       = "NO ACTION"
+	restrictAction = "RESTRICT"
+	cascadeAction  = "CASCADE"
+	setNullAction  = "SET NULL"
+)
+
+var (
+	ErrForeignKeyViolation = AuraError{Code: "FOREIGN_KEY_VIOLATION", Message: "foreign key constraint is violated"}
+	ErrInvalidForeignKey   = AuraError{Code: "INVALID_FOREIGN_KEY", Message: "referenced columns are not covered by unique constraint"}
+	ErrForeignKeyMismatch  = AuraError{Code: "INVALID_FOREIGN_KEY", Message: "referencing and referenced columns don't match"}
+)
+
+// resolveForeignKey checks foreign key against referenced table, referenced
+// columns default to its primary key and have to be covered by unique index.
+// Referenced table is locked in share mode so its writers started before see
+// the foreign key when they remove referenced rows
+func resolveForeignKey(tx *Transaction, table Table, constraint *Constraint) error {
+	parent, err := getTable(tx, constraint.references)
+	if err != nil {
+		return err
+	}
+
+	// constraints of the new table aren't registered yet
+	if parent.schemaTable == table.schemaTable {
+		parent.constraints = table.constraints
+	}
+
+	if err := lockManager.lock(tx, tableLockTag(parent), shared); err != nil {
+		return err
+	}
+
+	if len(constraint.referencedColumns) == 0 {
+		i := slices.IndexFunc(parent.constraints, func(c Constraint) bool { return c.kind == primaryKeyConstraint })
+		if i == -1 {
+			return ErrInvalidForeignKey
+		}
+		constraint.referencedColumns = parent.constraints[i].columns
+	}
+
+	if len(constraint.referencedColumns) != len(constraint.columns) {
+		return ErrForeignKeyMismatch
+	}
+
+	for i, name := range constraint.referencedColumns {
+		j := slices.IndexFunc(parent.columns, func(cd Column) bool { return cd.name == name })
+		if j == -1 {
+			return ErrColumnNotFound
+		}
+
+		k := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == constraint.columns[i] })
+		if parent.columns[j].dataType != table.columns[k].dataType {
+			return ErrForeignKeyMismatch
+		}
+	}
+
+	covered := slices.ContainsFunc(parent.indexes, func(index Index) bool {
+		return index.unique && len(index.columns) == len(constraint.referencedColumns) &&
+			!slices.ContainsFunc(index.columns, func(cd Column) bool { return !slices.Contains(constraint.referencedColumns, cd.name) })
+	})
+	if !covered {
+		return ErrInvalidForeignKey
+	}
+
+	return nil
+}
+
+// referencingConstraints returns foreign keys referencing table committed so
+// far or created by transaction, like indexes they are listed again after the
+// table lock is acquired
+func referencingConstraints(tx *Transaction, table Table) ([]Constraint, error) {
+	snapshot := writeAheadLog.latestSnapshot()
+	return readConstraints(&Transaction{id: tx.id, snapshot: &snapshot}, []Condition{
+		{target: "ref_schema", sign: "=", value: table.schemaTable.schema},
+		{target: "ref_table", sign: "=", value: table.schemaTable.name},
+	})
+}
+
+// rowValues returns values of columns of row
+func rowValues(table Table, row Row, columns []string) []any {
+	values := []any{}
+	for _, name := range columns {
+		i := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == name })
+		values = append(values, row.cells[i])
+	}
+
+	return values
+}
+
+// findRowsByValues returns locations and all column values of rows whose
+// columns are equal to values, rows are found by index of columns when available
+func findRowsByValues(tx *Transaction, table Table, columns []string, values []any) ([]RowID, []Row, error) {
+	names := []string{}
+	for _, cd := range table.columns {
+		names = append(names, cd.name)
+	}
+
+	conditions := []Condition{}
+	for i, name := range columns {
+		conditions = append(conditions, Condition{target: name, sign: "=", value: values[i]})
+	}
+
+	ids, rows := []RowID{}, []Row{}
+	err := scanRows(tx, table, conditions, func(id RowID, tuple []byte) error {
+		row, err := decodeRow(table, tuple, names)
+		if err != nil {
+			return err
+		}
+
+		for i, value := range rowValues(table, row, columns) {
+			if compareValues(value, values[i]) != 0 {
+				return nil
+			}
+		}
+
+		ids = append(ids, id)
+		rows = append(rows, row)
+		return nil
+	})
+
+	return ids, rows, err
+}
+
+// checkForeignKeys verifies that rows reference existing rows of referenced
+// tables, referenced rows are locked in share mode so they can't be removed
+// until transaction ends. Rows with null foreign key columns aren't checked
+func checkForeignKeys(tx *Transaction, table Table, rows []Row) error {
+	for _, constraint := range table.constraints {
+		if constraint.kind != foreignKeyConstraint || len(rows) == 0 {
+			continue
+		}
+
+		parent := table
+		if constraint.references != table.schemaTable {
+			var err error
+			if parent, err = getTable(tx, constraint.references); err != nil {
+				return err
+			}
+		}
+
+		if err := lockManager.lock(tx, tableLockTag(parent), intentionShared); err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			values := rowValues(table, row, constraint.columns)
+			if slices.Contains(values, nil) {
+				continue
+			}
+
+			ids, _, err := findRowsByValues(tx, parent, constraint.referencedColumns, values)
+			if err != nil {
+				return err
+			}
+
+			if len(ids) == 0 {
+				return AuraError{
+					Code: ErrForeignKeyViolation.Code,
+					Message: fmt.Sprintf("key %v is not present in table %s referenced by constraint %s",
+						values, parent.schemaTable.name, constraint.name)}
+			}
+
+			if err := tx.lockRow(ids[0], shared); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// keyChange is referenced key removed from table or replaced by new key
+type keyChange struct {
+	old, new []any
+}
+
+// changedKeys returns referenced keys of old rows removed or changed by updated
+// rows, updated is nil when rows are deleted
+func changedKeys(table Table, columns []string, old []Row, updated []Row) []keyChange {
+	changes := []keyChange{}
+	seen := map[string]bool{}
+	for i, row := range old {
+		change := keyChange{old: rowValues(table, row, columns)}
+		if updated != nil {
+			change.new = rowValues(table, updated[i], columns)
+			if slices.EqualFunc(change.old, change.new, func(a, b any) bool { return compareValues(a, b) == 0 }) {
+				continue
+			}
+		}
+
+		key := fmt.Sprintf("%v", change.old)
+		if slices.Contains(change.old, nil) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// referentialActions handles rows referencing keys of table removed or changed
+// by statement, updated holds new versions of old rows and is nil on delete.
+// Restrict is checked before rows are changed, other actions afterwards.
+// No action fails only when the key isn't provided by other row of table
+func referentialActions(tx *Transaction, table Table, old []Row, updated []Row, before bool) error {
+	if len(old) == 0 {
+		return nil
+	}
+
+	constraints, err := referencingConstraints(tx, table)
+	if err != nil {
+		return err
+	}
+
+	for _, constraint := range constraints {
+		action := constraint.onDelete
+		if updated != nil {
+			action = constraint.onUpdate
+		}
+
+		if (action == restrictAction) != before {
+			continue
+		}
+
+		changes := changedKeys(table, constraint.referencedColumns, old, updated)
+		if len(changes) == 0 {
+			continue
+		}
+
+		child := table
+		if constraint.source != table.schemaTable {
+			if child, err = getTable(tx, constraint.source); err != nil {
+				return err
+			}
+		}
+
+		if err := lockManager.lock(tx, tableLockTag(child), intentionExclusive); err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			ids, rows, err := findRowsByValues(tx, child, constraint.columns, change.old)
+			if err != nil {
+				return err
+			}
+
+			if len(ids) == 0 {
+				continue
+			}
+
+			switch {
+			case action == cascadeAction && updated == nil:
+				_, err = deleteRows(tx, child, ids, rows)
+			case action == cascadeAction || action == setNullAction:
+				_, err = updateRows(tx, child, ids, rows, func(row Row) (Row, error) {
+					changed := Row{cells: slices.Clone(row.cells)}
+					for i, name := range constraint.columns {
+						j := slices.IndexFunc(child.columns, func(cd Column) bool { return cd.name == name })
+						changed.cells[j] = nil
+						if action == cascadeAction {
+							changed.cells[j] = change.new[i]
+						}
+					}
+
+					return changed, nil
+				})
+			default:
+				if action == noAction {
+					provided, _, err := findRowsByValues(tx, table, constraint.referencedColumns, change.old)
+					if err != nil {
+						return err
+					}
+
+					if len(provided) > 0 {
+						continue
+					}
+				}
+
+				return AuraError{
+					Code: ErrForeignKeyViolation.Code,
+					Message: fmt.Sprintf("key %v of table %s is still referenced from table %s by constraint %s",
+						change.old, table.schemaTable.name, child.schemaTable.name, constraint.name)}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package auralis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestForeignKeys(t *testing.T) {
+	testCases := map[string]struct {
+		create   string
+		queries  []string
+		err      string  // code of expected error
+		expected [][]any // id and parent of children
+	}{
+		"insert referencing missing row": {
+			create:  "CREATE TABLE children (id smallint, parent smallint REFERENCES parents)",
+			queries: []string{"INSERT INTO children (id, parent) VALUES ('3', '3')"},
+			err:     ErrForeignKeyViolation.Code,
+		},
+		"update referencing missing row": {
+			create:  "CREATE TABLE children (id smallint, parent smallint REFERENCES parents (id))",
+			queries: []string{"UPDATE children SET parent = 3 WHERE id = 1"},
+			err:     ErrForeignKeyViolation.Code,
+		},
+		"delete of referenced row with no action": {
+			create:  "CREATE TABLE children (id smallint, parent smallint REFERENCES parents)",
+			queries: []string{"DELETE FROM parents WHERE id = 1"},
+			err:     ErrForeignKeyViolation.Code,
+		},
+		"delete of referenced row with restrict": {
+			create:  "CREATE TABLE children (id smallint, parent smallint REFERENCES parents ON DELETE RESTRICT)",
+			queries: []string{"DELETE FROM parents WHERE id = 1"},
+			err:     ErrForeignKeyViolation.Code,
+		},
+		"delete of unreferenced row": {
+			create:   "CREATE TABLE children (id smallint, parent smallint REFERENCES parents)",
+			queries:  []string{"DELETE FROM children WHERE id = 2", "DELETE FROM parents WHERE id = 2"},
+			expected: [][]any{{int16(1), int16(1)}},
+		},
+		"delete cascades": {
+			create:   "CREATE TABLE children (id smallint, parent smallint, FOREIGN KEY (parent) REFERENCES parents ON DELETE CASCADE)",
+			queries:  []string{"DELETE FROM parents WHERE id = 1"},
+			expected: [][]any{{int16(2), int16(2)}},
+		},
+		"delete sets null": {
+			create:   "CREATE TABLE children (id smallint, parent smallint REFERENCES parents ON DELETE SET NULL)",
+			queries:  []string{"DELETE FROM parents WHERE id = 1"},
+			expected: [][]any{{int16(1), nil}, {int16(2), int16(2)}},
+		},
+		"update cascades": {
+			create:   "CREATE TABLE children (id smallint, parent smallint REFERENCES parents ON UPDATE CASCADE ON DELETE NO ACTION)",
+			queries:  []string{"UPDATE parents SET id = 5 WHERE id = 2"},
+			expected: [][]any{{int16(1), int16(1)}, {int16(2), int16(5)}},
+		},
+		"update of referenced key with restrict": {
+			create:  "CREATE TABLE children (id smallint, parent smallint REFERENCES parents ON UPDATE RESTRICT)",
+			queries: []string{"UPDATE parents SET id = 5 WHERE id = 2"},
+			err:     ErrForeignKeyViolation.Code,
+		},
+		"update of other column keeps references": {
+			create:   "CREATE TABLE children (id smallint, parent smallint REFERENCES parents ON UPDATE RESTRICT)",
+			queries:  []string{"UPDATE parents SET name = 'c'"},
+			expected: [][]any{{int16(1), int16(1)}, {int16(2), int16(2)}},
+		},
+		"key provided by other row with no action": {
+			create:   "CREATE TABLE children (id smallint, parent smallint REFERENCES parents)",
+			queries:  []string{"UPDATE parents SET id = CASE WHEN id = 1 THEN 2 ELSE 1 END"},
+			expected: [][]any{{int16(1), int16(1)}, {int16(2), int16(2)}},
+		},
+		"self reference cascades": {
+			create: "CREATE TABLE children (id smallint PRIMARY KEY, parent smallint REFERENCES children ON DELETE CASCADE)",
+			queries: []string{
+				"DELETE FROM children",
+				"INSERT INTO children (id, parent) VALUES ('1', '1')",
+				"INSERT INTO children (id, parent) VALUES ('2', '1')",
+				"INSERT INTO children (id, parent) VALUES ('3', '2')",
+				"DELETE FROM children WHERE id = 1",
+			},
+		},
+		"referenced columns without unique constraint": {
+			create: "CREATE TABLE children (id smallint, parent varchar REFERENCES parents (name))",
+			err:    ErrInvalidForeignKey.Code,
+		},
+		"referenced columns of other type": {
+			create: "CREATE TABLE children (id smallint, parent varchar REFERENCES parents)",
+			err:    ErrForeignKeyMismatch.Code,
+		},
+		"referenced table without primary key": {
+			create: "CREATE TABLE children (id smallint, parent smallint REFERENCES others)",
+			err:    ErrInvalidForeignKey.Code,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE parents (id smallint PRIMARY KEY, name varchar)",
+				"CREATE TABLE others (id smallint)",
+				"INSERT INTO parents (id, name) VALUES ('1', 'a')",
+				"INSERT INTO parents (id, name) VALUES ('2', 'b')",
+			)
+
+			queries := []string{tC.create}
+			if tC.err != ErrInvalidForeignKey.Code && tC.err != ErrForeignKeyMismatch.Code {
+				queries = append(queries,
+					"INSERT INTO children (id, parent) VALUES ('1', '1')",
+					"INSERT INTO children (id, parent) VALUES ('2', '2')",
+				)
+			}
+
+			var err error
+			for _, query := range append(queries, tC.queries...) {
+				if _, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			code := ""
+			var auraErr AuraError
+			if errors.As(err, &auraErr) {
+				code = auraErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+
+			if tC.err != "" {
+				return
+			}
+
+			rows := queryCells(t, "SELECT id, parent FROM children ORDER BY id")
+			if len(rows) == 0 && len(tC.expected) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestForeignKeysCatalog(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE parents (id smallint PRIMARY KEY, name varchar)",
+		"CREATE TABLE children (id smallint, parent smallint REFERENCES parents ON DELETE CASCADE ON UPDATE RESTRICT)",
+	)
+
+	expected := [][]any{{"children_pa_fkey", "parent", "dbo", "parents", "id", "RESTRICT", "CASCADE"}}
+	query := "SELECT constraint_name, column_name, ref_schema, ref_table, ref_column, update_rule, delete_rule " +
+		"FROM auralis.constraints WHERE constraint_type = 'FOREIGN KEY'"
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
@@ -1,4 +1,4 @@
-module auralis
+module github.com/font3r/auralis
 
 go 1.24.1
 
@@ -0,0 +1,401 @@
+package auralis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"math/bits"
+	"slices"
+)
+
+// Hash index uses linear hashing, page 0 of index file holds metadata and
+// buckets are chains of leaf nodes linked by next page. Buckets are split one
+// by one in order so the number of buckets grows smoothly with entries.
+// Metadata layout: level (1B), split bucket (8B), entries (8B) followed by
+// page of each bucket (8B)
+const hashMetaSize = 17
+
+const (
+	// number of buckets is limited by metadata page, chains grow afterwards
+	maxHashBuckets = (pageSize - pageHeaderSize - hashMetaSize) / 8
+	// buckets are split when they are filled over fill factor on average
+	hashFillFactor = 0.75
+)
+
+// HashIndex is linear hash index stored in index file, it supports only
+// lookups of keys with all columns equal. Changes are logged like B+tree ones
+type HashIndex struct {
+	file string
+}
+
+type hashMeta struct {
+	level   int   // buckets before split use level+1 low bits of hash, others level bits
+	split   int64 // the next bucket to split
+	entries int64
+	buckets []int64
+}
+
+func decodeHashMeta(page *Page) hashMeta {
+	data := page.data[pageHeaderSize:]
+	m := hashMeta{
+		level:   int(data[0]),
+		split:   int64(binary.BigEndian.Uint64(data[1:9])),
+		entries: int64(binary.BigEndian.Uint64(data[9:17])),
+	}
+
+	count := int64(1)<<m.level + m.split
+	for i := range count {
+		offset := hashMetaSize + i*8
+		m.buckets = append(m.buckets, int64(binary.BigEndian.Uint64(data[offset:offset+8])))
+	}
+
+	return m
+}
+
+func (m hashMeta) encode() []byte {
+	data := []byte{byte(m.level)}
+	data = binary.BigEndian.AppendUint64(data, uint64(m.split))
+	data = binary.BigEndian.AppendUint64(data, uint64(m.entries))
+	for _, number := range m.buckets {
+		data = binary.BigEndian.AppendUint64(data, uint64(number))
+	}
+
+	return data
+}
+
+// bucket returns bucket of key prefix, buckets before split bucket are already
+// split by the next level
+func (m hashMeta) bucket(prefix []byte) int64 {
+	h := fnv.New32a()
+	h.Write(prefix)
+	sum := int64(h.Sum32())
+
+	bucket := sum % (1 << m.level)
+	if bucket < m.split {
+		bucket = sum % (1 << (m.level + 1))
+	}
+
+	return bucket
+}
+
+// full reports whether buckets are filled over fill factor by entries of key size
+func (m hashMeta) full(keySize int) bool {
+	return len(m.buckets) < maxHashBuckets &&
+		float64(m.entries) > float64(len(m.buckets)*bucketCapacity(keySize))*hashFillFactor
+}
+
+func bucketCapacity(keySize int) int {
+	return (pageSize - pageHeaderSize - nodeHeaderSize) / (keySize + 2)
+}
+
+func (m *hashMeta) addBucket(number int64) {
+	m.buckets = append(m.buckets, number)
+	m.split++
+	if m.split == 1<<m.level {
+		m.level++
+		m.split = 0
+	}
+}
+
+func (h HashIndex) meta() (hashMeta, error) {
+	page, err := bufferPool.fetchPage(PageID{file: h.file})
+	if err != nil {
+		return hashMeta{}, err
+	}
+	defer bufferPool.unpinPage(page.id, false)
+
+	return decodeHashMeta(page), nil
+}
+
+func (h HashIndex) node(number int64) (Node, error) {
+	return BTree{file: h.file}.node(number)
+}
+
+// chain returns pages and nodes of bucket, nodes changed but not applied yet
+// are taken from changes
+func (h HashIndex) chain(changes *nodeChanges, number int64) ([]int64, []Node, error) {
+	numbers, nodes := []int64{}, []Node{}
+	for {
+		n, ok := changes.nodes[number]
+		if !ok {
+			var err error
+			if n, err = h.node(number); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		numbers, nodes = append(numbers, number), append(nodes, n)
+		if n.next == 0 {
+			return numbers, nodes, nil
+		}
+		number = n.next
+	}
+}
+
+// insert adds key into its bucket, existing key is kept. Bucket is split
+// after insert when buckets are full
+func (h HashIndex) insert(tx *Transaction, key []byte) error {
+	meta, err := h.meta()
+	if err != nil {
+		return err
+	}
+
+	changes := newNodeChanges(h.file)
+	numbers, nodes, err := h.chain(changes, meta.buckets[meta.bucket(key[:len(key)-rowLocatorSize])])
+	if err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		if _, found := slices.BinarySearchFunc(n.keys, key, bytes.Compare); found {
+			return nil
+		}
+	}
+
+	placed := false
+	for i, n := range nodes {
+		at, _ := slices.BinarySearchFunc(n.keys, key, bytes.Compare)
+		n.keys = slices.Insert(slices.Clone(n.keys), at, key)
+		if n.fits() {
+			changes.set(numbers[i], n)
+			placed = true
+			break
+		}
+	}
+
+	// overflow page is linked to the end of chain
+	if !placed {
+		number, err := changes.allocate()
+		if err != nil {
+			return err
+		}
+
+		last := nodes[len(nodes)-1]
+		last.next = number
+		changes.set(numbers[len(numbers)-1], last)
+		changes.set(number, Node{leaf: true, keys: [][]byte{key}})
+	}
+
+	meta.entries++
+	if meta.full(len(key)) {
+		if err := h.split(changes, &meta); err != nil {
+			return err
+		}
+	}
+
+	changes.setImage(0, meta.encode())
+	return changes.apply(tx)
+}
+
+// split moves keys of split bucket which hash into a new bucket by the
+// next level, pages of split bucket are reused
+func (h HashIndex) split(changes *nodeChanges, meta *hashMeta) error {
+	split := meta.split
+	numbers, nodes, err := h.chain(changes, meta.buckets[split])
+	if err != nil {
+		return err
+	}
+
+	number, err := changes.allocate()
+	if err != nil {
+		return err
+	}
+	meta.addBucket(number)
+
+	kept, moved := [][]byte{}, [][]byte{}
+	for _, n := range nodes {
+		for _, key := range n.keys {
+			if meta.bucket(key[:len(key)-rowLocatorSize]) == split {
+				kept = append(kept, key)
+			} else {
+				moved = append(moved, key)
+			}
+		}
+	}
+
+	if err := fillChain(changes, numbers, kept); err != nil {
+		return err
+	}
+
+	return fillChain(changes, []int64{number}, moved)
+}
+
+// fillChain packs sorted keys into pages of chain, pages are allocated when
+// the chain is too short and its remaining pages are left empty
+func fillChain(changes *nodeChanges, numbers []int64, keys [][]byte) error {
+	slices.SortFunc(keys, bytes.Compare)
+	nodes := packLeaves(keys)
+	for len(numbers) < len(nodes) {
+		number, err := changes.allocate()
+		if err != nil {
+			return err
+		}
+		numbers = append(numbers, number)
+	}
+
+	for i, number := range numbers {
+		n := Node{leaf: true}
+		if i < len(nodes) {
+			n = nodes[i]
+		}
+
+		if i+1 < len(numbers) {
+			n.next = numbers[i+1]
+		}
+		changes.set(number, n)
+	}
+
+	return nil
+}
+
+// packLeaves fills leaf nodes by sorted keys, at least one node is returned
+func packLeaves(keys [][]byte) []Node {
+	nodes := []Node{{leaf: true}}
+	for _, key := range keys {
+		last := &nodes[len(nodes)-1]
+		last.keys = append(last.keys, key)
+		if !last.fits() {
+			last.keys = last.keys[:len(last.keys)-1]
+			nodes = append(nodes, Node{leaf: true, keys: [][]byte{key}})
+		}
+	}
+
+	return nodes
+}
+
+// scan calls fn with keys of the bucket of bounds, bounds have to be equal and
+// cover all columns. Keys of all buckets are scanned without bounds
+func (h HashIndex) scan(low *KeyBound, high *KeyBound, fn func(key []byte) error) error {
+	meta, err := h.meta()
+	if err != nil {
+		return err
+	}
+
+	buckets := meta.buckets
+	if low != nil && high != nil {
+		buckets = []int64{meta.buckets[meta.bucket(low.key)]}
+	}
+
+	changes := newNodeChanges(h.file)
+	for _, bucket := range buckets {
+		_, nodes, err := h.chain(changes, bucket)
+		if err != nil {
+			return err
+		}
+
+		for _, n := range nodes {
+			for _, key := range n.keys {
+				if (low != nil && !low.below(key)) || (high != nil && !high.above(key)) {
+					continue
+				}
+
+				if err := fn(key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeKeys removes keys rejected by keep from all buckets, buckets are
+// not merged
+func (h HashIndex) removeKeys(tx *Transaction, keep func(key []byte) (bool, error)) (int64, error) {
+	meta, err := h.meta()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := int64(0)
+	for _, bucket := range meta.buckets {
+		numbers, nodes, err := h.chain(newNodeChanges(h.file), bucket)
+		if err != nil {
+			return 0, err
+		}
+
+		for i, n := range nodes {
+			kept := [][]byte{}
+			for _, key := range n.keys {
+				ok, err := keep(key)
+				if err != nil {
+					return 0, err
+				}
+
+				if ok {
+					kept = append(kept, key)
+				}
+			}
+
+			if len(kept) == len(n.keys) {
+				continue
+			}
+
+			removed += int64(len(n.keys) - len(kept))
+			meta.entries -= int64(len(n.keys) - len(kept))
+			n.keys = kept
+
+			changes := newNodeChanges(h.file)
+			changes.set(numbers[i], n)
+			changes.setImage(0, meta.encode())
+			if err := changes.apply(tx); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// buildHash distributes keys into pages of a new hash index, number of buckets
+// is chosen so buckets are filled by fill factor
+func buildHash(file string, keys [][]byte) []*Page {
+	count := 1
+	if len(keys) > 0 {
+		perBucket := float64(bucketCapacity(len(keys[0]))) * hashFillFactor
+		count = min(max(int(float64(len(keys))/perBucket)+1, 1), maxHashBuckets)
+	}
+
+	level := bits.Len(uint(count)) - 1
+	meta := hashMeta{level: level, split: int64(count - 1<<level), entries: int64(len(keys))}
+	for i := range count {
+		meta.buckets = append(meta.buckets, int64(i+1))
+	}
+
+	buckets := make([][][]byte, count)
+	for _, key := range keys {
+		bucket := meta.bucket(key[:len(key)-rowLocatorSize])
+		buckets[bucket] = append(buckets[bucket], key)
+	}
+
+	// overflow pages follow bucket pages
+	images := [][]byte{meta.encode()}
+	overflows := [][]byte{}
+	for _, keys := range buckets {
+		slices.SortFunc(keys, bytes.Compare)
+		nodes := packLeaves(keys)
+		base := count + 1 + len(overflows)
+		for i := range nodes {
+			// node i+1 of chain is overflow page base+i
+			if i+1 < len(nodes) {
+				nodes[i].next = int64(base + i)
+			}
+
+			if i == 0 {
+				images = append(images, nodes[i].encode())
+			} else {
+				overflows = append(overflows, nodes[i].encode())
+			}
+		}
+	}
+
+	pages := []*Page{}
+	for number, image := range append(images, overflows...) {
+		page := &Page{id: PageID{file: file, number: int64(number)}}
+		page.init()
+		writeNodePage(page, image)
+		pages = append(pages, page)
+	}
+
+	return pages
+}
@@ -0,0 +1,118 @@
+package auralis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func createHashIndex(t *testing.T, keys [][]byte) HashIndex {
+	t.Helper()
+
+	index := HashIndex{file: dataPath + "/dbo.test.index"}
+	err := autocommit(func(tx *Transaction) error {
+		return replaceFiles(tx, map[string][]*Page{index.file: buildHash(index.file, keys)})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return index
+}
+
+func TestHashIndex(t *testing.T) {
+	// two rows share each key prefix
+	keys := [][]byte{}
+	for i := range 5000 {
+		key := binary.BigEndian.AppendUint64([]byte(fmt.Sprintf("%06d", i/2)), uint64(i))
+		keys = append(keys, binary.BigEndian.AppendUint16(key, 0))
+	}
+
+	testCases := map[string]struct {
+		build func(t *testing.T) HashIndex
+	}{
+		"keys inserted in random order": {
+			build: func(t *testing.T) HashIndex {
+				index := createHashIndex(t, nil)
+				err := autocommit(func(tx *Transaction) error {
+					for _, i := range rand.Perm(len(keys)) {
+						if err := index.insert(tx, keys[i]); err != nil {
+							return err
+						}
+					}
+
+					// existing key is kept once
+					return index.insert(tx, keys[0])
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return index
+			},
+		},
+		"keys loaded in bulk": {
+			build: func(t *testing.T) HashIndex {
+				return createHashIndex(t, keys)
+			},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t)
+			index := tC.build(t)
+
+			meta, err := index.meta()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if meta.entries != int64(len(keys)) || len(meta.buckets) < 8 {
+				t.Fatalf("\nexp %+v entries in more buckets\ngot %+v entries in %+v buckets", len(keys), meta.entries, len(meta.buckets))
+			}
+
+			lookup := func(prefix int) int {
+				bound := &KeyBound{key: []byte(fmt.Sprintf("%06d", prefix)), inclusive: true}
+				found := 0
+				err := index.scan(bound, bound, func(key []byte) error {
+					found++
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return found
+			}
+
+			for prefix := range len(keys) / 2 {
+				if found := lookup(prefix); found != 2 {
+					t.Fatalf("\nexp %+v\ngot %+v", 2, found)
+				}
+			}
+
+			err = autocommit(func(tx *Transaction) error {
+				removed, err := index.removeKeys(tx, func(key []byte) (bool, error) {
+					return key[len(key)-3]%2 == 0, nil
+				})
+				if removed != int64(len(keys)/2) {
+					t.Errorf("\nexp %+v\ngot %+v", len(keys)/2, removed)
+				}
+
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if found := lookup(7); found != 1 {
+				t.Fatalf("\nexp %+v\ngot %+v", 1, found)
+			}
+
+			if meta, err := index.meta(); err != nil || meta.entries != int64(len(keys)/2) {
+				t.Fatalf("\nexp %+v\ngot %+v %v", len(keys)/2, meta.entries, err)
+			}
+		})
+	}
+}
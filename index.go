@@ -0,0 +1,655 @@
+package auralis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const indexes string = "indexes"
+
+var auralisIndexes = Table{
+	schemaTable: SchemaTable[string, string]{internalSchema, indexes},
+	columns: []Column{
+		{
+			name:     "table_schema",
+			dataType: varchar,
+			position: 1,
+		},
+		{
+			name:     "table_name",
+			dataType: varchar,
+			position: 2,
+		},
+		{
+			name:     "index_name",
+			dataType: varchar,
+			position: 3,
+		},
+		{
+			name:     "column_name",
+			dataType: varchar,
+			position: 4,
+		},
+		{
+			name:     "position",
+			dataType: smallint,
+			position: 5,
+		},
+		{
+			name:     "is_unique",
+			dataType: boolean,
+			position: 6,
+		},
+		{
+			name:     "method",
+			dataType: varchar,
+			position: 7,
+		},
+	},
+}
+
+var (
+	ErrIndexExists     = AuraError{Code: "INDEX_EXISTS", Message: "index already exists"}
+	ErrUniqueViolation = AuraError{Code: "UNIQUE_VIOLATION", Message: "duplicate key value violates unique index"}
+	ErrInternalIndex   = AuraError{Code: "INSUFFICIENT_PRIVILEGE", Message: "indexes of catalog tables are not supported"}
+
+	ErrDuplicateIndexColumn = AuraError{Code: "DUPLICATE_COLUMN", Message: "column is listed in index more than once"}
+)
+
+// index access methods
+const (
+	btreeIndex = "btree"
+	hashIndex  = "hash"
+)
+
+// Index stores entries of table rows by values of index columns. Entry key
+// consists of encoded column values followed by locator of row so entries are
+// unique also for non-unique index
+type Index struct {
+	name    string
+	columns []Column
+	unique  bool
+	method  string
+}
+
+// IndexAccess is structure of index file, bounds of hash index scan have to
+// be equal and cover all index columns
+type IndexAccess interface {
+	insert(tx *Transaction, key []byte) error
+	scan(low *KeyBound, high *KeyBound, fn func(key []byte) error) error
+	removeKeys(tx *Transaction, keep func(key []byte) (bool, error)) (int64, error)
+}
+
+// row locator layout: page number (8B), slot (2B)
+const rowLocatorSize = 10
+
+func getIndexDiskPath(schema string, name string) string {
+	return fmt.Sprintf("%s/%s.%s.index", dataPath, schema, name)
+}
+
+func (index Index) file(table Table) string {
+	return getIndexDiskPath(table.schemaTable.schema, index.name)
+}
+
+func (index Index) access(table Table) IndexAccess {
+	if index.method == hashIndex {
+		return HashIndex{file: index.file(table)}
+	}
+
+	return BTree{file: index.file(table)}
+}
+
+// build returns pages of index file holding sorted keys
+func (index Index) build(table Table, keys [][]byte) []*Page {
+	if index.method == hashIndex {
+		return buildHash(index.file(table), keys)
+	}
+
+	return buildTree(index.file(table), keys)
+}
+
+// orderedValue makes encoded value comparable as bytes, sign bit of two's
+// complement integers is flipped so negative values sort first
+func orderedValue(dataType DataType, encoded []byte) []byte {
+	switch dataType {
+	case smallint, integer, bigint, timestamp:
+		encoded[0] ^= 0x80
+	}
+
+	return encoded
+}
+
+// key value of each column starts with null flag, nulls sort after values
+const (
+	keyNotNull byte = 0
+	keyNull    byte = 1
+)
+
+// keyValue encodes condition value compared with index column, value of other
+// type than the column or too long to be stored can't be used for index lookup
+func keyValue(cd Column, value any) ([]byte, bool) {
+	if valueDataType(value) != cd.dataType {
+		return nil, false
+	}
+
+	if v, ok := value.(string); ok && len(v) > getDataTypeByteSize(varchar) {
+		return nil, false
+	}
+
+	encoded, err := appendValue([]byte{keyNotNull}, cd.dataType, value)
+	if err != nil {
+		return nil, false
+	}
+	orderedValue(cd.dataType, encoded[1:])
+
+	return encoded, true
+}
+
+// columnOffset returns position of column within table and offset of its value within tuple
+func columnOffset(table Table, name string) (int, int) {
+	offset := valuesOffset(table)
+	for i, cd := range table.columns {
+		if cd.name == name {
+			return i, offset
+		}
+		offset += getDataTypeByteSize(cd.dataType)
+	}
+
+	return -1, offset
+}
+
+// keyPrefix returns encoded values of index columns of tuple
+func keyPrefix(table Table, index Index, tuple []byte) []byte {
+	key := []byte{}
+	for _, cd := range index.columns {
+		i, offset := columnOffset(table, cd.name)
+		if isNull(tuple, i) {
+			key = append(key, keyNull)
+			key = append(key, make([]byte, getDataTypeByteSize(cd.dataType))...)
+			continue
+		}
+
+		value := slices.Clone(tuple[offset : offset+getDataTypeByteSize(cd.dataType)])
+		key = append(key, keyNotNull)
+		key = append(key, orderedValue(cd.dataType, value)...)
+	}
+
+	return key
+}
+
+// hasNullKey reports whether any index column of tuple is null, such rows
+// never conflict in unique index
+func hasNullKey(table Table, index Index, tuple []byte) bool {
+	return slices.ContainsFunc(index.columns, func(cd Column) bool {
+		i, _ := columnOffset(table, cd.name)
+		return isNull(tuple, i)
+	})
+}
+
+func indexKey(table Table, index Index, tuple []byte, id RowID) []byte {
+	key := binary.BigEndian.AppendUint64(keyPrefix(table, index, tuple), uint64(id.page.number))
+	return binary.BigEndian.AppendUint16(key, uint16(id.slot))
+}
+
+// keyRow returns row located by index entry
+func keyRow(table Table, key []byte) RowID {
+	locator := key[len(key)-rowLocatorSize:]
+	return RowID{
+		page: PageID{file: getTableDiskPath(table.schemaTable), number: int64(binary.BigEndian.Uint64(locator[0:8]))},
+		slot: int(binary.BigEndian.Uint16(locator[8:10])),
+	}
+}
+
+// readTuple calls fn with tuple of row, nil is passed for removed row. Index
+// entries may locate rows removed by rollback or pages truncated by vacuum
+func readTuple(id RowID, fn func(tuple []byte) error) error {
+	count, err := bufferPool.numberOfPages(id.page.file)
+	if err != nil {
+		return err
+	}
+
+	if id.page.number >= count {
+		return fn(nil)
+	}
+
+	page, err := bufferPool.fetchPage(id.page)
+	if err != nil {
+		return err
+	}
+	defer bufferPool.unpinPage(id.page, false)
+
+	return fn(page.tuple(id.slot))
+}
+
+// tableIndexes returns indexes of table committed so far or created by transaction.
+// Snapshot of transaction is not used, rows inserted by older snapshot have to
+// be added into index created after it
+func tableIndexes(tx *Transaction, table Table) ([]Index, error) {
+	snapshot := writeAheadLog.latestSnapshot()
+	dataSet, err := readFromTable(&Transaction{id: tx.id, snapshot: &snapshot}, auralisIndexes, SelectQuery{
+		source:      auralisIndexes.schemaTable,
+		dataColumns: []string{"table_schema", "table_name", "index_name", "column_name", "position", "is_unique", "method"},
+		conditions: []Condition{
+			{target: "table_schema", sign: "=", value: table.schemaTable.schema},
+			{target: "table_name", sign: "=", value: table.schemaTable.name},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	positions := map[string][]int16{}
+	found := map[string]*Index{}
+	names := []string{}
+	for _, row := range dataSet.rows {
+		name, column := row.cells[2].(string), row.cells[3].(string)
+		if _, ok := found[name]; !ok {
+			found[name] = &Index{name: name, unique: row.cells[5].(bool), method: row.cells[6].(string)}
+			names = append(names, name)
+		}
+
+		i := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == column })
+		if i == -1 {
+			return nil, ErrColumnNotFound
+		}
+
+		// columns are kept in position order
+		position := row.cells[4].(int16)
+		at, _ := slices.BinarySearch(positions[name], position)
+		positions[name] = slices.Insert(positions[name], at, position)
+		found[name].columns = slices.Insert(found[name].columns, at, table.columns[i])
+	}
+
+	slices.Sort(names)
+	result := []Index{}
+	for _, name := range names {
+		result = append(result, *found[name])
+	}
+
+	return result, nil
+}
+
+// schemaIndexExists reports whether index name is used within schema
+func schemaIndexExists(tx *Transaction, schema string, name string) (bool, error) {
+	snapshot := writeAheadLog.latestSnapshot()
+	dataSet, err := readFromTable(&Transaction{id: tx.id, snapshot: &snapshot}, auralisIndexes, SelectQuery{
+		source:      auralisIndexes.schemaTable,
+		dataColumns: []string{"table_schema", "index_name"},
+		conditions: []Condition{
+			{target: "table_schema", sign: "=", value: schema},
+			{target: "index_name", sign: "=", value: name},
+		},
+	})
+
+	return len(dataSet.rows) > 0, err
+}
+
+// createIndex builds index of existing rows and registers it in catalog, table
+// is locked in share mode so no rows are added until transaction ends
+func createIndex(tx *Transaction, table Table, index Index) error {
+	if err := lockManager.lock(tx, tableLockTag(table), shared); err != nil {
+		return err
+	}
+
+	exists, err := schemaIndexExists(tx, table.schemaTable.schema, index.name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrIndexExists
+	}
+
+	keys := [][]byte{}
+	live := map[string]bool{}
+	err = scanTuples(table, func(id RowID, tuple []byte) error {
+		// concurrent writers are finished so only deleted tuples can share key
+		if index.unique && tupleXmax(tuple) == 0 && !hasNullKey(table, index, tuple) {
+			prefix := string(keyPrefix(table, index, tuple))
+			if live[prefix] {
+				return ErrUniqueViolation
+			}
+			live[prefix] = true
+		}
+
+		keys = append(keys, indexKey(table, index, tuple, id))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(keys, bytes.Compare)
+	if err := replaceFiles(tx, map[string][]*Page{index.file(table): index.build(table, keys)}); err != nil {
+		return err
+	}
+
+	rows := []Row{}
+	for i, cd := range index.columns {
+		rows = append(rows, Row{cells: []any{
+			table.schemaTable.schema, table.schemaTable.name, index.name, cd.name, int16(i + 1), index.unique, index.method,
+		}})
+	}
+
+	return writeIntoTable(tx, auralisIndexes, DataSet{columns: auralisIndexes.columns, rows: rows})
+}
+
+// scanTuples calls fn with each tuple stored in table regardless of its visibility
+func scanTuples(table Table, fn func(id RowID, tuple []byte) error) error {
+	path := getTableDiskPath(table.schemaTable)
+	count, err := bufferPool.numberOfPages(path)
+	if err != nil {
+		return err
+	}
+
+	for number := range count {
+		page, err := bufferPool.fetchPage(PageID{file: path, number: number})
+		if err != nil {
+			return err
+		}
+
+		for slot := range page.slotCount() {
+			tuple := page.tuple(slot)
+			if tuple == nil {
+				continue
+			}
+
+			if err := fn(RowID{page: page.id, slot: slot}, tuple); err != nil {
+				bufferPool.unpinPage(page.id, false)
+				return err
+			}
+		}
+
+		bufferPool.unpinPage(page.id, false)
+	}
+
+	return nil
+}
+
+// insertIndexEntries adds entries of inserted rows into all indexes of table,
+// unique indexes are checked before the entry is added. Indexes are listed
+// again as index could be created while the table lock was awaited
+func insertIndexEntries(tx *Transaction, table Table, ids []RowID, tuples [][]byte) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	indexes, err := tableIndexes(tx, table)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		access := index.access(table)
+		for i, id := range ids {
+			if index.unique && !hasNullKey(table, index, tuples[i]) {
+				if err := checkUnique(tx, table, index, keyPrefix(table, index, tuples[i]), id); err != nil {
+					return err
+				}
+			}
+
+			if err := access.insert(tx, indexKey(table, index, tuples[i], id)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkUnique looks for other row with the same key which is not deleted by
+// committed transaction. Transaction which inserted or deletes such row is
+// waited for, the row conflicts only when its insert is committed
+func checkUnique(tx *Transaction, table Table, index Index, prefix []byte, id RowID) error {
+	bound := &KeyBound{key: prefix, inclusive: true}
+	for {
+		var wait uint64
+		conflict := false
+		err := index.access(table).scan(bound, bound, func(key []byte) error {
+			other := keyRow(table, key)
+			if other == id {
+				return nil
+			}
+
+			return readTuple(other, func(tuple []byte) error {
+				if tuple == nil || !bytes.Equal(keyPrefix(table, index, tuple), prefix) {
+					return nil
+				}
+
+				xmin, xmax := tupleXmin(tuple), tupleXmax(tuple)
+				switch {
+				case xmin != tx.id && writeAheadLog.running(xmin):
+					wait = xmin
+				case xmax == 0:
+					conflict = true
+				case xmax != tx.id && writeAheadLog.running(xmax):
+					wait = xmax
+				}
+
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		if conflict {
+			return ErrUniqueViolation
+		}
+
+		if wait == 0 {
+			return nil
+		}
+
+		if err := lockManager.lock(tx, transactionLockTag(wait), shared); err != nil {
+			return err
+		}
+	}
+}
+
+// IndexScan is range of index entries which may match conditions
+type IndexScan struct {
+	index     Index
+	low, high *KeyBound
+	columns   int // number of restricted index columns
+}
+
+// planIndexScan picks index with the most leading columns restricted by
+// conditions, columns compared for equality may be followed by one column
+// compared by range. Hash index needs all its columns compared for equality.
+// Conditions have to be converted into column types
+func planIndexScan(table Table, conditions []Condition) (IndexScan, bool) {
+	best := IndexScan{}
+	for _, index := range table.indexes {
+		scan := IndexScan{index: index}
+		prefix := []byte{}
+		for _, cd := range index.columns {
+			if value, ok := conditionKey(cd, conditions, "="); ok {
+				prefix = append(prefix, value...)
+				scan.columns++
+				continue
+			}
+
+			// hash index is usable only for equality of all columns
+			if index.method != hashIndex {
+				scan.low, scan.high = rangeBounds(cd, conditions, prefix)
+			}
+			if scan.low != nil || scan.high != nil {
+				scan.columns++
+			}
+			break
+		}
+
+		if index.method == hashIndex && scan.columns < len(index.columns) {
+			continue
+		}
+
+		if scan.low == nil && scan.high == nil && len(prefix) > 0 {
+			scan.low = &KeyBound{key: prefix, inclusive: true}
+			scan.high = scan.low
+		}
+
+		if scan.columns > best.columns {
+			best = scan
+		}
+	}
+
+	return best, best.columns > 0
+}
+
+// conditionKey returns encoded value compared with column by sign
+func conditionKey(cd Column, conditions []Condition, sign string) ([]byte, bool) {
+	for _, condition := range conditions {
+		if condition.expr != nil || condition.target != cd.name || condition.sign != sign {
+			continue
+		}
+
+		if value, ok := keyValue(cd, condition.value); ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// rangeBounds returns bounds of column compared by range following prefix
+func rangeBounds(cd Column, conditions []Condition, prefix []byte) (*KeyBound, *KeyBound) {
+	var low, high *KeyBound
+	bound := func(value []byte, inclusive bool) *KeyBound {
+		return &KeyBound{key: append(slices.Clone(prefix), value...), inclusive: inclusive}
+	}
+
+	for _, sign := range []string{">", ">="} {
+		if value, ok := conditionKey(cd, conditions, sign); ok && low == nil {
+			low = bound(value, sign == ">=")
+		}
+	}
+
+	for _, sign := range []string{"<", "<="} {
+		if value, ok := conditionKey(cd, conditions, sign); ok && high == nil {
+			high = bound(value, sign == "<=")
+		}
+	}
+
+	for _, condition := range conditions {
+		if condition.expr != nil || condition.target != cd.name || condition.sign != "between" || low != nil || high != nil {
+			continue
+		}
+
+		bounds := condition.value.([]any)
+		from, fromOk := keyValue(cd, bounds[0])
+		to, toOk := keyValue(cd, bounds[1])
+		if fromOk && toOk {
+			low, high = bound(from, true), bound(to, true)
+		}
+	}
+
+	// prefix alone limits range of the other side
+	if len(prefix) > 0 && low == nil && high != nil {
+		low = &KeyBound{key: prefix, inclusive: true}
+	}
+
+	if len(prefix) > 0 && high == nil && low != nil {
+		high = &KeyBound{key: prefix, inclusive: true}
+	}
+
+	return low, high
+}
+
+// scanRows calls fn with rows of table visible to transaction, rows are found
+// by index when conditions restrict its columns. Rows found by index have to
+// be checked against conditions as well
+func scanRows(tx *Transaction, table Table, conditions []Condition, fn func(id RowID, tuple []byte) error) error {
+	scan, ok := planIndexScan(table, conditions)
+	if !ok {
+		return scanTable(tx, table, fn)
+	}
+
+	tx.read(getTableDiskPath(table.schemaTable))
+
+	// row is listed by stale entries of its previous locations as well
+	ids := []RowID{}
+	seen := map[RowID]bool{}
+	err := scan.index.access(table).scan(scan.low, scan.high, func(key []byte) error {
+		if id := keyRow(table, key); !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := readTuple(id, func(tuple []byte) error {
+			if tuple == nil || !tx.sees(tuple) {
+				return nil
+			}
+
+			return fn(id, tuple)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// typedConditions returns conditions comparing columns of table with literals
+// converted into column types, only those can be used by index scan
+func typedConditions(table Table, conditions []Condition) []Condition {
+	typed := []Condition{}
+	for _, condition := range conditions {
+		if condition.expr != nil || isColumnReference(condition.value) {
+			continue
+		}
+
+		condition.target = unqualifiedName(condition.target)
+		i := slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == condition.target })
+		if i == -1 || strings.HasPrefix(condition.sign, "not ") {
+			continue
+		}
+
+		if _, ok := condition.value.(string); !ok && condition.sign != "between" {
+			continue
+		}
+
+		value, err := convertConditionValue(table.columns[i].dataType, condition)
+		if err != nil {
+			continue
+		}
+
+		condition.value = value
+		typed = append(typed, condition)
+	}
+
+	return typed
+}
+
+// vacuumIndexes removes entries of removed rows, rows dead before horizon
+// and stale entries whose row has other key
+func vacuumIndexes(tx *Transaction, table Table, horizon uint64) error {
+	for _, index := range table.indexes {
+		_, err := index.access(table).removeKeys(tx, func(key []byte) (bool, error) {
+			keep := false
+			err := readTuple(keyRow(table, key), func(tuple []byte) error {
+				keep = tuple != nil && !dead(tuple, horizon) &&
+					bytes.Equal(keyPrefix(table, index, tuple), key[:len(key)-rowLocatorSize])
+				return nil
+			})
+
+			return keep, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
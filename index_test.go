@@ -0,0 +1,407 @@
+package auralis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// indexEntries returns number of entries of index
+func indexEntries(t *testing.T, index Index) int {
+	t.Helper()
+
+	entries := 0
+	err := index.access(Table{schemaTable: SchemaTable[string, string]{defaultScheme, "items"}}).scan(nil, nil, func(key []byte) error {
+		entries++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entries
+}
+
+func TestCreateIndex(t *testing.T) {
+	testCases := map[string]struct {
+		queries []string
+		err     error
+	}{
+		"unique index rejects duplicate": {
+			queries: []string{
+				"CREATE UNIQUE INDEX items_id ON items (id)",
+				"INSERT INTO items (id, name) VALUES ('7', 'x')",
+			},
+			err: ErrUniqueViolation,
+		},
+		"unique index rejects duplicate update": {
+			queries: []string{
+				"CREATE UNIQUE INDEX items_id ON items (id)",
+				"UPDATE items SET id = 7 WHERE id = 8",
+			},
+			err: ErrUniqueViolation,
+		},
+		"unique index accepts key of deleted row": {
+			queries: []string{
+				"CREATE UNIQUE INDEX items_id ON items (id)",
+				"DELETE FROM items WHERE id = 7",
+				"INSERT INTO items (id, name) VALUES ('7', 'x')",
+			},
+		},
+		"unique index can't be created over duplicates": {
+			queries: []string{
+				"INSERT INTO items (id, name) VALUES ('7', 'x')",
+				"CREATE UNIQUE INDEX items_id ON items (id)",
+			},
+			err: ErrUniqueViolation,
+		},
+		"non unique index accepts duplicate": {
+			queries: []string{
+				"CREATE INDEX items_id ON items (id)",
+				"INSERT INTO items (id, name) VALUES ('7', 'x')",
+			},
+		},
+		"unique index of more columns": {
+			queries: []string{
+				"CREATE UNIQUE INDEX items_id_name ON items (id, name)",
+				"INSERT INTO items (id, name) VALUES ('7', 'x')",
+			},
+		},
+		"index name is used": {
+			queries: []string{
+				"CREATE INDEX items_id ON items (id)",
+				"CREATE INDEX items_id ON items (name)",
+			},
+			err: ErrIndexExists,
+		},
+		"unknown column": {
+			queries: []string{"CREATE INDEX items_price ON items (price)"},
+			err:     ErrColumnNotFound,
+		},
+		"column listed twice": {
+			queries: []string{"CREATE INDEX items_id ON items (id, id)"},
+			err:     ErrDuplicateIndexColumn,
+		},
+		"catalog table": {
+			queries: []string{"CREATE INDEX tables_name ON auralis.tables (table_name)"},
+			err:     ErrInternalIndex,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+			fillItems(t, 20)
+
+			var err error
+			for _, query := range tC.queries {
+				if _, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			if err != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+		})
+	}
+}
+
+func TestIndexesCatalog(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id smallint, name varchar)",
+		"CREATE UNIQUE INDEX items_name_id ON items (name, id)",
+		"CREATE INDEX items_id ON items (id)",
+	)
+
+	expected := [][]any{
+		{"items_id", "id", int16(1), false},
+		{"items_name_id", "id", int16(2), true},
+		{"items_name_id", "name", int16(1), true},
+	}
+	query := "SELECT index_name, column_name, position, is_unique FROM auralis.indexes " +
+		"WHERE table_name = 'items' ORDER BY index_name, column_name"
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+
+	// rolled back index isn't listed
+	if _, err := ExecuteQuery("BEGIN; CREATE INDEX items_name ON items (name); ROLLBACK"); err != nil {
+		t.Fatal(err)
+	}
+
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
+
+func TestPlanIndexScan(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id smallint, name varchar)",
+		"CREATE INDEX items_id ON items (id)",
+		"CREATE INDEX items_name_id ON items (name, id)",
+		"CREATE INDEX items_name ON items USING hash (name)",
+	)
+
+	testCases := map[string]struct {
+		conditions []Condition
+		index      string
+		columns    int
+	}{
+		"equality": {
+			conditions: []Condition{{target: "id", sign: "=", value: "5"}},
+			index:      "items_id",
+			columns:    1,
+		},
+		"range": {
+			conditions: []Condition{{target: "id", sign: ">", value: "5"}, {target: "id", sign: "<=", value: "9"}},
+			index:      "items_id",
+			columns:    1,
+		},
+		"between": {
+			conditions: []Condition{{target: "id", sign: "between", value: []any{"5", "9"}}},
+			index:      "items_id",
+			columns:    1,
+		},
+		"equality followed by range": {
+			conditions: []Condition{{target: "name", sign: "=", value: "'a'"}, {target: "id", sign: ">=", value: "5"}},
+			index:      "items_name_id",
+			columns:    2,
+		},
+		"hash equality": {
+			conditions: []Condition{{target: "name", sign: "=", value: "'a'"}},
+			index:      "items_name",
+			columns:    1,
+		},
+		"hash isn't used for range": {
+			conditions: []Condition{{target: "name", sign: ">", value: "'a'"}},
+			index:      "items_name_id",
+			columns:    1,
+		},
+		"leading column isn't restricted": {
+			conditions: []Condition{{target: "name", sign: "like", value: "'a%'"}},
+		},
+		"negated condition": {
+			conditions: []Condition{{target: "id", sign: "not between", value: []any{"5", "9"}}},
+		},
+		"compared with column": {
+			conditions: []Condition{{target: "id", sign: "=", value: "name"}},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			err := autocommit(func(tx *Transaction) error {
+				table, err := getTable(tx, SchemaTable[string, string]{defaultScheme, "items"})
+				if err != nil {
+					return err
+				}
+
+				scan, _ := planIndexScan(table, typedConditions(table, tC.conditions))
+				if scan.index.name != tC.index || scan.columns != tC.columns {
+					t.Errorf("\nexp %+v %+v\ngot %+v %+v", tC.index, tC.columns, scan.index.name, scan.columns)
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestIndexScan(t *testing.T) {
+	queries := []string{
+		"SELECT count(*), min(id), max(id) FROM items WHERE id = 10",
+		"SELECT count(*), min(id), max(id) FROM items WHERE id BETWEEN 100 AND 199",
+		"SELECT count(*), min(id), max(id) FROM items WHERE id > 450",
+		"SELECT count(*), min(id), max(id) FROM items WHERE id >= '-5' AND id < 5",
+		"SELECT count(*), min(id), max(id) FROM items WHERE id < 5 AND name = 'item 3'",
+		"SELECT count(*), min(id), max(id) FROM items WHERE name = 'item 3' AND id <= 3",
+		"SELECT count(*), min(id), max(id) FROM items WHERE name = 'item 3' AND id > 3",
+		"SELECT count(*), min(id), max(id) FROM items WHERE id = 1000",
+	}
+
+	// results of table scan are compared with results of index scan
+	for _, method := range []string{btreeIndex, hashIndex} {
+		t.Run(method, func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+			fillItems(t, 500)
+			if _, err := ExecuteQuery("INSERT INTO items (id, name) VALUES ('-3', 'a'); INSERT INTO items (id, name) VALUES ('-7', 'b'); " +
+				"DELETE FROM items WHERE id = 300"); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[string][][]any{}
+			for _, query := range queries {
+				expected[query] = queryCells(t, query)
+			}
+
+			_, err := ExecuteQuery(fmt.Sprintf("CREATE INDEX items_id ON items USING %s (id); "+
+				"CREATE INDEX items_name_id ON items USING %[1]s (name, id)", method))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			steps := map[string]string{
+				"created index":   "",
+				"updated rows":    "UPDATE items SET id = 1000 WHERE id = 10; UPDATE items SET id = 10 WHERE id = 1000",
+				"vacuumed table":  "VACUUM items",
+				"rewritten table": "VACUUM FULL items",
+			}
+			for _, step := range []string{"created index", "updated rows", "vacuumed table", "rewritten table"} {
+				if steps[step] != "" {
+					if _, err := ExecuteQuery(steps[step]); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				for _, query := range queries {
+					if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected[query]) {
+						t.Errorf("%s %s\nexp %+v\ngot %+v", step, query, expected[query], rows)
+					}
+				}
+			}
+
+			// entries of removed versions are removed by vacuum
+			for _, name := range []string{"items_id", "items_name_id"} {
+				if entries := indexEntries(t, Index{name: name, method: method}); entries != 501 {
+					t.Errorf("\nexp %+v\ngot %+v", 501, entries)
+				}
+			}
+		})
+	}
+}
+
+func TestUniqueIndexWaitsForInsert(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id smallint, name varchar)",
+		"CREATE UNIQUE INDEX items_id ON items (id)",
+	)
+
+	first, second := newSession(), newSession()
+	defer first.close()
+	defer second.close()
+
+	for _, end := range []string{"ROLLBACK", "COMMIT"} {
+		if _, err := first.executeQuery("BEGIN; INSERT INTO items (id, name) VALUES ('1', 'a')"); err != nil {
+			t.Fatal(err)
+		}
+
+		// conflict depends on the end of the first transaction
+		done := make(chan error)
+		go func() {
+			_, err := second.executeQuery("INSERT INTO items (id, name) VALUES ('1', 'b')")
+			done <- err
+		}()
+
+		waitForLockWaits(t, 1)
+		if _, err := first.executeQuery(end); err != nil {
+			t.Fatal(err)
+		}
+
+		err := <-done
+		if end == "ROLLBACK" && err != nil {
+			t.Fatal(err)
+		}
+
+		if end == "COMMIT" && err != ErrUniqueViolation {
+			t.Fatalf("\nexp %+v\ngot %+v", ErrUniqueViolation, err)
+		}
+
+		if _, err := ExecuteQuery("DELETE FROM items"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIndexCrashRecovery(t *testing.T) {
+	const poolSize = 3
+
+	queries := []string{
+		"CREATE INDEX items_id ON items (id)",
+		"INSERT INTO items (id, name) VALUES ('1000', 'x')",
+		"VACUUM items",
+		"CREATE INDEX items_id ON items USING hash (id)",
+	}
+	for _, query := range queries {
+		for crashAt := 1; ; crashAt++ {
+			crashed := false
+			t.Run(fmt.Sprintf("%s crash at write %d", query, crashAt), func(t *testing.T) {
+				setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+				defer func() { crashHook = nil }()
+
+				bufferPool.close()
+				bufferPool = newBufferPool(poolSize)
+
+				fillItems(t, 500)
+				if !strings.HasPrefix(query, "CREATE") {
+					if _, err := ExecuteQuery(queries[0] + "; DELETE FROM items WHERE id < 100"); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				flag := simulateCrashAt(crashAt)
+				if _, err := ExecuteQuery(query); err != nil && !errors.Is(err, errSimulatedCrash) {
+					t.Fatal(err)
+				}
+				crashed = *flag
+
+				crashHook = nil
+				if err := restartDatabase(poolSize); err != nil {
+					t.Fatal(err)
+				}
+
+				// index either exists with all rows or doesn't exist
+				indexes := queryCells(t, "SELECT count(*) FROM auralis.indexes")[0][0]
+				if indexes == int64(1) {
+					entries := queryCells(t, "SELECT count(*) FROM items")[0][0].(int64)
+					index := Index{name: "items_id", method: btreeIndex}
+					if strings.Contains(query, hashIndex) {
+						index.method = hashIndex
+					}
+					if query != "VACUUM items" && int64(indexEntries(t, index)) < entries {
+						t.Fatalf("\nexp at least %+v\ngot %+v", entries, indexEntries(t, index))
+					}
+				}
+
+				if _, err := ExecuteQuery("INSERT INTO items (id, name) VALUES ('2000', 'y')"); err != nil {
+					t.Fatal(err)
+				}
+
+				expected := [][]any{{int64(1)}}
+				if rows := queryCells(t, "SELECT count(*) FROM items WHERE id = 2000"); !reflect.DeepEqual(rows, expected) {
+					t.Fatalf("\nexp %+v\ngot %+v", expected, rows)
+				}
+
+				if rows := queryCells(t, "SELECT count(*) FROM items WHERE id BETWEEN 200 AND 299"); rows[0][0] != int64(100) {
+					t.Fatalf("\nexp %+v\ngot %+v", 100, rows[0][0])
+				}
+			})
+
+			if !crashed {
+				break
+			}
+		}
+	}
+}
+
+func TestHashIndexOfUUID(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE users (id uniqueidentifier, name varchar)",
+		"CREATE UNIQUE INDEX users_id ON users USING hash (id)",
+		"INSERT INTO users (id, name) VALUES ('e28c20d7-483d-4f6e-9b31-9d0d6819ba39', 'a')",
+		"INSERT INTO users (id, name) VALUES ('0b1c5e6a-2f0e-4d4c-8d8a-5b7f1c9e3a21', 'b')",
+	)
+
+	expected := [][]any{{"b"}}
+	if rows := queryCells(t, "SELECT name FROM users WHERE id = '0b1c5e6a-2f0e-4d4c-8d8a-5b7f1c9e3a21'"); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+
+	_, err := ExecuteQuery("INSERT INTO users (id, name) VALUES ('e28c20d7-483d-4f6e-9b31-9d0d6819ba39', 'c')")
+	if err != ErrUniqueViolation {
+		t.Errorf("\nexp %+v\ngot %+v", ErrUniqueViolation, err)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package auralis
 
 import (
 	"slices"
@@ -19,6 +19,8 @@ const (
 	greaterorequal
 	less
 	lessorequal
+	semicolon
+	regexmatch
 )
 
 var keywords []string = []string{
@@ -32,6 +34,64 @@ var keywords []string = []string{
 
 	"create",
 	"table",
+
+	"update",
+	"delete",
+
+	"begin",
+	"commit",
+	"rollback",
+	"savepoint",
+	"release",
+	"lock",
+	"for",
+	"vacuum",
+	"analyze",
+
+	"with",
+	"recursive",
+	"as",
+	"union",
+	"all",
+	"inner",
+	"join",
+	"on",
+	"and",
+
+	"group",
+	"order",
+	"by",
+	"asc",
+	"desc",
+	"over",
+	"partition",
+	"rows",
+	"between",
+	"unbounded",
+	"preceding",
+	"following",
+	"current",
+	"row",
+
+	"like",
+	"ilike",
+	"in",
+	"escape",
+
+	"case",
+	"when",
+	"then",
+	"else",
+	"end",
+
+	"set",
+	"show",
+
+	"explain",
+
+	"prepare",
+	"execute",
+	"deallocate",
 }
 
 type TokenLiteral struct {
@@ -45,19 +105,14 @@ func Analyze(raw string) []TokenLiteral {
 	l := 0
 	for r := 0; r < len(raw); r++ {
 		switch raw[r] {
-		case byte(' '):
+		case byte(' '), byte('\n'), byte('\t'), byte('\r'):
 			{
 				if r == l {
 					l++
 					continue
 				}
 
-				frag := strings.ToLower(string(raw[l:r]))
-				if slices.Contains(keywords, frag) {
-					tokens = append(tokens, TokenLiteral{kind: keyword, value: frag})
-				} else {
-					tokens = append(tokens, TokenLiteral{kind: symbol, value: frag})
-				}
+				tokens = append(tokens, wordToken(raw[l:r]))
 
 				l = r + 1
 			}
@@ -68,7 +123,7 @@ func Analyze(raw string) []TokenLiteral {
 			}
 		case byte('>'):
 			{
-				if raw[r+1] == byte('=') {
+				if r+1 < len(raw) && raw[r+1] == byte('=') {
 					tokens = append(tokens, TokenLiteral{kind: greaterorequal, value: string(">=")})
 					// r + 1 incremented by next loop iteration
 					l = r + 2
@@ -80,11 +135,16 @@ func Analyze(raw string) []TokenLiteral {
 			}
 		case byte('<'):
 			{
-				if raw[r+1] == byte('=') {
+				if r+1 < len(raw) && raw[r+1] == byte('=') {
 					tokens = append(tokens, TokenLiteral{kind: lessorequal, value: string("<=")})
 					// r + 1 incremented by next loop iteration
 					l = r + 2
 					r += 1
+				} else if r+1 < len(raw) && raw[r+1] == byte('>') {
+					// <> is an alias of !=
+					tokens = append(tokens, TokenLiteral{kind: notequal, value: string("!=")})
+					l = r + 2
+					r += 1
 				} else {
 					tokens = append(tokens, TokenLiteral{kind: less, value: string('<')})
 					l = r + 1
@@ -92,7 +152,15 @@ func Analyze(raw string) []TokenLiteral {
 			}
 		case byte('!'):
 			{
-				if raw[r+1] == byte('=') {
+				if r+1 < len(raw) && raw[r+1] == byte('~') {
+					value := regexMatchOperator(raw, r+1)
+					tokens = append(tokens, TokenLiteral{kind: regexmatch, value: "!" + value})
+					l = r + 1 + len(value)
+					r += len(value)
+					continue
+				}
+
+				if r+1 < len(raw) && raw[r+1] == byte('=') {
 					tokens = append(tokens, TokenLiteral{kind: notequal, value: string("!=")})
 				}
 
@@ -100,11 +168,44 @@ func Analyze(raw string) []TokenLiteral {
 				l = r + 2
 				r += 1
 			}
+		case byte('~'):
+			{
+				value := regexMatchOperator(raw, r)
+				tokens = append(tokens, TokenLiteral{kind: regexmatch, value: value})
+				l = r + len(value)
+				r += len(value) - 1
+			}
+		case byte('\''):
+			{
+				// quoted literal is kept as a whole with quotes and original letter case
+				if r != l {
+					break
+				}
+
+				end := r + 1
+				for ; end < len(raw); end++ {
+					if raw[end] != byte('\'') {
+						continue
+					}
+
+					// '' is an escaped quote within literal
+					if end+1 < len(raw) && raw[end+1] == byte('\'') {
+						end++
+						continue
+					}
+
+					break
+				}
+				end = min(end, len(raw)-1)
+
+				tokens = append(tokens, TokenLiteral{kind: symbol, value: raw[r : end+1]})
+				l = end + 1
+				r = end
+			}
 		case byte('('):
 			{
 				if l != r {
-					frag := strings.ToLower(string(raw[l:r]))
-					tokens = append(tokens, TokenLiteral{kind: symbol, value: frag})
+					tokens = append(tokens, wordToken(raw[l:r]))
 				}
 
 				tokens = append(tokens, TokenLiteral{kind: openingroundbracket, value: string('(')})
@@ -113,18 +214,25 @@ func Analyze(raw string) []TokenLiteral {
 		case byte(')'):
 			{
 				if l != r {
-					frag := strings.ToLower(string(raw[l:r]))
-					tokens = append(tokens, TokenLiteral{kind: symbol, value: frag})
+					tokens = append(tokens, wordToken(raw[l:r]))
 				}
 
 				tokens = append(tokens, TokenLiteral{kind: closingroundbracket, value: string(')')})
 				l = r + 1
 			}
+		case byte(';'):
+			{
+				if l != r {
+					tokens = append(tokens, wordToken(raw[l:r]))
+				}
+
+				tokens = append(tokens, TokenLiteral{kind: semicolon, value: string(';')})
+				l = r + 1
+			}
 		case byte(','):
 			{
 				if raw[r-1] != byte(' ') && r != l {
-					frag := strings.ToLower(string(raw[l:r]))
-					tokens = append(tokens, TokenLiteral{kind: symbol, value: frag})
+					tokens = append(tokens, wordToken(raw[l:r]))
 				}
 
 				tokens = append(tokens, TokenLiteral{kind: comma, value: string(',')})
@@ -134,9 +242,28 @@ func Analyze(raw string) []TokenLiteral {
 
 		// l-1 because we assign l = r + 1
 		if r == len(raw)-1 && r != l-1 {
-			tokens = append(tokens, TokenLiteral{kind: symbol, value: string(raw[l:])})
+			tokens = append(tokens, wordToken(raw[l:]))
 		}
 	}
 
 	return tokens
 }
+
+// wordToken classifies fragment between separators as keyword or symbol
+func wordToken(frag string) TokenLiteral {
+	frag = strings.ToLower(frag)
+	if slices.Contains(keywords, frag) {
+		return TokenLiteral{kind: keyword, value: frag}
+	}
+
+	return TokenLiteral{kind: symbol, value: frag}
+}
+
+// regexMatchOperator returns ~ or ~* operator starting at i
+func regexMatchOperator(raw string, i int) string {
+	if i+1 < len(raw) && raw[i+1] == byte('*') {
+		return "~*"
+	}
+
+	return "~"
+}
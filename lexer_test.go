@@ -1,4 +1,4 @@
-package main
+package auralis
 
 import (
 	"slices"
@@ -161,6 +161,49 @@ func TestSelectStatementLexer(t *testing.T) {
 				{kind: symbol, value: "1"},
 			},
 		},
+		{
+			raw: "SELECT * FROM users WHERE name LIKE 'John %' AND name ~* '^j' AND id <> 1",
+			expected: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "name"},
+				{kind: keyword, value: "like"},
+				{kind: symbol, value: "'John %'"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "name"},
+				{kind: regexmatch, value: "~*"},
+				{kind: symbol, value: "'^j'"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "id"},
+				{kind: notequal, value: "!="},
+				{kind: symbol, value: "1"},
+			},
+		},
+		{
+			raw: "SELECT * FROM users WHERE name !~ 'it''s' AND id NOT IN (1, 2)",
+			expected: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "name"},
+				{kind: regexmatch, value: "!~"},
+				{kind: symbol, value: "'it''s'"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "id"},
+				{kind: symbol, value: "not"},
+				{kind: keyword, value: "in"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "1"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "2"},
+				{kind: closingroundbracket, value: ")"},
+			},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.raw, func(t *testing.T) {
@@ -0,0 +1,260 @@
+package auralis
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LockMode of multiple granularity locking, intention modes are taken on table
+// before shared or exclusive locks of its rows
+type LockMode uint8
+
+const (
+	intentionShared LockMode = iota + 1
+	intentionExclusive
+	shared
+	sharedIntentionExclusive
+	exclusive
+)
+
+// lockCompatibility[held][requested] reports whether modes can be held together
+var lockCompatibility = map[LockMode]map[LockMode]bool{
+	intentionShared: {
+		intentionShared: true, intentionExclusive: true, shared: true, sharedIntentionExclusive: true},
+	intentionExclusive:       {intentionShared: true, intentionExclusive: true},
+	shared:                   {intentionShared: true, shared: true},
+	sharedIntentionExclusive: {intentionShared: true},
+	exclusive:                {},
+}
+
+var (
+	ErrDeadlockDetected = AuraError{
+		Code:    "DEADLOCK_DETECTED",
+		Message: "deadlock detected, transaction was rolled back"}
+	ErrLockTimeout = AuraError{Code: "LOCK_NOT_AVAILABLE", Message: "canceling statement due to lock timeout"}
+)
+
+// LockTag identifies locked table, row of table or transaction
+type LockTag struct {
+	file        string
+	row         RowID
+	transaction uint64
+}
+
+func tableLockTag(table Table) LockTag {
+	return LockTag{file: getTableDiskPath(table.schemaTable)}
+}
+
+func rowLockTag(id RowID) LockTag {
+	return LockTag{file: id.page.file, row: id}
+}
+
+// transactionLockTag is locked exclusively by writing transaction so others
+// can wait for its end
+func transactionLockTag(id uint64) LockTag {
+	return LockTag{transaction: id}
+}
+
+// LockManager grants locks held until end of transaction, conflicting requests
+// wait for release. Waiting releases statementLock so other sessions can finish
+// their transactions, lock manager is guarded by it as well
+type LockManager struct {
+	held     map[LockTag]map[uint64]LockMode // granted modes by transaction
+	owned    map[uint64][]LockTag            // locks of transaction released together
+	waiting  map[uint64]lockRequest          // blocked transactions used by deadlock detection
+	released *sync.Cond
+}
+
+type lockRequest struct {
+	tag  LockTag
+	mode LockMode
+}
+
+var lockManager = newLockManager()
+
+func newLockManager() *LockManager {
+	return &LockManager{
+		held:     map[LockTag]map[uint64]LockMode{},
+		owned:    map[uint64][]LockTag{},
+		waiting:  map[uint64]lockRequest{},
+		released: sync.NewCond(&statementLock),
+	}
+}
+
+// lock acquires lock for transaction, it waits while other transactions hold
+// conflicting lock. Waiting transaction closing cycle of waits is the deadlock
+// victim, waiting longer than lock_timeout setting or until context of the
+// statement is canceled cancels the statement
+func (lm *LockManager) lock(tx *Transaction, tag LockTag, mode LockMode) error {
+	current, holds := lm.held[tag][tx.id]
+	if holds && covers(current, mode) {
+		return nil
+	}
+
+	// upgrade requests the combination of held and new mode
+	if holds {
+		mode = combineLockModes(current, mode)
+	}
+
+	var deadline time.Time
+	if timeout := getIntSetting("lock_timeout"); timeout > 0 {
+		deadline = time.Now().Add(time.Duration(timeout) * time.Millisecond)
+		timer := time.AfterFunc(time.Duration(timeout)*time.Millisecond, lm.released.Broadcast)
+		defer timer.Stop()
+	}
+
+	ctx := activeStatement.ctx
+	if ctx != nil {
+		stop := context.AfterFunc(ctx, lm.released.Broadcast)
+		defer stop()
+	}
+
+	for len(lm.conflicting(tx.id, tag, mode)) > 0 {
+		lm.waiting[tx.id] = lockRequest{tag: tag, mode: mode}
+		if lm.deadlocked(tx.id) {
+			delete(lm.waiting, tx.id)
+			return ErrDeadlockDetected
+		}
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			delete(lm.waiting, tx.id)
+			return ErrLockTimeout
+		}
+
+		if ctx != nil && ctx.Err() != nil {
+			delete(lm.waiting, tx.id)
+			return ctx.Err()
+		}
+
+		statement := activeStatement
+		lm.released.Wait()
+		activeStatement = statement
+		delete(lm.waiting, tx.id)
+	}
+
+	if _, ok := lm.held[tag]; !ok {
+		lm.held[tag] = map[uint64]LockMode{}
+	}
+
+	if !holds {
+		lm.owned[tx.id] = append(lm.owned[tx.id], tag)
+	}
+	lm.held[tag][tx.id] = mode
+
+	return nil
+}
+
+// tryLock acquires lock only when it's granted without waiting
+func (lm *LockManager) tryLock(tx *Transaction, tag LockTag, mode LockMode) bool {
+	if len(lm.conflicting(tx.id, tag, mode)) > 0 {
+		return false
+	}
+
+	return lm.lock(tx, tag, mode) == nil
+}
+
+// releaseAll releases locks of finished transaction and wakes up waiting ones
+func (lm *LockManager) releaseAll(tx *Transaction) {
+	for _, tag := range lm.owned[tx.id] {
+		delete(lm.held[tag], tx.id)
+		if len(lm.held[tag]) == 0 {
+			delete(lm.held, tag)
+		}
+	}
+
+	if _, ok := lm.owned[tx.id]; ok {
+		delete(lm.owned, tx.id)
+		lm.released.Broadcast()
+	}
+}
+
+// conflicting returns transactions holding lock incompatible with requested mode
+func (lm *LockManager) conflicting(id uint64, tag LockTag, mode LockMode) []uint64 {
+	ids := []uint64{}
+	for holder, held := range lm.held[tag] {
+		if holder != id && !lockCompatibility[held][mode] {
+			ids = append(ids, holder)
+		}
+	}
+
+	return ids
+}
+
+// deadlocked searches wait-for graph for a cycle leading back to transaction
+func (lm *LockManager) deadlocked(id uint64) bool {
+	visited := map[uint64]bool{}
+	stack := []uint64{id}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		request, ok := lm.waiting[current]
+		if !ok {
+			continue
+		}
+
+		for _, holder := range lm.conflicting(current, request.tag, request.mode) {
+			if holder == id {
+				return true
+			}
+
+			if !visited[holder] {
+				visited[holder] = true
+				stack = append(stack, holder)
+			}
+		}
+	}
+
+	return false
+}
+
+// covers reports whether held mode includes all rights of requested one
+func covers(held LockMode, requested LockMode) bool {
+	return combineLockModes(held, requested) == held
+}
+
+// combineLockModes returns the weakest mode including both modes
+func combineLockModes(a LockMode, b LockMode) LockMode {
+	modes := []LockMode{a, b}
+	switch {
+	case slices.Contains(modes, exclusive):
+		return exclusive
+	case slices.Contains(modes, sharedIntentionExclusive),
+		slices.Contains(modes, shared) && slices.Contains(modes, intentionExclusive):
+		return sharedIntentionExclusive
+	case slices.Contains(modes, shared):
+		return shared
+	case slices.Contains(modes, intentionExclusive):
+		return intentionExclusive
+	}
+
+	return intentionShared
+}
+
+// lockRow locks row of table and checks that it wasn't changed by transaction
+// committed after snapshot, such row can't be locked or modified
+func (tx *Transaction) lockRow(id RowID, mode LockMode) error {
+	if err := lockManager.lock(tx, rowLockTag(id), mode); err != nil {
+		return err
+	}
+
+	page, err := bufferPool.fetchPage(id.page)
+	if err != nil {
+		return err
+	}
+	defer bufferPool.unpinPage(id.page, false)
+
+	tuple := page.tuple(id.slot)
+	if tuple == nil {
+		return ErrSerializationFailure
+	}
+
+	// deleting transaction held the row lock so it has already finished
+	if xmax := tupleXmax(tuple); xmax != 0 && xmax != tx.id {
+		return ErrSerializationFailure
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package auralis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCombineLockModes(t *testing.T) {
+	testCases := map[string]struct {
+		a, b     LockMode
+		expected LockMode
+	}{
+		"same mode":                       {shared, shared, shared},
+		"intention modes":                 {intentionShared, intentionExclusive, intentionExclusive},
+		"shared with intention exclusive": {shared, intentionExclusive, sharedIntentionExclusive},
+		"shared with intention shared":    {intentionShared, shared, shared},
+		"exclusive covers all":            {sharedIntentionExclusive, exclusive, exclusive},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			if mode := combineLockModes(tC.a, tC.b); mode != tC.expected {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, mode)
+			}
+		})
+	}
+}
+
+func TestLockConflicts(t *testing.T) {
+	type step struct {
+		session int
+		query   string
+
+		expected    [][]any
+		expectedErr error
+	}
+
+	testCases := map[string][]step{
+		"for share is compatible with for share": {
+			{session: 0, query: "BEGIN; SELECT id FROM items WHERE id = 1 FOR SHARE"},
+			{session: 1, query: "BEGIN; SELECT id FROM items FOR SHARE"},
+			{session: 1, query: "UPDATE items SET name = 'x' WHERE id = 2"},
+			{session: 1, query: "UPDATE items SET name = 'x' WHERE id = 1", expectedErr: ErrLockTimeout},
+		},
+		"for update blocks locking reads but not plain ones": {
+			{session: 0, query: "BEGIN; SELECT id FROM items WHERE id = 1 FOR UPDATE"},
+			{session: 1, query: "SELECT id FROM items WHERE id = 1 FOR SHARE", expectedErr: ErrLockTimeout},
+			{session: 1, query: "SELECT name FROM items WHERE id = 1", expected: [][]any{{"a"}}},
+			{session: 0, query: "COMMIT"},
+			{session: 1, query: "SELECT id FROM items WHERE id = 1 FOR SHARE", expected: [][]any{{int16(1)}}},
+		},
+		"lock table in default mode blocks reads": {
+			{session: 0, query: "BEGIN; LOCK TABLE items"},
+			{session: 1, query: "SELECT id FROM items", expectedErr: ErrLockTimeout},
+			{session: 0, query: "ROLLBACK"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+		},
+		"lock table in share mode blocks writes": {
+			{session: 0, query: "BEGIN; LOCK TABLE items IN SHARE MODE"},
+			{session: 1, query: "SELECT id FROM items WHERE id = 2", expected: [][]any{{int16(2)}}},
+			{session: 1, query: "INSERT INTO items (id, name) VALUES ('3', 'c')", expectedErr: ErrLockTimeout},
+			{session: 0, query: "INSERT INTO items (id, name) VALUES ('3', 'c')"},
+		},
+		"lock table outside of transaction": {
+			{session: 0, query: "LOCK TABLE items IN ROW EXCLUSIVE MODE", expectedErr: ErrNoActiveTransaction},
+		},
+		"lock missing table": {
+			{session: 0, query: "BEGIN; LOCK TABLE missing", expectedErr: ErrTableNotFound},
+		},
+		"for update of row changed after repeatable read snapshot": {
+			{session: 1, query: "BEGIN ISOLATION LEVEL REPEATABLE READ"},
+			{session: 1, query: "SELECT id FROM items WHERE id = 1", expected: [][]any{{int16(1)}}},
+			{session: 0, query: "UPDATE items SET name = 'x' WHERE id = 1"},
+			{session: 1, query: "SELECT id FROM items WHERE id = 1 FOR UPDATE", expectedErr: ErrSerializationFailure},
+		},
+	}
+	for test, steps := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id smallint, name varchar)",
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('2', 'b')",
+				"SET lock_timeout = 10",
+			)
+
+			sessions := []*Session{newSession(), newSession()}
+			defer func() {
+				for _, s := range sessions {
+					s.close()
+				}
+				setSetting("lock_timeout", "0")
+			}()
+
+			for i, s := range steps {
+				dataSet, err := sessions[s.session].executeQuery(s.query)
+				if err != s.expectedErr {
+					t.Fatalf("step %d\nexp %+v\ngot %+v", i, s.expectedErr, err)
+				}
+
+				if s.expected != nil && !reflect.DeepEqual(resultCells(dataSet), s.expected) {
+					t.Fatalf("step %d\nexp %+v\ngot %+v", i, s.expected, resultCells(dataSet))
+				}
+			}
+		})
+	}
+}
+
+// waitForLockWaits blocks until n transactions wait for locks
+func waitForLockWaits(t *testing.T, n int) {
+	t.Helper()
+
+	for range 1000 {
+		statementLock.Lock()
+		waiting := len(lockManager.waiting)
+		statementLock.Unlock()
+
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("%d transactions don't wait for locks", n)
+}
+
+func TestRowLockWait(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id smallint, name varchar)",
+		"INSERT INTO items (id, name) VALUES ('1', 'a')",
+	)
+
+	first, second := newSession(), newSession()
+	defer first.close()
+	defer second.close()
+
+	if _, err := first.executeQuery("BEGIN; UPDATE items SET name = 'x' WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// read committed update waits for the first one and is applied to its version
+	done := make(chan error)
+	go func() {
+		_, err := second.executeQuery("UPDATE items SET name = concat(name, 'y') WHERE id = 1")
+		done <- err
+	}()
+
+	waitForLockWaits(t, 1)
+	if _, err := first.executeQuery("COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	dataSet, err := ExecuteQuery("SELECT id, name FROM items")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{int16(1), "xy"}}
+	if !reflect.DeepEqual(resultCells(dataSet), expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, resultCells(dataSet))
+	}
+}
+
+func TestDeadlockDetection(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE items (id smallint, name varchar)",
+		"INSERT INTO items (id, name) VALUES ('1', 'a')",
+		"INSERT INTO items (id, name) VALUES ('2', 'b')",
+	)
+
+	first, second := newSession(), newSession()
+	defer first.close()
+	defer second.close()
+
+	if _, err := first.executeQuery("BEGIN; UPDATE items SET name = 'x' WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := second.executeQuery("BEGIN; UPDATE items SET name = 'y' WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := first.executeQuery("UPDATE items SET name = 'x' WHERE id = 2")
+		done <- err
+	}()
+
+	// second transaction closes the cycle and is rolled back
+	waitForLockWaits(t, 1)
+	if _, err := second.executeQuery("UPDATE items SET name = 'y' WHERE id = 1"); err != ErrDeadlockDetected {
+		t.Fatalf("\nexp %+v\ngot %+v", ErrDeadlockDetected, err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := second.executeQuery("COMMIT"); err != ErrNoActiveTransaction {
+		t.Fatalf("\nexp %+v\ngot %+v", ErrNoActiveTransaction, err)
+	}
+
+	if _, err := first.executeQuery("COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+
+	dataSet, err := ExecuteQuery("SELECT id, name FROM items ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{int16(1), "x"}, {int16(2), "x"}}
+	if !reflect.DeepEqual(resultCells(dataSet), expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, resultCells(dataSet))
+	}
+}
@@ -0,0 +1,157 @@
+package auralis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+type IsolationLevel string
+
+const (
+	readCommitted  IsolationLevel = "read committed"
+	repeatableRead IsolationLevel = "repeatable read"
+	serializable   IsolationLevel = "serializable"
+)
+
+// tuple header layout: xmin (8B) id of inserting transaction, xmax (8B) id of
+// deleting transaction or zero for live tuples
+const tupleHeaderSize = 16
+
+var (
+	ErrSerializationFailure = AuraError{
+		Code:    "SERIALIZATION_FAILURE",
+		Message: "could not serialize access due to concurrent update"}
+	ErrReadWriteDependency = AuraError{
+		Code:    "SERIALIZATION_FAILURE",
+		Message: "could not serialize access due to read/write dependencies among transactions"}
+)
+
+func tupleXmin(tuple []byte) uint64 {
+	return binary.BigEndian.Uint64(tuple[0:8])
+}
+
+func setTupleXmin(tuple []byte, xmin uint64) {
+	binary.BigEndian.PutUint64(tuple[0:8], xmin)
+}
+
+func tupleXmax(tuple []byte) uint64 {
+	return binary.BigEndian.Uint64(tuple[8:16])
+}
+
+func setTupleXmax(tuple []byte, xmax uint64) {
+	binary.BigEndian.PutUint64(tuple[8:16], xmax)
+}
+
+// Snapshot captures which transactions were committed when it was taken, changes
+// of transactions running at that time or started later are not visible
+type Snapshot struct {
+	xmax   uint64          // first transaction id not assigned yet
+	active map[uint64]bool // transactions running when snapshot was taken
+}
+
+func (s *Snapshot) committed(id uint64) bool {
+	return id < s.xmax && !s.active[id]
+}
+
+// sees reports whether tuple version is visible to transaction. Changes of
+// aborted transactions are undone physically so every finished transaction
+// seen by snapshot is a committed one
+func (tx *Transaction) sees(tuple []byte) bool {
+	xmin, xmax := tupleXmin(tuple), tupleXmax(tuple)
+	if xmin != tx.id && !tx.snapshot.committed(xmin) {
+		return false
+	}
+
+	return xmax == 0 || (xmax != tx.id && !tx.snapshot.committed(xmax))
+}
+
+func validateIsolationLevel(value string) error {
+	if !slices.Contains([]IsolationLevel{readCommitted, repeatableRead, serializable}, IsolationLevel(value)) {
+		return AuraError{
+			Code:    "INVALID_SETTING_VALUE",
+			Message: fmt.Sprintf("invalid value %s, expected read committed, repeatable read or serializable", value)}
+	}
+
+	return nil
+}
+
+// serializableTransactions are checked for read-write conflicts at commit, committed
+// transactions are kept while concurrent serializable transactions still run.
+// It is guarded by statementLock
+var serializableTransactions = []*Transaction{}
+
+// concurrent reports whether neither transaction saw the other one committed
+func concurrent(a *Transaction, b *Transaction) bool {
+	if a.snapshot == nil || b.snapshot == nil {
+		return false
+	}
+
+	return !a.snapshot.committed(b.id) && !b.snapshot.committed(a.id)
+}
+
+// checkSerializable looks for read-write dependencies between committing transaction
+// and concurrent serializable transactions, dependency A -> B exists when A read
+// table written by B and didn't see the change. Transaction with both incoming and
+// outgoing dependency can be part of a cycle, committing it would make the
+// history not serializable. Conflicts are tracked per table so they can be false
+func checkSerializable(tx *Transaction) error {
+	in, out := tx.inConflict, tx.outConflict
+	type conflict struct{ in, out bool }
+	committed := map[*Transaction]conflict{}
+	for _, other := range serializableTransactions {
+		if other == tx || !concurrent(tx, other) {
+			continue
+		}
+
+		c := conflict{other.inConflict, other.outConflict}
+		if overlaps(other.reads, tx.writes) {
+			in, c.out = true, true
+		}
+		if overlaps(tx.reads, other.writes) {
+			out, c.in = true, true
+		}
+
+		// committed transaction can't be aborted anymore
+		if other.committed && c.in && c.out {
+			return ErrReadWriteDependency
+		}
+
+		if other.committed {
+			committed[other] = c
+		}
+	}
+
+	if in && out {
+		return ErrReadWriteDependency
+	}
+
+	tx.inConflict, tx.outConflict = in, out
+	for other, c := range committed {
+		other.inConflict, other.outConflict = c.in, c.out
+	}
+
+	return nil
+}
+
+// releaseSerializable removes finished transaction, committed ones are dropped
+// once no serializable transaction runs
+func releaseSerializable(tx *Transaction) {
+	serializableTransactions = slices.DeleteFunc(serializableTransactions, func(other *Transaction) bool {
+		return other == tx && !tx.committed
+	})
+
+	if !slices.ContainsFunc(serializableTransactions, func(other *Transaction) bool { return !other.committed }) {
+		serializableTransactions = serializableTransactions[:0]
+	}
+}
+
+func overlaps(a map[string]bool, b map[string]bool) bool {
+	for key := range a {
+		if b[key] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,244 @@
+package auralis
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestIsolationLevels(t *testing.T) {
+	type step struct {
+		session int
+		query   string
+
+		expected    [][]any
+		expectedErr error
+	}
+
+	testCases := map[string][]step{
+		"uncommitted insert is not visible": {
+			{session: 0, query: "BEGIN"},
+			{session: 0, query: "INSERT INTO items (id, name) VALUES ('3', 'c')"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 0, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}, {int16(3)}}},
+			{session: 0, query: "COMMIT"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}, {int16(3)}}},
+		},
+		"rolled back delete stays visible": {
+			{session: 0, query: "BEGIN"},
+			{session: 0, query: "DELETE FROM items"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 0, query: "SELECT id FROM items", expected: [][]any{}},
+			{session: 0, query: "ROLLBACK"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+		},
+		"read committed sees changes committed before statement": {
+			{session: 1, query: "BEGIN"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 0, query: "DELETE FROM items WHERE id = 1"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(2)}}},
+		},
+		"read committed updates committed version": {
+			{session: 1, query: "BEGIN"},
+			{session: 1, query: "SELECT name FROM items WHERE id = 1", expected: [][]any{{"a"}}},
+			{session: 0, query: "UPDATE items SET name = 'x' WHERE id = 1"},
+			{session: 1, query: "UPDATE items SET name = concat(name, 'y') WHERE id = 1"},
+			{session: 1, query: "COMMIT"},
+			{session: 0, query: "SELECT name FROM items WHERE id = 1", expected: [][]any{{"xy"}}},
+		},
+		"repeatable read keeps snapshot of first statement": {
+			{session: 1, query: "BEGIN ISOLATION LEVEL REPEATABLE READ"},
+			{session: 0, query: "INSERT INTO items (id, name) VALUES ('3', 'c')"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}, {int16(3)}}},
+			{session: 0, query: "DELETE FROM items WHERE id = 1"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}, {int16(3)}}},
+			{session: 1, query: "INSERT INTO items (id, name) VALUES ('4', 'd')"},
+			{session: 1, query: "SELECT count(*) FROM items", expected: [][]any{{int64(4)}}},
+			{session: 1, query: "COMMIT"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(2)}, {int16(3)}, {int16(4)}}},
+		},
+		"set transaction isolation level": {
+			{session: 1, query: "BEGIN; SET TRANSACTION ISOLATION LEVEL REPEATABLE READ"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 0, query: "DELETE FROM items"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+		},
+		"set transaction isolation level after query": {
+			{session: 0, query: "BEGIN"},
+			{session: 0, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 0, query: "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE", expectedErr: ErrIsolationLevelSet},
+		},
+		"default isolation level": {
+			{session: 1, query: "SET default_transaction_isolation = 'repeatable read'"},
+			{session: 1, query: "BEGIN"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 0, query: "DELETE FROM items"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 1, query: "SET default_transaction_isolation = 'read committed'"},
+		},
+		"concurrent update of uncommitted row": {
+			{session: 0, query: "BEGIN"},
+			{session: 0, query: "UPDATE items SET name = 'x' WHERE id = 1"},
+			{session: 1, query: "SET lock_timeout = 10"},
+			{session: 1, query: "DELETE FROM items WHERE id = 1", expectedErr: ErrLockTimeout},
+			{session: 1, query: "DELETE FROM items WHERE id = 2"},
+			{session: 0, query: "COMMIT"},
+			{session: 1, query: "SELECT id, name FROM items", expected: [][]any{{int16(1), "x"}}},
+		},
+		"repeatable read update of row changed after snapshot": {
+			{session: 1, query: "BEGIN ISOLATION LEVEL REPEATABLE READ"},
+			{session: 1, query: "SELECT id FROM items", expected: [][]any{{int16(1)}, {int16(2)}}},
+			{session: 0, query: "UPDATE items SET name = 'x' WHERE id = 1"},
+			{session: 1, query: "UPDATE items SET name = 'y'", expectedErr: ErrSerializationFailure},
+			{session: 1, query: "UPDATE items SET name = 'y' WHERE id = 2"},
+			{session: 1, query: "COMMIT"},
+			{session: 0, query: "SELECT id, name FROM items", expected: [][]any{{int16(1), "x"}, {int16(2), "y"}}},
+		},
+		"repeatable read allows write skew": {
+			{session: 0, query: "BEGIN ISOLATION LEVEL REPEATABLE READ"},
+			{session: 1, query: "BEGIN ISOLATION LEVEL REPEATABLE READ"},
+			{session: 0, query: "SELECT count(*) FROM items WHERE name = 'a'", expected: [][]any{{int64(1)}}},
+			{session: 1, query: "SELECT count(*) FROM items WHERE name = 'b'", expected: [][]any{{int64(1)}}},
+			{session: 0, query: "UPDATE items SET name = 'b' WHERE id = 1"},
+			{session: 1, query: "UPDATE items SET name = 'a' WHERE id = 2"},
+			{session: 0, query: "COMMIT"},
+			{session: 1, query: "COMMIT"},
+			{session: 0, query: "SELECT id, name FROM items", expected: [][]any{{int16(1), "b"}, {int16(2), "a"}}},
+		},
+		"serializable prevents write skew": {
+			{session: 0, query: "BEGIN ISOLATION LEVEL SERIALIZABLE"},
+			{session: 1, query: "BEGIN ISOLATION LEVEL SERIALIZABLE"},
+			{session: 0, query: "SELECT count(*) FROM items WHERE name = 'a'", expected: [][]any{{int64(1)}}},
+			{session: 1, query: "SELECT count(*) FROM items WHERE name = 'b'", expected: [][]any{{int64(1)}}},
+			{session: 0, query: "UPDATE items SET name = 'b' WHERE id = 1"},
+			{session: 1, query: "UPDATE items SET name = 'a' WHERE id = 2"},
+			{session: 0, query: "COMMIT", expectedErr: ErrReadWriteDependency},
+			{session: 1, query: "COMMIT"},
+			{session: 0, query: "SELECT id, name FROM items", expected: [][]any{{int16(1), "a"}, {int16(2), "a"}}},
+		},
+		"serializable transactions without dependency cycle": {
+			{session: 0, query: "BEGIN ISOLATION LEVEL SERIALIZABLE"},
+			{session: 1, query: "BEGIN ISOLATION LEVEL SERIALIZABLE"},
+			{session: 0, query: "SELECT count(*) FROM items", expected: [][]any{{int64(2)}}},
+			{session: 1, query: "INSERT INTO items (id, name) VALUES ('3', 'c')"},
+			{session: 1, query: "COMMIT"},
+			{session: 0, query: "SELECT count(*) FROM items", expected: [][]any{{int64(2)}}},
+			{session: 0, query: "COMMIT"},
+		},
+	}
+	for test, steps := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id smallint, name varchar)",
+				"INSERT INTO items (id, name) VALUES ('1', 'a')",
+				"INSERT INTO items (id, name) VALUES ('2', 'b')",
+			)
+
+			sessions := []*Session{newSession(), newSession()}
+			defer func() {
+				for _, s := range sessions {
+					s.close()
+				}
+				setSetting("default_transaction_isolation", string(readCommitted))
+				setSetting("lock_timeout", "0")
+			}()
+
+			for i, s := range steps {
+				dataSet, err := sessions[s.session].executeQuery(s.query)
+				if err != s.expectedErr {
+					t.Fatalf("step %d\nexp %+v\ngot %+v", i, s.expectedErr, err)
+				}
+
+				if s.expected != nil && !reflect.DeepEqual(resultCells(dataSet), s.expected) {
+					t.Fatalf("step %d\nexp %+v\ngot %+v", i, s.expected, resultCells(dataSet))
+				}
+			}
+		})
+	}
+}
+
+func TestConcurrentReadersAndWriters(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE entries (batch smallint, amount smallint)")
+
+	// each writer transaction inserts balanced entries, readers never see
+	// a partial batch and keep seeing the same rows within repeatable read
+	const writers, batches = 4, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*writers)
+	for w := range writers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			s := newSession()
+			defer s.close()
+			for b := range batches {
+				_, err := s.executeQuery(fmt.Sprintf("BEGIN; "+
+					"INSERT INTO entries (batch, amount) VALUES ('%d', '5'); "+
+					"INSERT INTO entries (batch, amount) VALUES ('%d', '-5'); COMMIT", w*batches+b, w*batches+b))
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			s := newSession()
+			defer s.close()
+			for range batches {
+				if _, err := s.executeQuery("BEGIN ISOLATION LEVEL REPEATABLE READ"); err != nil {
+					errs <- err
+					return
+				}
+
+				first, err := s.executeQuery("SELECT count(*), sum(amount) FROM entries")
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				second, err := s.executeQuery("SELECT count(*), sum(amount) FROM entries")
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if !reflect.DeepEqual(first.rows, second.rows) {
+					errs <- fmt.Errorf("snapshot changed from %+v to %+v", first.rows, second.rows)
+					return
+				}
+
+				count := first.rows[0].cells[0].(int64)
+				if sum := first.rows[0].cells[1]; count%2 != 0 || (count > 0 && sum != int64(0)) {
+					errs <- fmt.Errorf("partial batch seen with %d rows of sum %v", count, sum)
+					return
+				}
+
+				if _, err := s.executeQuery("COMMIT"); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	dataSet, err := ExecuteQuery("SELECT count(*) FROM entries")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{int64(2 * writers * batches)}}
+	if !reflect.DeepEqual(resultCells(dataSet), expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, resultCells(dataSet))
+	}
+}
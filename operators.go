@@ -0,0 +1,659 @@
+package auralis
+
+import (
+	"slices"
+)
+
+// Operator is node of physical plan producing rows one by one. Rows are pulled
+// from inputs by next until it reports there are no more rows, operators open
+// and close their inputs
+type Operator interface {
+	open() error
+	next() (Row, bool, error)
+	close() error
+}
+
+// buildOperator returns physical operators executing logical plan, table scan
+// reads index when conditions restrict its columns. Wrap is called with each
+// built operator, inputs first, and may replace it, eg. by instrumented one
+func buildOperator(tx *Transaction, plan LogicalPlan, wrap func(LogicalPlan, Operator) Operator) (Operator, error) {
+	inputs := []Operator{}
+	for _, input := range plan.inputs() {
+		operator, err := buildOperator(tx, input, wrap)
+		if err != nil {
+			return nil, err
+		}
+
+		inputs = append(inputs, operator)
+	}
+
+	operator, err := buildPlanOperator(tx, plan, inputs)
+	if err != nil || wrap == nil {
+		return operator, err
+	}
+
+	return wrap(plan, operator), nil
+}
+
+func buildPlanOperator(tx *Transaction, plan LogicalPlan, inputs []Operator) (Operator, error) {
+	switch p := plan.(type) {
+	case ScanPlan:
+		if p.table == nil {
+			return &valuesOperator{rows: p.rows, columns: p.output, conditions: p.conditions}, nil
+		}
+
+		return buildTableScan(tx, p)
+	case FilterPlan:
+		return &filterOperator{input: inputs[0], columns: p.columns(), conditions: p.conditions}, nil
+	case JoinPlan:
+		return &nestedLoopJoinOperator{left: inputs[0], right: inputs[1], columns: p.columns(), conditions: p.conditions}, nil
+	case AggregatePlan:
+		return &materializeOperator{input: inputs[0], process: func(rows []Row) ([]Row, error) {
+			return groupRows(p.input.columns(), p.groupBy, p.aggregates, rows)
+		}}, nil
+	case WindowPlan:
+		return &materializeOperator{input: inputs[0], process: func(rows []Row) ([]Row, error) {
+			return windowRows(p.input.columns(), p.calls, rows)
+		}}, nil
+	case SortPlan:
+		return &materializeOperator{input: inputs[0], process: func(rows []Row) ([]Row, error) {
+			return sortRows(p.columns(), rows, p.orderBy)
+		}}, nil
+	case ProjectPlan:
+		return &projectOperator{input: inputs[0], columns: p.input.columns(), projections: p.projections}, nil
+	case LimitPlan:
+		return &limitOperator{input: inputs[0], limit: p.limit, offset: p.offset}, nil
+	case UnionPlan:
+		return &unionOperator{inputs: inputs, all: p.all}, nil
+	default:
+		panic("unsupported plan")
+	}
+}
+
+// runOperator opens operator and collects all its rows
+func runOperator(operator Operator, columns []Column) (*DataSet, error) {
+	if err := operator.open(); err != nil {
+		operator.close()
+		return &DataSet{}, err
+	}
+
+	rows, err := drainOperator(operator)
+	if err != nil {
+		operator.close()
+		return &DataSet{}, err
+	}
+
+	if err := operator.close(); err != nil {
+		return &DataSet{}, err
+	}
+
+	return &DataSet{columns: columns, rows: rows}, nil
+}
+
+func drainOperator(operator Operator) ([]Row, error) {
+	rows := []Row{}
+	for {
+		row, ok, err := operator.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return rows, nil
+		}
+
+		rows = append(rows, row)
+	}
+}
+
+func closeOperators(operators ...Operator) error {
+	for _, operator := range operators {
+		if err := operator.close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tableScan decodes visible tuples of table and keeps rows matching conditions
+// until they are pulled. Rows scanned with lock mode are all read and locked
+// when the scan is opened, as waiting for lock releases statement lock
+type tableScan struct {
+	tx         *Transaction
+	table      Table
+	columns    []Column
+	names      []string
+	conditions []Condition // converted into column types
+	lockMode   LockMode
+	rows       []Row
+	ids        []RowID
+	pages      int64 // number of table pages read
+}
+
+func buildTableScan(tx *Transaction, plan ScanPlan) (Operator, error) {
+	scan := tableScan{tx: tx, table: *plan.table, columns: plan.output, lockMode: plan.lockMode}
+	for _, cd := range plan.output {
+		scan.names = append(scan.names, cd.name)
+	}
+
+	// TODO: validate conditions, eg. data types
+	for _, condition := range plan.conditions {
+		condition.target = unqualifiedName(condition.target)
+		if err := ConvertConditionType(scan.table, &condition); err != nil {
+			return nil, err
+		}
+
+		scan.conditions = append(scan.conditions, condition)
+	}
+
+	// usable index is preferred unless statistics show that reading whole
+	// table is cheaper
+	if index, ok := planIndexScan(scan.table, scan.conditions); ok &&
+		(plan.statistics == nil || estimateIndexScan(plan, index).total < estimateSeqScan(plan).total) {
+		return &indexScanOperator{tableScan: scan, scan: index}, nil
+	}
+
+	return &seqScanOperator{tableScan: scan, unordered: plan.unordered}, nil
+}
+
+// lockTable locks table in intention mode of row locks taken by scan
+func (s *tableScan) lockTable() error {
+	logger.Load().Printf("INFO: scanning table %s.%s", s.table.schemaTable.schema, s.table.schemaTable.name)
+
+	mode := intentionShared
+	if s.lockMode == exclusive {
+		mode = intentionExclusive
+	}
+
+	if err := lockManager.lock(s.tx, tableLockTag(s.table), mode); err != nil {
+		return err
+	}
+
+	s.tx.read(getTableDiskPath(s.table.schemaTable))
+	return nil
+}
+
+func (s *tableScan) add(id RowID, tuple []byte) error {
+	row, err := decodeRow(s.table, tuple, s.names)
+	if err != nil {
+		return err
+	}
+
+	if matchesRow(s.columns, row, s.conditions) {
+		s.rows, s.ids = append(s.rows, row), append(s.ids, id)
+	}
+
+	return nil
+}
+
+func (s *tableScan) lockRows() error {
+	for _, id := range s.ids {
+		if err := s.tx.lockRow(id, s.lockMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *tableScan) pagesRead() int64 {
+	return s.pages
+}
+
+func (s *tableScan) pop() Row {
+	row := s.rows[0]
+	s.rows, s.ids = s.rows[1:], s.ids[1:]
+	return row
+}
+
+// seqScanOperator reads table pages one by one, tuples of page are decoded
+// and filtered in batch. Pages of larger tables are read by parallel workers
+// started when the first row is pulled
+type seqScanOperator struct {
+	tableScan
+	batch     *Batch
+	page      int64 // next page to read
+	count     int64 // number of table pages
+	unordered bool  // rows may come in any order
+	parallel  *parallelScan
+}
+
+func (o *seqScanOperator) open() error {
+	if err := o.lockTable(); err != nil {
+		return err
+	}
+
+	batch, err := newBatch(o.table, o.names)
+	if err != nil {
+		return err
+	}
+	o.batch = batch
+
+	count, err := bufferPool.numberOfPages(getTableDiskPath(o.table.schemaTable))
+	if err != nil {
+		return err
+	}
+	o.count = count
+
+	if o.lockMode == 0 {
+		return nil
+	}
+
+	for o.page < o.count {
+		if err := o.readPage(); err != nil {
+			return err
+		}
+	}
+
+	return o.lockRows()
+}
+
+func (o *seqScanOperator) readPage() error {
+	page, err := bufferPool.fetchPage(PageID{file: getTableDiskPath(o.table.schemaTable), number: o.page})
+	if err != nil {
+		return err
+	}
+	o.page++
+	o.pages++
+
+	o.batch.decodePage(o.tx, page)
+	bufferPool.unpinPage(page.id, false)
+
+	o.batch.filter(o.conditions)
+	for _, i := range o.batch.selection {
+		o.rows, o.ids = append(o.rows, o.batch.row(i)), append(o.ids, o.batch.ids[i])
+	}
+
+	return nil
+}
+
+func (o *seqScanOperator) next() (Row, bool, error) {
+	workers := min(int64(getIntSetting("max_parallel_workers")), o.count)
+	if o.parallel == nil && o.page == 0 && workers > 1 {
+		o.parallel = startParallelScan(&o.tableScan, o.count, int(workers), !o.unordered)
+	}
+
+	for len(o.rows) == 0 && o.parallel != nil {
+		result, ok := o.parallel.receive()
+		if !ok {
+			return Row{}, false, nil
+		}
+
+		if result.err != nil {
+			return Row{}, false, result.err
+		}
+		o.rows, o.ids = result.rows, result.ids
+	}
+
+	for len(o.rows) == 0 {
+		if o.page >= o.count {
+			return Row{}, false, nil
+		}
+
+		if err := o.readPage(); err != nil {
+			return Row{}, false, err
+		}
+	}
+
+	return o.pop(), true, nil
+}
+
+func (o *seqScanOperator) close() error {
+	if o.parallel != nil {
+		o.parallel.stop()
+		o.pages += o.parallel.pages.Load()
+		o.parallel = nil
+	}
+
+	return nil
+}
+
+// indexScanOperator reads rows listed by index entries within scan range
+type indexScanOperator struct {
+	tableScan
+	scan    IndexScan
+	entries []RowID
+}
+
+func (o *indexScanOperator) open() error {
+	if err := o.lockTable(); err != nil {
+		return err
+	}
+
+	// row is listed by stale entries of its previous locations as well
+	seen := map[RowID]bool{}
+	err := o.scan.index.access(o.table).scan(o.scan.low, o.scan.high, func(key []byte) error {
+		if id := keyRow(o.table, key); !seen[id] {
+			seen[id] = true
+			o.entries = append(o.entries, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.lockMode == 0 {
+		return nil
+	}
+
+	for len(o.entries) > 0 {
+		if err := o.readEntry(); err != nil {
+			return err
+		}
+	}
+
+	return o.lockRows()
+}
+
+func (o *indexScanOperator) readEntry() error {
+	id := o.entries[0]
+	o.entries = o.entries[1:]
+	o.pages++
+
+	return readTuple(id, func(tuple []byte) error {
+		if tuple == nil || !o.tx.sees(tuple) {
+			return nil
+		}
+
+		return o.add(id, tuple)
+	})
+}
+
+func (o *indexScanOperator) next() (Row, bool, error) {
+	for len(o.rows) == 0 {
+		if len(o.entries) == 0 {
+			return Row{}, false, nil
+		}
+
+		if err := o.readEntry(); err != nil {
+			return Row{}, false, err
+		}
+	}
+
+	return o.pop(), true, nil
+}
+
+func (o *indexScanOperator) close() error {
+	return nil
+}
+
+// valuesOperator returns rows of common table expression or select without source
+type valuesOperator struct {
+	rows       []Row
+	columns    []Column
+	conditions []Condition
+	position   int
+}
+
+func (o *valuesOperator) open() error {
+	o.position = 0
+	return nil
+}
+
+func (o *valuesOperator) next() (Row, bool, error) {
+	for o.position < len(o.rows) {
+		row := o.rows[o.position]
+		o.position++
+
+		ok, err := matchesConditions(o.columns, row, o.conditions)
+		if err != nil {
+			return Row{}, false, err
+		}
+
+		if ok {
+			return row, true, nil
+		}
+	}
+
+	return Row{}, false, nil
+}
+
+func (o *valuesOperator) close() error {
+	return nil
+}
+
+type filterOperator struct {
+	input      Operator
+	columns    []Column
+	conditions []Condition
+}
+
+func (o *filterOperator) open() error {
+	return o.input.open()
+}
+
+func (o *filterOperator) next() (Row, bool, error) {
+	for {
+		row, ok, err := o.input.next()
+		if err != nil || !ok {
+			return Row{}, false, err
+		}
+
+		ok, err = matchesConditions(o.columns, row, o.conditions)
+		if err != nil {
+			return Row{}, false, err
+		}
+
+		if ok {
+			return row, true, nil
+		}
+	}
+}
+
+func (o *filterOperator) close() error {
+	return o.input.close()
+}
+
+// nestedLoopJoinOperator combines each row of left input with all rows of
+// right input, which are read when the join is opened
+type nestedLoopJoinOperator struct {
+	left       Operator
+	right      Operator
+	columns    []Column
+	conditions []Condition
+	inner      []Row
+	outer      Row
+	position   int
+}
+
+func (o *nestedLoopJoinOperator) open() error {
+	if err := o.left.open(); err != nil {
+		return err
+	}
+
+	if err := o.right.open(); err != nil {
+		return err
+	}
+
+	rows, err := drainOperator(o.right)
+	if err != nil {
+		return err
+	}
+	o.inner, o.position = rows, len(rows)
+
+	return nil
+}
+
+func (o *nestedLoopJoinOperator) next() (Row, bool, error) {
+	for {
+		if o.position >= len(o.inner) {
+			outer, ok, err := o.left.next()
+			if err != nil || !ok {
+				return Row{}, false, err
+			}
+
+			o.outer, o.position = outer, 0
+			continue
+		}
+
+		row := Row{cells: slices.Concat(o.outer.cells, o.inner[o.position].cells)}
+		o.position++
+
+		ok, err := matchesConditions(o.columns, row, o.conditions)
+		if err != nil {
+			return Row{}, false, err
+		}
+
+		if ok {
+			return row, true, nil
+		}
+	}
+}
+
+func (o *nestedLoopJoinOperator) close() error {
+	return closeOperators(o.left, o.right)
+}
+
+// materializeOperator reads all rows of input when opened and returns rows
+// computed from them, eg. groups of aggregate or sorted rows
+type materializeOperator struct {
+	input   Operator
+	process func(rows []Row) ([]Row, error)
+	rows    []Row
+}
+
+func (o *materializeOperator) open() error {
+	if err := o.input.open(); err != nil {
+		return err
+	}
+
+	rows, err := drainOperator(o.input)
+	if err != nil {
+		return err
+	}
+
+	o.rows, err = o.process(rows)
+	return err
+}
+
+func (o *materializeOperator) next() (Row, bool, error) {
+	if len(o.rows) == 0 {
+		return Row{}, false, nil
+	}
+
+	row := o.rows[0]
+	o.rows = o.rows[1:]
+	return row, true, nil
+}
+
+func (o *materializeOperator) close() error {
+	return o.input.close()
+}
+
+type projectOperator struct {
+	input       Operator
+	columns     []Column
+	projections []Expression
+}
+
+func (o *projectOperator) open() error {
+	return o.input.open()
+}
+
+func (o *projectOperator) next() (Row, bool, error) {
+	row, ok, err := o.input.next()
+	if err != nil || !ok {
+		return Row{}, false, err
+	}
+
+	row, err = projectRow(o.columns, o.projections, row)
+	if err != nil {
+		return Row{}, false, err
+	}
+
+	return row, true, nil
+}
+
+func (o *projectOperator) close() error {
+	return o.input.close()
+}
+
+// limitOperator skips offset rows and stops pulling input after limit rows
+type limitOperator struct {
+	input    Operator
+	limit    *int64
+	offset   int64
+	returned int64
+}
+
+func (o *limitOperator) open() error {
+	o.returned = 0
+	return o.input.open()
+}
+
+func (o *limitOperator) next() (Row, bool, error) {
+	for ; o.offset > 0; o.offset-- {
+		if _, ok, err := o.input.next(); err != nil || !ok {
+			return Row{}, false, err
+		}
+	}
+
+	if o.limit != nil && o.returned >= *o.limit {
+		return Row{}, false, nil
+	}
+
+	row, ok, err := o.input.next()
+	if err != nil || !ok {
+		return Row{}, false, err
+	}
+
+	o.returned++
+	return row, true, nil
+}
+
+func (o *limitOperator) close() error {
+	return o.input.close()
+}
+
+// unionOperator returns rows of its inputs one after another, duplicates are
+// removed unless all rows are requested
+type unionOperator struct {
+	inputs  []Operator
+	all     bool
+	current int
+	seen    map[string]bool
+}
+
+func (o *unionOperator) open() error {
+	o.current, o.seen = 0, map[string]bool{}
+	for _, input := range o.inputs {
+		if err := input.open(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *unionOperator) next() (Row, bool, error) {
+	for o.current < len(o.inputs) {
+		row, ok, err := o.inputs[o.current].next()
+		if err != nil {
+			return Row{}, false, err
+		}
+
+		if !ok {
+			o.current++
+			continue
+		}
+
+		if !o.all {
+			key := row.key()
+			if o.seen[key] {
+				continue
+			}
+			o.seen[key] = true
+		}
+
+		return row, true, nil
+	}
+
+	return Row{}, false, nil
+}
+
+func (o *unionOperator) close() error {
+	return closeOperators(o.inputs...)
+}
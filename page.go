@@ -0,0 +1,187 @@
+package auralis
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+const (
+	pageSize = 8192
+
+	// header layout: lsn (8B), slots count (2B), free space start (2B), free space end (2B),
+	// 2B reserved
+	pageHeaderSize = 16
+	// slot layout: tuple offset (2B), tuple length (2B)
+	slotSize = 4
+)
+
+// PageID identifies page by its position within file
+type PageID struct {
+	file   string
+	number int64
+}
+
+// Page is a slotted page, slot directory grows from the header towards the end
+// of the page while tuples are stored from the end of the page backwards.
+// Free space is the gap between the slot directory and the first tuple
+type Page struct {
+	id   PageID
+	data [pageSize]byte
+}
+
+// init resets page to empty state, page of zeros is not initialized
+func (p *Page) init() {
+	clear(p.data[:])
+	p.setSlotCount(0)
+	p.setFreeStart(pageHeaderSize)
+	p.setFreeEnd(pageSize)
+}
+
+func (p *Page) initialized() bool {
+	return p.freeEnd() != 0
+}
+
+func (p *Page) lsn() uint64 {
+	return binary.BigEndian.Uint64(p.data[0:8])
+}
+
+func (p *Page) setLSN(lsn uint64) {
+	binary.BigEndian.PutUint64(p.data[0:8], lsn)
+}
+
+func (p *Page) slotCount() int {
+	return int(binary.BigEndian.Uint16(p.data[8:10]))
+}
+
+func (p *Page) setSlotCount(count int) {
+	binary.BigEndian.PutUint16(p.data[8:10], uint16(count))
+}
+
+func (p *Page) freeStart() int {
+	return int(binary.BigEndian.Uint16(p.data[10:12]))
+}
+
+func (p *Page) setFreeStart(offset int) {
+	binary.BigEndian.PutUint16(p.data[10:12], uint16(offset))
+}
+
+func (p *Page) freeEnd() int {
+	return int(binary.BigEndian.Uint16(p.data[12:14]))
+}
+
+func (p *Page) setFreeEnd(offset int) {
+	binary.BigEndian.PutUint16(p.data[12:14], uint16(offset))
+}
+
+// freeSpace returns number of bytes available for a new tuple with its slot
+func (p *Page) freeSpace() int {
+	return max(0, p.freeEnd()-p.freeStart()-slotSize)
+}
+
+func (p *Page) slot(i int) (int, int) {
+	offset := pageHeaderSize + i*slotSize
+	return int(binary.BigEndian.Uint16(p.data[offset : offset+2])),
+		int(binary.BigEndian.Uint16(p.data[offset+2 : offset+4]))
+}
+
+func (p *Page) setSlot(i int, tupleOffset int, length int) {
+	offset := pageHeaderSize + i*slotSize
+	binary.BigEndian.PutUint16(p.data[offset:offset+2], uint16(tupleOffset))
+	binary.BigEndian.PutUint16(p.data[offset+2:offset+4], uint16(length))
+}
+
+// insertTuple copies tuple into page and returns its slot, false is returned
+// when there is not enough free space
+func (p *Page) insertTuple(tuple []byte) (int, bool) {
+	if len(tuple) > p.freeSpace() {
+		return 0, false
+	}
+
+	slot := p.slotCount()
+	offset := p.freeEnd() - len(tuple)
+	copy(p.data[offset:], tuple)
+
+	p.setSlot(slot, offset, len(tuple))
+	p.setSlotCount(slot + 1)
+	p.setFreeStart(p.freeStart() + slotSize)
+	p.setFreeEnd(offset)
+
+	return slot, true
+}
+
+// tuple returns tuple stored in slot, nil is returned for removed tuples.
+// Returned slice references page memory
+func (p *Page) tuple(slot int) []byte {
+	if slot < 0 || slot >= p.slotCount() {
+		return nil
+	}
+
+	offset, length := p.slot(slot)
+	if length == 0 {
+		return nil
+	}
+
+	return p.data[offset : offset+length]
+}
+
+// deleteTuple removes tuple from slot, space of the tuple is not reclaimed
+func (p *Page) deleteTuple(slot int) {
+	offset, _ := p.slot(slot)
+	p.setSlot(slot, offset, 0)
+}
+
+// restoreTuple puts back removed tuple, tuple space is still reserved by its slot
+func (p *Page) restoreTuple(slot int, tuple []byte) {
+	offset, _ := p.slot(slot)
+	copy(p.data[offset:], tuple)
+	p.setSlot(slot, offset, len(tuple))
+}
+
+// vacuum removes tuples from slots and compacts page so its free space is
+// contiguous again, empty slots at the end of slot directory are released
+// while the others keep their numbers
+func (p *Page) vacuum(slots []int) {
+	for _, slot := range slots {
+		p.deleteTuple(slot)
+	}
+
+	count := p.slotCount()
+	for count > 0 && p.tuple(count-1) == nil {
+		count--
+	}
+
+	tuples := make([][]byte, count)
+	for slot := range count {
+		tuples[slot] = slices.Clone(p.tuple(slot))
+	}
+
+	end := pageSize
+	for slot, tuple := range tuples {
+		if tuple == nil {
+			p.setSlot(slot, 0, 0)
+			continue
+		}
+
+		end -= len(tuple)
+		copy(p.data[end:], tuple)
+		p.setSlot(slot, end, len(tuple))
+	}
+
+	p.setSlotCount(count)
+	p.setFreeStart(pageHeaderSize + count*slotSize)
+	p.setFreeEnd(end)
+	clear(p.data[p.freeStart():p.freeEnd()])
+}
+
+// fragmented reports whether removed tuples still occupy space of page
+func (p *Page) fragmented() bool {
+	used := 0
+	for slot := range p.slotCount() {
+		used += len(p.tuple(slot))
+	}
+
+	return used < pageSize-p.freeEnd() || (p.slotCount() > 0 && p.tuple(p.slotCount()-1) == nil)
+}
+
+// maxTupleSize is the size of the largest tuple which fits into empty page
+const maxTupleSize = pageSize - pageHeaderSize - slotSize
@@ -0,0 +1,92 @@
+package auralis
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPageInsertTuple(t *testing.T) {
+	page := Page{}
+	page.init()
+
+	tuples := [][]byte{}
+	for i := 0; ; i++ {
+		tuple := bytes.Repeat([]byte{byte(i)}, 100)
+		slot, ok := page.insertTuple(tuple)
+		if !ok {
+			break
+		}
+
+		if slot != i {
+			t.Fatalf("\nexp %+v\ngot %+v", i, slot)
+		}
+		tuples = append(tuples, tuple)
+	}
+
+	// each tuple takes its length and slot
+	expected := (pageSize - pageHeaderSize) / (100 + slotSize)
+	if len(tuples) != expected {
+		t.Errorf("\nexp %+v\ngot %+v", expected, len(tuples))
+	}
+
+	for i, tuple := range tuples {
+		if !bytes.Equal(page.tuple(i), tuple) {
+			t.Errorf("slot %d\nexp %+v\ngot %+v", i, tuple, page.tuple(i))
+		}
+	}
+
+	if page.tuple(len(tuples)) != nil {
+		t.Errorf("expected nil tuple for missing slot")
+	}
+}
+
+func TestPageInitialization(t *testing.T) {
+	page := Page{}
+	if page.initialized() {
+		t.Fatalf("zeroed page reported as initialized")
+	}
+
+	page.init()
+	page.setLSN(42)
+	if !page.initialized() || page.lsn() != 42 || page.freeSpace() != maxTupleSize {
+		t.Errorf("unexpected page header, lsn %d free space %d", page.lsn(), page.freeSpace())
+	}
+
+	if _, ok := page.insertTuple(make([]byte, maxTupleSize+1)); ok {
+		t.Errorf("tuple larger than page inserted")
+	}
+
+	if _, ok := page.insertTuple(make([]byte, maxTupleSize)); !ok || page.freeSpace() != 0 {
+		t.Errorf("tuple of max size not inserted, free space %d", page.freeSpace())
+	}
+}
+
+func TestPageVacuum(t *testing.T) {
+	page := Page{}
+	page.init()
+	for i := range 4 {
+		page.insertTuple(bytes.Repeat([]byte{byte(i)}, 100))
+	}
+	free := page.freeSpace()
+
+	// removed slots in the middle keep their numbers, the last ones are released
+	page.vacuum([]int{1, 3})
+
+	if page.slotCount() != 3 {
+		t.Fatalf("\nexp %+v\ngot %+v", 3, page.slotCount())
+	}
+
+	for slot, expected := range [][]byte{bytes.Repeat([]byte{0}, 100), nil, bytes.Repeat([]byte{2}, 100)} {
+		if !bytes.Equal(page.tuple(slot), expected) {
+			t.Errorf("slot %d\nexp %+v\ngot %+v", slot, expected, page.tuple(slot))
+		}
+	}
+
+	if expected := free + 2*100 + slotSize; page.freeSpace() != expected || page.fragmented() {
+		t.Errorf("\nexp %+v\ngot %+v", expected, page.freeSpace())
+	}
+
+	if slot, _ := page.insertTuple([]byte{9}); slot != 3 {
+		t.Errorf("\nexp %+v\ngot %+v", 3, slot)
+	}
+}
@@ -0,0 +1,114 @@
+package auralis
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// pageRows are rows of page which satisfied conditions of scan
+type pageRows struct {
+	rows []Row
+	ids  []RowID
+	err  error
+}
+
+// parallelScan splits table file into ranges of pages scanned by workers,
+// each worker decodes and filters its pages in batches. Ordered scan merges
+// rows of ranges one after another so rows come in the order of pages,
+// otherwise they are merged as workers send them
+type parallelScan struct {
+	results []chan pageRows // channel of each range, single shared one when unordered
+	current int             // channel being received
+	done    chan struct{}
+	workers sync.WaitGroup
+	pages   atomic.Int64 // number of pages read by workers
+}
+
+func startParallelScan(scan *tableScan, count int64, workers int, ordered bool) *parallelScan {
+	p := &parallelScan{done: make(chan struct{})}
+	shared := make(chan pageRows, workers)
+	if !ordered {
+		p.results = []chan pageRows{shared}
+	}
+
+	path := getTableDiskPath(scan.table.schemaTable)
+	for w := range workers {
+		results := shared
+		if ordered {
+			results = make(chan pageRows, 1)
+			p.results = append(p.results, results)
+		}
+
+		first, last := int64(w)*count/int64(workers), int64(w+1)*count/int64(workers)
+		p.workers.Add(1)
+		go func() {
+			defer p.workers.Done()
+			if ordered {
+				defer close(results)
+			}
+
+			p.scanRange(scan, path, first, last, results)
+		}()
+	}
+
+	if !ordered {
+		go func() {
+			p.workers.Wait()
+			close(shared)
+		}()
+	}
+
+	return p
+}
+
+// scanRange sends rows of each page of range, sending stops when scan is closed
+func (p *parallelScan) scanRange(scan *tableScan, path string, first int64, last int64, results chan<- pageRows) {
+	batch, err := newBatch(scan.table, scan.names)
+	for number := first; number < last && err == nil; number++ {
+		var page *Page
+		if page, err = bufferPool.fetchPage(PageID{file: path, number: number}); err != nil {
+			break
+		}
+		p.pages.Add(1)
+
+		batch.decodePage(scan.tx, page)
+		bufferPool.unpinPage(page.id, false)
+
+		batch.filter(scan.conditions)
+		result := pageRows{}
+		for _, i := range batch.selection {
+			result.rows, result.ids = append(result.rows, batch.row(i)), append(result.ids, batch.ids[i])
+		}
+
+		select {
+		case results <- result:
+		case <-p.done:
+			return
+		}
+	}
+
+	if err != nil {
+		select {
+		case results <- pageRows{err: err}:
+		case <-p.done:
+		}
+	}
+}
+
+// receive returns rows of the next page, false when all pages were received
+func (p *parallelScan) receive() (pageRows, bool) {
+	for p.current < len(p.results) {
+		if result, ok := <-p.results[p.current]; ok {
+			return result, true
+		}
+		p.current++
+	}
+
+	return pageRows{}, false
+}
+
+// stop cancels workers and waits until they finish
+func (p *parallelScan) stop() {
+	close(p.done)
+	p.workers.Wait()
+}
@@ -0,0 +1,84 @@
+package auralis
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// setWorkers changes number of parallel workers for the test
+func setWorkers(t *testing.T, workers string) {
+	t.Helper()
+
+	previous, err := getSetting("max_parallel_workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setSetting("max_parallel_workers", workers); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { setSetting("max_parallel_workers", previous) })
+}
+
+func TestParallelScan(t *testing.T) {
+	testCases := map[string]struct {
+		query string
+		rows  int
+	}{
+		"rows in order of pages": {
+			query: "SELECT id, name FROM items",
+			rows:  1000,
+		},
+		"filtered rows": {
+			query: "SELECT name FROM items WHERE name LIKE 'item 9%' AND id != 900",
+			rows:  110,
+		},
+		"sorted rows": {
+			query: "SELECT id FROM items ORDER BY name DESC",
+			rows:  1000,
+		},
+		"limit stops workers": {
+			query: "SELECT id FROM items LIMIT 3 OFFSET 500",
+			rows:  3,
+		},
+		"join": {
+			query: "SELECT a.id FROM items a JOIN items b ON a.id = b.id WHERE b.name = 'item 7'",
+			rows:  1,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE items (id integer, name varchar)")
+			insertGenerated(t, "items", "name", 1000, func(id int) string { return fmt.Sprintf("'item %d'", id) })
+
+			setWorkers(t, "0")
+			expected := queryCells(t, tC.query)
+
+			setWorkers(t, "3")
+			rows := queryCells(t, tC.query)
+
+			if len(rows) != tC.rows || !reflect.DeepEqual(rows, expected) {
+				t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+			}
+		})
+	}
+}
+
+func TestParallelScanPages(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE items (id integer, name varchar)")
+	insertGenerated(t, "items", "name", 1000, func(id int) string { return fmt.Sprintf("'item %d'", id) })
+	setWorkers(t, "2")
+
+	pages, err := bufferPool.numberOfPages(getTableDiskPath(SchemaTable[string, string]{defaultScheme, "items"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all pages are read once by workers
+	plan := queryCells(t, "EXPLAIN ANALYZE SELECT id FROM items WHERE id > 10")
+	expected := fmt.Sprintf("        Pages Read: %d", pages)
+	if line := plan[3][0]; pages < 2 || line != expected {
+		t.Errorf("\nexp %+v\ngot %+v", expected, line)
+	}
+}
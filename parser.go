@@ -1,8 +1,10 @@
-package main
+package auralis
 
 import (
 	"errors"
-	"log"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -14,20 +16,162 @@ type SchemaTable[T, U string] struct {
 }
 
 type SelectQuery struct {
+	ctes        []CommonTableExpression
 	source      SchemaTable[string, string]
-	dataColumns []string
+	alias       string
+	joins       []JoinClause
+	dataColumns []string     // result column names
+	projections []Expression // nil when all result columns are plain column names
 	conditions  []Condition
+	groupBy     []Expression
+	orderBy     []OrderByItem
+	limit       *int64 // nil when all rows are returned
+	offset      int64
+	limitParam  int          // n of $n placeholder of limit, zero when limit is not a parameter
+	offsetParam int          // n of $n placeholder of offset
+	union       *SelectQuery // following select of union
+	unionAll    bool
+	lockMode    LockMode // row lock of for update or for share
+}
+
+type JoinClause struct {
+	source     SchemaTable[string, string]
+	alias      string
+	conditions []Condition
+}
+
+type CommonTableExpression struct {
+	name      string
+	columns   []string // optional column names overriding query ones
+	query     SelectQuery
+	recursive bool
 }
 
 type InsertQuery struct {
 	source      SchemaTable[string, string]
-	dataColumns []string // column names
-	values      [][]any  // column values
+	dataColumns []string     // column names
+	values      [][]any      // column values
+	query       *SelectQuery // query providing rows instead of values
+	onConflict  *OnConflictClause
+	returning   ReturningClause
+}
+
+// OnConflictClause is action taken for inserted row which conflicts with
+// existing row in unique index of conflict target columns
+type OnConflictClause struct {
+	columns     []string // conflict target, any unique index when empty
+	update      bool     // do update, otherwise do nothing
+	assignments []Assignment
+}
+
+// ReturningClause lists expressions computed from rows changed by data
+// modifying statement, names are names of result columns
+type ReturningClause struct {
+	names       []string
+	projections []Expression
 }
 
 type CreateTableQuery struct {
+	source      SchemaTable[string, string]
+	columns     []ColumnDefinition // in order of definitions, which is order of column positions
+	constraints []Constraint       // column and table constraints
+	defaults    []ColumnDefault    // defaults and identities of columns
+}
+
+type ColumnDefinition struct {
+	name       string
+	attributes []string // data type followed by words of column constraints
+}
+
+type CreateSequenceQuery struct {
+	name     SchemaTable[string, string]
+	dataType DataType
+	options  map[string]int64 // start, increment, minvalue and maxvalue
+}
+
+type CreateIndexQuery struct {
+	name    string
 	source  SchemaTable[string, string]
-	columns map[string][]string
+	columns []string
+	unique  bool
+	method  string // btree when empty
+}
+
+type UpdateQuery struct {
+	source      SchemaTable[string, string]
+	assignments []Assignment
+	conditions  []Condition
+	returning   ReturningClause
+}
+
+type Assignment struct {
+	column string
+	value  Expression
+}
+
+type DeleteQuery struct {
+	source     SchemaTable[string, string]
+	conditions []Condition
+	returning  ReturningClause
+}
+
+type TransactionQuery struct {
+	command        string // begin, commit, rollback, savepoint, rollback to, release or set transaction
+	savepoint      string
+	isolationLevel IsolationLevel
+}
+
+type LockTableQuery struct {
+	sources []SchemaTable[string, string]
+	mode    LockMode
+}
+
+// VacuumQuery reclaims space of dead tuples, all tables are vacuumed when
+// sources are empty
+type VacuumQuery struct {
+	sources []SchemaTable[string, string]
+	full    bool
+}
+
+// AnalyzeQuery gathers statistics used by planner, all tables are analyzed
+// when sources are empty
+type AnalyzeQuery struct {
+	sources []SchemaTable[string, string]
+}
+
+// ExplainQuery shows plan of select query, analyze executes the query and
+// reports what the plan operators actually did
+type ExplainQuery struct {
+	query   SelectQuery
+	analyze bool
+	format  string // text or json
+}
+
+type SetQuery struct {
+	name  string
+	value string
+}
+
+type ShowQuery struct {
+	name string
+}
+
+// PrepareQuery stores statement whose $n placeholders are bound to values of
+// execute, types are optional declared types of parameters
+type PrepareQuery struct {
+	name      string
+	types     []DataType
+	statement []TokenLiteral
+}
+
+type ExecutePreparedQuery struct {
+	name string
+	args []TokenLiteral
+}
+
+type DeallocateQuery struct {
+	name string
+	all  bool
 }
 
 func ParseTokens(tokens []TokenLiteral) (any, error) {
@@ -38,12 +182,49 @@ func ParseTokens(tokens []TokenLiteral) (any, error) {
 
 	// TODO: detect which query type to analyze
 	switch tokens[0].value {
-	case "select":
+	case "select", "with":
 		return parseSelect(&tokens)
 	case "insert":
 		return parseInsert(&tokens)
 	case "create":
+		if len(tokens) > 1 && tokens[1].kind == symbol &&
+			(strings.EqualFold(tokens[1].value, "index") || strings.EqualFold(tokens[1].value, "unique")) {
+			return parseCreateIndex(&tokens)
+		}
+
+		if len(tokens) > 1 && tokens[1].kind == symbol && strings.EqualFold(tokens[1].value, "sequence") {
+			return parseCreateSequence(&tokens)
+		}
+
 		return parseCreate(&tokens)
+	case "update":
+		return parseUpdate(&tokens)
+	case "delete":
+		return parseDelete(&tokens)
+	case "begin", "commit", "end", "rollback", "savepoint", "release":
+		return parseTransaction(&tokens)
+	case "lock":
+		return parseLockTable(&tokens)
+	case "vacuum":
+		return parseVacuum(&tokens)
+	case "analyze":
+		return parseAnalyze(&tokens)
+	case "explain":
+		return parseExplain(&tokens)
+	case "set":
+		if len(tokens) > 1 && tokens[1].kind == symbol && strings.ToLower(tokens[1].value) == "transaction" {
+			return parseTransaction(&tokens)
+		}
+
+		return parseSet(&tokens)
+	case "show":
+		return parseShow(&tokens)
+	case "prepare":
+		return parsePrepare(&tokens)
+	case "execute":
+		return parseExecutePrepared(&tokens)
+	case "deallocate":
+		return parseDeallocate(&tokens)
 	}
 
 	return Command{}, errors.New("unsupported keyword")
@@ -68,6 +249,17 @@ func parseSelect(tokens *[]TokenLiteral) (SelectQuery, error) {
 	q := SelectQuery{}
 	i := 0
 
+	// with
+	if i < len(v) && v[i].kind == keyword && v[i].value == "with" {
+		ctes, n, err := parseCommonTableExpressions(v, i+1)
+		if err != nil {
+			return SelectQuery{}, err
+		}
+
+		q.ctes = ctes
+		i = n
+	}
+
 	// select
 	if i >= len(v) || v[i].kind != keyword || v[i].value != "select" {
 		return SelectQuery{}, errors.New("missing select keyword")
@@ -75,22 +267,59 @@ func parseSelect(tokens *[]TokenLiteral) (SelectQuery, error) {
 	i++
 
 	// * or csv columns
+	if i >= len(v) || (v[i].kind != symbol && v[i].kind != openingroundbracket && !isCaseKeyword(v[i])) {
+		return SelectQuery{}, errors.New("missing columns")
+	}
+
+	plain := true
+	projections := []Expression{}
 	for ; i < len(v); i++ {
-		if v[i].kind == comma {
-			continue
+		var expr Expression
+		if v[i].kind == symbol && v[i].value == "*" {
+			expr = ColumnReference{name: "*"}
+			i++
+		} else {
+			e, n, err := parseExpression(v, i)
+			if err != nil {
+				return SelectQuery{}, err
+			}
+
+			expr, i = e, n
 		}
 
-		if v[i].kind != symbol {
+		name := expr.String()
+		if ref, ok := expr.(ColumnReference); ok {
+			name = ref.name
+		} else {
+			plain = false
+		}
+
+		if i < len(v) && v[i].kind == keyword && v[i].value == "as" {
+			if i+1 >= len(v) || v[i+1].kind != symbol {
+				return SelectQuery{}, errors.New("missing column alias")
+			}
+
+			name = v[i+1].value
+			plain = false
+			i += 2
+		}
+
+		q.dataColumns = append(q.dataColumns, name)
+		projections = append(projections, expr)
+
+		if i >= len(v) || v[i].kind != comma {
 			break
 		}
+	}
 
-		q.dataColumns = append(q.dataColumns, v[i].value)
+	if !plain {
+		q.projections = projections
 	}
 
-	if len(q.dataColumns) == 0 {
-		return SelectQuery{}, errors.New("missing columns")
+	// expressions without columns can be selected without source, eg. select nextval('ids')
+	if i >= len(v) && !plain {
+		return q, nil
 	}
-	// i is incremented by loop
 
 	// from
 	if i >= len(v) || v[i].kind != keyword || v[i].value != "from" {
@@ -103,200 +332,2155 @@ func parseSelect(tokens *[]TokenLiteral) (SelectQuery, error) {
 		return SelectQuery{}, errors.New("missing source table")
 	}
 
-	s := strings.Split(v[i].value, ".")
-	if len(s) == 1 {
-		q.source = SchemaTable[string, string]{defaultScheme, s[0]}
-	} else {
-		q.source = SchemaTable[string, string]{s[0], s[1]}
-	}
+	q.source = parseSchemaTable(v[i].value)
 	i++
 
-	if i >= len(v) {
-		return q, nil
+	q.alias, i = parseAlias(v, i)
+
+	// joins
+	for i < len(v) && v[i].kind == keyword && (v[i].value == "join" || v[i].value == "inner") {
+		join, n, err := parseJoin(v, i)
+		if err != nil {
+			return SelectQuery{}, err
+		}
+
+		q.joins = append(q.joins, join)
+		i = n
 	}
 
 	// where clause
-	if v[i].kind == keyword && v[i].value == "where" {
-		i++
+	if i < len(v) && v[i].kind == keyword && v[i].value == "where" {
+		conditions, n, err := parseConditions(v, i+1)
+		if err != nil {
+			return SelectQuery{}, err
+		}
 
-		condition := Condition{}
-		for ; i < len(v); i++ {
-			if v[i].kind == symbol {
-				if condition.sign == "" {
-					condition.target = v[i].value
-				} else {
-					condition.value = v[i].value
-					q.conditions = append(q.conditions, condition)
-				}
-				continue
+		q.conditions = conditions
+		i = n
+	}
+
+	// group by
+	if i < len(v) && v[i].kind == keyword && v[i].value == "group" {
+		if i+1 >= len(v) || v[i+1].kind != keyword || v[i+1].value != "by" {
+			return SelectQuery{}, errors.New("missing by keyword")
+		}
+
+		groupBy, n, err := parseExpressionList(v, i+2)
+		if err != nil {
+			return SelectQuery{}, err
+		}
+
+		q.groupBy = groupBy
+		i = n
+	}
+
+	// order by
+	if i < len(v) && v[i].kind == keyword && v[i].value == "order" {
+		orderBy, n, err := parseOrderBy(v, i)
+		if err != nil {
+			return SelectQuery{}, err
+		}
+
+		q.orderBy = orderBy
+		i = n
+	}
+
+	// limit and offset
+	if isWord(v, i, "limit") {
+		if n, ok := parameterAt(v, i+1); ok {
+			q.limitParam = n
+		} else if !isWord(v, i+1, "all") {
+			limit, err := parseRowCount(v, i+1, "limit")
+			if err != nil {
+				return SelectQuery{}, err
 			}
 
-			if v[i].kind == equal ||
-				v[i].kind == notequal ||
-				v[i].kind == greater ||
-				v[i].kind == greaterorequal ||
-				v[i].kind == less ||
-				v[i].kind == lessorequal {
-				condition.sign = v[i].value
-				continue
+			q.limit = &limit
+		}
+		i += 2
+	}
+
+	if isWord(v, i, "offset") {
+		if n, ok := parameterAt(v, i+1); ok {
+			q.offsetParam = n
+		} else {
+			offset, err := parseRowCount(v, i+1, "offset")
+			if err != nil {
+				return SelectQuery{}, err
 			}
+
+			q.offset = offset
 		}
+		i += 2
+	}
+
+	// for update or for share
+	if i < len(v) && v[i].kind == keyword && v[i].value == "for" {
+		switch {
+		case i+1 < len(v) && v[i+1].kind == keyword && v[i+1].value == "update":
+			q.lockMode = exclusive
+		case i+1 < len(v) && v[i+1].kind == symbol && strings.EqualFold(v[i+1].value, "share"):
+			q.lockMode = shared
+		default:
+			return SelectQuery{}, errors.New("missing for update or for share lock strength")
+		}
+		i += 2
+	}
+
+	// union [all] with following select
+	if i < len(v) && v[i].kind == keyword && v[i].value == "union" {
+		i++
+		if i < len(v) && v[i].kind == keyword && v[i].value == "all" {
+			q.unionAll = true
+			i++
+		}
+
+		rest := v[i:]
+		union, err := parseSelect(&rest)
+		if err != nil {
+			return SelectQuery{}, err
+		}
+
+		q.union = &union
+		return q, nil
+	}
+
+	if i < len(v) {
+		return SelectQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
 	}
-	i++
 
 	return q, nil
 }
 
-func parseInsert(tokens *[]TokenLiteral) (InsertQuery, error) {
-	v := *tokens
-	q := InsertQuery{}
-	i := 0
+// parseRowCount reads non-negative number of rows of limit or offset clause
+func parseRowCount(v []TokenLiteral, i int, clause string) (int64, error) {
+	if i >= len(v) || v[i].kind != symbol {
+		return 0, fmt.Errorf("missing %s value", clause)
+	}
 
-	// insert
-	if i >= len(v) || v[i].kind != keyword || v[i].value != "insert" {
-		return InsertQuery{}, errors.New("missing insert keyword")
+	count, err := strconv.ParseInt(v[i].value, 10, 64)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid %s value %s", clause, v[i].value)
 	}
-	i++
 
-	// into
-	if i >= len(v) || v[i].kind != keyword || v[i].value != "into" {
-		return InsertQuery{}, errors.New("missing into keyword")
+	return count, nil
+}
+
+// parseExpression reads single expression starting at i
+func parseExpression(v []TokenLiteral, i int) (Expression, int, error) {
+	if i >= len(v) {
+		return nil, i, errors.New("missing expression")
+	}
+
+	switch v[i].kind {
+	case openingroundbracket:
+		expr, n, err := parseExpression(v, i+1)
+		if err != nil {
+			return nil, n, err
+		}
+
+		if n >= len(v) || v[n].kind != closingroundbracket {
+			return nil, n, errors.New("missing closing bracket")
+		}
+
+		return expr, n + 1, nil
+	case symbol:
+		if i+1 < len(v) && v[i+1].kind == openingroundbracket {
+			return parseFunctionCall(v, i)
+		}
+
+		return parseOperand(v[i].value), i + 1, nil
+	case keyword:
+		if v[i].value == "case" {
+			return parseCaseExpression(v, i)
+		}
+
+		return nil, i, fmt.Errorf("unexpected token %s", v[i].value)
+	default:
+		return nil, i, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+}
+
+// parseExpressionList reads comma separated expressions
+func parseExpressionList(v []TokenLiteral, i int) ([]Expression, int, error) {
+	exprs := []Expression{}
+	for {
+		expr, n, err := parseExpression(v, i)
+		if err != nil {
+			return nil, n, err
+		}
+
+		exprs = append(exprs, expr)
+		i = n
+
+		if i >= len(v) || v[i].kind != comma {
+			return exprs, i, nil
+		}
+		i++
+	}
+}
+
+func parseFunctionCall(v []TokenLiteral, i int) (FunctionCall, int, error) {
+	call := FunctionCall{name: v[i].value}
+	i += 2
+
+	if i < len(v) && v[i].kind == symbol && v[i].value == "*" {
+		call.star = true
+		i++
+	} else if i < len(v) && v[i].kind != closingroundbracket {
+		args, n, err := parseExpressionList(v, i)
+		if err != nil {
+			return FunctionCall{}, n, err
+		}
+
+		call.args = args
+		i = n
+	}
+
+	if i >= len(v) || v[i].kind != closingroundbracket {
+		return FunctionCall{}, i, fmt.Errorf("missing closing bracket of %s function call", call.name)
 	}
 	i++
 
-	// destination table
-	if i >= len(v) || v[i].kind != symbol {
-		return InsertQuery{}, errors.New("missing destination table")
-	} else {
-		s := strings.Split(v[i].value, ".")
-		if len(s) == 1 {
-			q.source = SchemaTable[string, string]{defaultScheme, s[0]}
-		} else {
-			q.source = SchemaTable[string, string]{s[0], s[1]}
+	if i < len(v) && v[i].kind == keyword && v[i].value == "over" {
+		window, n, err := parseWindowDefinition(v, i+1)
+		if err != nil {
+			return FunctionCall{}, n, err
 		}
+
+		call.over = &window
+		i = n
 	}
+
+	return call, i, nil
+}
+
+// parseCaseExpression reads case [operand] when ... then ... [else ...] end expression
+func parseCaseExpression(v []TokenLiteral, i int) (CaseExpression, int, error) {
+	expr := CaseExpression{}
 	i++
 
-	// TODO: implement better algorithm for data sets and columns specification, eg. parenthesis stack
-	// values or csv columns
-	if i >= len(v) || (v[i].kind == keyword && v[i].value != "values") {
-		return InsertQuery{}, errors.New("missing values keyword")
-	} else if v[i].kind == openingroundbracket {
-		// csv columns or skip
-		for ; i < len(v); i++ {
-			if v[i].kind == comma || v[i].kind == openingroundbracket {
-				continue
+	if i < len(v) && !(v[i].kind == keyword && v[i].value == "when") {
+		operand, n, err := parseExpression(v, i)
+		if err != nil {
+			return CaseExpression{}, n, err
+		}
+
+		expr.operand = operand
+		i = n
+	}
+
+	for i < len(v) && v[i].kind == keyword && v[i].value == "when" {
+		branch := CaseBranch{}
+		if expr.operand != nil {
+			value, n, err := parseExpression(v, i+1)
+			if err != nil {
+				return CaseExpression{}, n, err
 			}
 
-			if v[i].kind != symbol {
-				if v[i].kind == closingroundbracket {
-					i++
-				}
-				break
+			branch.value = value
+			i = n
+		} else {
+			conditions, n, err := parseConditions(v, i+1)
+			if err != nil {
+				return CaseExpression{}, n, err
 			}
 
-			q.dataColumns = append(q.dataColumns, v[i].value)
+			// conditions reference columns through expressions so they can be
+			// rewritten same as the other parts of expression
+			for _, condition := range conditions {
+				if condition.expr == nil {
+					condition.expr = ColumnReference{name: condition.target}
+				}
+
+				if value, ok := condition.value.(string); ok {
+					if ref, ok := parseOperand(value).(ColumnReference); ok {
+						condition.value = ref
+					}
+				}
+
+				branch.conditions = append(branch.conditions, condition)
+			}
+			i = n
 		}
 
-		if len(q.dataColumns) == 0 {
-			return InsertQuery{}, errors.New("invalid columns specification")
+		if i >= len(v) || v[i].kind != keyword || v[i].value != "then" {
+			return CaseExpression{}, i, errors.New("missing then keyword")
 		}
-		// i is incremented by loop
 
-		// values after csv
-		if i >= len(v) || v[i].kind != keyword && v[i].value != "values" {
-			return InsertQuery{}, errors.New("missing values keyword")
+		result, n, err := parseExpression(v, i+1)
+		if err != nil {
+			return CaseExpression{}, n, err
 		}
-		i++
+
+		branch.result = result
+		expr.branches = append(expr.branches, branch)
+		i = n
 	}
 
-	// column values
-	dataSet := 0
-	for ; i < len(v); i++ {
-		if v[i].kind == comma || v[i].kind == openingroundbracket {
-			continue
-		}
+	if len(expr.branches) == 0 {
+		return CaseExpression{}, i, errors.New("missing when clause")
+	}
 
-		// TODO: after closing bracket we should handle next data set
-		if v[i].kind != symbol || v[i].kind == closingroundbracket {
-			break
+	if i < len(v) && v[i].kind == keyword && v[i].value == "else" {
+		fallback, n, err := parseExpression(v, i+1)
+		if err != nil {
+			return CaseExpression{}, n, err
 		}
 
-		if len(q.values)-1 < dataSet {
-			q.values = append(q.values, []any{})
-		}
+		expr.fallback = fallback
+		i = n
+	}
 
-		q.values[dataSet] = append(q.values[dataSet], v[i].value)
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "end" {
+		return CaseExpression{}, i, errors.New("missing end keyword")
 	}
 
-	return q, nil
+	return expr, i + 1, nil
 }
 
-func parseCreate(tokens *[]TokenLiteral) (CreateTableQuery, error) {
-	v := *tokens
-	q := CreateTableQuery{}
-	i := 0
+// parseWindowDefinition reads (partition by ... order by ... rows ...) clause
+func parseWindowDefinition(v []TokenLiteral, i int) (WindowDefinition, int, error) {
+	window := WindowDefinition{}
 
-	// create
-	if i >= len(v) || v[i].kind != keyword || v[i].value != "create" {
-		return CreateTableQuery{}, errors.New("missing create keyword")
+	if i >= len(v) || v[i].kind != openingroundbracket {
+		return WindowDefinition{}, i, errors.New("missing window definition")
 	}
 	i++
 
-	// table
-	if i >= len(v) || v[i].kind != keyword || v[i].value != "table" {
-		return CreateTableQuery{}, errors.New("missing table keyword")
+	if i < len(v) && v[i].kind == keyword && v[i].value == "partition" {
+		if i+1 >= len(v) || v[i+1].kind != keyword || v[i+1].value != "by" {
+			return WindowDefinition{}, i, errors.New("missing by keyword")
+		}
+
+		partitionBy, n, err := parseExpressionList(v, i+2)
+		if err != nil {
+			return WindowDefinition{}, n, err
+		}
+
+		window.partitionBy = partitionBy
+		i = n
 	}
-	i++
 
-	// table name
-	if i >= len(v) || v[i].kind != symbol {
-		return CreateTableQuery{}, errors.New("missing table name")
-	} else {
-		s := strings.Split(v[i].value, ".")
-		if len(s) == 1 {
-			q.source = SchemaTable[string, string]{defaultScheme, s[0]}
-		} else {
-			q.source = SchemaTable[string, string]{s[0], s[1]}
+	if i < len(v) && v[i].kind == keyword && v[i].value == "order" {
+		orderBy, n, err := parseOrderBy(v, i)
+		if err != nil {
+			return WindowDefinition{}, n, err
 		}
+
+		window.orderBy = orderBy
+		i = n
 	}
-	i++
 
-	// csv columns or skip
-	q.columns = make(map[string][]string)
-	columnsAttributes := []string{}
-	for ; i < len(v); i++ {
-		if v[i].kind == openingroundbracket {
-			continue
+	if i < len(v) && v[i].kind == keyword && v[i].value == "rows" {
+		frame, n, err := parseWindowFrame(v, i+1)
+		if err != nil {
+			return WindowDefinition{}, n, err
 		}
 
-		if v[i].kind == comma {
-			attributes := make([]string, len(columnsAttributes[1:]))
-			copy(attributes, columnsAttributes[1:])
-			q.columns[columnsAttributes[0]] = attributes
-			columnsAttributes = []string{}
-			continue
+		window.frame = &frame
+		i = n
+	}
+
+	if i >= len(v) || v[i].kind != closingroundbracket {
+		return WindowDefinition{}, i, errors.New("missing closing bracket of window definition")
+	}
+
+	return window, i + 1, nil
+}
+
+// parseWindowFrame reads frame after rows keyword, either single start bound
+// or between start and end bounds
+func parseWindowFrame(v []TokenLiteral, i int) (WindowFrame, int, error) {
+	if i < len(v) && v[i].kind == keyword && v[i].value == "between" {
+		start, n, err := parseFrameBound(v, i+1)
+		if err != nil {
+			return WindowFrame{}, n, err
 		}
+		i = n
 
-		if v[i].kind != symbol {
-			if v[i].kind == closingroundbracket {
-				attributes := make([]string, len(columnsAttributes[1:]))
-				copy(attributes, columnsAttributes[1:])
-				q.columns[columnsAttributes[0]] = attributes
-				i++
-			}
-			break
+		if i >= len(v) || v[i].kind != keyword || v[i].value != "and" {
+			return WindowFrame{}, i, errors.New("missing and keyword")
 		}
 
-		columnsAttributes = append(columnsAttributes, v[i].value)
+		end, n, err := parseFrameBound(v, i+1)
+		if err != nil {
+			return WindowFrame{}, n, err
+		}
+
+		if start.kind > end.kind || start.kind == unboundedFollowing || end.kind == unboundedPreceding {
+			return WindowFrame{}, n, errors.New("invalid window frame bounds")
+		}
+
+		return WindowFrame{start: start, end: end}, n, nil
 	}
 
-	log.Println(q)
+	start, n, err := parseFrameBound(v, i)
+	if err != nil {
+		return WindowFrame{}, n, err
+	}
 
-	if len(q.columns) == 0 {
-		return CreateTableQuery{}, errors.New("invalid columns specification")
+	if start.kind == unboundedFollowing || start.kind == offsetFollowing {
+		return WindowFrame{}, n, errors.New("invalid window frame bounds")
+	}
+
+	return WindowFrame{start: start, end: FrameBound{kind: currentRow}}, n, nil
+}
+
+func parseFrameBound(v []TokenLiteral, i int) (FrameBound, int, error) {
+	if i+1 >= len(v) {
+		return FrameBound{}, i, errors.New("missing window frame bound")
+	}
+
+	direction := v[i+1]
+	if direction.kind != keyword {
+		return FrameBound{}, i, errors.New("invalid window frame bound")
+	}
+
+	switch {
+	case v[i].kind == keyword && v[i].value == "current" && direction.value == "row":
+		return FrameBound{kind: currentRow}, i + 2, nil
+	case v[i].kind == keyword && v[i].value == "unbounded" && direction.value == "preceding":
+		return FrameBound{kind: unboundedPreceding}, i + 2, nil
+	case v[i].kind == keyword && v[i].value == "unbounded" && direction.value == "following":
+		return FrameBound{kind: unboundedFollowing}, i + 2, nil
+	case v[i].kind == symbol:
+		offset, err := strconv.Atoi(v[i].value)
+		if err != nil || offset < 0 {
+			return FrameBound{}, i, errors.New("window frame offset must be non negative integer")
+		}
+
+		switch direction.value {
+		case "preceding":
+			return FrameBound{kind: offsetPreceding, offset: offset}, i + 2, nil
+		case "following":
+			return FrameBound{kind: offsetFollowing, offset: offset}, i + 2, nil
+		}
+	}
+
+	return FrameBound{}, i, errors.New("invalid window frame bound")
+}
+
+// parseOrderBy reads order by clause starting at order keyword
+func parseOrderBy(v []TokenLiteral, i int) ([]OrderByItem, int, error) {
+	if i+1 >= len(v) || v[i+1].kind != keyword || v[i+1].value != "by" {
+		return nil, i, errors.New("missing by keyword")
+	}
+	i += 2
+
+	items := []OrderByItem{}
+	for {
+		expr, n, err := parseExpression(v, i)
+		if err != nil {
+			return nil, n, err
+		}
+
+		item := OrderByItem{expression: expr}
+		i = n
+
+		if i < len(v) && v[i].kind == keyword && (v[i].value == "asc" || v[i].value == "desc") {
+			item.descending = v[i].value == "desc"
+			i++
+		}
+
+		items = append(items, item)
+
+		if i >= len(v) || v[i].kind != comma {
+			return items, i, nil
+		}
+		i++
+	}
+}
+
+func parseCommonTableExpressions(v []TokenLiteral, i int) ([]CommonTableExpression, int, error) {
+	recursive := false
+	if i < len(v) && v[i].kind == keyword && v[i].value == "recursive" {
+		recursive = true
+		i++
+	}
+
+	ctes := []CommonTableExpression{}
+	for {
+		if i >= len(v) || v[i].kind != symbol {
+			return nil, i, errors.New("missing common table expression name")
+		}
+
+		cte := CommonTableExpression{name: v[i].value, recursive: recursive}
+		i++
+
+		// optional column names
+		if i < len(v) && v[i].kind == openingroundbracket {
+			for i++; i < len(v) && v[i].kind != closingroundbracket; i++ {
+				if v[i].kind == comma {
+					continue
+				}
+
+				if v[i].kind != symbol {
+					return nil, i, errors.New("invalid common table expression columns")
+				}
+
+				cte.columns = append(cte.columns, v[i].value)
+			}
+			i++
+		}
+
+		if i >= len(v) || v[i].kind != keyword || v[i].value != "as" {
+			return nil, i, errors.New("missing as keyword")
+		}
+		i++
+
+		end := findClosingBracket(v, i)
+		if end == -1 {
+			return nil, i, errors.New("missing common table expression query")
+		}
+
+		body := v[i+1 : end]
+		query, err := parseSelect(&body)
+		if err != nil {
+			return nil, i, err
+		}
+
+		cte.query = query
+		ctes = append(ctes, cte)
+		i = end + 1
+
+		if i < len(v) && v[i].kind == comma {
+			i++
+			continue
+		}
+
+		return ctes, i, nil
+	}
+}
+
+func parseJoin(v []TokenLiteral, i int) (JoinClause, int, error) {
+	join := JoinClause{}
+
+	if v[i].value == "inner" {
+		i++
+	}
+
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "join" {
+		return JoinClause{}, i, errors.New("missing join keyword")
+	}
+	i++
+
+	if i >= len(v) || v[i].kind != symbol {
+		return JoinClause{}, i, errors.New("missing join source table")
+	}
+
+	join.source = parseSchemaTable(v[i].value)
+	i++
+
+	join.alias, i = parseAlias(v, i)
+
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "on" {
+		return JoinClause{}, i, errors.New("missing on keyword")
+	}
+
+	conditions, i, err := parseConditions(v, i+1)
+	if err != nil {
+		return JoinClause{}, i, err
+	}
+
+	join.conditions = conditions
+
+	return join, i, nil
+}
+
+// parseConditions reads conditions joined by and keyword, eg. a = 1 and b > c
+func parseConditions(v []TokenLiteral, i int) ([]Condition, int, error) {
+	conditions := []Condition{}
+	for {
+		condition, n, err := parseCondition(v, i)
+		if err != nil {
+			return nil, n, err
+		}
+
+		conditions = append(conditions, condition)
+		i = n
+
+		if i < len(v) && v[i].kind == keyword && v[i].value == "and" {
+			i++
+			continue
+		}
+
+		return conditions, i, nil
+	}
+}
+
+// parseCondition reads single condition, supported forms are
+// target sign value, target [not] like|ilike value [escape value],
+// target [not] in (values), target [not] between value and value,
+// target is [not] null
+func parseCondition(v []TokenLiteral, i int) (Condition, int, error) {
+	if i >= len(v) || !(v[i].kind == symbol || isCaseKeyword(v[i])) {
+		return Condition{}, i, errors.New("missing condition target")
+	}
+
+	condition := Condition{target: v[i].value}
+
+	// function call or case expression compared instead of column
+	if isCaseKeyword(v[i]) || (i+1 < len(v) && v[i+1].kind == openingroundbracket) {
+		expr, n, err := parseExpression(v, i)
+		if err != nil {
+			return Condition{}, n, err
+		}
+
+		condition = Condition{target: expr.String(), expr: expr}
+		i = n
+	} else {
+		i++
+	}
+
+	negated := false
+	if i < len(v) && v[i].kind == symbol && v[i].value == "not" {
+		negated = true
+		i++
+	}
+
+	if i >= len(v) {
+		return Condition{}, i, errors.New("missing condition sign")
+	}
+
+	switch {
+	case !negated && v[i].kind == symbol && v[i].value == "is":
+		condition.sign = "is null"
+		if i+1 < len(v) && v[i+1].kind == symbol && v[i+1].value == "not" {
+			condition.sign = "is not null"
+			i++
+		}
+
+		if i+1 >= len(v) || v[i+1].kind != symbol || v[i+1].value != "null" {
+			return Condition{}, i, errors.New("invalid is null condition")
+		}
+
+		return condition, i + 2, nil
+	case !negated && (isComparisonSign(v[i].kind) || v[i].kind == regexmatch):
+		condition.sign = v[i].value
+		i++
+	case v[i].kind == keyword && (v[i].value == "like" || v[i].value == "ilike"):
+		condition.sign = v[i].value
+		i++
+	case v[i].kind == keyword && v[i].value == "in":
+		condition.sign = "in"
+		i++
+
+		end := findClosingBracket(v, i)
+		if end == -1 {
+			return Condition{}, i, errors.New("missing in values")
+		}
+
+		values := []any{}
+		for j := i + 1; j < end; j++ {
+			if v[j].kind == comma {
+				continue
+			}
+
+			if v[j].kind != symbol {
+				return Condition{}, j, errors.New("invalid in values")
+			}
+
+			values = append(values, parseRawValue(v[j].value))
+		}
+
+		if len(values) == 0 {
+			return Condition{}, i, errors.New("missing in values")
+		}
+
+		condition.value = values
+		i = end + 1
+	case v[i].kind == keyword && v[i].value == "between":
+		condition.sign = "between"
+		if i+3 >= len(v) || v[i+1].kind != symbol || v[i+2].kind != keyword ||
+			v[i+2].value != "and" || v[i+3].kind != symbol {
+			return Condition{}, i, errors.New("invalid between bounds")
+		}
+
+		condition.value = []any{parseRawValue(v[i+1].value), parseRawValue(v[i+3].value)}
+		i += 4
+	default:
+		return Condition{}, i, errors.New("missing condition sign")
+	}
+
+	if negated {
+		condition.sign = "not " + condition.sign
+	}
+
+	if condition.value != nil {
+		return condition, i, nil
+	}
+
+	if i >= len(v) || !(v[i].kind == symbol || isCaseKeyword(v[i])) {
+		return Condition{}, i, errors.New("missing condition value")
+	}
+
+	if isCaseKeyword(v[i]) || (i+1 < len(v) && v[i+1].kind == openingroundbracket) {
+		expr, n, err := parseExpression(v, i)
+		if err != nil {
+			return Condition{}, n, err
+		}
+
+		condition.value = expr
+		i = n
+	} else {
+		condition.value = parseRawValue(v[i].value)
+		i++
+	}
+
+	if i+1 < len(v) && v[i].kind == keyword && v[i].value == "escape" {
+		if !strings.HasSuffix(condition.sign, "like") || v[i+1].kind != symbol {
+			return Condition{}, i, errors.New("invalid escape clause")
+		}
+
+		condition.escape = unquote(v[i+1].value)
+		i += 2
+	}
+
+	return condition, i, nil
+}
+
+func isCaseKeyword(token TokenLiteral) bool {
+	return token.kind == keyword && token.value == "case"
+}
+
+func isComparisonSign(kind TokenKind) bool {
+	return kind == equal ||
+		kind == notequal ||
+		kind == greater ||
+		kind == greaterorequal ||
+		kind == less ||
+		kind == lessorequal
+}
+
+func parseAlias(v []TokenLiteral, i int) (string, int) {
+	explicit := i < len(v) && v[i].kind == keyword && v[i].value == "as"
+	if explicit {
+		i++
+	}
+
+	// limit and offset aren't keywords, they are alias only after as
+	if !explicit && (isWord(v, i, "limit") || isWord(v, i, "offset")) {
+		return "", i
+	}
+
+	if i < len(v) && v[i].kind == symbol {
+		return v[i].value, i + 1
+	}
+
+	return "", i
+}
+
+func parseSchemaTable(value string) SchemaTable[string, string] {
+	s := strings.Split(value, ".")
+	if len(s) == 1 {
+		return SchemaTable[string, string]{defaultScheme, s[0]}
+	}
+
+	return SchemaTable[string, string]{s[0], s[1]}
+}
+
+// findClosingBracket returns index of bracket closing the one at i or -1
+func findClosingBracket(v []TokenLiteral, i int) int {
+	if i >= len(v) || v[i].kind != openingroundbracket {
+		return -1
+	}
+
+	depth := 0
+	for ; i < len(v); i++ {
+		switch v[i].kind {
+		case openingroundbracket:
+			depth++
+		case closingroundbracket:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func parseInsert(tokens *[]TokenLiteral) (InsertQuery, error) {
+	v := *tokens
+	q := InsertQuery{}
+	i := 0
+
+	// insert
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "insert" {
+		return InsertQuery{}, errors.New("missing insert keyword")
+	}
+	i++
+
+	// into
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "into" {
+		return InsertQuery{}, errors.New("missing into keyword")
+	}
+	i++
+
+	// destination table
+	if i >= len(v) || v[i].kind != symbol {
+		return InsertQuery{}, errors.New("missing destination table")
+	} else {
+		s := strings.Split(v[i].value, ".")
+		if len(s) == 1 {
+			q.source = SchemaTable[string, string]{defaultScheme, s[0]}
+		} else {
+			q.source = SchemaTable[string, string]{s[0], s[1]}
+		}
+	}
+	i++
+
+	// TODO: implement better algorithm for columns specification, eg. parenthesis stack
+	// values or csv columns
+	if i >= len(v) || (v[i].kind == keyword && !isInsertSource(v, i)) {
+		return InsertQuery{}, errors.New("missing values keyword")
+	} else if v[i].kind == openingroundbracket {
+		// csv columns or skip
+		for ; i < len(v); i++ {
+			if v[i].kind == comma || v[i].kind == openingroundbracket {
+				continue
+			}
+
+			if v[i].kind != symbol {
+				if v[i].kind == closingroundbracket {
+					i++
+				}
+				break
+			}
+
+			q.dataColumns = append(q.dataColumns, v[i].value)
+		}
+
+		if len(q.dataColumns) == 0 {
+			return InsertQuery{}, errors.New("invalid columns specification")
+		}
+		// i is incremented by loop
+	}
+
+	if !isInsertSource(v, i) {
+		return InsertQuery{}, errors.New("missing values keyword")
+	}
+
+	if v[i].value == "values" {
+		values, n, err := parseValues(v, i+1)
+		if err != nil {
+			return InsertQuery{}, err
+		}
+
+		q.values = values
+		i = n
+	} else {
+		// query ends before clauses of insert
+		end := i
+		for depth := 0; end < len(v); end++ {
+			if depth == 0 && (isWord(v, end, "returning") || (isWord(v, end, "on") && isWord(v, end+1, "conflict"))) {
+				break
+			}
+
+			switch v[end].kind {
+			case openingroundbracket:
+				depth++
+			case closingroundbracket:
+				depth--
+			}
+		}
+
+		body := v[i:end]
+		query, err := parseSelect(&body)
+		if err != nil {
+			return InsertQuery{}, err
+		}
+
+		q.query = &query
+		i = end
+	}
+
+	if isWord(v, i, "on") && isWord(v, i+1, "conflict") {
+		onConflict, n, err := parseOnConflict(v, i+2)
+		if err != nil {
+			return InsertQuery{}, err
+		}
+
+		q.onConflict = &onConflict
+		i = n
+	}
+
+	if isWord(v, i, "returning") {
+		returning, n, err := parseReturning(v, i+1)
+		if err != nil {
+			return InsertQuery{}, err
+		}
+
+		q.returning = returning
+		i = n
+	}
+
+	if i < len(v) {
+		return InsertQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+// isInsertSource reports whether values keyword or query providing rows of
+// insert starts at i
+func isInsertSource(v []TokenLiteral, i int) bool {
+	return i < len(v) && v[i].kind == keyword && (v[i].value == "values" || v[i].value == "select" || v[i].value == "with")
+}
+
+// parseValues reads (value[, value])[, (value[, value])] tuples of insert
+func parseValues(v []TokenLiteral, i int) ([][]any, int, error) {
+	values := [][]any{}
+	for {
+		if i >= len(v) || v[i].kind != openingroundbracket {
+			return nil, i, errors.New("missing values")
+		}
+		i++
+
+		row := []any{}
+		for {
+			if i >= len(v) || v[i].kind != symbol {
+				return nil, i, errors.New("invalid values")
+			}
+			row = append(row, parseRawValue(v[i].value))
+			i++
+
+			if i < len(v) && v[i].kind == comma {
+				i++
+				continue
+			}
+
+			if i >= len(v) || v[i].kind != closingroundbracket {
+				return nil, i, errors.New("missing closing bracket")
+			}
+			i++
+			break
+		}
+		values = append(values, row)
+
+		if i >= len(v) || v[i].kind != comma {
+			return values, i, nil
+		}
+		i++
+	}
+}
+
+// parseRawValue keeps value of condition or insert as raw literal or column
+// name, $n values are parameter placeholders
+func parseRawValue(value string) any {
+	if n, ok := parameterIndex(value); ok {
+		return Parameter{n: n}
+	}
+
+	return value
+}
+
+// parseOnConflict reads [(columns)] do nothing | do update set column = expression, ...
+func parseOnConflict(v []TokenLiteral, i int) (OnConflictClause, int, error) {
+	clause := OnConflictClause{}
+	if i < len(v) && v[i].kind == openingroundbracket {
+		columns, n, err := parseColumnList(v, i)
+		if err != nil {
+			return OnConflictClause{}, n, err
+		}
+
+		clause.columns = columns
+		i = n
+	}
+
+	if !isWord(v, i, "do") {
+		return OnConflictClause{}, i, errors.New("missing do keyword")
+	}
+	i++
+
+	if isWord(v, i, "nothing") {
+		return clause, i + 1, nil
+	}
+
+	if !isWord(v, i, "update") || !isWord(v, i+1, "set") {
+		return OnConflictClause{}, i, errors.New("missing conflict action")
+	}
+
+	if len(clause.columns) == 0 {
+		return OnConflictClause{}, i, errors.New("on conflict do update requires conflict target columns")
+	}
+	i += 2
+
+	clause.update = true
+	for {
+		if i+1 >= len(v) || v[i].kind != symbol || v[i+1].kind != equal {
+			return OnConflictClause{}, i, errors.New("invalid column assignment")
+		}
+
+		value, n, err := parseExpression(v, i+2)
+		if err != nil {
+			return OnConflictClause{}, n, err
+		}
+
+		clause.assignments = append(clause.assignments, Assignment{column: v[i].value, value: value})
+		i = n
+
+		if i >= len(v) || v[i].kind != comma {
+			return clause, i, nil
+		}
+		i++
+	}
+}
+
+// parseReturning reads * | expression [as alias], ... of returning clause
+func parseReturning(v []TokenLiteral, i int) (ReturningClause, int, error) {
+	returning := ReturningClause{}
+	for {
+		var expr Expression
+		if i < len(v) && v[i].kind == symbol && v[i].value == "*" {
+			expr = ColumnReference{name: "*"}
+			i++
+		} else {
+			e, n, err := parseExpression(v, i)
+			if err != nil {
+				return ReturningClause{}, n, err
+			}
+
+			expr, i = e, n
+		}
+
+		name := expr.String()
+		if ref, ok := expr.(ColumnReference); ok {
+			name = ref.name
+		}
+
+		if i < len(v) && v[i].kind == keyword && v[i].value == "as" {
+			if i+1 >= len(v) || v[i+1].kind != symbol {
+				return ReturningClause{}, i, errors.New("missing column alias")
+			}
+
+			name = v[i+1].value
+			i += 2
+		}
+
+		returning.names = append(returning.names, name)
+		returning.projections = append(returning.projections, expr)
+
+		if i >= len(v) || v[i].kind != comma {
+			return returning, i, nil
+		}
+		i++
+	}
+}
+
+func parseCreate(tokens *[]TokenLiteral) (CreateTableQuery, error) {
+	v := *tokens
+	q := CreateTableQuery{}
+	i := 0
+
+	// create
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "create" {
+		return CreateTableQuery{}, errors.New("missing create keyword")
+	}
+	i++
+
+	// table
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "table" {
+		return CreateTableQuery{}, errors.New("missing table keyword")
+	}
+	i++
+
+	// table name
+	if i >= len(v) || v[i].kind != symbol {
+		return CreateTableQuery{}, errors.New("missing table name")
+	} else {
+		s := strings.Split(v[i].value, ".")
+		if len(s) == 1 {
+			q.source = SchemaTable[string, string]{defaultScheme, s[0]}
+		} else {
+			q.source = SchemaTable[string, string]{s[0], s[1]}
+		}
+	}
+	i++
+
+	// column definitions and table constraints
+	if i >= len(v) || v[i].kind != openingroundbracket {
+		return CreateTableQuery{}, errors.New("invalid columns specification")
+	}
+	i++
+
+	for {
+		var err error
+		if isTableConstraint(v, i) {
+			var constraint Constraint
+			constraint, i, err = parseTableConstraint(v, i)
+			q.constraints = append(q.constraints, constraint)
+		} else {
+			i, err = parseColumnDefinition(v, i, &q)
+		}
+		if err != nil {
+			return CreateTableQuery{}, err
+		}
+
+		if i < len(v) && v[i].kind == comma {
+			i++
+			continue
+		}
+
+		if i >= len(v) || v[i].kind != closingroundbracket {
+			return CreateTableQuery{}, errors.New("missing closing bracket")
+		}
+		i++
+		break
+	}
+
+	if len(q.columns) == 0 {
+		return CreateTableQuery{}, errors.New("invalid columns specification")
+	}
+
+	if i < len(v) {
+		return CreateTableQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+// isTableConstraint reports whether table constraint starts at i, column
+// can't be named by constraint keyword
+func isTableConstraint(v []TokenLiteral, i int) bool {
+	if i >= len(v) || v[i].kind != symbol {
+		return false
+	}
+
+	switch strings.ToLower(v[i].value) {
+	case "constraint", "primary", "unique", "check", "foreign":
+		return true
+	}
+
+	return false
+}
+
+// parseColumnDefinition reads name data type followed by column constraints
+// [constraint name] not null | null | primary key | unique | check (conditions) |
+// references table [(column)] [on delete action] [on update action]
+func parseColumnDefinition(v []TokenLiteral, i int, q *CreateTableQuery) (int, error) {
+	if i >= len(v) || v[i].kind != symbol {
+		return i, errors.New("missing column name")
+	}
+	name := v[i].value
+	if slices.ContainsFunc(q.columns, func(c ColumnDefinition) bool { return c.name == name }) {
+		return i, fmt.Errorf("column %s specified more than once", name)
+	}
+	i++
+
+	if i >= len(v) || v[i].kind != symbol {
+		return i, errors.New("missing data type for columns")
+	}
+	attributes := []string{v[i].value}
+	i++
+
+	for i < len(v) && v[i].kind == symbol {
+		constraint := Constraint{columns: []string{name}}
+		if strings.EqualFold(v[i].value, "constraint") {
+			if i+1 >= len(v) || v[i+1].kind != symbol {
+				return i, errors.New("missing constraint name")
+			}
+
+			constraint.name = v[i+1].value
+			attributes = append(attributes, v[i].value, v[i+1].value)
+			i += 2
+			if i >= len(v) || v[i].kind != symbol {
+				return i, errors.New("missing constraint")
+			}
+		}
+
+		attributes = append(attributes, v[i].value)
+		switch strings.ToLower(v[i].value) {
+		case "null":
+			// columns are nullable by default
+			i++
+			continue
+		case "not":
+			if i+1 >= len(v) || v[i+1].kind != symbol || !strings.EqualFold(v[i+1].value, "null") {
+				return i, errors.New("missing null keyword")
+			}
+
+			attributes = append(attributes, v[i+1].value)
+			constraint.kind = notNullConstraint
+			i += 2
+		case "primary":
+			if i+1 >= len(v) || v[i+1].kind != symbol || !strings.EqualFold(v[i+1].value, "key") {
+				return i, errors.New("missing key keyword")
+			}
+
+			attributes = append(attributes, v[i+1].value)
+			constraint.kind = primaryKeyConstraint
+			i += 2
+		case "unique":
+			constraint.kind = uniqueConstraint
+			i++
+		case "check":
+			var err error
+			if constraint.conditions, constraint.clause, i, err = parseCheck(v, i+1); err != nil {
+				return i, err
+			}
+			constraint.kind = checkConstraint
+		case "references":
+			var err error
+			if i, err = parseReferences(v, i+1, &constraint); err != nil {
+				return i, err
+			}
+			constraint.kind = foreignKeyConstraint
+		case "default":
+			expr, n, err := parseExpression(v, i+1)
+			if err != nil {
+				return n, err
+			}
+
+			words := []string{}
+			for _, token := range v[i+1 : n] {
+				words = append(words, token.value)
+			}
+
+			q.defaults = append(q.defaults, ColumnDefault{column: name, expr: expr, clause: strings.Join(words, " ")})
+			i = n
+			continue
+		case "generated":
+			d, n, err := parseIdentity(v, i+1)
+			if err != nil {
+				return n, err
+			}
+
+			d.column = name
+			q.defaults = append(q.defaults, d)
+			i = n
+			continue
+		default:
+			return i, fmt.Errorf("unexpected token %s", v[i].value)
+		}
+
+		q.constraints = append(q.constraints, constraint)
+	}
+
+	q.columns = append(q.columns, ColumnDefinition{name: name, attributes: attributes})
+	return i, nil
+}
+
+// parseIdentity reads always | by default as identity [(sequence options)]
+func parseIdentity(v []TokenLiteral, i int) (ColumnDefault, int, error) {
+	d := ColumnDefault{sequence: true, options: map[string]int64{}}
+	switch {
+	case isWord(v, i, "always"):
+		d.identity = identityAlways
+		i++
+	case isWord(v, i, "by") && isWord(v, i+1, "default"):
+		d.identity = identityByDefault
+		i += 2
+	default:
+		return ColumnDefault{}, i, errors.New("missing always or by default keywords")
+	}
+
+	if !isWord(v, i, "as") || !isWord(v, i+1, "identity") {
+		return ColumnDefault{}, i, errors.New("missing as identity keywords")
+	}
+	i += 2
+
+	if i < len(v) && v[i].kind == openingroundbracket {
+		var err error
+		if d.options, i, err = parseSequenceOptions(v, i+1); err != nil {
+			return ColumnDefault{}, i, err
+		}
+
+		if i >= len(v) || v[i].kind != closingroundbracket {
+			return ColumnDefault{}, i, errors.New("missing closing bracket")
+		}
+		i++
+	}
+
+	return d, i, nil
+}
+
+// parseTableConstraint reads [constraint name] primary key (columns) |
+// unique (columns) | check (conditions) | foreign key (columns) references ...
+func parseTableConstraint(v []TokenLiteral, i int) (Constraint, int, error) {
+	constraint := Constraint{}
+	if strings.EqualFold(v[i].value, "constraint") {
+		i++
+		if i >= len(v) || v[i].kind != symbol {
+			return Constraint{}, i, errors.New("missing constraint name")
+		}
+		constraint.name = v[i].value
+		i++
+	}
+
+	if i >= len(v) || v[i].kind != symbol {
+		return Constraint{}, i, errors.New("missing constraint")
+	}
+
+	var err error
+	switch strings.ToLower(v[i].value) {
+	case "primary":
+		if i+1 >= len(v) || v[i+1].kind != symbol || !strings.EqualFold(v[i+1].value, "key") {
+			return Constraint{}, i, errors.New("missing key keyword")
+		}
+
+		constraint.kind = primaryKeyConstraint
+		constraint.columns, i, err = parseColumnList(v, i+2)
+	case "unique":
+		constraint.kind = uniqueConstraint
+		constraint.columns, i, err = parseColumnList(v, i+1)
+	case "check":
+		constraint.kind = checkConstraint
+		constraint.conditions, constraint.clause, i, err = parseCheck(v, i+1)
+	case "foreign":
+		if i+1 >= len(v) || v[i+1].kind != symbol || !strings.EqualFold(v[i+1].value, "key") {
+			return Constraint{}, i, errors.New("missing key keyword")
+		}
+
+		constraint.kind = foreignKeyConstraint
+		if constraint.columns, i, err = parseColumnList(v, i+2); err != nil {
+			return Constraint{}, i, err
+		}
+
+		if i >= len(v) || v[i].kind != symbol || !strings.EqualFold(v[i].value, "references") {
+			return Constraint{}, i, errors.New("missing references keyword")
+		}
+		i, err = parseReferences(v, i+1, &constraint)
+	default:
+		return Constraint{}, i, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+	if err != nil {
+		return Constraint{}, i, err
+	}
+
+	return constraint, i, nil
+}
+
+// parseReferences reads table [(column[, column])] [on delete action]
+// [on update action] of foreign key, action is cascade, set null, restrict
+// or no action
+func parseReferences(v []TokenLiteral, i int, constraint *Constraint) (int, error) {
+	if i >= len(v) || v[i].kind != symbol {
+		return i, errors.New("missing referenced table")
+	}
+	constraint.references = parseSchemaTable(v[i].value)
+	constraint.onDelete, constraint.onUpdate = noAction, noAction
+	i++
+
+	if i < len(v) && v[i].kind == openingroundbracket {
+		var err error
+		if constraint.referencedColumns, i, err = parseColumnList(v, i); err != nil {
+			return i, err
+		}
+	}
+
+	for i < len(v) && v[i].kind == keyword && v[i].value == "on" {
+		i++
+		if i >= len(v) || (v[i].value != "delete" && v[i].value != "update") {
+			return i, errors.New("missing delete or update keyword")
+		}
+		event := v[i].value
+		i++
+
+		action := ""
+		switch {
+		case isWord(v, i, "cascade"):
+			action = cascadeAction
+		case isWord(v, i, "restrict"):
+			action = restrictAction
+		case isWord(v, i, "set") && isWord(v, i+1, "null"):
+			action = setNullAction
+			i++
+		case isWord(v, i, "no") && isWord(v, i+1, "action"):
+			action = noAction
+			i++
+		default:
+			return i, errors.New("missing referential action")
+		}
+		i++
+
+		if event == "delete" {
+			constraint.onDelete = action
+		} else {
+			constraint.onUpdate = action
+		}
+	}
+
+	return i, nil
+}
+
+// isWord reports whether token at i is word regardless of its kind and case
+func isWord(v []TokenLiteral, i int, word string) bool {
+	return i < len(v) && strings.EqualFold(v[i].value, word)
+}
+
+// parseColumnList reads (column[, column])
+func parseColumnList(v []TokenLiteral, i int) ([]string, int, error) {
+	if i >= len(v) || v[i].kind != openingroundbracket {
+		return nil, i, errors.New("missing columns")
+	}
+	i++
+
+	columns := []string{}
+	for {
+		if i >= len(v) || v[i].kind != symbol {
+			return nil, i, errors.New("missing column name")
+		}
+		columns = append(columns, v[i].value)
+		i++
+
+		if i < len(v) && v[i].kind == comma {
+			i++
+			continue
+		}
+
+		if i >= len(v) || v[i].kind != closingroundbracket {
+			return nil, i, errors.New("missing closing bracket")
+		}
+
+		return columns, i + 1, nil
+	}
+}
+
+// parseCheck reads (conditions) of check constraint, text of conditions is
+// returned as well so they can be stored and parsed again
+func parseCheck(v []TokenLiteral, i int) ([]Condition, string, int, error) {
+	if i >= len(v) || v[i].kind != openingroundbracket {
+		return nil, "", i, errors.New("missing check conditions")
+	}
+	start := i + 1
+
+	conditions, i, err := parseConditions(v, start)
+	if err != nil {
+		return nil, "", i, err
+	}
+
+	if i >= len(v) || v[i].kind != closingroundbracket {
+		return nil, "", i, errors.New("missing closing bracket")
+	}
+
+	words := []string{}
+	for _, token := range v[start:i] {
+		words = append(words, token.value)
+	}
+
+	return conditions, strings.Join(words, " "), i + 1, nil
+}
+
+// parseCheckClause parses conditions of check constraint stored in catalog
+func parseCheckClause(clause string) ([]Condition, error) {
+	tokens := Analyze(clause)
+	conditions, i, err := parseConditions(tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if i < len(tokens) {
+		return nil, fmt.Errorf("unexpected token %s", tokens[i].value)
+	}
+
+	return conditions, nil
+}
+
+// parseDefaultClause parses default expression stored in catalog
+func parseDefaultClause(clause string) (Expression, error) {
+	tokens := Analyze(clause)
+	expr, i, err := parseExpression(tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if i < len(tokens) {
+		return nil, fmt.Errorf("unexpected token %s", tokens[i].value)
+	}
+
+	return expr, nil
+}
+
+// parseCreateSequence reads create sequence name [as type] [sequence options]
+func parseCreateSequence(tokens *[]TokenLiteral) (CreateSequenceQuery, error) {
+	v := *tokens
+	q := CreateSequenceQuery{dataType: bigint}
+	i := 2
+
+	if i >= len(v) || v[i].kind != symbol {
+		return CreateSequenceQuery{}, errors.New("missing sequence name")
+	}
+	q.name = parseSchemaTable(v[i].value)
+	i++
+
+	if i < len(v) && v[i].kind == keyword && v[i].value == "as" {
+		if i+1 >= len(v) || v[i+1].kind != symbol {
+			return CreateSequenceQuery{}, errors.New("missing data type of sequence")
+		}
+
+		q.dataType = DataType(v[i+1].value)
+		i += 2
+	}
+
+	options, i, err := parseSequenceOptions(v, i)
+	if err != nil {
+		return CreateSequenceQuery{}, err
+	}
+	q.options = options
+
+	if i < len(v) {
+		return CreateSequenceQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+// parseSequenceOptions reads start [with] n, increment [by] n, minvalue n and
+// maxvalue n options in any order
+func parseSequenceOptions(v []TokenLiteral, i int) (map[string]int64, int, error) {
+	options := map[string]int64{}
+	for i < len(v) && v[i].kind == symbol {
+		option := strings.ToLower(v[i].value)
+		if !slices.Contains([]string{"start", "increment", "minvalue", "maxvalue"}, option) {
+			break
+		}
+		i++
+
+		if (option == "start" && isWord(v, i, "with")) || (option == "increment" && isWord(v, i, "by")) {
+			i++
+		}
+
+		if i >= len(v) || v[i].kind != symbol {
+			return nil, i, fmt.Errorf("missing value of %s option", option)
+		}
+
+		value, err := strconv.ParseInt(unquote(v[i].value), 10, 64)
+		if err != nil {
+			return nil, i, fmt.Errorf("invalid value of %s option", option)
+		}
+
+		if _, ok := options[option]; ok {
+			return nil, i, fmt.Errorf("conflicting %s options", option)
+		}
+		options[option] = value
+		i++
+	}
+
+	return options, i, nil
+}
+
+// parseCreateIndex reads create [unique] index name on table [using method] (column[, column])
+func parseCreateIndex(tokens *[]TokenLiteral) (CreateIndexQuery, error) {
+	v := *tokens
+	q := CreateIndexQuery{}
+	i := 1
+
+	if i < len(v) && v[i].kind == symbol && strings.EqualFold(v[i].value, "unique") {
+		q.unique = true
+		i++
+	}
+
+	if i >= len(v) || v[i].kind != symbol || !strings.EqualFold(v[i].value, "index") {
+		return CreateIndexQuery{}, errors.New("missing index keyword")
+	}
+	i++
+
+	if i >= len(v) || v[i].kind != symbol {
+		return CreateIndexQuery{}, errors.New("missing index name")
+	}
+	q.name = v[i].value
+	i++
+
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "on" {
+		return CreateIndexQuery{}, errors.New("missing on keyword")
+	}
+	i++
+
+	if i >= len(v) || v[i].kind != symbol {
+		return CreateIndexQuery{}, errors.New("missing table name")
+	}
+	q.source = parseSchemaTable(v[i].value)
+	i++
+
+	if i < len(v) && v[i].kind == symbol && strings.EqualFold(v[i].value, "using") {
+		i++
+		if i >= len(v) || v[i].kind != symbol {
+			return CreateIndexQuery{}, errors.New("missing index method")
+		}
+
+		q.method = strings.ToLower(v[i].value)
+		if q.method != btreeIndex && q.method != hashIndex {
+			return CreateIndexQuery{}, fmt.Errorf("unsupported index method %s", v[i].value)
+		}
+		i++
+	}
+
+	if i >= len(v) || v[i].kind != openingroundbracket {
+		return CreateIndexQuery{}, errors.New("missing index columns")
+	}
+
+	var err error
+	if q.columns, i, err = parseColumnList(v, i); err != nil {
+		return CreateIndexQuery{}, err
+	}
+
+	if i < len(v) {
+		return CreateIndexQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+func parseUpdate(tokens *[]TokenLiteral) (UpdateQuery, error) {
+	v := *tokens
+	q := UpdateQuery{}
+	i := 0
+
+	// update
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "update" {
+		return UpdateQuery{}, errors.New("missing update keyword")
+	}
+	i++
+
+	// table
+	if i >= len(v) || v[i].kind != symbol {
+		return UpdateQuery{}, errors.New("missing table name")
+	}
+
+	q.source = parseSchemaTable(v[i].value)
+	i++
+
+	// set
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "set" {
+		return UpdateQuery{}, errors.New("missing set keyword")
+	}
+	i++
+
+	// column = expression, ...
+	for {
+		if i+1 >= len(v) || v[i].kind != symbol || v[i+1].kind != equal {
+			return UpdateQuery{}, errors.New("invalid column assignment")
+		}
+
+		value, n, err := parseExpression(v, i+2)
+		if err != nil {
+			return UpdateQuery{}, err
+		}
+
+		q.assignments = append(q.assignments, Assignment{column: v[i].value, value: value})
+		i = n
+
+		if i >= len(v) || v[i].kind != comma {
+			break
+		}
+		i++
+	}
+
+	// where
+	if i < len(v) && v[i].kind == keyword && v[i].value == "where" {
+		conditions, n, err := parseConditions(v, i+1)
+		if err != nil {
+			return UpdateQuery{}, err
+		}
+
+		q.conditions = conditions
+		i = n
+	}
+
+	if isWord(v, i, "returning") {
+		returning, n, err := parseReturning(v, i+1)
+		if err != nil {
+			return UpdateQuery{}, err
+		}
+
+		q.returning = returning
+		i = n
+	}
+
+	if i < len(v) {
+		return UpdateQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+func parseDelete(tokens *[]TokenLiteral) (DeleteQuery, error) {
+	v := *tokens
+	q := DeleteQuery{}
+	i := 0
+
+	// delete from
+	if i+1 >= len(v) || v[i].kind != keyword || v[i].value != "delete" ||
+		v[i+1].kind != keyword || v[i+1].value != "from" {
+		return DeleteQuery{}, errors.New("missing delete from keywords")
+	}
+	i += 2
+
+	// table
+	if i >= len(v) || v[i].kind != symbol {
+		return DeleteQuery{}, errors.New("missing table name")
+	}
+
+	q.source = parseSchemaTable(v[i].value)
+	i++
+
+	// where
+	if i < len(v) && v[i].kind == keyword && v[i].value == "where" {
+		conditions, n, err := parseConditions(v, i+1)
+		if err != nil {
+			return DeleteQuery{}, err
+		}
+
+		q.conditions = conditions
+		i = n
+	}
+
+	if isWord(v, i, "returning") {
+		returning, n, err := parseReturning(v, i+1)
+		if err != nil {
+			return DeleteQuery{}, err
+		}
+
+		q.returning = returning
+		i = n
+	}
+
+	if i < len(v) {
+		return DeleteQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+// parseTransaction reads transaction control statements, optional work and
+// transaction words are skipped
+func parseTransaction(tokens *[]TokenLiteral) (TransactionQuery, error) {
+	v := *tokens
+	q := TransactionQuery{command: v[0].value}
+	i := 1
+
+	switch q.command {
+	case "end":
+		q.command = "commit"
+	case "set":
+		q.command = "set transaction"
+	}
+
+	if i < len(v) && v[i].kind == symbol &&
+		(strings.EqualFold(v[i].value, "work") || strings.EqualFold(v[i].value, "transaction")) {
+		i++
+	}
+
+	switch q.command {
+	case "begin":
+		if i >= len(v) {
+			break
+		}
+		fallthrough
+	case "set transaction":
+		level, next, err := parseIsolationLevel(v, i)
+		if err != nil {
+			return TransactionQuery{}, err
+		}
+
+		q.isolationLevel = level
+		i = next
+	case "rollback":
+		if i >= len(v) || v[i].kind != symbol || v[i].value != "to" {
+			break
+		}
+		q.command = "rollback to"
+		i++
+
+		if i < len(v) && v[i].kind == keyword && v[i].value == "savepoint" {
+			i++
+		}
+		fallthrough
+	case "savepoint", "release":
+		if i < len(v) && v[i].kind == keyword && v[i].value == "savepoint" {
+			i++
+		}
+
+		if i >= len(v) || v[i].kind != symbol {
+			return TransactionQuery{}, errors.New("missing savepoint name")
+		}
+
+		q.savepoint = v[i].value
+		i++
+	}
+
+	if i < len(v) {
+		return TransactionQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+// parseIsolationLevel reads isolation level clause, read uncommitted behaves
+// as read committed
+func parseIsolationLevel(v []TokenLiteral, i int) (IsolationLevel, int, error) {
+	words := []string{}
+	for _, token := range v[i:] {
+		if token.kind != symbol {
+			break
+		}
+		words = append(words, strings.ToLower(token.value))
+	}
+
+	if len(words) < 3 || words[0] != "isolation" || words[1] != "level" {
+		return "", i, errors.New("missing isolation level")
+	}
+
+	level := strings.Join(words[2:min(len(words), 4)], " ")
+	switch level {
+	case "read uncommitted", "read committed":
+		return readCommitted, i + 4, nil
+	case "repeatable read":
+		return repeatableRead, i + 4, nil
+	}
+
+	if words[2] == "serializable" {
+		return serializable, i + 3, nil
+	}
+
+	return "", i, fmt.Errorf("unsupported isolation level %s", words[2])
+}
+
+// lockTableModes maps names of lock table modes, access share and row share
+// modes are intention shared as they are used by reading transactions
+var lockTableModes = map[string]LockMode{
+	"access share":        intentionShared,
+	"row share":           intentionShared,
+	"row exclusive":       intentionExclusive,
+	"share":               shared,
+	"share row exclusive": sharedIntentionExclusive,
+	"exclusive":           exclusive,
+	"access exclusive":    exclusive,
+}
+
+// parseLockTable reads lock [table] name[, name] [in mode mode], tables are
+// locked in access exclusive mode by default
+func parseLockTable(tokens *[]TokenLiteral) (LockTableQuery, error) {
+	v := *tokens
+	q := LockTableQuery{mode: exclusive}
+	i := 1
+
+	if i < len(v) && v[i].kind == keyword && v[i].value == "table" {
+		i++
+	}
+
+	for {
+		if i >= len(v) || v[i].kind != symbol {
+			return LockTableQuery{}, errors.New("missing table name")
+		}
+
+		q.sources = append(q.sources, parseSchemaTable(v[i].value))
+		i++
+
+		if i >= len(v) || v[i].kind != comma {
+			break
+		}
+		i++
+	}
+
+	if i < len(v) && v[i].kind == keyword && v[i].value == "in" {
+		words := []string{}
+		for i++; i < len(v) && !(v[i].kind == symbol && strings.EqualFold(v[i].value, "mode")); i++ {
+			words = append(words, strings.ToLower(v[i].value))
+		}
+
+		mode, ok := lockTableModes[strings.Join(words, " ")]
+		if i >= len(v) || !ok {
+			return LockTableQuery{}, errors.New("invalid lock mode")
+		}
+
+		q.mode = mode
+		i++
+	}
+
+	if i < len(v) {
+		return LockTableQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+// parseVacuum reads vacuum [full] [name[, name]]
+func parseVacuum(tokens *[]TokenLiteral) (VacuumQuery, error) {
+	v := *tokens
+	q := VacuumQuery{}
+	i := 1
+
+	if i < len(v) && v[i].kind == symbol && strings.EqualFold(v[i].value, "full") {
+		q.full = true
+		i++
+	}
+
+	for i < len(v) {
+		if v[i].kind != symbol {
+			return VacuumQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+		}
+
+		q.sources = append(q.sources, parseSchemaTable(v[i].value))
+		i++
+
+		if i >= len(v) {
+			break
+		}
+
+		if v[i].kind != comma || i+1 >= len(v) {
+			return VacuumQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+		}
+		i++
+	}
+
+	return q, nil
+}
+
+// parseAnalyze reads analyze [name[, name]]
+func parseAnalyze(tokens *[]TokenLiteral) (AnalyzeQuery, error) {
+	v := *tokens
+	q := AnalyzeQuery{}
+
+	for i := 1; i < len(v); i++ {
+		if v[i].kind != symbol {
+			return AnalyzeQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+		}
+
+		q.sources = append(q.sources, parseSchemaTable(v[i].value))
+		i++
+
+		if i < len(v) && (v[i].kind != comma || i+1 >= len(v)) {
+			return AnalyzeQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+		}
+	}
+
+	return q, nil
+}
+
+func parseExplain(tokens *[]TokenLiteral) (ExplainQuery, error) {
+	v := *tokens
+	q := ExplainQuery{format: "text"}
+	i := 1
+
+	// options list, eg. (analyze, format json)
+	if i < len(v) && v[i].kind == openingroundbracket {
+		end := findClosingBracket(v, i)
+		if end == -1 {
+			return ExplainQuery{}, errors.New("missing closing bracket")
+		}
+
+		for i++; i < end; i++ {
+			switch {
+			case isWord(v, i, "analyze"):
+				q.analyze = true
+				if i+1 < end && v[i+1].kind == symbol {
+					switch strings.ToLower(v[i+1].value) {
+					case "true", "on":
+					case "false", "off":
+						q.analyze = false
+					default:
+						return ExplainQuery{}, fmt.Errorf("invalid analyze value %s", v[i+1].value)
+					}
+					i++
+				}
+			case isWord(v, i, "format"):
+				if i+1 >= end || (!isWord(v, i+1, "text") && !isWord(v, i+1, "json")) {
+					return ExplainQuery{}, errors.New("explain format must be text or json")
+				}
+
+				q.format = strings.ToLower(v[i+1].value)
+				i++
+			default:
+				return ExplainQuery{}, fmt.Errorf("unrecognized explain option %s", v[i].value)
+			}
+
+			if i+1 < end && v[i+1].kind != comma {
+				return ExplainQuery{}, fmt.Errorf("unexpected token %s", v[i+1].value)
+			}
+			i++
+		}
+		i = end + 1
+	} else if isWord(v, i, "analyze") {
+		q.analyze = true
+		i++
+	}
+
+	if i >= len(v) {
+		return ExplainQuery{}, errors.New("missing explained query")
+	}
+
+	if v[i].kind != keyword || (v[i].value != "select" && v[i].value != "with") {
+		return ExplainQuery{}, errors.New("only select queries can be explained")
+	}
+
+	rest := v[i:]
+	query, err := parseSelect(&rest)
+	if err != nil {
+		return ExplainQuery{}, err
+	}
+	q.query = query
+
+	return q, nil
+}
+
+func parseSet(tokens *[]TokenLiteral) (SetQuery, error) {
+	v := *tokens
+	q := SetQuery{}
+	i := 0
+
+	// set
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "set" {
+		return SetQuery{}, errors.New("missing set keyword")
+	}
+	i++
+
+	// setting name
+	if i >= len(v) || v[i].kind != symbol {
+		return SetQuery{}, errors.New("missing setting name")
+	}
+
+	q.name = v[i].value
+	i++
+
+	// = or to
+	if i >= len(v) || (v[i].kind != equal && !(v[i].kind == symbol && v[i].value == "to")) {
+		return SetQuery{}, errors.New("missing setting assignment")
+	}
+	i++
+
+	// setting value, on is a keyword of joins
+	if i >= len(v) || (v[i].kind != symbol && !(v[i].kind == keyword && v[i].value == "on")) {
+		return SetQuery{}, errors.New("missing setting value")
+	}
+
+	q.value = unquote(v[i].value)
+
+	return q, nil
+}
+
+func parseShow(tokens *[]TokenLiteral) (ShowQuery, error) {
+	v := *tokens
+	i := 0
+
+	// show
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "show" {
+		return ShowQuery{}, errors.New("missing show keyword")
+	}
+	i++
+
+	// setting name
+	if i >= len(v) || v[i].kind != symbol {
+		return ShowQuery{}, errors.New("missing setting name")
+	}
+
+	return ShowQuery{name: v[i].value}, nil
+}
+
+// parsePrepare reads prepare name [(type[, type])] as statement
+func parsePrepare(tokens *[]TokenLiteral) (PrepareQuery, error) {
+	v := *tokens
+	q := PrepareQuery{}
+	i := 1
+
+	if i >= len(v) || v[i].kind != symbol {
+		return PrepareQuery{}, errors.New("missing prepared statement name")
+	}
+	q.name = v[i].value
+	i++
+
+	if i < len(v) && v[i].kind == openingroundbracket {
+		end := findClosingBracket(v, i)
+		if end == -1 {
+			return PrepareQuery{}, errors.New("missing closing bracket")
+		}
+
+		for i++; i < end; i++ {
+			if v[i].kind != symbol {
+				return PrepareQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+			}
+			q.types = append(q.types, DataType(strings.ToLower(v[i].value)))
+			i++
+
+			if i < end && (v[i].kind != comma || i+1 >= end) {
+				return PrepareQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+			}
+		}
+		i = end + 1
+	}
+
+	if i >= len(v) || v[i].kind != keyword || v[i].value != "as" {
+		return PrepareQuery{}, errors.New("missing as keyword")
+	}
+	i++
+
+	if i >= len(v) {
+		return PrepareQuery{}, errors.New("missing prepared statement")
+	}
+	q.statement = v[i:]
+
+	return q, nil
+}
+
+// parseExecutePrepared reads execute name [(value[, value])], values are literals
+func parseExecutePrepared(tokens *[]TokenLiteral) (ExecutePreparedQuery, error) {
+	v := *tokens
+	q := ExecutePreparedQuery{}
+	i := 1
+
+	if i >= len(v) || v[i].kind != symbol {
+		return ExecutePreparedQuery{}, errors.New("missing prepared statement name")
+	}
+	q.name = v[i].value
+	i++
+
+	if i < len(v) && v[i].kind == openingroundbracket {
+		end := findClosingBracket(v, i)
+		if end == -1 {
+			return ExecutePreparedQuery{}, errors.New("missing closing bracket")
+		}
+
+		for i++; i < end; i++ {
+			if v[i].kind != symbol {
+				return ExecutePreparedQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+			}
+
+			if _, ok := parseOperand(v[i].value).(Literal); !ok {
+				return ExecutePreparedQuery{}, fmt.Errorf("parameter %s is not a literal", v[i].value)
+			}
+			q.args = append(q.args, v[i])
+			i++
+
+			if i < end && (v[i].kind != comma || i+1 >= end) {
+				return ExecutePreparedQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+			}
+		}
+		i = end + 1
+	}
+
+	if i < len(v) {
+		return ExecutePreparedQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
+	}
+
+	return q, nil
+}
+
+// parseDeallocate reads deallocate [prepare] name|all
+func parseDeallocate(tokens *[]TokenLiteral) (DeallocateQuery, error) {
+	v := *tokens
+	i := 1
+
+	if i < len(v) && v[i].kind == keyword && v[i].value == "prepare" {
+		i++
+	}
+
+	q := DeallocateQuery{}
+	switch {
+	case i < len(v) && v[i].kind == keyword && v[i].value == "all":
+		q.all = true
+	case i < len(v) && v[i].kind == symbol:
+		q.name = v[i].value
+	default:
+		return DeallocateQuery{}, errors.New("missing prepared statement name")
+	}
+	i++
+
+	if i < len(v) {
+		return DeallocateQuery{}, fmt.Errorf("unexpected token %s", v[i].value)
 	}
-	// i is incremented by loop
 
 	return q, nil
 }
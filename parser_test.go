@@ -1,4 +1,4 @@
-package main
+package auralis
 
 import (
 	"errors"
@@ -7,6 +7,7 @@ import (
 )
 
 func TestSelectParser(t *testing.T) {
+	limit := int64(10)
 	testCases := map[string]struct {
 		tokens      []TokenLiteral
 		expectedCmd any
@@ -103,6 +104,373 @@ func TestSelectParser(t *testing.T) {
 				},
 			},
 		},
+		"valid select with pattern, list and range conditions": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "name"},
+				{kind: symbol, value: "not"},
+				{kind: keyword, value: "ilike"},
+				{kind: symbol, value: "'a!%%'"},
+				{kind: keyword, value: "escape"},
+				{kind: symbol, value: "'!'"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "in"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "1"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "2"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "age"},
+				{kind: keyword, value: "between"},
+				{kind: symbol, value: "18"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "30"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "name"},
+				{kind: regexmatch, value: "~"},
+				{kind: symbol, value: "'^a'"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				dataColumns: []string{"*"},
+				conditions: []Condition{
+					{target: "name", sign: "not ilike", value: "'a!%%'", escape: "!"},
+					{target: "id", sign: "in", value: []any{"1", "2"}},
+					{target: "age", sign: "between", value: []any{"18", "30"}},
+					{target: "name", sign: "~", value: "'^a'"},
+				},
+			},
+		},
+		"valid select with null conditions": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "name"},
+				{kind: symbol, value: "is"},
+				{kind: symbol, value: "null"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "age"},
+				{kind: symbol, value: "is"},
+				{kind: symbol, value: "not"},
+				{kind: symbol, value: "null"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				dataColumns: []string{"*"},
+				conditions: []Condition{
+					{target: "name", sign: "is null"},
+					{target: "age", sign: "is not null"},
+				},
+			},
+		},
+		"valid select with join": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "u.id"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: symbol, value: "u"},
+				{kind: keyword, value: "join"},
+				{kind: symbol, value: "orders"},
+				{kind: keyword, value: "as"},
+				{kind: symbol, value: "o"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "o.user_id"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "u.id"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "o.id"},
+				{kind: greater, value: ">"},
+				{kind: symbol, value: "1"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				alias:       "u",
+				dataColumns: []string{"u.id"},
+				joins: []JoinClause{
+					{
+						source: SchemaTable[string, string]{"dbo", "orders"},
+						alias:  "o",
+						conditions: []Condition{
+							{target: "o.user_id", sign: "=", value: "u.id"},
+							{target: "o.id", sign: ">", value: "1"},
+						},
+					},
+				},
+			},
+		},
+		"valid recursive common table expression": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "with"},
+				{kind: keyword, value: "recursive"},
+				{kind: symbol, value: "t"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "n"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "as"},
+				{kind: openingroundbracket, value: "("},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "union"},
+				{kind: keyword, value: "all"},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "t"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "n"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "t"},
+			},
+			expectedCmd: SelectQuery{
+				ctes: []CommonTableExpression{
+					{
+						name:      "t",
+						columns:   []string{"n"},
+						recursive: true,
+						query: SelectQuery{
+							source:      SchemaTable[string, string]{"dbo", "users"},
+							dataColumns: []string{"id"},
+							unionAll:    true,
+							union: &SelectQuery{
+								source:      SchemaTable[string, string]{"dbo", "t"},
+								dataColumns: []string{"id"},
+							},
+						},
+					},
+				},
+				source:      SchemaTable[string, string]{"dbo", "t"},
+				dataColumns: []string{"n"},
+			},
+		},
+		"valid select with window function and order by": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "id"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "sum"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "amount"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "over"},
+				{kind: openingroundbracket, value: "("},
+				{kind: keyword, value: "partition"},
+				{kind: keyword, value: "by"},
+				{kind: symbol, value: "region"},
+				{kind: keyword, value: "order"},
+				{kind: keyword, value: "by"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "desc"},
+				{kind: keyword, value: "rows"},
+				{kind: keyword, value: "between"},
+				{kind: symbol, value: "2"},
+				{kind: keyword, value: "preceding"},
+				{kind: keyword, value: "and"},
+				{kind: keyword, value: "current"},
+				{kind: keyword, value: "row"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "as"},
+				{kind: symbol, value: "total"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "sales"},
+				{kind: keyword, value: "order"},
+				{kind: keyword, value: "by"},
+				{kind: symbol, value: "total"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "sales"},
+				dataColumns: []string{"id", "total"},
+				projections: []Expression{
+					ColumnReference{name: "id"},
+					FunctionCall{
+						name: "sum",
+						args: []Expression{ColumnReference{name: "amount"}},
+						over: &WindowDefinition{
+							partitionBy: []Expression{ColumnReference{name: "region"}},
+							orderBy:     []OrderByItem{{expression: ColumnReference{name: "id"}, descending: true}},
+							frame: &WindowFrame{
+								start: FrameBound{kind: offsetPreceding, offset: 2},
+								end:   FrameBound{kind: currentRow},
+							},
+						},
+					},
+				},
+				orderBy: []OrderByItem{{expression: ColumnReference{name: "total"}}},
+			},
+		},
+		"window frame starting after its end": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "count"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "*"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "over"},
+				{kind: openingroundbracket, value: "("},
+				{kind: keyword, value: "rows"},
+				{kind: keyword, value: "between"},
+				{kind: keyword, value: "current"},
+				{kind: keyword, value: "row"},
+				{kind: keyword, value: "and"},
+				{kind: symbol, value: "1"},
+				{kind: keyword, value: "preceding"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "sales"},
+			},
+			expectedCmd: SelectQuery{},
+			expectedErr: errors.New("invalid window frame bounds"),
+		},
+		"valid select with searched case": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: keyword, value: "case"},
+				{kind: keyword, value: "when"},
+				{kind: symbol, value: "age"},
+				{kind: less, value: "<"},
+				{kind: symbol, value: "18"},
+				{kind: keyword, value: "then"},
+				{kind: symbol, value: "'minor'"},
+				{kind: keyword, value: "else"},
+				{kind: symbol, value: "name"},
+				{kind: keyword, value: "end"},
+				{kind: keyword, value: "as"},
+				{kind: symbol, value: "label"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				dataColumns: []string{"label"},
+				projections: []Expression{
+					CaseExpression{
+						branches: []CaseBranch{{
+							conditions: []Condition{
+								{target: "age", expr: ColumnReference{name: "age"}, sign: "<", value: "18"},
+							},
+							result: Literal{value: "minor"},
+						}},
+						fallback: ColumnReference{name: "name"},
+					},
+				},
+			},
+		},
+		"case without end keyword": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: keyword, value: "case"},
+				{kind: symbol, value: "age"},
+				{kind: keyword, value: "when"},
+				{kind: symbol, value: "18"},
+				{kind: keyword, value: "then"},
+				{kind: symbol, value: "'adult'"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+			},
+			expectedCmd: SelectQuery{},
+			expectedErr: errors.New("missing end keyword"),
+		},
+		"common table expression without as keyword": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "with"},
+				{kind: symbol, value: "t"},
+				{kind: openingroundbracket, value: "("},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: SelectQuery{},
+			expectedErr: errors.New("invalid common table expression columns"),
+		},
+		"select for update": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "id"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "1"},
+				{kind: keyword, value: "for"},
+				{kind: keyword, value: "update"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				dataColumns: []string{"*"},
+				conditions:  []Condition{{target: "id", sign: "=", value: "1"}},
+				lockMode:    exclusive,
+			},
+		},
+		"select for without lock strength": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "for"},
+			},
+			expectedCmd: SelectQuery{},
+			expectedErr: errors.New("missing for update or for share lock strength"),
+		},
+		"select with limit and offset": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: symbol, value: "limit"},
+				{kind: symbol, value: "10"},
+				{kind: symbol, value: "offset"},
+				{kind: symbol, value: "20"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				dataColumns: []string{"*"},
+				limit:       &limit,
+				offset:      20,
+			},
+		},
+		"select with limit all": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: symbol, value: "limit"},
+				{kind: keyword, value: "all"},
+			},
+			expectedCmd: SelectQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				dataColumns: []string{"*"},
+			},
+		},
+		"select with negative offset": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: symbol, value: "offset"},
+				{kind: symbol, value: "-1"},
+			},
+			expectedCmd: SelectQuery{},
+			expectedErr: errors.New("invalid offset value -1"),
+		},
 	}
 	for test, tC := range testCases {
 		t.Run(test, func(t *testing.T) {
@@ -221,6 +589,94 @@ func TestInsertParser(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		"valid insert with multiple value tuples": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "insert"},
+				{kind: keyword, value: "into"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "values"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "1"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "'a'"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: comma, value: ","},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "2"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "default"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: InsertQuery{
+				source: SchemaTable[string, string]{"dbo", "users"},
+				values: [][]any{
+					{"1", "'a'"},
+					{"2", "default"},
+				},
+			},
+			expectedErr: nil,
+		},
+		"insert with unclosed value tuple": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "insert"},
+				{kind: keyword, value: "into"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "values"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "1"},
+				{kind: comma, value: ","},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "2"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: InsertQuery{},
+			expectedErr: errors.New("invalid values"),
+		},
+		"valid insert with conflict do nothing": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "insert"},
+				{kind: keyword, value: "into"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "values"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "1"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "conflict"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "id"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: symbol, value: "do"},
+				{kind: symbol, value: "nothing"},
+			},
+			expectedCmd: InsertQuery{
+				source:     SchemaTable[string, string]{"dbo", "users"},
+				values:     [][]any{{"1"}},
+				onConflict: &OnConflictClause{columns: []string{"id"}},
+			},
+			expectedErr: nil,
+		},
+		"insert with conflict do update without target": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "insert"},
+				{kind: keyword, value: "into"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "values"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "1"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "conflict"},
+				{kind: symbol, value: "do"},
+				{kind: keyword, value: "update"},
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "id"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "2"},
+			},
+			expectedCmd: InsertQuery{},
+			expectedErr: errors.New("on conflict do update requires conflict target columns"),
+		},
 	}
 	for test, tC := range testCases {
 		t.Run(test, func(t *testing.T) {
@@ -279,17 +735,774 @@ func TestCreateParser(t *testing.T) {
 			},
 			expectedCmd: CreateTableQuery{
 				source: SchemaTable[string, string]{"dbo", "users"},
-				columns: map[string][]string{
-					"age":  {"smallint"},
-					"name": {"varchar", "not", "null"},
+				columns: []ColumnDefinition{
+					{name: "age", attributes: []string{"smallint"}},
+					{name: "name", attributes: []string{"varchar", "not", "null"}},
 				},
+				constraints: []Constraint{{kind: notNullConstraint, columns: []string{"name"}}},
 			},
 		},
-	}
-	for test, tC := range testCases {
-		t.Run(test, func(t *testing.T) {
-			cmd, err := ParseTokens(tC.tokens)
-			// TODO: compare constant errors
+		"create table with column and table constraints": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: keyword, value: "table"},
+				{kind: symbol, value: "items"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "id"},
+				{kind: symbol, value: "smallint"},
+				{kind: symbol, value: "PRIMARY"},
+				{kind: symbol, value: "KEY"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "price"},
+				{kind: symbol, value: "smallint"},
+				{kind: symbol, value: "CHECK"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "price"},
+				{kind: greater, value: ">"},
+				{kind: symbol, value: "0"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "CONSTRAINT"},
+				{kind: symbol, value: "items_id_price"},
+				{kind: symbol, value: "UNIQUE"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "id"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "price"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: CreateTableQuery{
+				source: SchemaTable[string, string]{"dbo", "items"},
+				columns: []ColumnDefinition{
+					{name: "id", attributes: []string{"smallint", "PRIMARY", "KEY"}},
+					{name: "price", attributes: []string{"smallint", "CHECK"}},
+				},
+				constraints: []Constraint{
+					{kind: primaryKeyConstraint, columns: []string{"id"}},
+					{
+						kind:       checkConstraint,
+						columns:    []string{"price"},
+						conditions: []Condition{{target: "price", sign: ">", value: "0"}},
+						clause:     "price > 0",
+					},
+					{name: "items_id_price", kind: uniqueConstraint, columns: []string{"id", "price"}},
+				},
+			},
+		},
+		"create table with foreign keys": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: keyword, value: "table"},
+				{kind: symbol, value: "items"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "owner"},
+				{kind: symbol, value: "smallint"},
+				{kind: symbol, value: "REFERENCES"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "on"},
+				{kind: keyword, value: "delete"},
+				{kind: symbol, value: "CASCADE"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "tag"},
+				{kind: symbol, value: "varchar"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "FOREIGN"},
+				{kind: symbol, value: "KEY"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "owner"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "tag"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: symbol, value: "REFERENCES"},
+				{kind: symbol, value: "app.tags"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "user_id"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "name"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "on"},
+				{kind: keyword, value: "update"},
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "NULL"},
+				{kind: keyword, value: "on"},
+				{kind: keyword, value: "delete"},
+				{kind: symbol, value: "RESTRICT"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: CreateTableQuery{
+				source: SchemaTable[string, string]{"dbo", "items"},
+				columns: []ColumnDefinition{
+					{name: "owner", attributes: []string{"smallint", "REFERENCES"}},
+					{name: "tag", attributes: []string{"varchar"}},
+				},
+				constraints: []Constraint{
+					{
+						kind:       foreignKeyConstraint,
+						columns:    []string{"owner"},
+						references: SchemaTable[string, string]{"dbo", "users"},
+						onDelete:   cascadeAction,
+						onUpdate:   noAction,
+					},
+					{
+						kind:              foreignKeyConstraint,
+						columns:           []string{"owner", "tag"},
+						references:        SchemaTable[string, string]{"app", "tags"},
+						referencedColumns: []string{"user_id", "name"},
+						onDelete:          restrictAction,
+						onUpdate:          setNullAction,
+					},
+				},
+			},
+		},
+		"create table with unclosed check": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: keyword, value: "table"},
+				{kind: symbol, value: "items"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "price"},
+				{kind: symbol, value: "smallint"},
+				{kind: symbol, value: "check"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "price"},
+				{kind: greater, value: ">"},
+				{kind: symbol, value: "0"},
+				{kind: comma, value: ","},
+			},
+			expectedCmd: CreateTableQuery{},
+			expectedErr: errors.New("missing closing bracket"),
+		},
+		"create table with default and identity": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: keyword, value: "table"},
+				{kind: symbol, value: "items"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "id"},
+				{kind: symbol, value: "bigint"},
+				{kind: symbol, value: "generated"},
+				{kind: keyword, value: "by"},
+				{kind: symbol, value: "default"},
+				{kind: keyword, value: "as"},
+				{kind: symbol, value: "identity"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "start"},
+				{kind: keyword, value: "with"},
+				{kind: symbol, value: "10"},
+				{kind: symbol, value: "maxvalue"},
+				{kind: symbol, value: "99"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "name"},
+				{kind: symbol, value: "varchar"},
+				{kind: symbol, value: "default"},
+				{kind: symbol, value: "'none'"},
+				{kind: symbol, value: "not"},
+				{kind: symbol, value: "null"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: CreateTableQuery{
+				source: SchemaTable[string, string]{"dbo", "items"},
+				columns: []ColumnDefinition{
+					{name: "id", attributes: []string{"bigint", "generated"}},
+					{name: "name", attributes: []string{"varchar", "default", "not", "null"}},
+				},
+				constraints: []Constraint{{kind: notNullConstraint, columns: []string{"name"}}},
+				defaults: []ColumnDefault{
+					{
+						column:   "id",
+						identity: identityByDefault,
+						sequence: true,
+						options:  map[string]int64{"start": 10, "maxvalue": 99},
+					},
+					{column: "name", expr: Literal{value: "none"}, clause: "'none'"},
+				},
+			},
+		},
+		"create sequence with options": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: symbol, value: "sequence"},
+				{kind: symbol, value: "app.ids"},
+				{kind: keyword, value: "as"},
+				{kind: symbol, value: "integer"},
+				{kind: symbol, value: "increment"},
+				{kind: keyword, value: "by"},
+				{kind: symbol, value: "-1"},
+				{kind: symbol, value: "minvalue"},
+				{kind: symbol, value: "-100"},
+			},
+			expectedCmd: CreateSequenceQuery{
+				name:     SchemaTable[string, string]{"app", "ids"},
+				dataType: integer,
+				options:  map[string]int64{"increment": -1, "minvalue": -100},
+			},
+		},
+		"create sequence with invalid option": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: symbol, value: "sequence"},
+				{kind: symbol, value: "ids"},
+				{kind: symbol, value: "start"},
+				{kind: symbol, value: "one"},
+			},
+			expectedCmd: CreateSequenceQuery{},
+			expectedErr: errors.New("invalid value of start option"),
+		},
+		"create unique index of two columns": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: symbol, value: "UNIQUE"},
+				{kind: symbol, value: "INDEX"},
+				{kind: symbol, value: "users_name_age"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "app.users"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "name"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "age"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: CreateIndexQuery{
+				name:    "users_name_age",
+				source:  SchemaTable[string, string]{"app", "users"},
+				columns: []string{"name", "age"},
+				unique:  true,
+			},
+		},
+		"create hash index": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: symbol, value: "index"},
+				{kind: symbol, value: "users_id"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "users"},
+				{kind: symbol, value: "USING"},
+				{kind: symbol, value: "HASH"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "id"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: CreateIndexQuery{
+				name:    "users_id",
+				source:  SchemaTable[string, string]{"dbo", "users"},
+				columns: []string{"id"},
+				method:  "hash",
+			},
+		},
+		"create index of unknown method": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: symbol, value: "index"},
+				{kind: symbol, value: "users_id"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "users"},
+				{kind: symbol, value: "using"},
+				{kind: symbol, value: "gist"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "id"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: CreateIndexQuery{},
+			expectedErr: errors.New("unsupported index method gist"),
+		},
+		"create index without columns": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: symbol, value: "index"},
+				{kind: symbol, value: "users_age"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "users"},
+			},
+			expectedCmd: CreateIndexQuery{},
+			expectedErr: errors.New("missing index columns"),
+		},
+		"create index without closing bracket": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "create"},
+				{kind: symbol, value: "index"},
+				{kind: symbol, value: "users_age"},
+				{kind: keyword, value: "on"},
+				{kind: symbol, value: "users"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "age"},
+			},
+			expectedCmd: CreateIndexQuery{},
+			expectedErr: errors.New("missing closing bracket"),
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			cmd, err := ParseTokens(tC.tokens)
+			// TODO: compare constant errors
+			if err != nil && err.Error() != tC.expectedErr.Error() {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			} else if !reflect.DeepEqual(cmd, tC.expectedCmd) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func TestModificationParser(t *testing.T) {
+	testCases := map[string]struct {
+		tokens      []TokenLiteral
+		expectedCmd any
+		expectedErr error
+	}{
+		"update with expression and condition": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "update"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "name"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "upper"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "name"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "age"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "'18'"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "age"},
+				{kind: less, value: "<"},
+				{kind: symbol, value: "18"},
+			},
+			expectedCmd: UpdateQuery{
+				source: SchemaTable[string, string]{"dbo", "users"},
+				assignments: []Assignment{
+					{column: "name", value: FunctionCall{name: "upper", args: []Expression{ColumnReference{name: "name"}}}},
+					{column: "age", value: Literal{value: "18"}},
+				},
+				conditions: []Condition{{target: "age", sign: "<", value: "18"}},
+			},
+		},
+		"update without assignment": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "update"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "set"},
+				{kind: keyword, value: "where"},
+			},
+			expectedCmd: UpdateQuery{},
+			expectedErr: errors.New("invalid column assignment"),
+		},
+		"delete all rows": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "delete"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "auralis.tables"},
+			},
+			expectedCmd: DeleteQuery{source: SchemaTable[string, string]{"auralis", "tables"}},
+		},
+		"delete returning columns": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "delete"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "age"},
+				{kind: less, value: "<"},
+				{kind: symbol, value: "18"},
+				{kind: symbol, value: "RETURNING"},
+				{kind: symbol, value: "id"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "upper"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "name"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "as"},
+				{kind: symbol, value: "title"},
+			},
+			expectedCmd: DeleteQuery{
+				source:     SchemaTable[string, string]{"dbo", "users"},
+				conditions: []Condition{{target: "age", sign: "<", value: "18"}},
+				returning: ReturningClause{
+					names: []string{"id", "title"},
+					projections: []Expression{
+						ColumnReference{name: "id"},
+						FunctionCall{name: "upper", args: []Expression{ColumnReference{name: "name"}}},
+					},
+				},
+			},
+		},
+		"update returning all columns": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "update"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "age"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "18"},
+				{kind: symbol, value: "returning"},
+				{kind: symbol, value: "*"},
+			},
+			expectedCmd: UpdateQuery{
+				source:      SchemaTable[string, string]{"dbo", "users"},
+				assignments: []Assignment{{column: "age", value: Literal{value: int64(18)}}},
+				returning: ReturningClause{
+					names:       []string{"*"},
+					projections: []Expression{ColumnReference{name: "*"}},
+				},
+			},
+		},
+		"update with incomplete returning": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "update"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "age"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "18"},
+				{kind: symbol, value: "returning"},
+			},
+			expectedCmd: UpdateQuery{},
+			expectedErr: errors.New("missing expression"),
+		},
+		"delete without from": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "delete"},
+				{kind: symbol, value: "users"},
+			},
+			expectedCmd: DeleteQuery{},
+			expectedErr: errors.New("missing delete from keywords"),
+		},
+		"begin work": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "begin"},
+				{kind: symbol, value: "work"},
+			},
+			expectedCmd: TransactionQuery{command: "begin"},
+		},
+		"rollback to savepoint": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "rollback"},
+				{kind: symbol, value: "to"},
+				{kind: keyword, value: "savepoint"},
+				{kind: symbol, value: "s1"},
+			},
+			expectedCmd: TransactionQuery{command: "rollback to", savepoint: "s1"},
+		},
+		"begin with isolation level": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "begin"},
+				{kind: symbol, value: "transaction"},
+				{kind: symbol, value: "isolation"},
+				{kind: symbol, value: "level"},
+				{kind: symbol, value: "repeatable"},
+				{kind: symbol, value: "read"},
+			},
+			expectedCmd: TransactionQuery{command: "begin", isolationLevel: repeatableRead},
+		},
+		"set transaction isolation level": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "TRANSACTION"},
+				{kind: symbol, value: "ISOLATION"},
+				{kind: symbol, value: "LEVEL"},
+				{kind: symbol, value: "SERIALIZABLE"},
+			},
+			expectedCmd: TransactionQuery{command: "set transaction", isolationLevel: serializable},
+		},
+		"unsupported isolation level": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "transaction"},
+				{kind: symbol, value: "isolation"},
+				{kind: symbol, value: "level"},
+				{kind: symbol, value: "snapshot"},
+			},
+			expectedCmd: TransactionQuery{},
+			expectedErr: errors.New("unsupported isolation level snapshot"),
+		},
+		"lock tables in share row exclusive mode": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "lock"},
+				{kind: keyword, value: "table"},
+				{kind: symbol, value: "users"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "auralis.tables"},
+				{kind: keyword, value: "in"},
+				{kind: symbol, value: "SHARE"},
+				{kind: keyword, value: "row"},
+				{kind: symbol, value: "EXCLUSIVE"},
+				{kind: symbol, value: "MODE"},
+			},
+			expectedCmd: LockTableQuery{
+				sources: []SchemaTable[string, string]{{"dbo", "users"}, {"auralis", "tables"}},
+				mode:    sharedIntentionExclusive,
+			},
+		},
+		"lock table in unknown mode": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "lock"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "in"},
+				{kind: symbol, value: "update"},
+				{kind: symbol, value: "mode"},
+			},
+			expectedCmd: LockTableQuery{},
+			expectedErr: errors.New("invalid lock mode"),
+		},
+		"vacuum full of tables": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "vacuum"},
+				{kind: symbol, value: "FULL"},
+				{kind: symbol, value: "users"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "auralis.tables"},
+			},
+			expectedCmd: VacuumQuery{
+				sources: []SchemaTable[string, string]{{"dbo", "users"}, {"auralis", "tables"}},
+				full:    true,
+			},
+		},
+		"vacuum of all tables": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "vacuum"},
+			},
+			expectedCmd: VacuumQuery{},
+		},
+		"vacuum with trailing comma": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "vacuum"},
+				{kind: symbol, value: "users"},
+				{kind: comma, value: ","},
+			},
+			expectedCmd: VacuumQuery{},
+			expectedErr: errors.New("unexpected token ,"),
+		},
+		"analyze of tables": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "analyze"},
+				{kind: symbol, value: "users"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "sales.orders"},
+			},
+			expectedCmd: AnalyzeQuery{sources: []SchemaTable[string, string]{{"dbo", "users"}, {"sales", "orders"}}},
+		},
+		"analyze with trailing comma": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "analyze"},
+				{kind: symbol, value: "users"},
+				{kind: comma, value: ","},
+			},
+			expectedCmd: AnalyzeQuery{},
+			expectedErr: errors.New("unexpected token ,"),
+		},
+		"set setting on": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "set"},
+				{kind: symbol, value: "autovacuum"},
+				{kind: equal, value: "="},
+				{kind: keyword, value: "on"},
+			},
+			expectedCmd: SetQuery{name: "autovacuum", value: "on"},
+		},
+		"savepoint without name": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "savepoint"},
+			},
+			expectedCmd: TransactionQuery{},
+			expectedErr: errors.New("missing savepoint name"),
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			cmd, err := ParseTokens(tC.tokens)
+			if err != nil && err.Error() != tC.expectedErr.Error() {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			} else if !reflect.DeepEqual(cmd, tC.expectedCmd) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func TestExplainParser(t *testing.T) {
+	testCases := map[string]struct {
+		tokens      []TokenLiteral
+		expectedCmd any
+		expectedErr error
+	}{
+		"explain analyze": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "explain"},
+				{kind: keyword, value: "analyze"},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "*"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+			},
+			expectedCmd: ExplainQuery{
+				query:   SelectQuery{source: SchemaTable[string, string]{"dbo", "users"}, dataColumns: []string{"*"}},
+				analyze: true,
+				format:  "text",
+			},
+		},
+		"explain with options": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "explain"},
+				{kind: openingroundbracket, value: "("},
+				{kind: keyword, value: "analyze"},
+				{kind: symbol, value: "false"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "format"},
+				{kind: symbol, value: "json"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+			},
+			expectedCmd: ExplainQuery{
+				query:  SelectQuery{source: SchemaTable[string, string]{"dbo", "users"}, dataColumns: []string{"id"}},
+				format: "json",
+			},
+		},
+		"explain with unknown format": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "explain"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "format"},
+				{kind: symbol, value: "xml"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+			},
+			expectedCmd: ExplainQuery{},
+			expectedErr: errors.New("explain format must be text or json"),
+		},
+		"explain of insert": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "explain"},
+				{kind: keyword, value: "insert"},
+				{kind: keyword, value: "into"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "values"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "1"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: ExplainQuery{},
+			expectedErr: errors.New("only select queries can be explained"),
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			cmd, err := ParseTokens(tC.tokens)
+			if err != nil && err.Error() != tC.expectedErr.Error() {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			} else if !reflect.DeepEqual(cmd, tC.expectedCmd) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func TestPreparedStatementParser(t *testing.T) {
+	testCases := map[string]struct {
+		tokens      []TokenLiteral
+		expectedCmd any
+		expectedErr error
+	}{
+		"prepare with types": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "prepare"},
+				{kind: symbol, value: "by_age"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "smallint"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "varchar"},
+				{kind: closingroundbracket, value: ")"},
+				{kind: keyword, value: "as"},
+				{kind: keyword, value: "select"},
+				{kind: symbol, value: "id"},
+				{kind: keyword, value: "from"},
+				{kind: symbol, value: "users"},
+				{kind: keyword, value: "where"},
+				{kind: symbol, value: "age"},
+				{kind: equal, value: "="},
+				{kind: symbol, value: "$1"},
+			},
+			expectedCmd: PrepareQuery{
+				name:  "by_age",
+				types: []DataType{smallint, varchar},
+				statement: []TokenLiteral{
+					{kind: keyword, value: "select"},
+					{kind: symbol, value: "id"},
+					{kind: keyword, value: "from"},
+					{kind: symbol, value: "users"},
+					{kind: keyword, value: "where"},
+					{kind: symbol, value: "age"},
+					{kind: equal, value: "="},
+					{kind: symbol, value: "$1"},
+				},
+			},
+		},
+		"prepare without statement": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "prepare"},
+				{kind: symbol, value: "by_age"},
+				{kind: keyword, value: "as"},
+			},
+			expectedCmd: PrepareQuery{},
+			expectedErr: errors.New("missing prepared statement"),
+		},
+		"execute with arguments": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "execute"},
+				{kind: symbol, value: "by_age"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "18"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "'bob'"},
+				{kind: comma, value: ","},
+				{kind: symbol, value: "null"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: ExecutePreparedQuery{
+				name: "by_age",
+				args: []TokenLiteral{{kind: symbol, value: "18"}, {kind: symbol, value: "'bob'"}, {kind: symbol, value: "null"}},
+			},
+		},
+		"execute with column argument": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "execute"},
+				{kind: symbol, value: "by_age"},
+				{kind: openingroundbracket, value: "("},
+				{kind: symbol, value: "age"},
+				{kind: closingroundbracket, value: ")"},
+			},
+			expectedCmd: ExecutePreparedQuery{},
+			expectedErr: errors.New("parameter age is not a literal"),
+		},
+		"deallocate all": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "deallocate"},
+				{kind: keyword, value: "prepare"},
+				{kind: keyword, value: "all"},
+			},
+			expectedCmd: DeallocateQuery{all: true},
+		},
+		"deallocate with trailing token": {
+			tokens: []TokenLiteral{
+				{kind: keyword, value: "deallocate"},
+				{kind: symbol, value: "by_age"},
+				{kind: symbol, value: "x"},
+			},
+			expectedCmd: DeallocateQuery{},
+			expectedErr: errors.New("unexpected token x"),
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			cmd, err := ParseTokens(tC.tokens)
 			if err != nil && err.Error() != tC.expectedErr.Error() {
 				t.Errorf("\nexp %+v\ngot %+v", tC.expectedErr, err)
 			} else if !reflect.DeepEqual(cmd, tC.expectedCmd) {
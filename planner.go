@@ -0,0 +1,700 @@
+package auralis
+
+import (
+	"maps"
+	"slices"
+)
+
+// LogicalPlan is node of relational operator tree built from select query,
+// columns describe rows produced by the node
+type LogicalPlan interface {
+	columns() []Column
+	inputs() []LogicalPlan
+}
+
+// ScanPlan reads rows of table, rows of common table expression or single
+// empty row of select without source. Conditions comparing columns with
+// literals are evaluated during the scan
+type ScanPlan struct {
+	source     SchemaTable[string, string]
+	qualifier  string   // alias or name of source
+	table      *Table   // nil when source isn't table
+	rows       []Row    // rows of source which isn't table
+	output     []Column // columns read from source
+	conditions []Condition
+	lockMode   LockMode
+	statistics *TableStatistics // nil when table wasn't analyzed
+	unordered  bool             // rows are sorted later so they may be scanned in any order
+}
+
+type FilterPlan struct {
+	input      LogicalPlan
+	conditions []Condition
+}
+
+type JoinPlan struct {
+	left       LogicalPlan
+	right      LogicalPlan
+	conditions []Condition
+}
+
+// AggregatePlan groups rows, output contains grouping keys followed by results
+// of aggregates
+type AggregatePlan struct {
+	input      LogicalPlan
+	groupBy    []Expression
+	aggregates []FunctionCall
+	output     []Column
+}
+
+// WindowPlan extends rows with results of window functions
+type WindowPlan struct {
+	input  LogicalPlan
+	calls  []FunctionCall
+	output []Column // columns of window function results
+}
+
+type SortPlan struct {
+	input   LogicalPlan
+	orderBy []OrderByItem
+}
+
+type ProjectPlan struct {
+	input       LogicalPlan
+	projections []Expression
+	output      []Column
+}
+
+type LimitPlan struct {
+	input  LogicalPlan
+	limit  *int64 // nil for all rows
+	offset int64
+}
+
+type UnionPlan struct {
+	left  LogicalPlan
+	right LogicalPlan
+	all   bool
+}
+
+func (p ScanPlan) columns() []Column      { return p.output }
+func (p FilterPlan) columns() []Column    { return p.input.columns() }
+func (p JoinPlan) columns() []Column      { return slices.Concat(p.left.columns(), p.right.columns()) }
+func (p AggregatePlan) columns() []Column { return p.output }
+func (p WindowPlan) columns() []Column    { return slices.Concat(p.input.columns(), p.output) }
+func (p SortPlan) columns() []Column      { return p.input.columns() }
+func (p ProjectPlan) columns() []Column   { return p.output }
+func (p LimitPlan) columns() []Column     { return p.input.columns() }
+func (p UnionPlan) columns() []Column     { return p.left.columns() }
+
+func (p ScanPlan) inputs() []LogicalPlan      { return nil }
+func (p FilterPlan) inputs() []LogicalPlan    { return []LogicalPlan{p.input} }
+func (p JoinPlan) inputs() []LogicalPlan      { return []LogicalPlan{p.left, p.right} }
+func (p AggregatePlan) inputs() []LogicalPlan { return []LogicalPlan{p.input} }
+func (p WindowPlan) inputs() []LogicalPlan    { return []LogicalPlan{p.input} }
+func (p SortPlan) inputs() []LogicalPlan      { return []LogicalPlan{p.input} }
+func (p ProjectPlan) inputs() []LogicalPlan   { return []LogicalPlan{p.input} }
+func (p LimitPlan) inputs() []LogicalPlan     { return []LogicalPlan{p.input} }
+func (p UnionPlan) inputs() []LogicalPlan     { return []LogicalPlan{p.left, p.right} }
+
+// planSelect builds logical plan of select query, common table expressions of
+// the query are materialized so they are scanned like tables. Expressions are
+// validated against columns of their input
+func planSelect(tx *Transaction, query SelectQuery, ctes map[string]*DataSet) (LogicalPlan, error) {
+	if len(query.ctes) > 0 {
+		scope := maps.Clone(ctes)
+		for _, cte := range query.ctes {
+			dataSet, err := materializeCommonTableExpression(tx, cte, scope)
+			if err != nil {
+				return nil, err
+			}
+
+			scope[cte.name] = dataSet
+		}
+		ctes = scope
+	}
+
+	var plan LogicalPlan
+	plan, err := planSource(tx, query.source, query.alias, query.lockMode, ctes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, join := range query.joins {
+		right, err := planSource(tx, join.source, join.alias, query.lockMode, ctes)
+		if err != nil {
+			return nil, err
+		}
+
+		plan = JoinPlan{left: plan, right: right, conditions: join.conditions}
+	}
+
+	projections := selectProjections(query)
+	orderBy := resolveOrderBy(query.orderBy, query.dataColumns, projections)
+
+	exprs := slices.Concat(projections, query.groupBy, orderByExpressions(orderBy))
+	for _, condition := range query.conditions {
+		if condition.expr != nil {
+			exprs = append(exprs, condition.expr)
+		} else {
+			exprs = append(exprs, ColumnReference{name: condition.target})
+		}
+
+		if expr, ok := condition.value.(Expression); ok {
+			exprs = append(exprs, expr)
+		}
+	}
+
+	for _, expr := range exprs {
+		if err := validateExpression(expr, plan.columns()); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(query.conditions) > 0 {
+		plan = FilterPlan{input: plan, conditions: query.conditions}
+	}
+
+	if len(query.groupBy) > 0 || len(collectFunctionCalls(projections, isAggregateCall)) > 0 {
+		aggregates, output, rewritten, rewrittenOrderBy, err := planAggregate(plan.columns(), query.groupBy, projections, orderBy)
+		if err != nil {
+			return nil, err
+		}
+
+		plan = AggregatePlan{input: plan, groupBy: query.groupBy, aggregates: aggregates, output: output}
+		projections, orderBy = rewritten, rewrittenOrderBy
+	}
+
+	if len(collectFunctionCalls(slices.Concat(projections, orderByExpressions(orderBy)), isWindowCall)) > 0 {
+		calls, output, rewritten, rewrittenOrderBy, err := planWindows(plan.columns(), projections, orderBy)
+		if err != nil {
+			return nil, err
+		}
+
+		plan = WindowPlan{input: plan, calls: calls, output: output[len(plan.columns()):]}
+		projections, orderBy = rewritten, rewrittenOrderBy
+	}
+
+	if len(orderBy) > 0 {
+		plan = SortPlan{input: plan, orderBy: orderBy}
+	}
+
+	output, err := projectColumns(plan.columns(), query.dataColumns, projections)
+	if err != nil {
+		return nil, err
+	}
+	plan = ProjectPlan{input: plan, projections: projections, output: output}
+
+	if query.limit != nil || query.offset > 0 {
+		plan = LimitPlan{input: plan, limit: query.limit, offset: query.offset}
+	}
+
+	if query.union != nil {
+		other, err := planSelect(tx, *query.union, ctes)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(other.columns()) != len(plan.columns()) {
+			return nil, ErrUnionColumnsMismatch
+		}
+
+		plan = UnionPlan{left: plan, right: other, all: query.unionAll}
+	}
+
+	return plan, nil
+}
+
+// planSource returns scan of common table expression or table, columns are
+// qualified with alias or source name
+func planSource(tx *Transaction, source SchemaTable[string, string], alias string, lockMode LockMode,
+	ctes map[string]*DataSet) (ScanPlan, error) {
+	scan := ScanPlan{source: source, qualifier: alias, lockMode: lockMode}
+	if scan.qualifier == "" {
+		scan.qualifier = source.name
+	}
+
+	// select without source table produces single row
+	if source == (SchemaTable[string, string]{}) {
+		scan.rows = []Row{{}}
+		return scan, nil
+	}
+
+	var columns []Column
+	if cte, ok := ctes[source.name]; ok && source.schema == defaultScheme {
+		columns, scan.rows = cte.columns, cte.rows
+	} else {
+		table, err := getTable(tx, source)
+		if err != nil {
+			return ScanPlan{}, err
+		}
+
+		if scan.statistics, err = tableStatistics(tx, table); err != nil {
+			return ScanPlan{}, err
+		}
+		columns, scan.table = table.columns, &table
+	}
+
+	scan.output = slices.Clone(columns)
+	for i := range scan.output {
+		scan.output[i].table = scan.qualifier
+	}
+
+	return scan, nil
+}
+
+// optimizePlan applies rewrite rules to logical plan
+func optimizePlan(plan LogicalPlan) LogicalPlan {
+	plan = foldPlanConstants(plan)
+	plan = pushDownPredicates(plan)
+	names := referencedColumns(plan)
+	plan = orderJoins(plan, names == nil)
+	plan = markUnorderedScans(plan, false)
+	return pruneColumns(plan, names)
+}
+
+// markUnorderedScans marks scans below sort whose order isn't changed by
+// limit nor window functions, rows of such scans are sorted anyway
+func markUnorderedScans(plan LogicalPlan, unordered bool) LogicalPlan {
+	switch p := plan.(type) {
+	case ScanPlan:
+		p.unordered = unordered
+		return p
+	case SortPlan:
+		unordered = true
+	case LimitPlan, WindowPlan:
+		unordered = false
+	}
+
+	inputs := []LogicalPlan{}
+	for _, input := range plan.inputs() {
+		inputs = append(inputs, markUnorderedScans(input, unordered))
+	}
+
+	return withInputs(plan, inputs)
+}
+
+// withInputs returns copy of plan node reading given inputs
+func withInputs(plan LogicalPlan, inputs []LogicalPlan) LogicalPlan {
+	switch p := plan.(type) {
+	case FilterPlan:
+		p.input = inputs[0]
+		return p
+	case JoinPlan:
+		p.left, p.right = inputs[0], inputs[1]
+		return p
+	case AggregatePlan:
+		p.input = inputs[0]
+		return p
+	case WindowPlan:
+		p.input = inputs[0]
+		return p
+	case SortPlan:
+		p.input = inputs[0]
+		return p
+	case ProjectPlan:
+		p.input = inputs[0]
+		return p
+	case LimitPlan:
+		p.input = inputs[0]
+		return p
+	case UnionPlan:
+		p.left, p.right = inputs[0], inputs[1]
+		return p
+	default:
+		return plan
+	}
+}
+
+// transformPlan rewrites inputs of plan node first and then the node itself
+func transformPlan(plan LogicalPlan, fn func(LogicalPlan) LogicalPlan) LogicalPlan {
+	inputs := []LogicalPlan{}
+	for _, input := range plan.inputs() {
+		inputs = append(inputs, transformPlan(input, fn))
+	}
+
+	return fn(withInputs(plan, inputs))
+}
+
+// foldPlanConstants replaces calls of immutable functions with constant
+// arguments by their results in expressions of all plan nodes
+func foldPlanConstants(plan LogicalPlan) LogicalPlan {
+	return transformPlan(plan, func(plan LogicalPlan) LogicalPlan {
+		switch p := plan.(type) {
+		case FilterPlan:
+			p.conditions = foldConditions(p.conditions)
+			return p
+		case JoinPlan:
+			p.conditions = foldConditions(p.conditions)
+			return p
+		case AggregatePlan:
+			p.groupBy = foldExpressions(p.groupBy)
+			for i, call := range p.aggregates {
+				call.args = foldExpressions(call.args)
+				p.aggregates[i] = call
+			}
+			return p
+		case SortPlan:
+			p.orderBy = foldOrderBy(p.orderBy)
+			return p
+		case ProjectPlan:
+			p.projections = foldExpressions(p.projections)
+			return p
+		default:
+			return plan
+		}
+	})
+}
+
+func foldExpressions(exprs []Expression) []Expression {
+	folded := make([]Expression, 0, len(exprs))
+	for _, expr := range exprs {
+		folded = append(folded, foldConstants(expr))
+	}
+
+	return folded
+}
+
+func foldOrderBy(items []OrderByItem) []OrderByItem {
+	folded := make([]OrderByItem, 0, len(items))
+	for _, item := range items {
+		folded = append(folded, OrderByItem{expression: foldConstants(item.expression), descending: item.descending})
+	}
+
+	return folded
+}
+
+func foldConditions(conditions []Condition) []Condition {
+	folded := slices.Clone(conditions)
+	for i, condition := range folded {
+		if condition.expr != nil {
+			folded[i].expr = foldConstants(condition.expr)
+		}
+
+		if expr, ok := condition.value.(Expression); ok {
+			folded[i].value = foldConstants(expr)
+		}
+	}
+
+	return folded
+}
+
+// foldConstants evaluates calls of immutable scalar functions whose arguments
+// are literals. Calls failing or producing value of other type than the call
+// are left to be evaluated for each row
+func foldConstants(expr Expression) Expression {
+	folded, _ := rewriteExpression(expr, func(e Expression) (Expression, bool, error) {
+		call, ok := e.(FunctionCall)
+		if !ok || call.over != nil || isAggregateFunction(call.name) {
+			return nil, false, nil
+		}
+
+		call.args = foldExpressions(call.args)
+		fn, ok := scalarFunctions[call.name]
+		if !ok || fn.volatile || slices.ContainsFunc(call.args, func(arg Expression) bool { return !isLiteral(arg) }) {
+			return call, true, nil
+		}
+
+		value, err := evaluateExpression(call, nil, Row{})
+		if err != nil || value == nil || valueDataType(value) != expressionDataType(call, nil) {
+			return call, true, nil
+		}
+
+		return Literal{value: value}, true, nil
+	})
+
+	return folded
+}
+
+func isLiteral(expr Expression) bool {
+	_, ok := expr.(Literal)
+	return ok
+}
+
+// pushDownPredicates moves filter conditions comparing column with literal
+// into scans of sources providing the column, below joins when possible
+func pushDownPredicates(plan LogicalPlan) LogicalPlan {
+	return transformPlan(plan, func(plan LogicalPlan) LogicalPlan {
+		filter, ok := plan.(FilterPlan)
+		if !ok {
+			return plan
+		}
+
+		input, rest := filter.input, []Condition{}
+		for _, condition := range filter.conditions {
+			pushed, ok := pushCondition(input, condition)
+			if !ok {
+				rest = append(rest, condition)
+				continue
+			}
+
+			input = pushed
+		}
+
+		if len(rest) == 0 {
+			return input
+		}
+
+		return FilterPlan{input: input, conditions: rest}
+	})
+}
+
+// pushCondition adds condition into scan below plan whose columns contain
+// the condition target, targets present in both inputs of join stay above it
+func pushCondition(plan LogicalPlan, condition Condition) (LogicalPlan, bool) {
+	if _, ok := condition.value.(Expression); ok || condition.expr != nil || isColumnReference(condition.value) {
+		return plan, false
+	}
+
+	if _, err := resolveColumn(plan.columns(), condition.target); err != nil {
+		return plan, false
+	}
+
+	switch p := plan.(type) {
+	case ScanPlan:
+		p.conditions = append(slices.Clone(p.conditions), condition)
+		return p, true
+	case FilterPlan:
+		input, ok := pushCondition(p.input, condition)
+		p.input = input
+		return p, ok
+	case JoinPlan:
+		if left, ok := pushCondition(p.left, condition); ok {
+			p.left = left
+			return p, true
+		}
+
+		if right, ok := pushCondition(p.right, condition); ok {
+			p.right = right
+			return p, true
+		}
+	}
+
+	return plan, false
+}
+
+// referencedColumns returns names of columns referenced by expressions and
+// conditions of plan, nil when all columns are referenced by *
+func referencedColumns(plan LogicalPlan) []string {
+	exprs := []Expression{}
+	conditions := []Condition{}
+	var collect func(plan LogicalPlan)
+	collect = func(plan LogicalPlan) {
+		switch p := plan.(type) {
+		case ScanPlan:
+			conditions = append(conditions, p.conditions...)
+		case FilterPlan:
+			conditions = append(conditions, p.conditions...)
+		case JoinPlan:
+			conditions = append(conditions, p.conditions...)
+		case AggregatePlan:
+			exprs = append(exprs, p.groupBy...)
+			for _, call := range p.aggregates {
+				exprs = append(exprs, call)
+			}
+		case WindowPlan:
+			for _, call := range p.calls {
+				exprs = append(exprs, call)
+			}
+		case SortPlan:
+			exprs = append(exprs, orderByExpressions(p.orderBy)...)
+		case ProjectPlan:
+			exprs = append(exprs, p.projections...)
+		}
+
+		for _, input := range plan.inputs() {
+			collect(input)
+		}
+	}
+	collect(plan)
+
+	for _, condition := range conditions {
+		exprs = append(exprs, conditionExpressions(condition)...)
+	}
+
+	names := expressionColumns(exprs)
+	if slices.Contains(names, "*") {
+		return nil
+	}
+
+	return names
+}
+
+// conditionExpressions returns compared expressions of condition, columns
+// are given by references
+func conditionExpressions(condition Condition) []Expression {
+	exprs := []Expression{}
+	if condition.expr != nil {
+		exprs = append(exprs, condition.expr)
+	} else {
+		exprs = append(exprs, ColumnReference{name: condition.target})
+	}
+
+	if expr, ok := condition.value.(Expression); ok {
+		exprs = append(exprs, expr)
+	} else if isColumnReference(condition.value) {
+		exprs = append(exprs, ColumnReference{name: condition.value.(string)})
+	}
+
+	return exprs
+}
+
+// expressionColumns returns names of columns referenced by expressions
+func expressionColumns(exprs []Expression) []string {
+	names := []string{}
+	for _, expr := range exprs {
+		rewriteExpression(expr, func(e Expression) (Expression, bool, error) {
+			if ref, ok := e.(ColumnReference); ok {
+				names = append(names, ref.name)
+			}
+
+			return nil, false, nil
+		})
+	}
+
+	return names
+}
+
+// orderJoins reorders inner joins of more than two analyzed tables, joins
+// start with the smallest input and continue with the input connected by
+// conditions which produces the fewest rows. Each condition is evaluated by
+// the first join having all its columns. Changed order of columns is
+// restored by projection when all columns are selected
+func orderJoins(plan LogicalPlan, restore bool) LogicalPlan {
+	join, ok := plan.(JoinPlan)
+	if !ok {
+		inputs := []LogicalPlan{}
+		for _, input := range plan.inputs() {
+			inputs = append(inputs, orderJoins(input, restore))
+		}
+
+		return withInputs(plan, inputs)
+	}
+
+	relations, conditions := flattenJoin(join)
+	if len(relations) < 3 {
+		return plan
+	}
+
+	estimates := []float64{}
+	for _, relation := range relations {
+		scan, ok := relation.(ScanPlan)
+		if !ok || (scan.table != nil && scan.statistics == nil) {
+			return plan
+		}
+
+		estimates = append(estimates, estimateScan(scan, nil).rows)
+	}
+
+	first := 0
+	for i := range estimates {
+		if estimates[i] < estimates[first] {
+			first = i
+		}
+	}
+
+	var ordered LogicalPlan = relations[first]
+	rows := estimates[first]
+	remaining := []int{}
+	for i := range relations {
+		if i != first {
+			remaining = append(remaining, i)
+		}
+	}
+
+	placed := make([]bool, len(conditions))
+	for len(remaining) > 0 {
+		best, bestRows, bestApplied := -1, 0.0, []int{}
+		for k, i := range remaining {
+			candidate := JoinPlan{left: ordered, right: relations[i]}
+			applied := []int{}
+			for j, condition := range conditions {
+				if !placed[j] && resolvesColumns(candidate.columns(), condition) {
+					applied = append(applied, j)
+					candidate.conditions = append(candidate.conditions, condition)
+				}
+			}
+
+			estimate := rows * estimates[i] * selectivity(candidate, candidate.conditions)
+			connected, bestConnected := len(applied) > 0, len(bestApplied) > 0
+			if best == -1 || (connected && !bestConnected) || (connected == bestConnected && estimate < bestRows) {
+				best, bestRows, bestApplied = k, estimate, applied
+			}
+		}
+
+		next := JoinPlan{left: ordered, right: relations[remaining[best]]}
+		for _, j := range bestApplied {
+			placed[j] = true
+			next.conditions = append(next.conditions, conditions[j])
+		}
+
+		ordered, rows = next, bestRows
+		remaining = slices.Delete(remaining, best, best+1)
+	}
+
+	// conditions were validated against all joined columns
+	top := ordered.(JoinPlan)
+	for j, condition := range conditions {
+		if !placed[j] {
+			top.conditions = append(top.conditions, condition)
+		}
+	}
+
+	if !restore || slices.Equal(top.columns(), join.columns()) {
+		return top
+	}
+
+	projections := []Expression{}
+	for _, cd := range join.columns() {
+		projections = append(projections, ColumnReference{name: cd.table + "." + cd.name})
+	}
+
+	return ProjectPlan{input: top, projections: projections, output: join.columns()}
+}
+
+// flattenJoin returns inputs of tree of joins and conditions of its joins
+func flattenJoin(plan LogicalPlan) ([]LogicalPlan, []Condition) {
+	join, ok := plan.(JoinPlan)
+	if !ok {
+		return []LogicalPlan{plan}, nil
+	}
+
+	left, leftConditions := flattenJoin(join.left)
+	right, rightConditions := flattenJoin(join.right)
+	return slices.Concat(left, right), slices.Concat(leftConditions, rightConditions, join.conditions)
+}
+
+// resolvesColumns reports whether all columns of condition are among columns
+func resolvesColumns(columns []Column, condition Condition) bool {
+	for _, name := range expressionColumns(conditionExpressions(condition)) {
+		if _, err := resolveColumn(columns, name); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pruneColumns removes columns which aren't referenced from table scans, so
+// only referenced columns are decoded from tuples
+func pruneColumns(plan LogicalPlan, names []string) LogicalPlan {
+	if names == nil {
+		return plan
+	}
+
+	return transformPlan(plan, func(plan LogicalPlan) LogicalPlan {
+		scan, ok := plan.(ScanPlan)
+		if !ok || scan.table == nil {
+			return plan
+		}
+
+		scan.output = slices.DeleteFunc(slices.Clone(scan.output), func(cd Column) bool {
+			return !slices.ContainsFunc(names, func(name string) bool {
+				qualifier, name := splitQualifiedName(name)
+				return cd.name == name && (qualifier == "" || qualifier == scan.qualifier)
+			})
+		})
+
+		return scan
+	})
+}
@@ -0,0 +1,211 @@
+package auralis
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// scanSummary describes scan of optimized plan
+type scanSummary struct {
+	qualifier  string
+	columns    []string // columns read from source
+	conditions []string // targets of conditions evaluated by scan
+}
+
+func optimizedPlan(t *testing.T, query string) LogicalPlan {
+	t.Helper()
+
+	parsed, err := ParseTokens(Analyze(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var plan LogicalPlan
+	err = autocommit(func(tx *Transaction) error {
+		plan, err = planSelect(tx, parsed.(SelectQuery), nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return optimizePlan(plan)
+}
+
+func planScans(plan LogicalPlan) []scanSummary {
+	if scan, ok := plan.(ScanPlan); ok {
+		summary := scanSummary{qualifier: scan.qualifier, columns: []string{}, conditions: []string{}}
+		for _, cd := range scan.output {
+			summary.columns = append(summary.columns, cd.name)
+		}
+
+		for _, condition := range scan.conditions {
+			summary.conditions = append(summary.conditions, condition.target)
+		}
+
+		return []scanSummary{summary}
+	}
+
+	scans := []scanSummary{}
+	for _, input := range plan.inputs() {
+		scans = append(scans, planScans(input)...)
+	}
+
+	return scans
+}
+
+func planFilters(plan LogicalPlan) int {
+	count := 0
+	if filter, ok := plan.(FilterPlan); ok {
+		count += len(filter.conditions)
+	}
+
+	for _, input := range plan.inputs() {
+		count += planFilters(input)
+	}
+
+	return count
+}
+
+func TestOptimizePlan(t *testing.T) {
+	testCases := map[string]struct {
+		query   string
+		scans   []scanSummary
+		filters int // conditions left above scans
+	}{
+		"condition pushed into scan": {
+			query: "SELECT name FROM users WHERE age > 18",
+			scans: []scanSummary{{qualifier: "users", columns: []string{"name", "age"}, conditions: []string{"age"}}},
+		},
+		"all columns are read for star": {
+			query: "SELECT * FROM users WHERE id = 1",
+			scans: []scanSummary{{qualifier: "users", columns: []string{"id", "name", "age"}, conditions: []string{"id"}}},
+		},
+		"conditions pushed below join": {
+			query: "SELECT u.name, o.total FROM users u JOIN orders o ON u.id = o.user_id WHERE o.total > 100 AND u.age = 30",
+			scans: []scanSummary{
+				{qualifier: "u", columns: []string{"id", "name", "age"}, conditions: []string{"u.age"}},
+				{qualifier: "o", columns: []string{"user_id", "total"}, conditions: []string{"o.total"}},
+			},
+		},
+		"condition comparing columns stays in filter": {
+			query:   "SELECT u.name FROM users u JOIN orders o ON u.id = o.user_id WHERE o.total > u.age",
+			scans:   []scanSummary{{qualifier: "u", columns: []string{"id", "name", "age"}, conditions: []string{}}, {qualifier: "o", columns: []string{"user_id", "total"}, conditions: []string{}}},
+			filters: 1,
+		},
+		"columns of aggregates and order are kept": {
+			query: "SELECT age, count(id) AS users FROM users GROUP BY age ORDER BY age",
+			scans: []scanSummary{{qualifier: "users", columns: []string{"id", "age"}, conditions: []string{}}},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE users (id integer PRIMARY KEY, name varchar, age integer)",
+				"CREATE TABLE orders (id integer PRIMARY KEY, user_id integer, total integer)",
+			)
+
+			plan := optimizedPlan(t, tC.query)
+			// positions of table columns aren't given
+			scans := planScans(plan)
+			for i := range scans {
+				slices.Sort(scans[i].columns)
+			}
+			for i := range tC.scans {
+				slices.Sort(tC.scans[i].columns)
+			}
+
+			if !reflect.DeepEqual(scans, tC.scans) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.scans, scans)
+			}
+
+			if filters := planFilters(plan); filters != tC.filters {
+				t.Errorf("\nexp %+v\ngot %+v", tC.filters, filters)
+			}
+		})
+	}
+}
+
+func TestFoldConstants(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE users (id integer PRIMARY KEY, name varchar)")
+
+	plan := optimizedPlan(t, "SELECT upper('a') AS a, coalesce(name, lower('B')) AS b, now() AS c FROM users")
+	projections := plan.(ProjectPlan).projections
+
+	if projections[0] != (Literal{value: "A"}) {
+		t.Errorf("\nexp %+v\ngot %+v", Literal{value: "A"}, projections[0])
+	}
+
+	if arg := projections[1].(FunctionCall).args[1]; arg != (Literal{value: "b"}) {
+		t.Errorf("\nexp %+v\ngot %+v", Literal{value: "b"}, arg)
+	}
+
+	// volatile function is evaluated for each row
+	if _, ok := projections[2].(FunctionCall); !ok {
+		t.Errorf("expression %+v was folded", projections[2])
+	}
+}
+
+func TestSelectLimit(t *testing.T) {
+	testCases := map[string]struct {
+		query    string
+		expected [][]any
+	}{
+		"limit": {
+			query:    "SELECT id FROM items ORDER BY id LIMIT 2",
+			expected: [][]any{{int32(1)}, {int32(2)}},
+		},
+		"limit with offset": {
+			query:    "SELECT id FROM items ORDER BY id DESC LIMIT 2 OFFSET 1",
+			expected: [][]any{{int32(4)}, {int32(3)}},
+		},
+		"offset past rows": {
+			query:    "SELECT id FROM items OFFSET 10",
+			expected: [][]any{},
+		},
+		"limit zero": {
+			query:    "SELECT id FROM items LIMIT 0",
+			expected: [][]any{},
+		},
+		"limit all": {
+			query:    "SELECT id FROM items i WHERE i.id > 3 ORDER BY id LIMIT ALL",
+			expected: [][]any{{int32(4)}, {int32(5)}},
+		},
+		"limit of groups": {
+			query:    "SELECT name, count(id) AS total FROM items GROUP BY name ORDER BY total DESC, name LIMIT 1",
+			expected: [][]any{{"b", int64(3)}},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id integer PRIMARY KEY, name varchar)",
+				"INSERT INTO items (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'b'), (4, 'a'), (5, 'b')",
+			)
+
+			if rows := queryCells(t, tC.query); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestLimitStopsReadingInput(t *testing.T) {
+	input := &valuesOperator{rows: []Row{{cells: []any{1}}, {cells: []any{2}}, {cells: []any{3}}, {cells: []any{4}}}}
+	limit := int64(1)
+
+	dataSet, err := runOperator(&limitOperator{input: input, limit: &limit, offset: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{2}}
+	if rows := resultCells(dataSet); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+
+	if input.position != 2 {
+		t.Errorf("\nexp %+v\ngot %+v", 2, input.position)
+	}
+}
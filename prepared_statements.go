@@ -0,0 +1,503 @@
+package auralis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPreparedStatementNotFound = AuraError{Code: "INVALID_SQL_STATEMENT_NAME", Message: "prepared statement does not exist"}
+	ErrPreparedStatementExists   = AuraError{Code: "DUPLICATE_PREPARED_STATEMENT", Message: "prepared statement already exists"}
+	ErrInvalidParameters         = AuraError{Code: "INVALID_PARAMETERS", Message: "wrong number of parameters"}
+	ErrInvalidParameterType      = AuraError{Code: "INVALID_PARAMETER_TYPE", Message: "parameter type is not supported"}
+	ErrUndefinedParameter        = AuraError{Code: "UNDEFINED_PARAMETER", Message: "there is no parameter"}
+	ErrInvalidPreparedStatement  = AuraError{
+		Code:    "INVALID_PREPARED_STATEMENT",
+		Message: "only select, insert, update and delete can be prepared"}
+)
+
+// parameterTypes are types which can be declared for parameters
+var parameterTypes = []DataType{smallint, integer, bigint, double, varchar, uniqueidentifier, timestamp}
+
+// statementCacheSize limits number of statements of Go API kept by cache
+const statementCacheSize = 256
+
+// statementCache keeps statements of Go API prepared by query text, it's shared
+// by sessions of DB
+type statementCache struct {
+	lock       sync.Mutex
+	statements map[string]*PreparedStatement
+}
+
+func newStatementCache() *statementCache {
+	return &statementCache{statements: map[string]*PreparedStatement{}}
+}
+
+// prepare returns statement of raw query prepared at its first use
+func (c *statementCache) prepare(raw string) (*PreparedStatement, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if p, ok := c.statements[raw]; ok {
+		return p, nil
+	}
+
+	p, err := prepareStatement(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.statements) >= statementCacheSize {
+		clear(c.statements)
+	}
+	c.statements[raw] = p
+
+	return p, nil
+}
+
+// PreparedStatement keeps parsed query of statement with parameter placeholders.
+// Values are bound into copy of the query, so they are never lexed as a part
+// of the statement
+type PreparedStatement struct {
+	types      []DataType // declared types of parameters, the rest are untyped
+	parameters int        // number of parameters, the highest n of $n
+	query      any
+}
+
+func newPreparedStatement(tokens []TokenLiteral, types []DataType) (*PreparedStatement, error) {
+	p := &PreparedStatement{types: types, parameters: len(types)}
+	for _, dataType := range types {
+		if !slices.Contains(parameterTypes, dataType) {
+			return nil, AuraError{
+				Code:    ErrInvalidParameterType.Code,
+				Message: fmt.Sprintf("parameter type %s is not supported", dataType)}
+		}
+	}
+
+	for _, token := range tokens {
+		n, ok, err := parameterNumber(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			p.parameters = max(p.parameters, n)
+		}
+	}
+
+	query, err := ParseTokens(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	switch query.(type) {
+	case SelectQuery, InsertQuery, UpdateQuery, DeleteQuery:
+	default:
+		return nil, ErrInvalidPreparedStatement
+	}
+
+	p.query = query
+	return p, nil
+}
+
+// parameterNumber returns n of $n placeholder, ok is false for other tokens
+func parameterNumber(token TokenLiteral) (int, bool, error) {
+	if token.kind != symbol || !strings.HasPrefix(token.value, "$") {
+		return 0, false, nil
+	}
+
+	n, ok := parameterIndex(token.value)
+	if !ok {
+		return 0, false, AuraError{
+			Code:    ErrInvalidParameters.Code,
+			Message: fmt.Sprintf("invalid parameter %s", token.value)}
+	}
+
+	return n, true, nil
+}
+
+// parameterIndex returns n of $n value
+func parameterIndex(value string) (int, bool) {
+	if !strings.HasPrefix(value, "$") {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(value[1:])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// parameterAt returns n of $n placeholder at position i of tokens
+func parameterAt(v []TokenLiteral, i int) (int, bool) {
+	if i >= len(v) || v[i].kind != symbol {
+		return 0, false
+	}
+
+	return parameterIndex(v[i].value)
+}
+
+// unboundParameter rejects placeholders of statement executed without arguments
+func unboundParameter(tokens []TokenLiteral) error {
+	for i := range tokens {
+		if n, ok := parameterAt(tokens, i); ok {
+			return AuraError{Code: ErrUndefinedParameter.Code, Message: fmt.Sprintf("there is no parameter $%d", n)}
+		}
+	}
+
+	return nil
+}
+
+// bind checks arguments against parameters and returns query with bound values
+func (p *PreparedStatement) bind(args []TokenLiteral) (any, error) {
+	if len(args) != p.parameters {
+		return nil, AuraError{
+			Code:    ErrInvalidParameters.Code,
+			Message: fmt.Sprintf("wrong number of parameters, expected %d got %d", p.parameters, len(args))}
+	}
+
+	if p.parameters == 0 {
+		return p.query, nil
+	}
+
+	for i, dataType := range p.types {
+		if args[i].value == "null" {
+			continue
+		}
+
+		if _, err := ConvertToConcreteType(dataType, args[i].value); err != nil {
+			return nil, err
+		}
+	}
+
+	return binder{args: args}.query(p.query)
+}
+
+// binder replaces parameter placeholders of parsed query by arguments. Parts of
+// query with placeholders are copied, so prepared query stays intact and can be
+// bound by many sessions at once
+type binder struct {
+	args []TokenLiteral
+}
+
+func (b binder) query(query any) (any, error) {
+	switch q := query.(type) {
+	case SelectQuery:
+		return b.selectQuery(q)
+	case InsertQuery:
+		q.values = slices.Clone(q.values)
+		for i, row := range q.values {
+			q.values[i] = b.values(row)
+		}
+
+		if q.query != nil {
+			bound, err := b.selectQuery(*q.query)
+			if err != nil {
+				return nil, err
+			}
+			q.query = &bound
+		}
+
+		if q.onConflict != nil {
+			onConflict := *q.onConflict
+			onConflict.assignments = b.assignments(onConflict.assignments)
+			q.onConflict = &onConflict
+		}
+
+		q.returning.projections = b.expressions(q.returning.projections)
+		return q, nil
+	case UpdateQuery:
+		q.assignments = b.assignments(q.assignments)
+		q.conditions = b.conditions(q.conditions)
+		q.returning.projections = b.expressions(q.returning.projections)
+		return q, nil
+	case DeleteQuery:
+		q.conditions = b.conditions(q.conditions)
+		q.returning.projections = b.expressions(q.returning.projections)
+		return q, nil
+	default:
+		return query, nil
+	}
+}
+
+func (b binder) selectQuery(q SelectQuery) (SelectQuery, error) {
+	q.ctes = slices.Clone(q.ctes)
+	for i, cte := range q.ctes {
+		query, err := b.selectQuery(cte.query)
+		if err != nil {
+			return SelectQuery{}, err
+		}
+		q.ctes[i].query = query
+	}
+
+	q.joins = slices.Clone(q.joins)
+	for i, join := range q.joins {
+		q.joins[i].conditions = b.conditions(join.conditions)
+	}
+
+	q.projections = b.expressions(q.projections)
+	q.conditions = b.conditions(q.conditions)
+	q.groupBy = b.expressions(q.groupBy)
+	q.orderBy = b.orderBy(q.orderBy)
+
+	if q.limitParam > 0 {
+		limit, err := b.rowCount(q.limitParam, "limit")
+		if err != nil {
+			return SelectQuery{}, err
+		}
+		q.limit, q.limitParam = &limit, 0
+	}
+
+	if q.offsetParam > 0 {
+		offset, err := b.rowCount(q.offsetParam, "offset")
+		if err != nil {
+			return SelectQuery{}, err
+		}
+		q.offset, q.offsetParam = offset, 0
+	}
+
+	if q.union != nil {
+		union, err := b.selectQuery(*q.union)
+		if err != nil {
+			return SelectQuery{}, err
+		}
+		q.union = &union
+	}
+
+	return q, nil
+}
+
+func (b binder) rowCount(n int, clause string) (int64, error) {
+	count, err := parseRowCount(b.args, n-1, clause)
+	if err != nil {
+		return 0, AuraError{Code: ErrInvalidParameters.Code, Message: err.Error()}
+	}
+
+	return count, nil
+}
+
+// value binds raw value of condition or insert
+func (b binder) value(value any) any {
+	if p, ok := value.(Parameter); ok {
+		return b.args[p.n-1].value
+	}
+
+	return value
+}
+
+func (b binder) values(values []any) []any {
+	if values == nil {
+		return nil
+	}
+
+	bound := make([]any, len(values))
+	for i, value := range values {
+		bound[i] = b.value(value)
+	}
+
+	return bound
+}
+
+func (b binder) expression(expr Expression) Expression {
+	switch e := expr.(type) {
+	case Parameter:
+		return parseOperand(b.args[e.n-1].value)
+	case FunctionCall:
+		e.args = b.expressions(e.args)
+		if e.over != nil {
+			over := *e.over
+			over.partitionBy = b.expressions(over.partitionBy)
+			over.orderBy = b.orderBy(over.orderBy)
+			e.over = &over
+		}
+
+		return e
+	case CaseExpression:
+		if e.operand != nil {
+			e.operand = b.expression(e.operand)
+		}
+
+		e.branches = slices.Clone(e.branches)
+		for i, branch := range e.branches {
+			e.branches[i].conditions = b.conditions(branch.conditions)
+			if branch.value != nil {
+				e.branches[i].value = b.expression(branch.value)
+			}
+			e.branches[i].result = b.expression(branch.result)
+		}
+
+		if e.fallback != nil {
+			e.fallback = b.expression(e.fallback)
+		}
+
+		return e
+	default:
+		return expr
+	}
+}
+
+func (b binder) expressions(exprs []Expression) []Expression {
+	if exprs == nil {
+		return nil
+	}
+
+	bound := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		bound[i] = b.expression(expr)
+	}
+
+	return bound
+}
+
+func (b binder) orderBy(items []OrderByItem) []OrderByItem {
+	items = slices.Clone(items)
+	for i, item := range items {
+		items[i].expression = b.expression(item.expression)
+	}
+
+	return items
+}
+
+func (b binder) assignments(assignments []Assignment) []Assignment {
+	assignments = slices.Clone(assignments)
+	for i, assignment := range assignments {
+		assignments[i].value = b.expression(assignment.value)
+	}
+
+	return assignments
+}
+
+func (b binder) conditions(conditions []Condition) []Condition {
+	conditions = slices.Clone(conditions)
+	for i, condition := range conditions {
+		if condition.expr != nil {
+			conditions[i].expr = b.expression(condition.expr)
+		}
+
+		switch value := condition.value.(type) {
+		case Parameter:
+			conditions[i].value = b.value(value)
+		case []any:
+			conditions[i].value = b.values(value)
+		case Expression:
+			conditions[i].value = b.expression(value)
+		}
+	}
+
+	return conditions
+}
+
+// parameterToken formats Go value as literal token bound to parameter
+func parameterToken(value any) (TokenLiteral, error) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		text = "null"
+	case string:
+		text = quoteLiteral(v)
+	case int:
+		text = strconv.FormatInt(int64(v), 10)
+	case int16:
+		text = strconv.FormatInt(int64(v), 10)
+	case int32:
+		text = strconv.FormatInt(int64(v), 10)
+	case int64:
+		text = strconv.FormatInt(v, 10)
+	case float32:
+		text = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		text = strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		text = strconv.FormatBool(v)
+	case time.Time:
+		text = quoteLiteral(v.UTC().Format(timestampLayouts[0]))
+	case uuid.UUID:
+		text = quoteLiteral(v.String())
+	default:
+		return TokenLiteral{}, AuraError{
+			Code:    ErrInvalidParameterType.Code,
+			Message: fmt.Sprintf("parameter of type %T is not supported", value)}
+	}
+
+	return TokenLiteral{kind: symbol, value: text}, nil
+}
+
+// prepareStatement prepares single statement of raw query
+func prepareStatement(raw string) (*PreparedStatement, error) {
+	statements := splitStatements(Analyze(raw))
+	if len(statements) != 1 {
+		return nil, AuraError{
+			Code:    "INVALID_QUERY",
+			Message: "query with parameters must be a single statement"}
+	}
+
+	return newPreparedStatement(statements[0], nil)
+}
+
+// executePrepared binds arguments to placeholders of statement and executes it
+func (s *Session) executePrepared(ctx context.Context, p *PreparedStatement, args []any) (*DataSet, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.executeArguments(ctx, p, args)
+}
+
+func (s *Session) executeArguments(ctx context.Context, p *PreparedStatement, args []any) (*DataSet, error) {
+	tokens := make([]TokenLiteral, len(args))
+	for i, arg := range args {
+		token, err := parameterToken(arg)
+		if err != nil {
+			return &DataSet{}, err
+		}
+		tokens[i] = token
+	}
+
+	query, err := p.bind(tokens)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	statementLock.Lock()
+	defer statementLock.Unlock()
+
+	return s.execute(ctx, query)
+}
+
+// prepare stores named statement of session
+func (s *Session) prepare(query PrepareQuery) error {
+	if _, ok := s.prepared[query.name]; ok {
+		return ErrPreparedStatementExists
+	}
+
+	p, err := newPreparedStatement(query.statement, query.types)
+	if err != nil {
+		return err
+	}
+
+	s.prepared[query.name] = p
+	return nil
+}
+
+// deallocate forgets named statement or all statements of session
+func (s *Session) deallocate(query DeallocateQuery) error {
+	if query.all {
+		clear(s.prepared)
+		return nil
+	}
+
+	if _, ok := s.prepared[query.name]; !ok {
+		return ErrPreparedStatementNotFound
+	}
+
+	delete(s.prepared, query.name)
+	return nil
+}
@@ -0,0 +1,169 @@
+package auralis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPreparedStatements(t *testing.T) {
+	testCases := map[string]struct {
+		queries     []string
+		expected    [][]any
+		expectedErr error
+	}{
+		"execute binds arguments": {
+			queries: []string{
+				"PREPARE by_price AS SELECT name FROM items WHERE price >= $1 AND name != $2 ORDER BY id",
+				"EXECUTE by_price(20, 'b')",
+			},
+			expected: [][]any{{"c"}},
+		},
+		"statement is executed repeatedly": {
+			queries: []string{
+				"PREPARE add (integer, varchar) AS INSERT INTO items (id, name, price) VALUES ($1, $2, $1)",
+				"EXECUTE add(4, 'd')",
+				"EXECUTE add(5, 'it''s')",
+				"PREPARE all_items AS SELECT name FROM items WHERE id > 3 ORDER BY id",
+				"EXECUTE all_items",
+				"EXECUTE all_items",
+			},
+			expected: [][]any{{"d"}, {"it's"}},
+		},
+		"quoted argument stays a single value": {
+			queries: []string{
+				"PREPARE by_name AS SELECT id FROM items WHERE name = $1",
+				"EXECUTE by_name('a'' OR name != ''x')",
+			},
+			expected: [][]any{},
+		},
+		"arguments of lists, assignments and row counts": {
+			queries: []string{
+				"PREPARE reprice AS UPDATE items SET price = $1 WHERE id IN ($2, $3)",
+				"EXECUTE reprice(15, 1, 3)",
+				"PREPARE page AS SELECT name FROM items WHERE price BETWEEN $1 AND $2 ORDER BY id LIMIT $3 OFFSET $4",
+				"EXECUTE page(15, 20, 1, 1)",
+			},
+			expected: [][]any{{"b"}},
+		},
+		"invalid row count argument": {
+			queries: []string{
+				"PREPARE page AS SELECT name FROM items LIMIT $1",
+				"EXECUTE page(-1)",
+			},
+			expectedErr: AuraError{Code: ErrInvalidParameters.Code, Message: "invalid limit value -1"},
+		},
+		"placeholder without arguments": {
+			queries:     []string{"SELECT name FROM items WHERE id = $1"},
+			expectedErr: AuraError{Code: ErrUndefinedParameter.Code, Message: "there is no parameter $1"},
+		},
+		"wrong number of arguments": {
+			queries: []string{
+				"PREPARE by_price AS SELECT name FROM items WHERE price >= $2",
+				"EXECUTE by_price(1)",
+			},
+			expectedErr: AuraError{Code: ErrInvalidParameters.Code, Message: "wrong number of parameters, expected 2 got 1"},
+		},
+		"argument of declared type": {
+			queries: []string{
+				"PREPARE by_price (integer) AS SELECT name FROM items WHERE price >= $1",
+				"EXECUTE by_price('x')",
+			},
+			expectedErr: ErrIntegerTypeConversion,
+		},
+		"duplicate name": {
+			queries: []string{
+				"PREPARE p AS SELECT name FROM items",
+				"PREPARE p AS SELECT id FROM items",
+			},
+			expectedErr: ErrPreparedStatementExists,
+		},
+		"deallocated statement": {
+			queries: []string{
+				"PREPARE p AS SELECT name FROM items",
+				"DEALLOCATE p",
+				"EXECUTE p",
+			},
+			expectedErr: ErrPreparedStatementNotFound,
+		},
+		"only data statements": {
+			queries:     []string{"PREPARE p AS BEGIN"},
+			expectedErr: ErrInvalidPreparedStatement,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id integer, name varchar, price integer)",
+				"INSERT INTO items (id, name, price) VALUES (1, 'a', 10), (2, 'b', 20), (3, 'c', 30)",
+			)
+			t.Cleanup(func() { ExecuteQuery("DEALLOCATE ALL") })
+
+			var rows [][]any
+			var err error
+			for _, query := range tC.queries {
+				var dataSet *DataSet
+				if dataSet, err = ExecuteQuery(query); err != nil {
+					break
+				}
+
+				if dataSet != nil {
+					rows = resultCells(dataSet)
+				}
+			}
+
+			if err != tC.expectedErr {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+			}
+
+			if tC.expectedErr == nil && !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestQueryArguments(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE events (id integer, kind varchar, created timestamp)")
+
+	created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	for i, kind := range []string{"login", "it's'; DELETE FROM events"} {
+		if _, err := ExecuteQuery("INSERT INTO events (id, kind, created) VALUES ($1, $2, $3)", i, kind, created); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataSet, err := ExecuteQuery("SELECT id, kind FROM events WHERE created = $1 AND id >= $2 ORDER BY id", created, int64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{int32(0), "login"}, {int32(1), "it's'; DELETE FR"}}
+	if rows := resultCells(dataSet); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+
+	if _, err := ExecuteQuery("SELECT id FROM events WHERE id = $1", struct{}{}); err == nil {
+		t.Errorf("\nexp %+v\ngot %+v", ErrInvalidParameterType, err)
+	}
+}
+
+func TestBooleanAndFloatArguments(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE flags (id integer, enabled boolean)")
+
+	for i, enabled := range []bool{true, false} {
+		if _, err := ExecuteQuery("INSERT INTO flags (id, enabled) VALUES ($1, $2)", i, enabled); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataSet, err := ExecuteQuery("SELECT id FROM flags WHERE enabled = $1 AND id < $2", false, float32(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{int32(1)}}
+	if rows := resultCells(dataSet); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
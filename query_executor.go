@@ -0,0 +1,421 @@
+package auralis
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrColumnNotFound       = AuraError{Code: "COLUMN_NOT_FOUND", Message: "column not found"}
+	ErrAmbiguousColumn      = AuraError{Code: "AMBIGUOUS_COLUMN", Message: "column reference is ambiguous"}
+	ErrUnionColumnsMismatch = AuraError{
+		Code:    "UNION_COLUMNS_MISMATCH",
+		Message: "each union query must have the same number of columns"}
+	ErrRecursionLimitExceeded = AuraError{
+		Code:    "RECURSION_LIMIT_EXCEEDED",
+		Message: "recursive query exceeded max_recursive_iterations"}
+)
+
+// executeSelect runs select query in memory, ctes contains already materialized
+// common table expressions visible for the query
+func executeSelect(tx *Transaction, query SelectQuery, ctes map[string]*DataSet) (*DataSet, error) {
+	plan, err := planSelect(tx, query, ctes)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	plan = optimizePlan(plan)
+	operator, err := buildOperator(tx, plan, nil)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	return runOperator(operator, plan.columns())
+}
+
+func materializeCommonTableExpression(tx *Transaction, cte CommonTableExpression,
+	ctes map[string]*DataSet) (*DataSet, error) {
+	if !cte.recursive || cte.query.union == nil || !referencesSource(*cte.query.union, cte.name) {
+		dataSet, err := executeSelect(tx, cte.query, ctes)
+		if err != nil {
+			return &DataSet{}, err
+		}
+
+		return renameColumns(dataSet, cte.columns)
+	}
+
+	// recursive query is evaluated as non recursive anchor followed by
+	// iterations of recursive term over rows produced by previous iteration
+	anchor := cte.query
+	anchor.union = nil
+
+	result, err := executeSelect(tx, anchor, ctes)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	result, err = renameColumns(result, cte.columns)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	seen := map[string]bool{}
+	if !cte.query.unionAll {
+		result.rows = distinctRows(result.rows, seen)
+	}
+
+	limit := getIntSetting("max_recursive_iterations")
+	working := &DataSet{columns: result.columns, rows: result.rows}
+	for iteration := 0; len(working.rows) > 0; iteration++ {
+		if iteration >= limit {
+			return &DataSet{}, ErrRecursionLimitExceeded
+		}
+
+		scope := maps.Clone(ctes)
+		scope[cte.name] = working
+
+		next, err := executeSelect(tx, *cte.query.union, scope)
+		if err != nil {
+			return &DataSet{}, err
+		}
+
+		if len(next.columns) != len(result.columns) {
+			return &DataSet{}, ErrUnionColumnsMismatch
+		}
+
+		if !cte.query.unionAll {
+			next.rows = distinctRows(next.rows, seen)
+		}
+
+		result.rows = append(result.rows, next.rows...)
+		working = &DataSet{columns: result.columns, rows: next.rows}
+	}
+
+	return result, nil
+}
+
+// referencesSource checks if query or any of its union queries reads from source name
+func referencesSource(query SelectQuery, name string) bool {
+	if query.source.schema == defaultScheme && query.source.name == name {
+		return true
+	}
+
+	for _, join := range query.joins {
+		if join.source.schema == defaultScheme && join.source.name == name {
+			return true
+		}
+	}
+
+	return query.union != nil && referencesSource(*query.union, name)
+}
+
+func renameColumns(dataSet *DataSet, names []string) (*DataSet, error) {
+	if len(names) == 0 {
+		return dataSet, nil
+	}
+
+	if len(names) != len(dataSet.columns) {
+		return &DataSet{}, AuraError{
+			Code:    "INVALID_QUERY",
+			Message: "common table expression columns count does not match query"}
+	}
+
+	for i := range dataSet.columns {
+		dataSet.columns[i].name = names[i]
+	}
+
+	return dataSet, nil
+}
+
+func matchesConditions(columns []Column, row Row, conditions []Condition) (bool, error) {
+	for _, condition := range conditions {
+		var value any
+		var dataType DataType
+		if condition.expr != nil {
+			v, err := evaluateExpression(condition.expr, columns, row)
+			if err != nil {
+				return false, err
+			}
+
+			value, dataType = v, expressionDataType(condition.expr, columns)
+		} else {
+			target, err := resolveColumn(columns, condition.target)
+			if err != nil {
+				return false, err
+			}
+
+			value, dataType = row.cells[target], columns[target].dataType
+		}
+
+		if isNullCondition(condition) {
+			if !EvaluateCondition(condition, value) {
+				return false, nil
+			}
+			continue
+		}
+
+		switch ref := condition.value.(type) {
+		case Expression:
+			v, err := evaluateExpression(ref, columns, row)
+			if err != nil {
+				return false, err
+			}
+
+			condition.value = v
+		case string:
+			if isColumnReference(ref) {
+				other, err := resolveColumn(columns, ref)
+				if err != nil {
+					return false, err
+				}
+
+				condition.value = row.cells[other]
+				break
+			}
+
+			v, err := convertConditionValue(dataType, condition)
+			if err != nil {
+				return false, err
+			}
+
+			condition.value = v
+		default:
+			v, err := convertConditionValue(dataType, condition)
+			if err != nil {
+				return false, err
+			}
+
+			condition.value = v
+		}
+
+		if condition.value == nil || !EvaluateCondition(condition, value) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// selectProjections returns select list expressions, plain column names are
+// represented only by data columns
+func selectProjections(query SelectQuery) []Expression {
+	if query.projections != nil {
+		return slices.Clone(query.projections)
+	}
+
+	projections := make([]Expression, 0, len(query.dataColumns))
+	for _, name := range query.dataColumns {
+		projections = append(projections, ColumnReference{name: name})
+	}
+
+	return projections
+}
+
+// resolveOrderBy replaces references to result column names and positions
+// with expressions producing them
+func resolveOrderBy(orderBy []OrderByItem, names []string, projections []Expression) []OrderByItem {
+	resolved := make([]OrderByItem, 0, len(orderBy))
+	for _, item := range orderBy {
+		switch expr := item.expression.(type) {
+		case ColumnReference:
+			if i := slices.Index(names, expr.name); i != -1 {
+				item.expression = projections[i]
+			}
+		case Literal:
+			if position, ok := expr.value.(int64); ok && position >= 1 && int(position) <= len(projections) {
+				item.expression = projections[position-1]
+			}
+		}
+
+		resolved = append(resolved, item)
+	}
+
+	return resolved
+}
+
+func isAggregateCall(call FunctionCall) bool {
+	return call.over == nil && isAggregateFunction(call.name)
+}
+
+func isWindowCall(call FunctionCall) bool {
+	return call.over != nil
+}
+
+func sortRows(columns []Column, rows []Row, orderBy []OrderByItem) ([]Row, error) {
+	type sortRow struct {
+		row  Row
+		keys []any
+	}
+
+	keyed := make([]sortRow, 0, len(rows))
+	for _, row := range rows {
+		sr := sortRow{row: row}
+		for _, item := range orderBy {
+			value, err := evaluateExpression(item.expression, columns, row)
+			if err != nil {
+				return nil, err
+			}
+
+			sr.keys = append(sr.keys, value)
+		}
+
+		keyed = append(keyed, sr)
+	}
+
+	slices.SortStableFunc(keyed, func(a, b sortRow) int {
+		return compareKeys(a.keys, b.keys, orderBy)
+	})
+
+	sorted := make([]Row, 0, len(keyed))
+	for _, sr := range keyed {
+		sorted = append(sorted, sr.row)
+	}
+
+	return sorted, nil
+}
+
+func projectDataSet(dataSet *DataSet, names []string, projections []Expression) (*DataSet, error) {
+	columns, err := projectColumns(dataSet.columns, names, projections)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	projected := &DataSet{columns: columns}
+	for _, row := range dataSet.rows {
+		row, err := projectRow(dataSet.columns, projections, row)
+		if err != nil {
+			return &DataSet{}, err
+		}
+
+		projected.rows = append(projected.rows, row)
+	}
+
+	return projected, nil
+}
+
+// projectColumns returns columns of rows produced by projections, * stands for
+// all columns which aren't hidden
+func projectColumns(columns []Column, names []string, projections []Expression) ([]Column, error) {
+	projected := []Column{}
+	for i, expr := range projections {
+		if ref, ok := expr.(ColumnReference); ok && ref.name == "*" {
+			for _, cd := range columns {
+				if !isHiddenColumn(cd) {
+					projected = append(projected, cd)
+				}
+			}
+			continue
+		}
+
+		if ref, ok := expr.(ColumnReference); ok {
+			if _, err := resolveColumn(columns, ref.name); err != nil {
+				return nil, err
+			}
+		}
+
+		projected = append(projected, Column{
+			name:     unqualifiedName(names[i]),
+			dataType: expressionDataType(expr, columns),
+		})
+	}
+
+	return projected, nil
+}
+
+func projectRow(columns []Column, projections []Expression, row Row) (Row, error) {
+	cells := []any{}
+	for _, expr := range projections {
+		if ref, ok := expr.(ColumnReference); ok && ref.name == "*" {
+			for i, cd := range columns {
+				if !isHiddenColumn(cd) {
+					cells = append(cells, row.cells[i])
+				}
+			}
+			continue
+		}
+
+		value, err := evaluateExpression(expr, columns, row)
+		if err != nil {
+			return Row{}, err
+		}
+
+		cells = append(cells, value)
+	}
+
+	return Row{cells: cells}, nil
+}
+
+func isHiddenColumn(cd Column) bool {
+	return strings.HasPrefix(cd.name, "#")
+}
+
+// resolveColumn returns index of column referenced by name optionally qualified
+// with table name or alias, eg. u.id
+func resolveColumn(columns []Column, name string) (int, error) {
+	qualifier, name := splitQualifiedName(name)
+
+	index := -1
+	for i, cd := range columns {
+		if cd.name != name || (qualifier != "" && cd.table != qualifier) {
+			continue
+		}
+
+		if index != -1 {
+			return -1, ErrAmbiguousColumn
+		}
+		index = i
+	}
+
+	if index == -1 {
+		return -1, ErrColumnNotFound
+	}
+
+	return index, nil
+}
+
+func splitQualifiedName(name string) (string, string) {
+	s := strings.Split(name, ".")
+	if len(s) == 1 {
+		return "", s[0]
+	}
+
+	return s[len(s)-2], s[len(s)-1]
+}
+
+func unqualifiedName(name string) string {
+	_, n := splitQualifiedName(name)
+	return n
+}
+
+// isColumnReference reports if condition value is a column name instead of literal,
+// literals are either quoted or start with a digit or sign
+func isColumnReference(value any) bool {
+	v, ok := value.(string)
+	if !ok || v == "" || isBooleanLiteral(v) {
+		return false
+	}
+
+	c := v[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isBooleanLiteral reports whether lexed symbol is true or false literal
+func isBooleanLiteral(value string) bool {
+	return value == "true" || value == "false"
+}
+
+func distinctRows(rows []Row, seen map[string]bool) []Row {
+	distinct := []Row{}
+	for _, row := range rows {
+		key := row.key()
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		distinct = append(distinct, row)
+	}
+
+	return distinct
+}
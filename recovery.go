@@ -0,0 +1,202 @@
+package auralis
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// recoverDatabase brings data pages to consistent state after crash in three passes:
+// analysis finds transactions without commit or abort, redo repeats history of all
+// logged page changes missing on disk and undo reverts changes of unfinished transactions
+func recoverDatabase(records []LogRecord) error {
+	// analysis
+	losers := map[uint64]*Transaction{}
+	order := []uint64{}
+	for _, record := range records {
+		if record.kind == checkpointRecord {
+			continue
+		}
+
+		tx, ok := losers[record.txID]
+		if !ok {
+			tx = &Transaction{id: record.txID, records: map[uint64]LogRecord{}}
+			losers[record.txID] = tx
+			order = append(order, record.txID)
+		}
+		tx.lastLSN = record.lsn
+		tx.records[record.lsn] = record
+
+		if record.kind == commitRecord || record.kind == abortRecord {
+			delete(losers, record.txID)
+		}
+	}
+
+	// redo, changes of rewritten file logged before its rewrite are already on disk
+	rewritten := map[string]uint64{}
+	for _, record := range records {
+		if record.kind == rewriteRecord {
+			rewritten[record.page.file] = record.lsn
+		}
+	}
+
+	if err := renameCopies(rewritten); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.lsn < rewritten[record.page.file] {
+			continue
+		}
+
+		if record.kind == truncateRecord {
+			if err := bufferPool.truncateFile(record.page.file, record.page.number); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if record.kind == indexRecord {
+			if err := redoIndexRecord(record); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !slices.Contains([]LogRecordKind{insertRecord, deleteRecord, compensationRecord, vacuumRecord}, record.kind) {
+			continue
+		}
+
+		page, err := bufferPool.fetchPage(record.page)
+		if err != nil {
+			return err
+		}
+
+		if page.lsn() >= record.lsn {
+			bufferPool.unpinPage(page.id, false)
+			continue
+		}
+
+		if err := redoRecord(page, record); err != nil {
+			bufferPool.unpinPage(page.id, false)
+			return err
+		}
+
+		page.setLSN(record.lsn)
+		bufferPool.unpinPage(page.id, true)
+	}
+
+	// undo
+	for _, id := range order {
+		tx, ok := losers[id]
+		if !ok {
+			continue
+		}
+
+		logger.Load().Printf("INFO: rolling back unfinished transaction %d\n", id)
+		writeAheadLog.resumeTransaction(id)
+		if err := tx.rollback(); err != nil {
+			return err
+		}
+	}
+
+	return writeAheadLog.checkpoint()
+}
+
+// renameCopies finishes replacement of files whose copy was written before crash,
+// copy is complete when its first page has LSN of the last rewrite of the file.
+// Other copies are removed
+func renameCopies(rewritten map[string]uint64) error {
+	// globbed paths are cleaned unlike logged ones
+	files := map[string]string{}
+	for file := range rewritten {
+		files[filepath.Clean(file)] = file
+	}
+
+	copies, err := filepath.Glob(filepath.Join(dataPath, "*.tmp"))
+	if err != nil {
+		return err
+	}
+
+	for _, copied := range copies {
+		page := &Page{}
+		f, err := os.Open(copied)
+		if err != nil {
+			return err
+		}
+
+		_, err = f.ReadAt(page.data[:], 0)
+		if err := errors.Join(err, f.Close()); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		file, ok := files[strings.TrimSuffix(copied, ".tmp")]
+		if !ok || page.lsn() != rewritten[file] {
+			if err := os.Remove(copied); err != nil {
+				return err
+			}
+			continue
+		}
+
+		logger.Load().Printf("INFO: replacing %s by its rewritten copy\n", file)
+		if err := bufferPool.dropFile(file); err != nil {
+			return err
+		}
+
+		if err := os.Rename(copied, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// redoIndexRecord writes logged node images into pages which don't have them yet
+func redoIndexRecord(record LogRecord) error {
+	for number, image := range decodeNodeImages(record.tuple) {
+		page, err := bufferPool.fetchPage(PageID{file: record.page.file, number: number})
+		if err != nil {
+			return err
+		}
+
+		if page.lsn() >= record.lsn {
+			bufferPool.unpinPage(page.id, false)
+			continue
+		}
+
+		writeNodePage(page, image)
+		page.setLSN(record.lsn)
+		bufferPool.unpinPage(page.id, true)
+	}
+
+	return nil
+}
+
+// redoRecord applies logged change to page
+func redoRecord(page *Page, record LogRecord) error {
+	switch {
+	case record.kind == insertRecord:
+		slot, ok := page.insertTuple(record.tuple)
+		if !ok || slot != record.slot {
+			return ErrLogCorrupted
+		}
+	case record.kind == deleteRecord:
+		tuple := page.tuple(record.slot)
+		if tuple == nil {
+			return ErrLogCorrupted
+		}
+		_, xmax := decodeXmaxChange(record.tuple)
+		setTupleXmax(tuple, xmax)
+	case record.kind == vacuumRecord:
+		page.vacuum(decodeVacuumedSlots(record.tuple))
+	case len(record.tuple) == 0:
+		page.deleteTuple(record.slot)
+	default:
+		page.restoreTuple(record.slot, record.tuple)
+	}
+
+	return nil
+}
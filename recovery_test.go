@@ -0,0 +1,246 @@
+package auralis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var errSimulatedCrash = errors.New("simulated crash")
+
+// simulateCrashAt installs crash hook failing physical write number n. Failing log
+// write is torn in half, pages are assumed to be written atomically. All writes
+// after the crash fail as if the process was gone
+func simulateCrashAt(n int) *bool {
+	crashed := false
+	writes := 0
+	crashHook = func(target string, data []byte) ([]byte, error) {
+		if crashed {
+			return nil, errSimulatedCrash
+		}
+
+		writes++
+		if writes < n {
+			return data, nil
+		}
+
+		crashed = true
+		if target == "wal" {
+			return data[:len(data)/2], errSimulatedCrash
+		}
+
+		return nil, errSimulatedCrash
+	}
+
+	return &crashed
+}
+
+// crashDatabase drops in-memory state of database without writing it
+func crashDatabase() {
+	hook := crashHook
+	crashHook = func(string, []byte) ([]byte, error) { return nil, errSimulatedCrash }
+	bufferPool.close()
+	writeAheadLog.close()
+	crashHook = hook
+}
+
+// restartDatabase recovers crashed database from disk, recovery panic is returned as error
+func restartDatabase(poolSize int) (err error) {
+	crashDatabase()
+
+	bufferPool = newBufferPool(poolSize)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovery failed: %v", r)
+		}
+	}()
+
+	return initDatabaseInternalStructure()
+}
+
+func itemRows(t *testing.T, batch int16, count int) (Table, []Row) {
+	t.Helper()
+
+	var table Table
+	err := autocommit(func(tx *Transaction) (err error) {
+		table, err = getTable(tx, SchemaTable[string, string]{defaultScheme, "items"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []Row{}
+	for i := range count {
+		row := Row{}
+		for _, cd := range table.columns {
+			switch cd.name {
+			case "batch":
+				row.cells = append(row.cells, batch)
+			case "id":
+				row.cells = append(row.cells, int16(i))
+			default:
+				row.cells = append(row.cells, fmt.Sprintf("item %d", i))
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return table, rows
+}
+
+func batchCounts(t *testing.T) map[int16]int64 {
+	t.Helper()
+
+	dataSet, err := ExecuteQuery("SELECT batch, count(*) FROM items GROUP BY batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[int16]int64{}
+	for _, row := range dataSet.rows {
+		counts[row.cells[0].(int16)] = row.cells[1].(int64)
+	}
+
+	return counts
+}
+
+func TestCrashRecovery(t *testing.T) {
+	// batches are inserted by separate transactions, large ones span several pages
+	// which are evicted from small buffer pool before commit
+	type step struct {
+		batch int16
+		rows  int
+	}
+	steps := []step{{1, 1}, {2, 700}, {0, 0}, {3, 1}, {4, 700}, {5, 1}}
+	const poolSize = 3
+
+	for crashAt := 1; ; crashAt++ {
+		crashed := false
+		t.Run(fmt.Sprintf("crash at write %d", crashAt), func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE items (batch smallint, id smallint, name varchar)")
+			defer func() { crashHook = nil }()
+
+			bufferPool.close()
+			bufferPool = newBufferPool(poolSize)
+
+			committed := map[int16]int64{}
+			var interrupted step
+			crashedFlag := simulateCrashAt(crashAt)
+			for _, s := range steps {
+				var err error
+				switch {
+				case s.rows == 0:
+					err = writeAheadLog.checkpoint()
+				case s.rows == 1:
+					_, err = ExecuteQuery(fmt.Sprintf(
+						"INSERT INTO items (batch, id, name) VALUES ('%d', '0', 'single')", s.batch))
+				default:
+					table, rows := itemRows(t, s.batch, s.rows)
+					err = autocommit(func(tx *Transaction) error {
+						return writeIntoTable(tx, table, DataSet{columns: table.columns, rows: rows})
+					})
+				}
+
+				if err != nil {
+					if !errors.Is(err, errSimulatedCrash) {
+						t.Fatal(err)
+					}
+
+					interrupted = s
+					break
+				}
+
+				if s.rows > 0 {
+					committed[s.batch] = int64(s.rows)
+				}
+			}
+
+			crashed = *crashedFlag
+			if !crashed {
+				return
+			}
+
+			crashHook = nil
+			if err := restartDatabase(poolSize); err != nil {
+				t.Fatal(err)
+			}
+
+			// interrupted transaction is atomic, it's either committed or rolled back
+			counts := batchCounts(t)
+			if n, ok := counts[interrupted.batch]; ok && interrupted.rows > 0 {
+				if n != int64(interrupted.rows) {
+					t.Fatalf("batch %d partially recovered with %d rows", interrupted.batch, n)
+				}
+				committed[interrupted.batch] = n
+			}
+
+			if !reflect.DeepEqual(counts, committed) {
+				t.Fatalf("\nexp %+v\ngot %+v", committed, counts)
+			}
+
+			// recovered database accepts writes and survives another restart
+			if _, err := ExecuteQuery("INSERT INTO items (batch, id, name) VALUES ('9', '0', 'after')"); err != nil {
+				t.Fatal(err)
+			}
+			committed[9] = 1
+
+			if err := restartDatabase(poolSize); err != nil {
+				t.Fatal(err)
+			}
+
+			if counts := batchCounts(t); !reflect.DeepEqual(counts, committed) {
+				t.Fatalf("\nexp %+v\ngot %+v", committed, counts)
+			}
+		})
+
+		if !crashed {
+			break
+		}
+	}
+}
+
+func TestCrashDuringRecovery(t *testing.T) {
+	const poolSize = 2
+
+	for crashAt := 1; ; crashAt++ {
+		crashed := false
+		t.Run(fmt.Sprintf("crash at recovery write %d", crashAt), func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (batch smallint, id smallint, name varchar)",
+				"INSERT INTO items (batch, id, name) VALUES ('1', '0', 'committed')",
+			)
+			defer func() { crashHook = nil }()
+
+			bufferPool.close()
+			bufferPool = newBufferPool(poolSize)
+
+			// unfinished transaction with pages already written by eviction
+			table, rows := itemRows(t, 2, 1000)
+			tx := beginTransaction(readCommitted)
+			if err := insertRows(tx, table, rows); err != nil {
+				t.Fatal(err)
+			}
+
+			// recovery itself crashes and is repeated
+			flag := simulateCrashAt(crashAt)
+			restartDatabase(poolSize)
+			crashed = *flag
+
+			crashHook = nil
+			if err := restartDatabase(poolSize); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[int16]int64{1: 1}
+			if counts := batchCounts(t); !reflect.DeepEqual(counts, expected) {
+				t.Fatalf("\nexp %+v\ngot %+v", expected, counts)
+			}
+		})
+
+		if !crashed {
+			break
+		}
+	}
+}
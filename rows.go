@@ -0,0 +1,157 @@
+package auralis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoCurrentRow    = AuraError{Code: "NO_DATA", Message: "no current row to scan"}
+	ErrScanColumnCount = AuraError{Code: "INVALID_PARAMETERS", Message: "number of destinations doesn't match number of columns"}
+	ErrScanNull        = AuraError{Code: "NULL_VALUE_NOT_ALLOWED", Message: "null can't be scanned into destination other than *any"}
+)
+
+// Rows iterates over rows returned by query, Next advances to the next row
+// whose values are copied by Scan
+type Rows struct {
+	ctx     context.Context
+	dataSet *DataSet
+	current int // index of the current row, -1 before the first Next
+	err     error
+	closed  bool
+}
+
+func newRows(ctx context.Context, dataSet *DataSet) *Rows {
+	if dataSet == nil {
+		dataSet = &DataSet{}
+	}
+
+	return &Rows{ctx: ctx, dataSet: dataSet, current: -1}
+}
+
+// Columns are columns of returned rows
+func (r *Rows) Columns() []Column {
+	return r.dataSet.columns
+}
+
+// Next advances to the next row, it returns false after the last row, when
+// rows are closed or their context is canceled
+func (r *Rows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+
+	if r.err = r.ctx.Err(); r.err != nil {
+		return false
+	}
+
+	r.current++
+	if r.current >= len(r.dataSet.rows) {
+		r.closed = true
+		return false
+	}
+
+	return true
+}
+
+// Scan copies values of the current row into destinations, integers are
+// converted into integer destinations wide enough for them
+func (r *Rows) Scan(dest ...any) error {
+	if r.closed || r.current < 0 {
+		return ErrNoCurrentRow
+	}
+
+	cells := r.dataSet.rows[r.current].cells
+	if len(dest) != len(cells) {
+		return ErrScanColumnCount
+	}
+
+	for i, value := range cells {
+		if err := scanValue(dest[i], value); err != nil {
+			return AuraError{
+				Code:    "TYPE_CONV_ERROR",
+				Message: fmt.Sprintf("column %s: %v", r.dataSet.columns[i].name, err)}
+		}
+	}
+
+	return nil
+}
+
+// Err returns error which stopped iteration
+func (r *Rows) Err() error {
+	return r.err
+}
+
+func (r *Rows) Close() error {
+	r.closed = true
+	return nil
+}
+
+// scanValue stores value into destination pointer
+func scanValue(dest any, value any) error {
+	if d, ok := dest.(*any); ok {
+		*d = value
+		return nil
+	}
+
+	if value == nil {
+		return ErrScanNull
+	}
+
+	var err error
+	switch d := dest.(type) {
+	case *string:
+		var v any
+		if v, err = castValue(varchar, value); err == nil {
+			*d = v.(string)
+		}
+	case *int16:
+		var v any
+		if v, err = castValue(smallint, value); err == nil {
+			*d = v.(int16)
+		}
+	case *int32:
+		var v any
+		if v, err = castValue(integer, value); err == nil {
+			*d = v.(int32)
+		}
+	case *int64:
+		var v any
+		if v, err = castValue(bigint, value); err == nil {
+			*d = v.(int64)
+		}
+	case *int:
+		var v any
+		if v, err = castValue(bigint, value); err == nil {
+			*d = int(v.(int64))
+		}
+	case *float64:
+		var v any
+		if v, err = castValue(double, value); err == nil {
+			*d = v.(float64)
+		}
+	case *bool:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("value of type %s can't be scanned into *bool", valueDataType(value))
+		}
+		*d = v
+	case *time.Time:
+		var v any
+		if v, err = castValue(timestamp, value); err == nil {
+			*d = v.(time.Time)
+		}
+	case *uuid.UUID:
+		var v any
+		if v, err = castValue(uniqueidentifier, value); err == nil {
+			*d = v.(uuid.UUID)
+		}
+	default:
+		return fmt.Errorf("unsupported destination %T", dest)
+	}
+
+	return err
+}
@@ -0,0 +1,325 @@
+package auralis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ArgumentType int
+
+const (
+	anyArgument ArgumentType = iota
+	textArgument
+	integerArgument
+	numericArgument
+)
+
+// ScalarFunction describes built-in function computing single value from its arguments
+type ScalarFunction struct {
+	minArgs int
+	maxArgs int // -1 for variadic functions
+	// expected type of each argument, the last one applies to all remaining arguments
+	argumentTypes []ArgumentType
+	// strict functions return null when any of arguments is null without being called
+	strict bool
+	// volatile functions can return different values for the same arguments so
+	// they aren't evaluated during planning
+	volatile   bool
+	returnType func(args []DataType) DataType
+	call       func(args []any) (any, error)
+}
+
+var (
+	ErrInvalidArgumentType   = AuraError{Code: "INVALID_ARGUMENT_TYPE", Message: "function argument has invalid type"}
+	ErrInvalidArgumentsCount = AuraError{
+		Code:    "INVALID_ARGUMENTS",
+		Message: "function called with invalid number of arguments"}
+	ErrDivisionByZero = AuraError{Code: "DIVISION_BY_ZERO", Message: "division by zero"}
+)
+
+func returns(dataType DataType) func(args []DataType) DataType {
+	return func(args []DataType) DataType { return dataType }
+}
+
+// returnsFirstKnown returns type of the first argument which type is known
+func returnsFirstKnown(args []DataType) DataType {
+	for _, arg := range args {
+		if arg != unknown {
+			return arg
+		}
+	}
+
+	return unknown
+}
+
+var scalarFunctions = map[string]ScalarFunction{
+	"lower": {
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{textArgument}, strict: true,
+		returnType: returns(varchar),
+		call: func(args []any) (any, error) {
+			return strings.ToLower(args[0].(string)), nil
+		},
+	},
+	"upper": {
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{textArgument}, strict: true,
+		returnType: returns(varchar),
+		call: func(args []any) (any, error) {
+			return strings.ToUpper(args[0].(string)), nil
+		},
+	},
+	"length": {
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{textArgument}, strict: true,
+		returnType: returns(bigint),
+		call: func(args []any) (any, error) {
+			return int64(len([]rune(args[0].(string)))), nil
+		},
+	},
+	"substring": {
+		minArgs: 2, maxArgs: 3, argumentTypes: []ArgumentType{textArgument, integerArgument}, strict: true,
+		returnType: returns(varchar),
+		call: func(args []any) (any, error) {
+			runes := []rune(args[0].(string))
+
+			// positions are counted from 1 and may start before the string
+			start := toInt64(args[1]) - 1
+			end := int64(len(runes))
+			if len(args) == 3 {
+				length := toInt64(args[2])
+				if length < 0 {
+					return nil, AuraError{Code: "INVALID_ARGUMENTS", Message: "negative substring length not allowed"}
+				}
+				end = min(end, start+length)
+			}
+			start = max(start, 0)
+
+			if start >= end {
+				return "", nil
+			}
+
+			return string(runes[start:end]), nil
+		},
+	},
+	"trim": {
+		minArgs: 1, maxArgs: 2, argumentTypes: []ArgumentType{textArgument}, strict: true,
+		returnType: returns(varchar),
+		call: func(args []any) (any, error) {
+			if len(args) == 2 {
+				return strings.Trim(args[0].(string), args[1].(string)), nil
+			}
+
+			return strings.Trim(args[0].(string), " "), nil
+		},
+	},
+	"replace": {
+		minArgs: 3, maxArgs: 3, argumentTypes: []ArgumentType{textArgument}, strict: true,
+		returnType: returns(varchar),
+		call: func(args []any) (any, error) {
+			return strings.ReplaceAll(args[0].(string), args[1].(string), args[2].(string)), nil
+		},
+	},
+	"concat": {
+		minArgs: 1, maxArgs: -1, argumentTypes: []ArgumentType{anyArgument},
+		returnType: returns(varchar),
+		call: func(args []any) (any, error) {
+			var b strings.Builder
+			for _, arg := range args {
+				if arg != nil {
+					fmt.Fprintf(&b, "%v", arg)
+				}
+			}
+
+			return b.String(), nil
+		},
+	},
+	"abs": {
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{numericArgument}, strict: true,
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case int16:
+				return max(v, -v), nil
+			case int32:
+				return max(v, -v), nil
+			case int64:
+				return max(v, -v), nil
+			default:
+				return math.Abs(toFloat64(v)), nil
+			}
+		},
+	},
+	"round": {
+		minArgs: 1, maxArgs: 2, argumentTypes: []ArgumentType{numericArgument, integerArgument}, strict: true,
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			if isInteger(args[0]) {
+				return args[0], nil
+			}
+
+			scale := 1.0
+			if len(args) == 2 {
+				scale = math.Pow(10, float64(toInt64(args[1])))
+			}
+
+			return math.Round(toFloat64(args[0])*scale) / scale, nil
+		},
+	},
+	"ceil": {
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{numericArgument}, strict: true,
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			if isInteger(args[0]) {
+				return args[0], nil
+			}
+
+			return math.Ceil(toFloat64(args[0])), nil
+		},
+	},
+	"floor": {
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{numericArgument}, strict: true,
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			if isInteger(args[0]) {
+				return args[0], nil
+			}
+
+			return math.Floor(toFloat64(args[0])), nil
+		},
+	},
+	"mod": {
+		minArgs: 2, maxArgs: 2, argumentTypes: []ArgumentType{integerArgument}, strict: true,
+		returnType: returns(bigint),
+		call: func(args []any) (any, error) {
+			divisor := toInt64(args[1])
+			if divisor == 0 {
+				return nil, ErrDivisionByZero
+			}
+
+			return toInt64(args[0]) % divisor, nil
+		},
+	},
+	"coalesce": {
+		minArgs: 1, maxArgs: -1, argumentTypes: []ArgumentType{anyArgument},
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			for _, arg := range args {
+				if arg != nil {
+					return arg, nil
+				}
+			}
+
+			return nil, nil
+		},
+	},
+	"nullif": {
+		minArgs: 2, maxArgs: 2, argumentTypes: []ArgumentType{anyArgument},
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			if args[0] != nil && args[1] != nil && compareValues(args[0], args[1]) == 0 {
+				return nil, nil
+			}
+
+			return args[0], nil
+		},
+	},
+	"greatest": {
+		minArgs: 1, maxArgs: -1, argumentTypes: []ArgumentType{anyArgument},
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			acc := extremumAccumulator{sign: 1}
+			for _, arg := range args {
+				acc.add(arg)
+			}
+
+			return acc.result(), nil
+		},
+	},
+	"least": {
+		minArgs: 1, maxArgs: -1, argumentTypes: []ArgumentType{anyArgument},
+		returnType: returnsFirstKnown,
+		call: func(args []any) (any, error) {
+			acc := extremumAccumulator{sign: -1}
+			for _, arg := range args {
+				acc.add(arg)
+			}
+
+			return acc.result(), nil
+		},
+	},
+	"gen_random_uuid": {
+		minArgs: 0, maxArgs: 0, volatile: true,
+		returnType: returns(uniqueidentifier),
+		call: func(args []any) (any, error) {
+			return uuid.New(), nil
+		},
+	},
+	"now": {
+		minArgs: 0, maxArgs: 0, volatile: true,
+		returnType: returns(timestamp),
+		call: func(args []any) (any, error) {
+			return time.Now().UTC().Truncate(time.Microsecond), nil
+		},
+	},
+}
+
+// checkFunctionArguments validates arguments count and types of scalar function call
+func checkFunctionArguments(name string, fn ScalarFunction, argTypes []DataType) error {
+	if len(argTypes) < fn.minArgs || (fn.maxArgs != -1 && len(argTypes) > fn.maxArgs) {
+		return AuraError{
+			Code:    ErrInvalidArgumentsCount.Code,
+			Message: fmt.Sprintf("function %s called with %d arguments", name, len(argTypes))}
+	}
+
+	for i, argType := range argTypes {
+		expected := fn.argumentTypes[min(i, len(fn.argumentTypes)-1)]
+		if !isArgumentTypeCompatible(expected, argType) {
+			return AuraError{
+				Code:    ErrInvalidArgumentType.Code,
+				Message: fmt.Sprintf("function %s argument %d can't be of type %s", name, i+1, argType)}
+		}
+	}
+
+	return nil
+}
+
+func isArgumentTypeCompatible(expected ArgumentType, dataType DataType) bool {
+	if dataType == unknown {
+		return true
+	}
+
+	switch expected {
+	case textArgument:
+		return dataType == varchar
+	case integerArgument:
+		return dataType == smallint || dataType == integer || dataType == bigint
+	case numericArgument:
+		return dataType == smallint || dataType == integer || dataType == bigint || dataType == double
+	default:
+		return true
+	}
+}
+
+// callScalarFunction checks types of evaluated arguments and calls function
+func callScalarFunction(name string, fn ScalarFunction, args []any) (any, error) {
+	argTypes := make([]DataType, 0, len(args))
+	for _, arg := range args {
+		if arg == nil {
+			argTypes = append(argTypes, unknown)
+			if fn.strict {
+				return nil, nil
+			}
+			continue
+		}
+
+		argTypes = append(argTypes, valueDataType(arg))
+	}
+
+	if err := checkFunctionArguments(name, fn, argTypes); err != nil {
+		return nil, err
+	}
+
+	return fn.call(args)
+}
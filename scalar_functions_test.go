@@ -0,0 +1,97 @@
+package auralis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCallScalarFunction(t *testing.T) {
+	testCases := map[string]struct {
+		name string
+		args []any
+
+		expected    any
+		expectedErr string // expected error code
+	}{
+		"lower":                    {name: "lower", args: []any{"AbC"}, expected: "abc"},
+		"upper":                    {name: "upper", args: []any{"AbC"}, expected: "ABC"},
+		"length counts characters": {name: "length", args: []any{"zażółć"}, expected: int64(6)},
+		"substring from position":  {name: "substring", args: []any{"database", int64(5)}, expected: "base"},
+		"substring with length":    {name: "substring", args: []any{"database", int64(0), int64(3)}, expected: "da"},
+		"substring negative length": {
+			name: "substring", args: []any{"database", int64(1), int64(-1)},
+			expectedErr: "INVALID_ARGUMENTS",
+		},
+		"trim spaces":          {name: "trim", args: []any{"  a b  "}, expected: "a b"},
+		"trim characters":      {name: "trim", args: []any{"xxaxx", "x"}, expected: "a"},
+		"replace":              {name: "replace", args: []any{"a-b-c", "-", "+"}, expected: "a+b+c"},
+		"concat skips nulls":   {name: "concat", args: []any{"a", nil, int16(1)}, expected: "a1"},
+		"abs of integer":       {name: "abs", args: []any{int16(-3)}, expected: int16(3)},
+		"abs of double":        {name: "abs", args: []any{-1.5}, expected: 1.5},
+		"round with scale":     {name: "round", args: []any{1.256, int64(2)}, expected: 1.26},
+		"round of integer":     {name: "round", args: []any{int32(7)}, expected: int32(7)},
+		"ceil":                 {name: "ceil", args: []any{1.2}, expected: 2.0},
+		"floor":                {name: "floor", args: []any{-1.2}, expected: -2.0},
+		"mod":                  {name: "mod", args: []any{int64(7), int16(3)}, expected: int64(1)},
+		"mod by zero":          {name: "mod", args: []any{int64(7), int64(0)}, expectedErr: "DIVISION_BY_ZERO"},
+		"coalesce":             {name: "coalesce", args: []any{nil, "b", "c"}, expected: "b"},
+		"nullif equal":         {name: "nullif", args: []any{int64(1), int16(1)}, expected: nil},
+		"nullif different":     {name: "nullif", args: []any{"a", "b"}, expected: "a"},
+		"greatest":             {name: "greatest", args: []any{int64(1), nil, int64(5), int64(3)}, expected: int64(5)},
+		"least":                {name: "least", args: []any{"b", "a", "c"}, expected: "a"},
+		"strict function null": {name: "upper", args: []any{nil}, expected: nil},
+		"text function with integer": {
+			name: "lower", args: []any{int16(1)},
+			expectedErr: "INVALID_ARGUMENT_TYPE",
+		},
+		"numeric function with text": {
+			name: "abs", args: []any{"1"},
+			expectedErr: "INVALID_ARGUMENT_TYPE",
+		},
+		"too many arguments": {
+			name: "lower", args: []any{"a", "b"},
+			expectedErr: "INVALID_ARGUMENTS",
+		},
+		"too few arguments": {
+			name: "replace", args: []any{"a"},
+			expectedErr: "INVALID_ARGUMENTS",
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			result, err := callScalarFunction(tC.name, scalarFunctions[tC.name], tC.args)
+			if tC.expectedErr != "" {
+				if auraErr, ok := err.(AuraError); !ok || auraErr.Code != tC.expectedErr {
+					t.Fatalf("\nexp %+v\ngot %+v", tC.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(result, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, result)
+			}
+		})
+	}
+}
+
+func TestGenRandomUUID(t *testing.T) {
+	fn := scalarFunctions["gen_random_uuid"]
+
+	a, err := callScalarFunction("gen_random_uuid", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := callScalarFunction("gen_random_uuid", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reflect.DeepEqual(a, b) {
+		t.Errorf("expected distinct values, got %v twice", a)
+	}
+}
@@ -0,0 +1,371 @@
+package auralis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+const sequences string = "sequences"
+
+// sequenceCacheSize is number of values reserved in state file at once, values
+// reserved but not returned before restart are skipped
+const sequenceCacheSize = 32
+
+// auralisSequences lists definitions of sequences, their current values are
+// kept in state files outside of the catalog
+var auralisSequences = Table{
+	schemaTable: SchemaTable[string, string]{internalSchema, sequences},
+	columns: []Column{
+		{
+			name:     "sequence_schema",
+			dataType: varchar,
+			position: 1,
+		},
+		{
+			name:     "sequence_name",
+			dataType: varchar,
+			position: 2,
+		},
+		{
+			name:     "data_type",
+			dataType: varchar,
+			position: 3,
+		},
+		{
+			name:     "start_value",
+			dataType: bigint,
+			position: 4,
+		},
+		{
+			name:     "increment_by",
+			dataType: bigint,
+			position: 5,
+		},
+		{
+			name:     "min_value",
+			dataType: bigint,
+			position: 6,
+		},
+		{
+			name:     "max_value",
+			dataType: bigint,
+			position: 7,
+		},
+	},
+}
+
+var (
+	ErrSequenceExists   = AuraError{Code: "DUPLICATE_OBJECT", Message: "sequence already exists"}
+	ErrSequenceNotFound = AuraError{Code: "UNDEFINED_OBJECT", Message: "sequence does not exist"}
+	ErrInvalidSequence  = AuraError{Code: "INVALID_PARAMETER_VALUE", Message: "invalid sequence parameters"}
+	ErrSequenceLimit    = AuraError{Code: "SEQUENCE_LIMIT_EXCEEDED", Message: "sequence reached its limit"}
+	ErrCurrvalUndefined = AuraError{
+		Code:    "OBJECT_NOT_IN_PREREQUISITE_STATE",
+		Message: "currval of sequence is not yet defined in this session"}
+)
+
+// Sequence generates integers within its bounds. Values are handed out
+// outside of transactions so they are never returned twice, also when
+// transaction which obtained them rolls back
+type Sequence struct {
+	name      SchemaTable[string, string]
+	dataType  DataType
+	start     int64
+	increment int64
+	min       int64
+	max       int64
+}
+
+// sequenceState holds the last value returned by sequence and the last value
+// reserved in its state file
+type sequenceState struct {
+	called   bool
+	last     int64
+	reserved int64
+}
+
+// sequenceStates are states of sequences used since start by state file path
+var sequenceStates = struct {
+	sync.Mutex
+	states map[string]*sequenceState
+}{states: map[string]*sequenceState{}}
+
+// sequenceLimits are bounds of sequence data types
+var sequenceLimits = map[DataType][2]int64{
+	smallint: {math.MinInt16, math.MaxInt16},
+	integer:  {math.MinInt32, math.MaxInt32},
+	bigint:   {math.MinInt64, math.MaxInt64},
+}
+
+func init() {
+	// sequence functions read catalog so they can't be part of map initialization
+	scalarFunctions["nextval"] = ScalarFunction{
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{textArgument}, strict: true, volatile: true,
+		returnType: returns(bigint),
+		call: func(args []any) (any, error) {
+			return nextval(args[0].(string))
+		},
+	}
+	scalarFunctions["currval"] = ScalarFunction{
+		minArgs: 1, maxArgs: 1, argumentTypes: []ArgumentType{textArgument}, strict: true, volatile: true,
+		returnType: returns(bigint),
+		call: func(args []any) (any, error) {
+			return currval(args[0].(string))
+		},
+	}
+}
+
+// newSequence applies options to defaults of ascending or descending sequence
+// of data type and checks that start value lies within bounds
+func newSequence(name SchemaTable[string, string], dataType DataType, options map[string]int64) (Sequence, error) {
+	limits, ok := sequenceLimits[dataType]
+	if !ok {
+		return Sequence{}, AuraError{
+			Code:    ErrInvalidSequence.Code,
+			Message: "sequence type must be smallint, integer or bigint"}
+	}
+
+	sequence := Sequence{name: name, dataType: dataType, increment: 1}
+	if increment, ok := options["increment"]; ok {
+		sequence.increment = increment
+	}
+
+	if sequence.increment == 0 {
+		return Sequence{}, AuraError{Code: ErrInvalidSequence.Code, Message: "sequence increment must not be zero"}
+	}
+
+	sequence.min, sequence.max = 1, limits[1]
+	if sequence.increment < 0 {
+		sequence.min, sequence.max = limits[0], -1
+	}
+
+	if min, ok := options["minvalue"]; ok {
+		sequence.min = min
+	}
+
+	if max, ok := options["maxvalue"]; ok {
+		sequence.max = max
+	}
+
+	if sequence.min < limits[0] || sequence.max > limits[1] || sequence.min >= sequence.max {
+		return Sequence{}, AuraError{
+			Code:    ErrInvalidSequence.Code,
+			Message: fmt.Sprintf("sequence bounds %d and %d are invalid for type %s", sequence.min, sequence.max, dataType)}
+	}
+
+	sequence.start = sequence.min
+	if sequence.increment < 0 {
+		sequence.start = sequence.max
+	}
+
+	if start, ok := options["start"]; ok {
+		sequence.start = start
+	}
+
+	if sequence.start < sequence.min || sequence.start > sequence.max {
+		return Sequence{}, AuraError{
+			Code:    ErrInvalidSequence.Code,
+			Message: fmt.Sprintf("sequence start value %d is out of its bounds", sequence.start)}
+	}
+
+	return sequence, nil
+}
+
+func getSequenceDiskPath(name SchemaTable[string, string]) string {
+	return fmt.Sprintf("%s/%s.%s.sequence", dataPath, name.schema, name.name)
+}
+
+func handleCreateSequenceQuery(tx *Transaction, query CreateSequenceQuery) error {
+	sequence, err := newSequence(query.name, query.dataType, query.options)
+	if err != nil {
+		return err
+	}
+
+	return createSequence(tx, sequence)
+}
+
+// createSequence registers sequence in catalog and resets its state file, file
+// of sequence whose creation rolled back is reset again by the next create
+func createSequence(tx *Transaction, sequence Sequence) error {
+	if _, err := getSequence(tx, sequence.name); err == nil {
+		return AuraError{
+			Code:    ErrSequenceExists.Code,
+			Message: fmt.Sprintf("sequence %s already exists", sequence.name.name)}
+	} else if err != ErrSequenceNotFound {
+		return err
+	}
+
+	path := getSequenceDiskPath(sequence.name)
+	sequenceStates.Lock()
+	delete(sequenceStates.states, path)
+	err := os.WriteFile(path, nil, 0644)
+	sequenceStates.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return writeIntoTable(tx, auralisSequences, DataSet{rows: []Row{{cells: []any{
+		sequence.name.schema, sequence.name.name, string(sequence.dataType),
+		sequence.start, sequence.increment, sequence.min, sequence.max,
+	}}}})
+}
+
+// getSequence returns definition of sequence registered in catalog
+func getSequence(tx *Transaction, name SchemaTable[string, string]) (Sequence, error) {
+	dataSet, err := readFromTable(tx, auralisSequences, SelectQuery{
+		source: auralisSequences.schemaTable,
+		dataColumns: []string{
+			"sequence_schema", "sequence_name", "data_type",
+			"start_value", "increment_by", "min_value", "max_value",
+		},
+		conditions: []Condition{
+			{target: "sequence_schema", sign: "=", value: name.schema},
+			{target: "sequence_name", sign: "=", value: name.name},
+		},
+	})
+	if err != nil {
+		return Sequence{}, err
+	}
+
+	if len(dataSet.rows) == 0 {
+		return Sequence{}, ErrSequenceNotFound
+	}
+
+	cells := dataSet.rows[0].cells
+	return Sequence{
+		name:      name,
+		dataType:  DataType(cells[2].(string)),
+		start:     cells[3].(int64),
+		increment: cells[4].(int64),
+		min:       cells[5].(int64),
+		max:       cells[6].(int64),
+	}, nil
+}
+
+// readSequenceState reads the last reserved value from state file, empty file
+// belongs to sequence which wasn't called yet
+func readSequenceState(path string) (*sequenceState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return &sequenceState{}, nil
+	}
+
+	if len(data) < 8 {
+		return nil, fmt.Errorf("sequence state file %s: %w", path, io.ErrUnexpectedEOF)
+	}
+
+	reserved := int64(binary.BigEndian.Uint64(data))
+	return &sequenceState{called: true, last: reserved, reserved: reserved}, nil
+}
+
+// writeSequenceState durably records the last reserved value
+func writeSequenceState(path string, reserved int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteAt(binary.BigEndian.AppendUint64(nil, uint64(reserved)), 0); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// sequenceName resolves name passed to sequence functions
+func sequenceName(name string) SchemaTable[string, string] {
+	return parseSchemaTable(strings.ToLower(name))
+}
+
+// nextval advances sequence and returns its new value. Values are reserved in
+// state file in batches so the file isn't written by each call, values of a
+// batch not returned before restart are skipped
+func nextval(name string) (int64, error) {
+	tx := activeStatement.tx
+	if tx == nil {
+		snapshot := writeAheadLog.latestSnapshot()
+		tx = &Transaction{snapshot: &snapshot}
+	}
+
+	sequence, err := getSequence(tx, sequenceName(name))
+	if err != nil {
+		return 0, err
+	}
+
+	path := getSequenceDiskPath(sequence.name)
+	sequenceStates.Lock()
+	defer sequenceStates.Unlock()
+
+	state, ok := sequenceStates.states[path]
+	if !ok {
+		if state, err = readSequenceState(path); err != nil {
+			return 0, err
+		}
+		sequenceStates.states[path] = state
+	}
+
+	next := sequence.start
+	if state.called {
+		// distance to bound is computed unsigned so it can't overflow
+		if (sequence.increment > 0 && (state.last >= sequence.max || uint64(sequence.max)-uint64(state.last) < uint64(sequence.increment))) ||
+			(sequence.increment < 0 && (state.last <= sequence.min || uint64(state.last)-uint64(sequence.min) < uint64(-sequence.increment))) {
+			return 0, AuraError{
+				Code:    ErrSequenceLimit.Code,
+				Message: fmt.Sprintf("sequence %s reached its limit", sequence.name.name)}
+		}
+		next = state.last + sequence.increment
+	}
+
+	if !state.called || (sequence.increment > 0 && next > state.reserved) || (sequence.increment < 0 && next < state.reserved) {
+		reserved := reserveSequenceValues(sequence, next)
+		if err := writeSequenceState(path, reserved); err != nil {
+			return 0, err
+		}
+		state.reserved = reserved
+	}
+
+	state.called, state.last = true, next
+	if session := activeStatement.session; session != nil {
+		session.sequenceValues[sequence.name] = next
+	}
+
+	return next, nil
+}
+
+// reserveSequenceValues returns the last value of batch starting at next
+// which doesn't exceed bounds of sequence
+func reserveSequenceValues(sequence Sequence, next int64) int64 {
+	steps := uint64(sequenceCacheSize - 1)
+	if sequence.increment > 0 {
+		steps = min(steps, (uint64(sequence.max)-uint64(next))/uint64(sequence.increment))
+		return next + int64(steps)*sequence.increment
+	}
+
+	steps = min(steps, (uint64(next)-uint64(sequence.min))/uint64(-sequence.increment))
+	return next + int64(steps)*sequence.increment
+}
+
+// currval returns value most recently obtained by nextval of sequence in
+// session of current statement
+func currval(name string) (int64, error) {
+	sequence := sequenceName(name)
+	if session := activeStatement.session; session != nil {
+		if value, ok := session.sequenceValues[sequence]; ok {
+			return value, nil
+		}
+	}
+
+	return 0, AuraError{
+		Code:    ErrCurrvalUndefined.Code,
+		Message: fmt.Sprintf("currval of sequence %s is not yet defined in this session", sequence.name)}
+}
@@ -0,0 +1,122 @@
+package auralis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSequences(t *testing.T) {
+	testCases := map[string]struct {
+		queries  []string
+		expected [][]any
+		err      string // code of expected error
+	}{
+		"next values": {
+			queries:  []string{"CREATE SEQUENCE ids", "SELECT nextval('ids')", "SELECT nextval('ids')"},
+			expected: [][]any{{int64(2)}},
+		},
+		"options": {
+			queries: []string{
+				"CREATE SEQUENCE ids START WITH 10 INCREMENT BY 5 MAXVALUE 100",
+				"SELECT nextval('ids')",
+				"SELECT nextval('dbo.ids')",
+			},
+			expected: [][]any{{int64(15)}},
+		},
+		"descending sequence": {
+			queries:  []string{"CREATE SEQUENCE ids INCREMENT -2", "SELECT nextval('ids')", "SELECT nextval('ids')"},
+			expected: [][]any{{int64(-3)}},
+		},
+		"current value": {
+			queries:  []string{"CREATE SEQUENCE ids START 7", "SELECT nextval('ids')", "SELECT currval('ids'), currval('ids')"},
+			expected: [][]any{{int64(7), int64(7)}},
+		},
+		"current value before next value": {
+			queries: []string{"CREATE SEQUENCE ids", "SELECT currval('ids')"},
+			err:     ErrCurrvalUndefined.Code,
+		},
+		"limit reached": {
+			queries: []string{
+				"CREATE SEQUENCE ids AS smallint START 32766",
+				"SELECT nextval('ids')",
+				"SELECT nextval('ids')",
+				"SELECT nextval('ids')",
+			},
+			err: ErrSequenceLimit.Code,
+		},
+		"values aren't reused after rollback": {
+			queries: []string{
+				"CREATE SEQUENCE ids",
+				"BEGIN",
+				"SELECT nextval('ids')",
+				"ROLLBACK",
+				"SELECT nextval('ids')",
+			},
+			expected: [][]any{{int64(2)}},
+		},
+		"rolled back sequence": {
+			queries: []string{"BEGIN", "CREATE SEQUENCE ids", "ROLLBACK", "SELECT nextval('ids')"},
+			err:     ErrSequenceNotFound.Code,
+		},
+		"existing sequence": {
+			queries: []string{"CREATE SEQUENCE ids", "CREATE SEQUENCE ids"},
+			err:     ErrSequenceExists.Code,
+		},
+		"start out of bounds": {
+			queries: []string{"CREATE SEQUENCE ids START 0"},
+			err:     ErrInvalidSequence.Code,
+		},
+		"zero increment": {
+			queries: []string{"CREATE SEQUENCE ids INCREMENT 0"},
+			err:     ErrInvalidSequence.Code,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t)
+
+			var dataSet *DataSet
+			var err error
+			for _, query := range tC.queries {
+				if dataSet, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			code := ""
+			var auraErr AuraError
+			if errors.As(err, &auraErr) {
+				code = auraErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+
+			if tC.err != "" {
+				return
+			}
+
+			if rows := resultCells(dataSet); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestSequenceSurvivesRestart(t *testing.T) {
+	setupTestDatabase(t, "CREATE SEQUENCE ids", "SELECT nextval('ids')", "SELECT nextval('ids')")
+
+	if err := restartDatabase(defaultBufferPoolSize); err != nil {
+		t.Fatal(err)
+	}
+
+	// values reserved before restart are skipped
+	expected := [][]any{{int64(sequenceCacheSize + 1)}}
+	if rows := queryCells(t, "SELECT nextval('ids')"); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
@@ -0,0 +1,259 @@
+package auralis
+
+import (
+	"context"
+	"sync"
+)
+
+// Session executes statements of a single client, transaction started by begin
+// stays open across its statements. Statements of concurrent sessions are
+// executed one at a time so their transactions interleave between statements,
+// session used by several goroutines runs their calls one after another
+type Session struct {
+	lock           sync.Mutex // held by call of session until its statements finish
+	transaction    *Transaction
+	sequenceValues map[SchemaTable[string, string]]int64 // the last values of nextval by sequence
+	prepared       map[string]*PreparedStatement         // statements prepared by name
+	statements     *statementCache                       // statements of Go API by query text
+}
+
+// statementContext describes statement being executed for functions which
+// depend on session or transaction, eg. currval
+type statementContext struct {
+	session *Session
+	tx      *Transaction
+	ctx     context.Context // canceling context stops waiting for locks
+}
+
+// statementLock serializes statements of all sessions
+var statementLock sync.Mutex
+
+// activeStatement is guarded by statementLock, lock manager restores it when
+// statement waiting for a lock resumes
+var activeStatement statementContext
+
+// defaultSession executes queries of ExecuteQuery
+var defaultSession = newSession()
+
+func newSession() *Session {
+	return &Session{
+		sequenceValues: map[SchemaTable[string, string]]int64{},
+		prepared:       map[string]*PreparedStatement{},
+		statements:     newStatementCache(),
+	}
+}
+
+// executeQuery executes semicolon separated statements and returns result of the last one
+func (s *Session) executeQuery(raw string) (*DataSet, error) {
+	return s.executeContext(context.Background(), raw)
+}
+
+// executeContext executes statements until context is canceled, query with
+// arguments must be a single statement whose $n placeholders they are bound to
+func (s *Session) executeContext(ctx context.Context, raw string, args ...any) (*DataSet, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(args) > 0 {
+		p, err := s.statements.prepare(raw)
+		if err != nil {
+			return &DataSet{}, err
+		}
+
+		return s.executeArguments(ctx, p, args)
+	}
+
+	statements := splitStatements(Analyze(raw))
+	if len(statements) <= 0 {
+		return &DataSet{}, AuraError{
+			Code:    "INVALID_QUERY",
+			Message: "missing query tokens"}
+	}
+
+	var dataSet *DataSet
+	for _, tokens := range statements {
+		var err error
+		dataSet, err = s.executeStatement(ctx, tokens)
+		if err != nil {
+			return &DataSet{}, err
+		}
+	}
+
+	return dataSet, nil
+}
+
+func (s *Session) executeStatement(ctx context.Context, tokens []TokenLiteral) (*DataSet, error) {
+	query, err := ParseTokens(tokens)
+	if err != nil {
+		return &DataSet{}, err
+	}
+
+	// placeholders of prepare are bound by execute
+	if _, ok := query.(PrepareQuery); !ok {
+		if err := unboundParameter(tokens); err != nil {
+			return &DataSet{}, err
+		}
+	}
+
+	statementLock.Lock()
+	defer statementLock.Unlock()
+
+	return s.execute(ctx, query)
+}
+
+// execute runs parsed query while statementLock is held
+func (s *Session) execute(ctx context.Context, query any) (*DataSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	switch query := query.(type) {
+	case PrepareQuery:
+		return nil, s.prepare(query)
+	case ExecutePreparedQuery:
+		p, ok := s.prepared[query.name]
+		if !ok {
+			return nil, ErrPreparedStatementNotFound
+		}
+
+		bound, err := p.bind(query.args)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.execute(ctx, bound)
+	case DeallocateQuery:
+		return nil, s.deallocate(query)
+	case TransactionQuery:
+		return nil, s.executeTransactionQuery(query)
+	case SetQuery:
+		return nil, setSetting(query.name, query.value)
+	case ShowQuery:
+		return handleShowQuery(query)
+	case VacuumQuery:
+		// each table is vacuumed in its own transaction
+		if s.transaction != nil {
+			return nil, ErrVacuumInTransaction
+		}
+
+		return nil, handleVacuumQuery(query)
+	case LockTableQuery:
+		// locks are released at the end of transaction
+		if s.transaction == nil {
+			return nil, ErrNoActiveTransaction
+		}
+	}
+
+	var dataSet *DataSet
+	err := s.runStatement(func(tx *Transaction) error {
+		activeStatement = statementContext{session: s, tx: tx, ctx: ctx}
+		defer func() { activeStatement = statementContext{} }()
+
+		var err error
+		dataSet, err = executeQuery(tx, query)
+		return err
+	})
+
+	return dataSet, err
+}
+
+// runStatement runs fn within session transaction, without explicit transaction
+// fn runs in a new transaction committed on success. Changes of failed fn are
+// rolled back while explicit transaction stays open unless it's a deadlock
+// victim, whole victim is rolled back to release its locks
+func (s *Session) runStatement(fn func(tx *Transaction) error) error {
+	tx := s.transaction
+	if tx == nil {
+		return autocommit(fn)
+	}
+
+	err := tx.execute(fn)
+	if err == ErrDeadlockDetected {
+		s.transaction = nil
+		if rollbackErr := tx.rollback(); rollbackErr != nil {
+			return rollbackErr
+		}
+	}
+
+	return err
+}
+
+func (s *Session) executeTransactionQuery(query TransactionQuery) error {
+	tx := s.transaction
+	if query.command == "begin" {
+		if tx != nil {
+			return ErrActiveTransaction
+		}
+
+		level := defaultIsolationLevel()
+		if query.isolationLevel != "" {
+			level = query.isolationLevel
+		}
+
+		s.transaction = beginTransaction(level)
+		return nil
+	}
+
+	if tx == nil {
+		return ErrNoActiveTransaction
+	}
+
+	switch query.command {
+	case "commit":
+		s.transaction = nil
+		return tx.commit()
+	case "rollback":
+		s.transaction = nil
+		return tx.rollback()
+	case "savepoint":
+		tx.savepoints = append(tx.savepoints, Savepoint{name: query.savepoint, lsn: tx.lastLSN})
+		return nil
+	case "set transaction":
+		if tx.snapshot != nil {
+			return ErrIsolationLevelSet
+		}
+
+		tx.isolationLevel = query.isolationLevel
+		return nil
+	}
+
+	// the most recent savepoint of the name is used
+	i := len(tx.savepoints) - 1
+	for i >= 0 && tx.savepoints[i].name != query.savepoint {
+		i--
+	}
+
+	if i == -1 {
+		return ErrSavepointNotFound
+	}
+
+	if query.command == "release" {
+		tx.savepoints = tx.savepoints[:i]
+		return nil
+	}
+
+	// rolled back savepoint stays defined
+	tx.savepoints = tx.savepoints[:i+1]
+	return undoTransaction(tx, tx.savepoints[i].lsn)
+}
+
+// close rolls back unfinished transaction of session and forgets values
+// returned to it by sequences and its prepared statements
+func (s *Session) close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	statementLock.Lock()
+	defer statementLock.Unlock()
+
+	clear(s.sequenceValues)
+	clear(s.prepared)
+
+	tx := s.transaction
+	if tx == nil {
+		return nil
+	}
+
+	s.transaction = nil
+	return tx.rollback()
+}
@@ -0,0 +1,96 @@
+package auralis
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Setting struct {
+	value    string
+	validate func(value string) error
+}
+
+var (
+	ErrUnknownSetting = AuraError{Code: "UNKNOWN_SETTING", Message: "unrecognized configuration parameter"}
+)
+
+// settings are session scoped, they live as long as the process
+var settings = map[string]*Setting{
+	"max_recursive_iterations":      {value: "1000", validate: validatePositiveInteger},
+	"default_transaction_isolation": {value: string(readCommitted), validate: validateIsolationLevel},
+	// milliseconds of waiting for lock, zero waits without limit
+	"lock_timeout": {value: "0", validate: validateNonNegativeInteger},
+	"autovacuum":   {value: "on", validate: validateBoolean},
+	// seconds between autovacuum runs
+	"autovacuum_naptime": {value: "60", validate: validatePositiveInteger},
+	// workers reading pages of sequential scan, scan of single page isn't parallel
+	"max_parallel_workers": {value: "4", validate: validateNonNegativeInteger},
+	// number of most common values and histogram buckets kept by analyze
+	"default_statistics_target": {value: "100", validate: validatePositiveInteger},
+}
+
+func getSetting(name string) (string, error) {
+	s, ok := settings[name]
+	if !ok {
+		return "", ErrUnknownSetting
+	}
+
+	return s.value, nil
+}
+
+func getIntSetting(name string) int {
+	value, err := getSetting(name)
+	if err != nil {
+		panic(err)
+	}
+
+	// values are validated on assignment
+	v, _ := strconv.Atoi(value)
+	return v
+}
+
+func setSetting(name string, value string) error {
+	s, ok := settings[name]
+	if !ok {
+		return ErrUnknownSetting
+	}
+
+	if err := s.validate(value); err != nil {
+		return err
+	}
+
+	s.value = value
+	return nil
+}
+
+func validatePositiveInteger(value string) error {
+	v, err := strconv.Atoi(value)
+	if err != nil || v <= 0 {
+		return AuraError{
+			Code:    "INVALID_SETTING_VALUE",
+			Message: fmt.Sprintf("invalid value %s, expected positive integer", value)}
+	}
+
+	return nil
+}
+
+func validateNonNegativeInteger(value string) error {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 0 {
+		return AuraError{
+			Code:    "INVALID_SETTING_VALUE",
+			Message: fmt.Sprintf("invalid value %s, expected non-negative integer", value)}
+	}
+
+	return nil
+}
+
+func validateBoolean(value string) error {
+	if value != "on" && value != "off" {
+		return AuraError{
+			Code:    "INVALID_SETTING_VALUE",
+			Message: fmt.Sprintf("invalid value %s, expected on or off", value)}
+	}
+
+	return nil
+}
@@ -0,0 +1,590 @@
+package auralis
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const statistics string = "statistics"
+
+// kinds of statistics rows, rows and pages describe the whole table
+const (
+	rowsStatistic      = "rows"
+	pagesStatistic     = "pages"
+	nullsStatistic     = "nulls"
+	distinctStatistic  = "distinct"
+	minStatistic       = "min"
+	maxStatistic       = "max"
+	commonStatistic    = "mcv"
+	histogramStatistic = "histogram"
+)
+
+// auralisStatistics holds statistics gathered by analyze, values are split into
+// chunks like default clauses. Count is number of rows of value of most common
+// values, position orders values of the same kind
+var auralisStatistics = Table{
+	schemaTable: SchemaTable[string, string]{internalSchema, statistics},
+	columns: []Column{
+		{
+			name:     "table_schema",
+			dataType: varchar,
+			position: 1,
+		},
+		{
+			name:     "table_name",
+			dataType: varchar,
+			position: 2,
+		},
+		{
+			name:     "column_name",
+			dataType: varchar,
+			position: 3,
+		},
+		{
+			name:     "kind",
+			dataType: varchar,
+			position: 4,
+		},
+		{
+			name:     "position",
+			dataType: smallint,
+			position: 5,
+		},
+		{
+			name:     "chunk",
+			dataType: smallint,
+			position: 6,
+		},
+		{
+			name:     "value",
+			dataType: varchar,
+			position: 7,
+		},
+		{
+			name:     "count",
+			dataType: bigint,
+			position: 8,
+		},
+	},
+}
+
+// TableStatistics describes table at the time it was analyzed
+type TableStatistics struct {
+	rows    int64
+	pages   int64
+	columns map[string]*ColumnStatistics
+}
+
+// ColumnStatistics describes distribution of column values, counts are
+// estimated numbers of rows. Histogram bounds split values which aren't
+// among the most common ones into buckets of equal number of rows
+type ColumnStatistics struct {
+	dataType    DataType
+	rows        int64 // rows of table
+	nulls       int64
+	distinct    int64
+	min         any
+	max         any
+	common      []any
+	frequencies []int64 // rows of each common value
+	histogram   []any
+}
+
+// handleAnalyzeQuery gathers statistics of tables, all tables are analyzed
+// when query has no sources
+func handleAnalyzeQuery(tx *Transaction, query AnalyzeQuery) error {
+	sources := query.sources
+	if len(sources) == 0 {
+		var err error
+		if sources, err = listTables(tx); err != nil {
+			return err
+		}
+	}
+
+	for _, source := range sources {
+		table, err := getTable(tx, source)
+		if err != nil {
+			return err
+		}
+
+		if err := analyzeTable(tx, table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// analyzeTable samples table and replaces its statistics in catalog
+func analyzeTable(tx *Transaction, table Table) error {
+	logger.Load().Printf("INFO: analyzing table %s.%s", table.schemaTable.schema, table.schemaTable.name)
+
+	if err := lockManager.lock(tx, tableLockTag(table), intentionShared); err != nil {
+		return err
+	}
+
+	target := getIntSetting("default_statistics_target")
+	sample, rows, pages, err := sampleTable(tx, table, 300*target)
+	if err != nil {
+		return err
+	}
+
+	stats := TableStatistics{rows: rows, pages: pages, columns: map[string]*ColumnStatistics{}}
+	for i, cd := range table.columns {
+		values := make([]any, 0, len(sample))
+		for _, row := range sample {
+			values = append(values, row.cells[i])
+		}
+
+		stats.columns[cd.name] = columnStatistics(cd.dataType, values, rows, target)
+	}
+
+	// previous statistics are replaced
+	_, err = deleteFromTable(tx, auralisStatistics, []Condition{
+		{target: "table_schema", sign: "=", value: quoteLiteral(table.schemaTable.schema)},
+		{target: "table_name", sign: "=", value: quoteLiteral(table.schemaTable.name)},
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeIntoTable(tx, auralisStatistics, DataSet{
+		columns: auralisStatistics.columns,
+		rows:    statisticsRows(table, stats),
+	})
+}
+
+// sampleTable reads rows of randomly chosen pages, at most size rows are kept
+// by reservoir sampling so each visible row has the same chance to be sampled.
+// Number of rows of table is extrapolated from rows of read pages
+func sampleTable(tx *Transaction, table Table, size int) ([]Row, int64, int64, error) {
+	path := getTableDiskPath(table.schemaTable)
+	tx.read(path)
+
+	count, err := bufferPool.numberOfPages(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	numbers := make([]int64, 0, count)
+	for number := range count {
+		numbers = append(numbers, number)
+	}
+
+	if count > int64(size) {
+		rand.Shuffle(len(numbers), func(i, j int) { numbers[i], numbers[j] = numbers[j], numbers[i] })
+		numbers = numbers[:size]
+		slices.Sort(numbers)
+	}
+
+	names := []string{}
+	for _, cd := range table.columns {
+		names = append(names, cd.name)
+	}
+
+	sample := []Row{}
+	seen := int64(0)
+	for _, number := range numbers {
+		page, err := bufferPool.fetchPage(PageID{file: path, number: number})
+		if err != nil {
+			return nil, 0, 0, err
+		}
+
+		for slot := range page.slotCount() {
+			tuple := page.tuple(slot)
+			if tuple == nil || !tx.sees(tuple) {
+				continue
+			}
+
+			row, err := decodeRow(table, tuple, names)
+			if err != nil {
+				bufferPool.unpinPage(page.id, false)
+				return nil, 0, 0, err
+			}
+
+			if len(sample) < size {
+				sample = append(sample, row)
+			} else if j := rand.Int64N(seen + 1); j < int64(size) {
+				sample[j] = row
+			}
+			seen++
+		}
+
+		bufferPool.unpinPage(page.id, false)
+	}
+
+	rows := seen
+	if len(numbers) > 0 {
+		rows = int64(math.Round(float64(seen) * float64(count) / float64(len(numbers))))
+	}
+
+	return sample, rows, count, nil
+}
+
+// columnStatistics describes sampled values of column of table with given
+// number of rows, at most target common values and histogram buckets are kept
+func columnStatistics(dataType DataType, values []any, rows int64, target int) *ColumnStatistics {
+	stats := &ColumnStatistics{dataType: dataType, rows: rows}
+	sampled := []any{}
+	for _, value := range values {
+		if value != nil {
+			sampled = append(sampled, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return stats
+	}
+
+	scale := float64(rows) / float64(len(values))
+	stats.nulls = int64(math.Round(float64(len(values)-len(sampled)) * scale))
+	if len(sampled) == 0 {
+		return stats
+	}
+
+	slices.SortFunc(sampled, compareValues)
+	stats.min, stats.max = sampled[0], sampled[len(sampled)-1]
+
+	// sorted values are grouped into runs of equal values
+	type run struct {
+		value any
+		count int
+	}
+	runs := []run{}
+	for _, value := range sampled {
+		if len(runs) > 0 && compareValues(runs[len(runs)-1].value, value) == 0 {
+			runs[len(runs)-1].count++
+			continue
+		}
+		runs = append(runs, run{value: value, count: 1})
+	}
+
+	n, d := float64(len(sampled)), float64(len(runs))
+	total := float64(rows - stats.nulls)
+	unique := float64(len(filter(runs, func(r run) bool { return r.count == 1 })))
+	switch {
+	case n >= total:
+		stats.distinct = int64(d)
+	case unique == n:
+		// values seem to be unique
+		stats.distinct = int64(math.Round(total))
+	default:
+		// estimator of Haas and Stokes
+		stats.distinct = int64(math.Round(n * d / (n - unique + unique*n/total)))
+	}
+
+	// values repeated more than average are the most common ones, all values
+	// are kept when none of them is unique and they fit into target
+	candidates := slices.Clone(runs)
+	slices.SortStableFunc(candidates, func(a, b run) int { return cmp.Compare(b.count, a.count) })
+	all := unique == 0 && len(runs) <= target
+	for _, r := range candidates {
+		if len(stats.common) == target || (!all && (r.count < 2 || float64(r.count) <= 1.25*n/d)) {
+			break
+		}
+
+		stats.common = append(stats.common, r.value)
+		stats.frequencies = append(stats.frequencies, int64(math.Round(float64(r.count)*scale)))
+	}
+
+	rest := []any{}
+	for _, r := range runs {
+		if slices.ContainsFunc(stats.common, func(v any) bool { return compareValues(v, r.value) == 0 }) {
+			continue
+		}
+
+		for range r.count {
+			rest = append(rest, r.value)
+		}
+	}
+
+	if len(runs)-len(stats.common) < 2 {
+		return stats
+	}
+
+	buckets := min(target, len(rest)-1)
+	for i := 0; i <= buckets; i++ {
+		bound := rest[i*(len(rest)-1)/buckets]
+		if len(stats.histogram) == 0 || compareValues(stats.histogram[len(stats.histogram)-1], bound) != 0 {
+			stats.histogram = append(stats.histogram, bound)
+		}
+	}
+
+	return stats
+}
+
+// statisticsRows lists catalog rows of table statistics
+func statisticsRows(table Table, stats TableStatistics) []Row {
+	rows := []Row{}
+	add := func(column, kind string, position int, value string, count int64) {
+		chunks := clauseChunks(value)
+		if len(chunks) == 0 {
+			chunks = []string{""}
+		}
+
+		for i, chunk := range chunks {
+			rows = append(rows, Row{cells: []any{
+				table.schemaTable.schema, table.schemaTable.name, column, kind, int16(position), int16(i + 1), chunk, count,
+			}})
+		}
+	}
+
+	add("", rowsStatistic, 1, "", stats.rows)
+	add("", pagesStatistic, 1, "", stats.pages)
+	for _, cd := range table.columns {
+		column := stats.columns[cd.name]
+		add(cd.name, nullsStatistic, 1, "", column.nulls)
+		add(cd.name, distinctStatistic, 1, "", column.distinct)
+		if column.min != nil {
+			add(cd.name, minStatistic, 1, formatStatistic(column.min), 0)
+			add(cd.name, maxStatistic, 1, formatStatistic(column.max), 0)
+		}
+
+		for i, value := range column.common {
+			add(cd.name, commonStatistic, i+1, formatStatistic(value), column.frequencies[i])
+		}
+
+		for i, value := range column.histogram {
+			add(cd.name, histogramStatistic, i+1, formatStatistic(value), 0)
+		}
+	}
+
+	return rows
+}
+
+// tableStatistics returns statistics of table stored by analyze, nil when
+// table wasn't analyzed
+func tableStatistics(tx *Transaction, table Table) (*TableStatistics, error) {
+	names := []string{}
+	for _, cd := range auralisStatistics.columns {
+		names = append(names, cd.name)
+	}
+
+	dataSet, err := readFromTable(tx, auralisStatistics, SelectQuery{
+		source:      auralisStatistics.schemaTable,
+		dataColumns: names,
+		conditions: []Condition{
+			{target: "table_schema", sign: "=", value: table.schemaTable.schema},
+			{target: "table_name", sign: "=", value: table.schemaTable.name},
+		},
+	})
+	if err != nil || len(dataSet.rows) == 0 {
+		return nil, err
+	}
+
+	// chunks of values are joined in order of their positions
+	type key struct {
+		column   string
+		kind     string
+		position int16
+	}
+	type entry struct {
+		chunks map[int16]string
+		count  int64
+	}
+	entries := map[key]*entry{}
+	keys := []key{}
+	for _, row := range dataSet.rows {
+		k := key{row.cells[2].(string), row.cells[3].(string), row.cells[4].(int16)}
+		if _, ok := entries[k]; !ok {
+			entries[k] = &entry{chunks: map[int16]string{}}
+			keys = append(keys, k)
+		}
+		entries[k].chunks[row.cells[5].(int16)] = row.cells[6].(string)
+		entries[k].count = row.cells[7].(int64)
+	}
+
+	slices.SortFunc(keys, func(a, b key) int { return cmp.Compare(a.position, b.position) })
+
+	stats := &TableStatistics{columns: map[string]*ColumnStatistics{}}
+	for _, cd := range table.columns {
+		stats.columns[cd.name] = &ColumnStatistics{dataType: cd.dataType}
+	}
+
+	for _, k := range keys {
+		e := entries[k]
+		text := ""
+		for chunk := int16(1); chunk <= int16(len(e.chunks)); chunk++ {
+			text += e.chunks[chunk]
+		}
+
+		switch k.kind {
+		case rowsStatistic:
+			stats.rows = e.count
+			continue
+		case pagesStatistic:
+			stats.pages = e.count
+			continue
+		}
+
+		column, ok := stats.columns[k.column]
+		if !ok {
+			continue
+		}
+
+		var value any
+		if k.kind == minStatistic || k.kind == maxStatistic || k.kind == commonStatistic || k.kind == histogramStatistic {
+			if value, err = parseStatistic(column.dataType, text); err != nil {
+				return nil, fmt.Errorf("statistics of column %s: %w", k.column, err)
+			}
+		}
+
+		switch k.kind {
+		case nullsStatistic:
+			column.nulls = e.count
+		case distinctStatistic:
+			column.distinct = e.count
+		case minStatistic:
+			column.min = value
+		case maxStatistic:
+			column.max = value
+		case commonStatistic:
+			column.common = append(column.common, value)
+			column.frequencies = append(column.frequencies, e.count)
+		case histogramStatistic:
+			column.histogram = append(column.histogram, value)
+		}
+	}
+
+	for _, column := range stats.columns {
+		column.rows = stats.rows
+	}
+
+	return stats, nil
+}
+
+// formatStatistic returns text of value stored in catalog
+func formatStatistic(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(timestampLayouts[0])
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseStatistic converts text of value stored in catalog into column type
+func parseStatistic(dataType DataType, text string) (any, error) {
+	if dataType == boolean {
+		return strconv.ParseBool(text)
+	}
+
+	return castValue(dataType, text)
+}
+
+// quoteLiteral returns sql literal of text
+func quoteLiteral(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
+
+// equalFraction is fraction of rows whose value equals given value, rows of
+// values which aren't common are spread evenly among distinct values
+func (s *ColumnStatistics) equalFraction(value any) float64 {
+	if s.rows == 0 {
+		return 0
+	}
+
+	if i := slices.IndexFunc(s.common, func(v any) bool { return compareValues(v, value) == 0 }); i != -1 {
+		return float64(s.frequencies[i]) / float64(s.rows)
+	}
+
+	if s.min != nil && (compareValues(value, s.min) < 0 || compareValues(value, s.max) > 0) {
+		return 0
+	}
+
+	others := s.distinct - int64(len(s.common))
+	if others <= 0 {
+		return 0
+	}
+
+	return s.restFraction() / float64(others)
+}
+
+// lessFraction is fraction of rows whose value is less than given value,
+// or equal to it when inclusive
+func (s *ColumnStatistics) lessFraction(value any, inclusive bool) float64 {
+	if s.rows == 0 {
+		return 0
+	}
+
+	fraction := 0.0
+	for i, v := range s.common {
+		if c := compareValues(v, value); c < 0 || (inclusive && c == 0) {
+			fraction += float64(s.frequencies[i]) / float64(s.rows)
+		}
+	}
+
+	bounds := s.histogram
+	if len(bounds) < 2 {
+		if s.restFraction() == 0 || s.min == nil {
+			return fraction
+		}
+		bounds = []any{s.min, s.max}
+	}
+
+	position := 0.0
+	switch {
+	case compareValues(value, bounds[0]) < 0:
+	case compareValues(value, bounds[len(bounds)-1]) >= 0:
+		position = 1
+	default:
+		i, _ := slices.BinarySearchFunc(bounds, value, func(bound any, value any) int {
+			if compareValues(bound, value) <= 0 {
+				return -1
+			}
+
+			return 1
+		})
+		position = (float64(i-1) + interpolate(bounds[i-1], bounds[i], value)) / float64(len(bounds)-1)
+	}
+
+	return fraction + position*s.restFraction()
+}
+
+// restFraction is fraction of rows with values which aren't null nor common
+func (s *ColumnStatistics) restFraction() float64 {
+	rest := s.rows - s.nulls
+	for _, frequency := range s.frequencies {
+		rest -= frequency
+	}
+
+	return math.Max(float64(rest)/float64(s.rows), 0)
+}
+
+// nullFraction is fraction of rows with null value
+func (s *ColumnStatistics) nullFraction() float64 {
+	if s.rows == 0 {
+		return 0
+	}
+
+	return float64(s.nulls) / float64(s.rows)
+}
+
+// interpolate returns relative position of value between bounds, values
+// which aren't numbers or timestamps are assumed to be in the middle
+func interpolate(low any, high any, value any) float64 {
+	toNumber := func(v any) (float64, bool) {
+		if t, ok := v.(time.Time); ok {
+			return float64(t.UnixMicro()), true
+		}
+
+		return toFloat64(v), isNumber(v)
+	}
+
+	l, ok1 := toNumber(low)
+	h, ok2 := toNumber(high)
+	v, ok3 := toNumber(value)
+	if !ok1 || !ok2 || !ok3 || h <= l {
+		return 0.5
+	}
+
+	return math.Min(math.Max((v-l)/(h-l), 0), 1)
+}
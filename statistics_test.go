@@ -0,0 +1,196 @@
+package auralis
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// insertGenerated inserts rows with ids from one, other values are given by id
+func insertGenerated(t *testing.T, table string, columns string, count int, values func(id int) string) {
+	t.Helper()
+
+	tuples := []string{}
+	for id := 1; id <= count; id++ {
+		tuples = append(tuples, fmt.Sprintf("(%d, %s)", id, values(id)))
+	}
+
+	if _, err := ExecuteQuery(fmt.Sprintf("INSERT INTO %s (id, %s) VALUES %s", table, columns, strings.Join(tuples, ", "))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE users (id integer PRIMARY KEY, name varchar, age integer)",
+		"INSERT INTO users (id, name, age) VALUES (1, 'a', 20), (2, 'b', 30), (3, 'c', 15), (4, 'b', 30), (5, 'b', 30)",
+		"ANALYZE users",
+		// statistics are replaced
+		"INSERT INTO users (id, name, age) VALUES (6, 'd', 40)",
+		"ANALYZE",
+	)
+
+	testCases := map[string]struct {
+		query    string
+		expected [][]any
+	}{
+		"table": {
+			query:    "SELECT kind, count FROM auralis.statistics WHERE table_name = 'users' AND column_name = '' ORDER BY kind",
+			expected: [][]any{{"pages", int64(1)}, {"rows", int64(6)}},
+		},
+		"distinct values": {
+			query:    "SELECT column_name, count FROM auralis.statistics WHERE table_name = 'users' AND kind = 'distinct' ORDER BY column_name",
+			expected: [][]any{{"age", int64(4)}, {"id", int64(6)}, {"name", int64(4)}},
+		},
+		"bounds": {
+			query:    "SELECT kind, value FROM auralis.statistics WHERE table_name = 'users' AND column_name = 'age' AND kind IN ('min', 'max') ORDER BY kind",
+			expected: [][]any{{"max", "40"}, {"min", "15"}},
+		},
+		"most common values": {
+			query:    "SELECT column_name, value, count FROM auralis.statistics WHERE table_name = 'users' AND kind = 'mcv' ORDER BY column_name",
+			expected: [][]any{{"age", "30", int64(3)}, {"name", "b", int64(3)}},
+		},
+		"histogram": {
+			query:    "SELECT value FROM auralis.statistics WHERE table_name = 'users' AND column_name = 'name' AND kind = 'histogram' ORDER BY position",
+			expected: [][]any{{"a"}, {"c"}, {"d"}},
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			if rows := queryCells(t, tC.query); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestTableStatistics(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE events (id integer PRIMARY KEY, name varchar, created timestamp)",
+		"INSERT INTO events (id, name, created) VALUES (1, 'launch', '2024-01-02 10:00:00'), "+
+			"(2, 'launch', '2024-03-04 12:30:00'), (3, 'short', '2024-02-01')",
+		"ANALYZE events",
+	)
+
+	var stats *TableStatistics
+	err := autocommit(func(tx *Transaction) error {
+		table, err := getTable(tx, SchemaTable[string, string]{defaultScheme, "events"})
+		if err != nil {
+			return err
+		}
+
+		stats, err = tableStatistics(tx, table)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// text of timestamps is longer than varchar so it's stored in chunks
+	created := stats.columns["created"]
+	if created.min != mustTimestamp(t, "2024-01-02 10:00:00") || created.max != mustTimestamp(t, "2024-03-04 12:30:00") {
+		t.Errorf("unexpected bounds %v %v", created.min, created.max)
+	}
+
+	name := stats.columns["name"]
+	expected := []any{"launch"}
+	if !reflect.DeepEqual(name.common, expected) || !reflect.DeepEqual(name.frequencies, []int64{2}) {
+		t.Errorf("\nexp %+v\ngot %+v %+v", expected, name.common, name.frequencies)
+	}
+
+	if fraction := name.equalFraction("short"); fraction != 1.0/3 {
+		t.Errorf("\nexp %+v\ngot %+v", 1.0/3, fraction)
+	}
+}
+
+func mustTimestamp(t *testing.T, text string) any {
+	t.Helper()
+
+	value, err := ConvertToConcreteType(timestamp, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestAnalyzedScan(t *testing.T) {
+	testCases := map[string]struct {
+		query    string
+		analyze  bool
+		expected string
+	}{
+		"index without statistics": {
+			query:    "EXPLAIN SELECT name FROM items WHERE id > 10",
+			expected: "Index Scan",
+		},
+		"index for few rows": {
+			query:    "EXPLAIN SELECT name FROM items WHERE id = 500",
+			analyze:  true,
+			expected: "Index Scan",
+		},
+		"sequential scan for most rows": {
+			query:    "EXPLAIN SELECT name FROM items WHERE id > 10",
+			analyze:  true,
+			expected: "Seq Scan",
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE items (id integer PRIMARY KEY, name varchar)")
+			insertGenerated(t, "items", "name", 1000, func(id int) string { return fmt.Sprintf("'item %d'", id) })
+			if tC.analyze {
+				if _, err := ExecuteQuery("ANALYZE items"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			plan := queryCells(t, tC.query)
+			if scan := plan[1][0].(string); !strings.Contains(scan, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, scan)
+			}
+		})
+	}
+}
+
+func TestJoinOrder(t *testing.T) {
+	setupTestDatabase(t,
+		"CREATE TABLE customers (id integer PRIMARY KEY, region_id integer)",
+		"CREATE TABLE orders (id integer PRIMARY KEY, customer_id integer)",
+		"CREATE TABLE regions (id integer PRIMARY KEY, name varchar)",
+		"INSERT INTO regions (id, name) VALUES (1, 'north'), (2, 'south')",
+	)
+	insertGenerated(t, "customers", "region_id", 20, func(id int) string { return fmt.Sprint(id%2 + 1) })
+	insertGenerated(t, "orders", "customer_id", 100, func(id int) string { return fmt.Sprint(id%20 + 1) })
+
+	const query = "SELECT * FROM orders o JOIN customers c ON o.customer_id = c.id " +
+		"JOIN regions r ON c.region_id = r.id WHERE r.name = 'north'"
+	before := queryCells(t, query)
+
+	if _, err := ExecuteQuery("ANALYZE"); err != nil {
+		t.Fatal(err)
+	}
+
+	scans := []string{}
+	for _, scan := range planScans(optimizedPlan(t, query)) {
+		scans = append(scans, scan.qualifier)
+	}
+
+	expected := []string{"r", "c", "o"}
+	if !reflect.DeepEqual(scans, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, scans)
+	}
+
+	// columns keep order of query
+	after := queryCells(t, query)
+	sortRows := func(rows [][]any) {
+		slices.SortFunc(rows, func(a, b []any) int { return compareValues(fmt.Sprint(a), fmt.Sprint(b)) })
+	}
+	sortRows(before)
+	sortRows(after)
+	if len(after) != 50 || !reflect.DeepEqual(before, after) {
+		t.Errorf("\nexp %+v\ngot %+v", before, after)
+	}
+}
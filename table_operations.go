@@ -1,131 +1,319 @@
-package main
+package auralis
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"slices"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// DataSet is result of statement, rows of queries or number of changed rows
 type DataSet struct {
-	columns []Column
-	rows    []Row
+	columns  []Column
+	rows     []Row
+	affected int // number of rows changed by data modifying statement
+}
+
+func (ds *DataSet) Columns() []Column {
+	return ds.columns
+}
+
+// Rows returns values of rows in order of columns, nulls are nil
+func (ds *DataSet) Rows() [][]any {
+	rows := make([][]any, len(ds.rows))
+	for i, row := range ds.rows {
+		rows[i] = row.cells
+	}
+
+	return rows
+}
+
+func (ds *DataSet) RowsAffected() int {
+	return ds.affected
 }
 
 type Row struct {
 	cells []any
 }
 
+// key returns row representation usable for equality of whole rows
+func (r Row) key() string {
+	return fmt.Sprintf("%v", r.cells)
+}
+
 var (
 	ErrTableNotFound = AuraError{Code: "TABLE_NOT_FOUND", Message: "table not found"}
+	ErrTableExists   = AuraError{Code: "TABLE_EXISTS", Message: "table already exists"}
+	ErrRowTooLarge   = AuraError{Code: "ROW_TOO_LARGE", Message: "row does not fit into a page"}
 )
 
-const terminationByte = byte(10)
+func cretateTable(tx *Transaction, table Table) error {
+	// name is locked until transaction ends, concurrent create of the same
+	// table waits and finds it in catalog afterwards
+	if err := lockManager.lock(tx, tableLockTag(table), exclusive); err != nil {
+		return err
+	}
 
-func cretateTable(table Table) error {
-	err := addTable(table)
+	exists, err := tableExists(tx, table.schemaTable)
 	if err != nil {
 		return err
 	}
 
-	f, err := os.Create(getTableDiskPath(table.schemaTable))
+	if exists {
+		return ErrTableExists
+	}
+
+	// file is created first so catalog never references missing file
+	path := getTableDiskPath(table.schemaTable)
+	if err := bufferPool.dropFile(path); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	f.Close()
 
-	return nil
+	return addTable(tx, userDatabase, table)
 }
 
-func writeIntoTable(table Table, dataSet DataSet) error {
-	log.Printf("INFO: executing insert query %+v", dataSet)
-	f, err := os.OpenFile(getTableDiskPath(table.schemaTable), os.O_WRONLY|os.O_APPEND, 0600)
+func writeIntoTable(tx *Transaction, table Table, dataSet DataSet) error {
+	logger.Load().Printf("INFO: executing insert query %+v", dataSet)
+
+	if err := lockManager.lock(tx, tableLockTag(table), intentionExclusive); err != nil {
+		return err
+	}
+
+	return insertRows(tx, table, dataSet.rows)
+}
+
+// insertRows appends rows into the last page of table with enough free space,
+// new pages are allocated when the last one is full. Rows are checked against
+// constraints of table, inserted rows are added into its indexes and checked
+// against its foreign keys afterwards
+func insertRows(tx *Transaction, table Table, rows []Row) error {
+	path := getTableDiskPath(table.schemaTable)
+
+	count, err := bufferPool.numberOfPages(path)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return ErrTableNotFound
-		}
 		return err
 	}
-	defer f.Close()
-
-	log.Printf("INFO: %s.%s table %+v\n", table.schemaTable.schema, table.schemaTable.name, table)
-
-	w := bufio.NewWriter(f)
-
-	for _, row := range dataSet.rows {
-		for cellIndex, cell := range row.cells {
-			var val []byte
-			switch table.columns[cellIndex].dataType {
-			case smallint:
-				{
-					buf := bytes.NewBuffer(make([]byte, 0, getDataTypeByteSize(smallint)))
-					// binary writer is implicitly converting to uint16 with two's complement
-					err := binary.Write(buf, binary.BigEndian, cell.(int16))
-					if err != nil {
-						return err
-					}
-
-					val = buf.Bytes()
-				}
-			case varchar:
-				{
-					// we don't care about endianness because we support only utf-8 for now
-					padded := make([]byte, getDataTypeByteSize(varchar))
-					copy(padded, cell.(string))
-					val = padded
-				}
-			case uniqueidentifier:
-				{
-					val, err = cell.(uuid.UUID).MarshalBinary()
-					if err != nil {
-						return err
-					}
-				}
-			default:
-				return errors.New("unhandled type")
+
+	var page *Page
+	if count > 0 {
+		page, err = bufferPool.fetchPage(PageID{file: path, number: count - 1})
+		if err != nil {
+			return err
+		}
+	}
+
+	ids, tuples := []RowID{}, [][]byte{}
+	for _, row := range rows {
+		if err := checkConstraints(table, row); err != nil {
+			if page != nil {
+				bufferPool.unpinPage(page.id, true)
+			}
+			return err
+		}
+
+		tuple, err := encodeRow(table, row)
+		if err != nil {
+			if page != nil {
+				bufferPool.unpinPage(page.id, true)
 			}
+			return err
+		}
 
-			_, err = w.Write(val)
-			if err != nil {
-				return err
+		if page != nil {
+			if slot, ok := tx.insertTuple(page, tuple); ok {
+				ids, tuples = append(ids, RowID{page: page.id, slot: slot}), append(tuples, tuple)
+				continue
 			}
+
+			bufferPool.unpinPage(page.id, true)
 		}
 
-		err = w.WriteByte(terminationByte)
+		page, err = bufferPool.newPage(path)
 		if err != nil {
 			return err
 		}
+
+		slot, ok := tx.insertTuple(page, tuple)
+		if !ok {
+			bufferPool.unpinPage(page.id, true)
+			return ErrRowTooLarge
+		}
+		ids, tuples = append(ids, RowID{page: page.id, slot: slot}), append(tuples, tuple)
 	}
 
-	err = w.Flush()
-	if err != nil {
+	if page != nil {
+		bufferPool.unpinPage(page.id, true)
+	}
+
+	if err := insertIndexEntries(tx, table, ids, tuples); err != nil {
 		return err
 	}
 
-	return nil
+	// rows may reference each other
+	return checkForeignKeys(tx, table, rows)
 }
 
-func readFromTable(table Table, query SelectQuery) (*DataSet, error) {
-	log.Printf("INFO: executing select query %+v", query)
-	f, err := os.Open(getTableDiskPath(table.schemaTable))
+// encodeRow returns tuple with empty header followed by null bitmap and column
+// values, null takes zeroed space of its column value
+func encodeRow(table Table, row Row) ([]byte, error) {
+	tuple := make([]byte, valuesOffset(table), valuesOffset(table)+calculateRowSize(table))
+	for cellIndex, cell := range row.cells {
+		if cell == nil {
+			tuple[tupleHeaderSize+cellIndex/8] |= 1 << (cellIndex % 8)
+			tuple = append(tuple, make([]byte, getDataTypeByteSize(table.columns[cellIndex].dataType))...)
+			continue
+		}
+
+		var err error
+		tuple, err = appendValue(tuple, table.columns[cellIndex].dataType, cell)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(tuple) > maxTupleSize {
+		return nil, ErrRowTooLarge
+	}
+
+	return tuple, nil
+}
+
+// appendValue appends fixed size encoding of value of data type
+func appendValue(data []byte, dataType DataType, value any) ([]byte, error) {
+	switch dataType {
+	case smallint:
+		// two's complement representation
+		return binary.BigEndian.AppendUint16(data, uint16(value.(int16))), nil
+	case integer:
+		return binary.BigEndian.AppendUint32(data, uint32(value.(int32))), nil
+	case bigint:
+		return binary.BigEndian.AppendUint64(data, uint64(value.(int64))), nil
+	case varchar:
+		// we don't care about endianness because we support only utf-8 for now
+		padded := make([]byte, getDataTypeByteSize(varchar))
+		copy(padded, value.(string))
+		return append(data, padded...), nil
+	case uniqueidentifier:
+		val, err := value.(uuid.UUID).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		return append(data, val...), nil
+	case timestamp:
+		return binary.BigEndian.AppendUint64(data, uint64(value.(time.Time).UnixMicro())), nil
+	case boolean:
+		if value.(bool) {
+			return append(data, 1), nil
+		}
+
+		return append(data, 0), nil
+	default:
+		return nil, errors.New("unhandled type")
+	}
+}
+
+// decodeRow reads values of columns included in dataColumns
+func decodeRow(table Table, tuple []byte, dataColumns []string) (Row, error) {
+	row := Row{}
+	offset := valuesOffset(table)
+	for i, cd := range table.columns {
+		size := getDataTypeByteSize(cd.dataType)
+		if !slices.Contains(dataColumns, cd.name) {
+			offset += size
+			continue
+		}
+
+		if isNull(tuple, i) {
+			row.cells = append(row.cells, nil)
+			offset += size
+			continue
+		}
+
+		data := tuple[offset : offset+size]
+		var value any
+		switch cd.dataType {
+		case smallint:
+			value = int16(binary.BigEndian.Uint16(data))
+		case integer:
+			value = int32(binary.BigEndian.Uint32(data))
+		case bigint:
+			value = int64(binary.BigEndian.Uint64(data))
+		case varchar:
+			value = string(bytes.TrimRight(data, "\x00"))
+		case uniqueidentifier:
+			value = uuid.UUID(data)
+		case timestamp:
+			value = time.UnixMicro(int64(binary.BigEndian.Uint64(data))).UTC()
+		case boolean:
+			value = data[0] == 1
+		default:
+			return Row{}, errors.New("unhandled type")
+		}
+
+		row.cells = append(row.cells, value)
+		offset += size
+	}
+
+	return row, nil
+}
+
+// RowID identifies tuple location within table file
+type RowID struct {
+	page PageID
+	slot int
+}
+
+// scanTable calls fn with each tuple of table visible to transaction, tuple
+// references page memory and is valid only during the call
+func scanTable(tx *Transaction, table Table, fn func(id RowID, tuple []byte) error) error {
+	path := getTableDiskPath(table.schemaTable)
+	tx.read(path)
+
+	count, err := bufferPool.numberOfPages(path)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, ErrTableNotFound
+		return err
+	}
+
+	for number := range count {
+		page, err := bufferPool.fetchPage(PageID{file: path, number: number})
+		if err != nil {
+			return err
 		}
 
-		return nil, err
+		for slot := range page.slotCount() {
+			tuple := page.tuple(slot)
+			if tuple == nil || !tx.sees(tuple) {
+				continue
+			}
+
+			if err := fn(RowID{page: page.id, slot: slot}, tuple); err != nil {
+				bufferPool.unpinPage(page.id, false)
+				return err
+			}
+		}
+
+		bufferPool.unpinPage(page.id, false)
 	}
-	defer f.Close()
 
-	log.Printf("INFO: %s.%s table %+v\n", table.schemaTable.schema,
-		table.schemaTable.name, table)
+	return nil
+}
+
+// readFromTable scans table pages or its index and returns rows matching query conditions,
+// matching rows are locked when query has lock mode
+func readFromTable(tx *Transaction, table Table, query SelectQuery) (*DataSet, error) {
+	logger.Load().Printf("INFO: executing select query %+v", query)
 
 	dataSet := DataSet{}
 	for _, v := range table.columns {
@@ -134,109 +322,208 @@ func readFromTable(table Table, query SelectQuery) (*DataSet, error) {
 		}
 	}
 
-	var fileOffset int64 = 0
-	rowBuffSize := calculateRowBuffer(table)
-	rowBuf := make([]byte, rowBuffSize)
+	ids := []RowID{}
+	var err error
+	if _, ok := planIndexScan(table, query.conditions); ok {
+		err = scanRows(tx, table, query.conditions, func(id RowID, tuple []byte) error {
+			row, err := decodeRow(table, tuple, query.dataColumns)
+			if err != nil {
+				return err
+			}
 
-	for {
-		n, err := f.ReadAt(rowBuf, fileOffset)
-		if err != nil && err != io.EOF {
-			panic(err)
+			if matchesRow(dataSet.columns, row, query.conditions) {
+				dataSet.rows = append(dataSet.rows, row)
+				ids = append(ids, id)
+			}
+
+			return nil
+		})
+	} else {
+		// tables without usable index are decoded and filtered in batches
+		err = scanBatches(tx, table, query.dataColumns, query.conditions, func(batch *Batch) error {
+			for _, i := range batch.selection {
+				dataSet.rows = append(dataSet.rows, batch.row(i))
+				ids = append(ids, batch.ids[i])
+			}
+
+			return nil
+		})
+	}
+	if err != nil {
+		return &dataSet, err
+	}
+
+	// rows are locked after the scan as waiting for lock releases statement lock
+	if query.lockMode != 0 {
+		for _, id := range ids {
+			if err := tx.lockRow(id, query.lockMode); err != nil {
+				return &dataSet, err
+			}
 		}
+	}
 
-		if n == 0 {
-			break
+	return &dataSet, nil
+}
+
+// findRows returns locations and all column values of rows matching conditions
+func findRows(tx *Transaction, table Table, conditions []Condition) ([]RowID, []Row, error) {
+	names := []string{}
+	columns := slices.Clone(table.columns)
+	for i := range columns {
+		names = append(names, columns[i].name)
+		columns[i].table = table.schemaTable.name
+	}
+
+	ids, rows := []RowID{}, []Row{}
+	err := scanRows(tx, table, typedConditions(table, conditions), func(id RowID, tuple []byte) error {
+		row, err := decodeRow(table, tuple, names)
+		if err != nil {
+			return err
 		}
 
-		row := Row{}
-		rowOffset := 0
-		includeRow := true
+		ok, err := matchesConditions(columns, row, conditions)
+		if err != nil || !ok {
+			return err
+		}
 
-		var cellDataSize int
-		for _, cd := range table.columns {
-			if !slices.Contains(query.dataColumns, cd.name) {
-				rowOffset += getDataTypeByteSize(cd.dataType)
-				continue
-			}
+		ids = append(ids, id)
+		rows = append(rows, row)
+		return nil
+	})
 
-			switch cd.dataType {
-			case smallint:
-				{
-					cellDataSize = getDataTypeByteSize(smallint)
-					data := make([]byte, cellDataSize)
-					copy(data, rowBuf[rowOffset:rowOffset+cellDataSize])
-					value := int16(binary.BigEndian.Uint16(data))
-
-					conditions := GetMatchingCondition(query.conditions, cd.name)
-					if len(conditions) > 0 {
-						// for now assume only one condition
-						if EvaluateIntCondition(conditions[0], value) {
-							row.cells = append(row.cells, value)
-						} else {
-							includeRow = false
-							break
-						}
-					} else {
-						row.cells = append(row.cells, value)
-					}
-				}
-			case varchar:
-				{
-					cellDataSize = getDataTypeByteSize(varchar)
-					data := make([]byte, cellDataSize)
-					copy(data, rowBuf[rowOffset:rowOffset+cellDataSize])
-					value := string(bytes.TrimRight(data, "\x00"))
-
-					conditions := GetMatchingCondition(query.conditions, cd.name)
-					if len(conditions) > 0 {
-						// for now assume only one condition
-						if EvaluateStringCondition(conditions[0], value) {
-							row.cells = append(row.cells, value)
-						} else {
-							includeRow = false
-							break
-						}
-					} else {
-						row.cells = append(row.cells, value)
-					}
-				}
-			case uniqueidentifier:
-				{
-					cellDataSize = getDataTypeByteSize(uniqueidentifier)
-					data := make([]byte, cellDataSize)
-					copy(data, rowBuf[rowOffset:rowOffset+cellDataSize])
-
-					row.cells = append(row.cells, uuid.UUID(data))
-
-				}
-			default:
-				return &dataSet, errors.New("unhandled type")
-			}
+	return ids, rows, err
+}
+
+// deleteFromTable removes rows matching conditions and returns removed rows
+func deleteFromTable(tx *Transaction, table Table, conditions []Condition) ([]Row, error) {
+	if err := lockManager.lock(tx, tableLockTag(table), intentionExclusive); err != nil {
+		return nil, err
+	}
+
+	ids, rows, err := findRows(tx, table, conditions)
+	if err != nil {
+		return nil, err
+	}
 
-			rowOffset += cellDataSize
+	return deleteRows(tx, table, ids, rows)
+}
+
+// deleteRows removes found rows of table, rows referencing them are handled by
+// referential actions of foreign keys
+func deleteRows(tx *Transaction, table Table, ids []RowID, rows []Row) ([]Row, error) {
+	if err := referentialActions(tx, table, rows, nil, true); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := tx.lockRow(id, exclusive); err != nil {
+			return nil, err
 		}
 
-		if includeRow {
-			dataSet.rows = append(dataSet.rows, row)
+		if err := tx.deleteTuple(id); err != nil {
+			return nil, err
 		}
+	}
 
-		fileOffset += int64(rowBuffSize)
-		clear(rowBuf)
+	if err := referentialActions(tx, table, rows, nil, false); err != nil {
+		return nil, err
 	}
 
-	return &dataSet, nil
+	return rows, nil
+}
+
+// updateTable replaces rows matching conditions with their new versions computed
+// by update function, old versions are deleted and new ones inserted.
+// New versions of updated rows are returned
+func updateTable(tx *Transaction, table Table, conditions []Condition, update func(Row) (Row, error)) ([]Row, error) {
+	if err := lockManager.lock(tx, tableLockTag(table), intentionExclusive); err != nil {
+		return nil, err
+	}
+
+	ids, rows, err := findRows(tx, table, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateRows(tx, table, ids, rows, update)
+}
+
+// updateRows replaces found rows of table with their new versions, rows
+// referencing changed keys are handled by referential actions of foreign keys
+func updateRows(tx *Transaction, table Table, ids []RowID, rows []Row, update func(Row) (Row, error)) ([]Row, error) {
+	updated := make([]Row, 0, len(rows))
+	for i, row := range rows {
+		if err := tx.lockRow(ids[i], exclusive); err != nil {
+			return nil, err
+		}
+
+		row, err := update(row)
+		if err != nil {
+			return nil, err
+		}
+
+		updated = append(updated, row)
+	}
+
+	if err := referentialActions(tx, table, rows, updated, true); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := tx.deleteTuple(id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := insertRows(tx, table, updated); err != nil {
+		return nil, err
+	}
+
+	if err := referentialActions(tx, table, rows, updated, false); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// matchesRow evaluates conditions targeting columns against row values
+func matchesRow(columns []Column, row Row, conditions []Condition) bool {
+	for i, cd := range columns {
+		for _, condition := range GetMatchingCondition(conditions, cd.name) {
+			if !EvaluateCondition(condition, row.cells[i]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// nullBitmapSize is size of bitmap following tuple header, bit of column is
+// set when its value is null
+func nullBitmapSize(table Table) int {
+	return (len(table.columns) + 7) / 8
+}
+
+// valuesOffset is offset of the first column value within tuple
+func valuesOffset(table Table) int {
+	return tupleHeaderSize + nullBitmapSize(table)
+}
+
+// isNull reports whether value of i-th column of tuple is null
+func isNull(tuple []byte, i int) bool {
+	return tuple[tupleHeaderSize+i/8]&(1<<(i%8)) != 0
 }
 
-func calculateRowBuffer(table Table) int {
+func calculateRowSize(table Table) int {
 	size := 0
 	for _, v := range table.columns {
 		size += getDataTypeByteSize(v.dataType)
 	}
-	size += 1 // termination byte
 
 	return size
 }
 
 func getTableDiskPath(source SchemaTable[string, string]) string {
-	return fmt.Sprintf("./data/%s.%s", source.schema, source.name)
+	return fmt.Sprintf("%s/%s.%s", dataPath, source.schema, source.name)
 }
@@ -0,0 +1,277 @@
+package auralis
+
+import (
+	"slices"
+)
+
+// Transaction groups page changes which are applied atomically,
+// changes are undone on rollback or by recovery after crash
+type Transaction struct {
+	id             uint64
+	lastLSN        uint64
+	records        map[uint64]LogRecord // records written by transaction used by undo
+	savepoints     []Savepoint
+	isolationLevel IsolationLevel
+	snapshot       *Snapshot // taken by the first statement, by each one for read committed
+
+	// tables read and written by serializable transaction with found dependencies
+	reads       map[string]bool
+	writes      map[string]bool
+	inConflict  bool
+	outConflict bool
+	committed   bool
+}
+
+// Savepoint marks position within transaction to which it can be rolled back
+type Savepoint struct {
+	name string
+	lsn  uint64
+}
+
+var (
+	ErrActiveTransaction = AuraError{
+		Code:    "ACTIVE_SQL_TRANSACTION",
+		Message: "there is already a transaction in progress"}
+	ErrNoActiveTransaction = AuraError{
+		Code:    "NO_ACTIVE_SQL_TRANSACTION",
+		Message: "there is no transaction in progress"}
+	ErrSavepointNotFound = AuraError{Code: "SAVEPOINT_NOT_FOUND", Message: "savepoint does not exist"}
+	ErrIsolationLevelSet = AuraError{
+		Code:    "ACTIVE_SQL_TRANSACTION",
+		Message: "SET TRANSACTION ISOLATION LEVEL must be called before any query"}
+)
+
+func beginTransaction(level IsolationLevel) *Transaction {
+	return &Transaction{
+		id:             writeAheadLog.newTransactionID(),
+		records:        map[uint64]LogRecord{},
+		isolationLevel: level,
+	}
+}
+
+// defaultIsolationLevel is used by transactions which don't set their own level
+func defaultIsolationLevel() IsolationLevel {
+	value, _ := getSetting("default_transaction_isolation")
+	return IsolationLevel(value)
+}
+
+// autocommit runs fn in a new transaction committed on success
+func autocommit(fn func(tx *Transaction) error) error {
+	tx := beginTransaction(defaultIsolationLevel())
+	if err := tx.execute(fn); err != nil {
+		if rollbackErr := tx.rollback(); rollbackErr != nil {
+			return rollbackErr
+		}
+
+		return err
+	}
+
+	return tx.commit()
+}
+
+// execute runs statement fn, changes of failed statement are rolled back.
+// Read committed statement which finds row changed by concurrent transaction
+// is restarted with a new snapshot including the change
+func (tx *Transaction) execute(fn func(tx *Transaction) error) error {
+	for {
+		tx.takeSnapshot()
+		start := tx.lastLSN
+		err := fn(tx)
+		if err == nil {
+			return nil
+		}
+
+		if undoErr := undoTransaction(tx, start); undoErr != nil {
+			return undoErr
+		}
+
+		if err != ErrSerializationFailure || tx.isolationLevel != readCommitted {
+			return err
+		}
+	}
+}
+
+// takeSnapshot starts statement, read committed transaction sees changes
+// committed before each statement while the others keep their first snapshot
+func (tx *Transaction) takeSnapshot() {
+	if tx.snapshot != nil && tx.isolationLevel != readCommitted {
+		return
+	}
+
+	snapshot := writeAheadLog.snapshot(tx.id)
+	if tx.snapshot == nil && tx.isolationLevel == serializable {
+		tx.reads, tx.writes = map[string]bool{}, map[string]bool{}
+		serializableTransactions = append(serializableTransactions, tx)
+	}
+	tx.snapshot = &snapshot
+}
+
+// log appends record of transaction to write-ahead log, transaction is logged
+// from its first change so read only transactions don't write into log
+func (tx *Transaction) log(record LogRecord) uint64 {
+	if tx.lastLSN == 0 && record.kind != beginRecord {
+		// lock of own id is granted immediately, it's held until the end
+		lockManager.lock(tx, transactionLockTag(tx.id), exclusive)
+		tx.log(LogRecord{kind: beginRecord})
+	}
+
+	record.txID = tx.id
+	record.prevLSN = tx.lastLSN
+	tx.lastLSN = writeAheadLog.append(&record)
+	tx.records[record.lsn] = record
+
+	return record.lsn
+}
+
+// commit returns after commit record is on disk
+func (tx *Transaction) commit() error {
+	if tx.isolationLevel == serializable && tx.snapshot != nil {
+		if err := checkSerializable(tx); err != nil {
+			if rollbackErr := tx.rollback(); rollbackErr != nil {
+				return rollbackErr
+			}
+
+			return err
+		}
+	}
+
+	if tx.lastLSN > 0 {
+		lsn := tx.log(LogRecord{kind: commitRecord})
+		if err := writeAheadLog.flush(lsn); err != nil {
+			return err
+		}
+	}
+	tx.committed = true
+	tx.finish()
+
+	// checkpoint failure doesn't affect already durable commit
+	if writeAheadLog.size > walCheckpointThreshold {
+		if err := writeAheadLog.checkpoint(); err != nil {
+			logger.Load().Printf("ERROR: checkpoint failed %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+func (tx *Transaction) rollback() error {
+	if err := undoTransaction(tx, 0); err != nil {
+		return err
+	}
+
+	if tx.lastLSN > 0 {
+		tx.log(LogRecord{kind: abortRecord})
+	}
+	tx.finish()
+
+	return nil
+}
+
+func (tx *Transaction) finish() {
+	writeAheadLog.endTransaction(tx.id)
+	lockManager.releaseAll(tx)
+	if tx.isolationLevel == serializable {
+		releaseSerializable(tx)
+	}
+}
+
+// insertTuple stamps tuple with transaction id, inserts it into pinned page
+// and logs the change. Slot of inserted tuple is returned
+func (tx *Transaction) insertTuple(page *Page, tuple []byte) (int, bool) {
+	setTupleXmin(tuple, tx.id)
+	slot, ok := page.insertTuple(tuple)
+	if !ok {
+		return 0, false
+	}
+
+	lsn := tx.log(LogRecord{kind: insertRecord, page: page.id, slot: slot, tuple: tuple})
+	page.setLSN(lsn)
+	tx.write(page.id.file)
+
+	return slot, true
+}
+
+// deleteTuple marks tuple deleted by transaction, tuple stays in page for
+// snapshots which still see it. Row has to be locked by lockRow first
+func (tx *Transaction) deleteTuple(id RowID) error {
+	page, err := bufferPool.fetchPage(id.page)
+	if err != nil {
+		return err
+	}
+	defer bufferPool.unpinPage(id.page, true)
+
+	tuple := page.tuple(id.slot)
+	if tuple == nil || tupleXmax(tuple) == tx.id {
+		return nil
+	}
+
+	if tupleXmax(tuple) != 0 {
+		return ErrSerializationFailure
+	}
+
+	lsn := tx.log(LogRecord{kind: deleteRecord, page: id.page, slot: id.slot, tuple: xmaxChange(0, tx.id)})
+	setTupleXmax(tuple, tx.id)
+	page.setLSN(lsn)
+	tx.write(id.page.file)
+
+	return nil
+}
+
+func (tx *Transaction) read(file string) {
+	if tx.reads != nil {
+		tx.reads[file] = true
+	}
+}
+
+func (tx *Transaction) write(file string) {
+	if tx.writes != nil {
+		tx.writes[file] = true
+	}
+}
+
+// undoTransaction reverts changes of transaction logged after stopLSN, each
+// reverted change is logged by compensation record so undo is not repeated
+// when recovery is interrupted by another crash
+func undoTransaction(tx *Transaction, stopLSN uint64) error {
+	next := tx.lastLSN
+	for next > stopLSN {
+		record, ok := tx.records[next]
+		if !ok {
+			return ErrLogCorrupted
+		}
+
+		switch record.kind {
+		case insertRecord, deleteRecord:
+			page, err := bufferPool.fetchPage(record.page)
+			if err != nil {
+				return err
+			}
+
+			compensation := LogRecord{
+				kind:     compensationRecord,
+				undoNext: record.prevLSN,
+				page:     record.page,
+				slot:     record.slot,
+			}
+			if record.kind == deleteRecord {
+				// tuple is logged with its previous xmax
+				previous, _ := decodeXmaxChange(record.tuple)
+				compensation.tuple = slices.Clone(page.tuple(record.slot))
+				setTupleXmax(compensation.tuple, previous)
+			}
+
+			compensation.lsn = tx.log(compensation)
+			redoRecord(page, compensation)
+			page.setLSN(compensation.lsn)
+			bufferPool.unpinPage(page.id, true)
+
+			next = record.prevLSN
+		case compensationRecord:
+			next = record.undoNext
+		default:
+			next = record.prevLSN
+		}
+	}
+
+	return nil
+}
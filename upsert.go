@@ -0,0 +1,212 @@
+package auralis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// excludedTable qualifies columns of row proposed for insertion in do update action
+const excludedTable string = "excluded"
+
+var (
+	ErrNoConflictIndex = AuraError{
+		Code:    "INVALID_COLUMN_REFERENCE",
+		Message: "there is no unique index matching the on conflict specification"}
+	ErrRowAffectedTwice = AuraError{
+		Code:    "CARDINALITY_VIOLATION",
+		Message: "on conflict do update command cannot affect row a second time"}
+)
+
+// resolveOnConflict returns unique indexes checked for conflicting rows, they
+// have to cover exactly the conflict target columns. Unqualified columns of do
+// update assignments are qualified with table name, row proposed for insertion
+// is referenced by excluded table
+func resolveOnConflict(tx *Transaction, table Table, clause OnConflictClause) (OnConflictClause, []Index, error) {
+	indexes, err := tableIndexes(tx, table)
+	if err != nil {
+		return OnConflictClause{}, nil, err
+	}
+
+	target := slices.Sorted(slices.Values(clause.columns))
+	arbiters := []Index{}
+	for _, index := range indexes {
+		if !index.unique {
+			continue
+		}
+
+		names := slices.Sorted(slices.Values(indexColumnNames(index)))
+		if len(target) > 0 && !slices.Equal(names, target) {
+			continue
+		}
+
+		arbiters = append(arbiters, index)
+	}
+
+	if len(target) > 0 && len(arbiters) == 0 {
+		return OnConflictClause{}, nil, ErrNoConflictIndex
+	}
+
+	columns := upsertColumns(table)
+	assignments := []Assignment{}
+	for _, assignment := range clause.assignments {
+		if !slices.ContainsFunc(table.columns, func(cd Column) bool { return cd.name == assignment.column }) {
+			return OnConflictClause{}, nil, ErrColumnNotFound
+		}
+
+		if err := checkGeneratedAlways(table, assignment.column); err != nil {
+			return OnConflictClause{}, nil, err
+		}
+
+		value, err := rewriteExpression(assignment.value, func(e Expression) (Expression, bool, error) {
+			if ref, ok := e.(ColumnReference); ok && !strings.Contains(ref.name, ".") {
+				return ColumnReference{name: fmt.Sprintf("%s.%s", table.schemaTable.name, ref.name)}, true, nil
+			}
+
+			return e, false, nil
+		})
+		if err != nil {
+			return OnConflictClause{}, nil, err
+		}
+
+		if err := validateExpression(value, columns); err != nil {
+			return OnConflictClause{}, nil, err
+		}
+
+		assignments = append(assignments, Assignment{column: assignment.column, value: value})
+	}
+	clause.assignments = assignments
+
+	return clause, arbiters, nil
+}
+
+// upsertColumns are columns of existing row followed by columns of row
+// proposed for insertion
+func upsertColumns(table Table) []Column {
+	excluded := slices.Clone(table.columns)
+	for i := range excluded {
+		excluded[i].table = excludedTable
+	}
+
+	return slices.Concat(qualifiedColumns(table), excluded)
+}
+
+// upsertRows inserts rows one by one so each of them is checked also against
+// rows inserted before it. Row conflicting with existing row in any of arbiter
+// indexes is skipped by do nothing action, do update replaces the existing row
+// by its updated version. Returned rows are the inserted and updated ones.
+// Conflicting row committed after snapshot of transaction isn't seen, its
+// insert fails with unique violation
+func upsertRows(tx *Transaction, table Table, clause OnConflictClause, arbiters []Index, rows []Row) ([]Row, error) {
+	if err := lockManager.lock(tx, tableLockTag(table), intentionExclusive); err != nil {
+		return nil, err
+	}
+
+	positions := []int{}
+	for _, assignment := range clause.assignments {
+		positions = append(positions, slices.IndexFunc(table.columns, func(cd Column) bool { return cd.name == assignment.column }))
+	}
+
+	columns := upsertColumns(table)
+	changed := map[string]bool{} // keys of rows inserted or updated by statement
+	keys := func(row Row) []string {
+		result := []string{}
+		for _, index := range arbiters {
+			result = append(result, index.name+Row{cells: rowValues(table, row, indexColumnNames(index))}.key())
+		}
+
+		return result
+	}
+
+	result := []Row{}
+	for _, row := range rows {
+		id, existing, found, err := findConflict(tx, table, arbiters, row)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			if err := insertRows(tx, table, []Row{row}); err != nil {
+				return nil, err
+			}
+
+			for _, key := range keys(row) {
+				changed[key] = true
+			}
+			result = append(result, row)
+			continue
+		}
+
+		if !clause.update {
+			continue
+		}
+
+		if slices.ContainsFunc(keys(existing), func(key string) bool { return changed[key] }) {
+			return nil, ErrRowAffectedTwice
+		}
+
+		updated, err := updateRows(tx, table, []RowID{id}, []Row{existing}, func(old Row) (Row, error) {
+			updated := Row{cells: slices.Clone(old.cells)}
+			source := Row{cells: slices.Concat(old.cells, row.cells)}
+			for i, assignment := range clause.assignments {
+				value, err := evaluateExpression(assignment.value, columns, source)
+				if err != nil {
+					return Row{}, err
+				}
+
+				value, err = castValue(table.columns[positions[i]].dataType, value)
+				if err != nil {
+					return Row{}, err
+				}
+
+				updated.cells[positions[i]] = value
+			}
+
+			return updated, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys(updated[0]) {
+			changed[key] = true
+		}
+		result = append(result, updated...)
+	}
+
+	return result, nil
+}
+
+// findConflict returns existing row with the same values of arbiter index
+// columns as row
+func findConflict(tx *Transaction, table Table, arbiters []Index, row Row) (RowID, Row, bool, error) {
+	for _, index := range arbiters {
+		// nulls are distinct from each other
+		names := indexColumnNames(index)
+		values := rowValues(table, row, names)
+		if slices.Contains(values, nil) {
+			continue
+		}
+
+		ids, rows, err := findRowsByValues(tx, table, names, values)
+		if err != nil {
+			return RowID{}, Row{}, false, err
+		}
+
+		if len(ids) > 0 {
+			return ids[0], rows[0], true, nil
+		}
+	}
+
+	return RowID{}, Row{}, false, nil
+}
+
+// indexColumnNames returns names of columns of index in their order
+func indexColumnNames(index Index) []string {
+	names := []string{}
+	for _, cd := range index.columns {
+		names = append(names, cd.name)
+	}
+
+	return names
+}
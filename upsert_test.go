@@ -0,0 +1,162 @@
+package auralis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInsertRows(t *testing.T) {
+	testCases := map[string]struct {
+		queries  []string
+		expected [][]any // items ordered by id
+		affected int     // rows affected by the last query
+		err      string  // code of expected error
+	}{
+		"multiple values": {
+			queries:  []string{"INSERT INTO items (id, name, stock) VALUES (3, 'c', 1), (4, DEFAULT, 2)"},
+			expected: [][]any{{int32(1), "a", int32(10)}, {int32(2), "b", int32(20)}, {int32(3), "c", int32(1)}, {int32(4), "none", int32(2)}},
+			affected: 2,
+		},
+		"values count mismatch of second row": {
+			queries: []string{"INSERT INTO items (id, name) VALUES (3, 'c'), (4)"},
+			err:     "INVALID_QUERY",
+		},
+		"insert from query": {
+			queries: []string{
+				"CREATE TABLE archive (id bigint, name varchar)",
+				"INSERT INTO archive (id, name) SELECT 12, upper(name) FROM items WHERE stock > 15",
+				"INSERT INTO items (id, name) SELECT id, name FROM archive",
+			},
+			expected: [][]any{{int32(1), "a", int32(10)}, {int32(2), "b", int32(20)}, {int32(12), "B", int32(0)}},
+			affected: 1,
+		},
+		"insert from query with common table expression": {
+			queries: []string{
+				"INSERT INTO items (id, stock) WITH totals AS (SELECT sum(stock) AS total FROM items) SELECT 3, total FROM totals",
+			},
+			expected: [][]any{{int32(1), "a", int32(10)}, {int32(2), "b", int32(20)}, {int32(3), "none", int32(30)}},
+			affected: 1,
+		},
+		"query columns count mismatch": {
+			queries: []string{"INSERT INTO items (id) SELECT id, name FROM items"},
+			err:     "INVALID_QUERY",
+		},
+		"query value of other type": {
+			queries: []string{"INSERT INTO items (id, name) SELECT name, name FROM items"},
+			err:     ErrIntegerTypeConversion.Code,
+		},
+		"conflict do nothing": {
+			queries:  []string{"INSERT INTO items (id, name) VALUES (1, 'x'), (3, 'c'), (3, 'd') ON CONFLICT (id) DO NOTHING"},
+			expected: [][]any{{int32(1), "a", int32(10)}, {int32(2), "b", int32(20)}, {int32(3), "c", int32(0)}},
+			affected: 1,
+		},
+		"conflict of any unique index": {
+			queries:  []string{"INSERT INTO items (id, name) VALUES (3, 'a') ON CONFLICT DO NOTHING"},
+			expected: [][]any{{int32(1), "a", int32(10)}, {int32(2), "b", int32(20)}},
+		},
+		"conflict do update": {
+			queries: []string{
+				"INSERT INTO items (id, name, stock) VALUES (1, 'x', 15), (3, 'c', 7) " +
+					"ON CONFLICT (id) DO UPDATE SET stock = greatest(stock, excluded.stock), name = items.name",
+			},
+			expected: [][]any{{int32(1), "a", int32(15)}, {int32(2), "b", int32(20)}, {int32(3), "c", int32(7)}},
+			affected: 2,
+		},
+		"conflict do update from query": {
+			queries: []string{
+				"INSERT INTO items (id, name, stock) SELECT id, name, 1 FROM items ON CONFLICT (id) DO UPDATE SET stock = excluded.stock",
+			},
+			expected: [][]any{{int32(1), "a", int32(1)}, {int32(2), "b", int32(1)}},
+			affected: 2,
+		},
+		"row affected twice": {
+			queries: []string{"INSERT INTO items (id, name) VALUES (1, 'x'), (1, 'y') ON CONFLICT (id) DO UPDATE SET name = excluded.name"},
+			err:     ErrRowAffectedTwice.Code,
+		},
+		"conflict target without unique index": {
+			queries: []string{"INSERT INTO items (id, name) VALUES (1, 'x') ON CONFLICT (stock) DO NOTHING"},
+			err:     ErrNoConflictIndex.Code,
+		},
+		"conflict of other unique index": {
+			queries: []string{"INSERT INTO items (id, name) VALUES (3, 'a') ON CONFLICT (id) DO NOTHING"},
+			err:     ErrUniqueViolation.Code,
+		},
+		"do update of unknown column": {
+			queries: []string{"INSERT INTO items (id, name) VALUES (1, 'x') ON CONFLICT (id) DO UPDATE SET price = 1"},
+			err:     ErrColumnNotFound.Code,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t,
+				"CREATE TABLE items (id integer PRIMARY KEY, name varchar UNIQUE DEFAULT 'none', stock integer DEFAULT 0)",
+				"INSERT INTO items (id, name, stock) VALUES (1, 'a', 10), (2, 'b', 20)",
+			)
+
+			var dataSet *DataSet
+			var err error
+			for _, query := range tC.queries {
+				if dataSet, err = ExecuteQuery(query); err != nil {
+					break
+				}
+			}
+
+			code := ""
+			var auraErr AuraError
+			if errors.As(err, &auraErr) {
+				code = auraErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tC.err {
+				t.Fatalf("\nexp %+v\ngot %+v", tC.err, err)
+			}
+
+			if tC.err != "" {
+				return
+			}
+
+			if dataSet.affected != tC.affected {
+				t.Errorf("\nexp %+v\ngot %+v", tC.affected, dataSet.affected)
+			}
+
+			if rows := queryCells(t, "SELECT id, name, stock FROM items ORDER BY id"); !reflect.DeepEqual(rows, tC.expected) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.expected, rows)
+			}
+		})
+	}
+}
+
+func TestInsertConflictReturning(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE items (id integer PRIMARY KEY, stock integer)", "INSERT INTO items (id, stock) VALUES (1, 10)")
+
+	query := "INSERT INTO items (id, stock) VALUES (1, 5), (2, 5) ON CONFLICT (id) DO UPDATE SET stock = coalesce(excluded.stock, stock) RETURNING id, stock"
+	expected := [][]any{{int32(1), int32(5)}, {int32(2), int32(5)}}
+	if rows := queryCells(t, query); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+	}
+}
+
+func TestInsertByPosition(t *testing.T) {
+	// positions of columns follow their definitions, not order of map iteration
+	for range 20 {
+		setupTestDatabase(t,
+			"CREATE TABLE events (id integer, kind varchar, amount smallint, created timestamp, tag uniqueidentifier)",
+			"INSERT INTO events VALUES (1, 'login', 25, '2024-05-01 12:30:00', 'e28c20d7-483d-4f6e-9b31-9d0d6819ba39')",
+			"INSERT INTO events SELECT id, kind, amount, created, tag FROM events",
+		)
+
+		expected := [][]any{{"id", "integer"}, {"kind", "varchar"}, {"amount", "smallint"}, {"created", "timestamp"}, {"tag", "uniqueidentifier"}}
+		columns := queryCells(t, "SELECT column_name, data_type FROM auralis.columns WHERE table_name = 'events' ORDER BY position")
+		if !reflect.DeepEqual(columns, expected) {
+			t.Fatalf("\nexp %+v\ngot %+v", expected, columns)
+		}
+
+		rows := queryCells(t, "SELECT id, kind, amount FROM events ORDER BY id")
+		if expected := [][]any{{int32(1), "login", int16(25)}, {int32(1), "login", int16(25)}}; !reflect.DeepEqual(rows, expected) {
+			t.Fatalf("\nexp %+v\ngot %+v", expected, rows)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package auralis
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// names are limited by varchar size of catalog
+const vacuumStatistics string = "vacuum_stats"
+
+// auralisVacuumStatistics records each vacuum of table, reclaimed bytes count
+// space made free within kept pages and size of truncated part of file
+var auralisVacuumStatistics = Table{
+	schemaTable: SchemaTable[string, string]{internalSchema, vacuumStatistics},
+	columns: []Column{
+		{
+			name:     "table_schema",
+			dataType: varchar,
+			position: 1,
+		},
+		{
+			name:     "table_name",
+			dataType: varchar,
+			position: 2,
+		},
+		{
+			name:     "operation",
+			dataType: varchar,
+			position: 3,
+		},
+		{
+			name:     "removed_tuples",
+			dataType: bigint,
+			position: 4,
+		},
+		{
+			name:     "reclaimed_bytes",
+			dataType: bigint,
+			position: 5,
+		},
+	},
+}
+
+var (
+	ErrVacuumInTransaction = AuraError{
+		Code:    "ACTIVE_SQL_TRANSACTION",
+		Message: "VACUUM cannot run inside a transaction block"}
+)
+
+// VacuumResult describes space reclaimed by vacuum of table
+type VacuumResult struct {
+	removedTuples  int64
+	reclaimedBytes int64
+}
+
+// handleVacuumQuery vacuums each table in its own transaction so locks of
+// vacuumed table are released before the next one
+func handleVacuumQuery(query VacuumQuery) error {
+	sources := query.sources
+	if len(sources) == 0 {
+		err := autocommit(func(tx *Transaction) error {
+			var err error
+			sources, err = listTables(tx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	operation, mode, vacuum := "vacuum", intentionExclusive, vacuumTable
+	if query.full {
+		operation, mode, vacuum = "vacuum full", exclusive, rewriteTable
+	}
+
+	for _, source := range sources {
+		err := autocommit(func(tx *Transaction) error {
+			table, err := getTable(tx, source)
+			if err != nil {
+				return err
+			}
+
+			if err := lockManager.lock(tx, tableLockTag(table), mode); err != nil {
+				return err
+			}
+
+			// index could be created while the lock was awaited
+			if table.indexes, err = tableIndexes(tx, table); err != nil {
+				return err
+			}
+
+			result, err := vacuum(tx, table)
+			if err != nil {
+				return err
+			}
+
+			return recordVacuum(tx, table, operation, result)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listTables returns tables registered in catalog
+func listTables(tx *Transaction) ([]SchemaTable[string, string], error) {
+	dataSet, err := readFromTable(tx, auralisTables, SelectQuery{
+		source:      auralisTables.schemaTable,
+		dataColumns: []string{"table_schema", "table_name"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []SchemaTable[string, string]{}
+	for _, row := range dataSet.rows {
+		source := SchemaTable[string, string]{row.cells[0].(string), row.cells[1].(string)}
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+
+	return sources, nil
+}
+
+func recordVacuum(tx *Transaction, table Table, operation string, result VacuumResult) error {
+	return writeIntoTable(tx, auralisVacuumStatistics, DataSet{
+		columns: auralisVacuumStatistics.columns,
+		rows: []Row{
+			{
+				cells: []any{
+					table.schemaTable.schema, table.schemaTable.name, operation,
+					result.removedTuples, result.reclaimedBytes,
+				},
+			},
+		},
+	})
+}
+
+// dead reports whether tuple is deleted by transaction committed before the
+// horizon so no snapshot can see it anymore
+func dead(tuple []byte, horizon uint64) bool {
+	xmax := tupleXmax(tuple)
+	return xmax != 0 && xmax < horizon
+}
+
+// vacuumTable removes dead tuples and compacts their pages in place, empty
+// pages at the end of file are truncated. Tuples keep their slots so table
+// can be read and modified concurrently
+func vacuumTable(tx *Transaction, table Table) (VacuumResult, error) {
+	path := getTableDiskPath(table.schemaTable)
+	horizon := writeAheadLog.horizon()
+
+	// entries are removed first so they never locate released slots
+	if err := vacuumIndexes(tx, table, horizon); err != nil {
+		return VacuumResult{}, err
+	}
+
+	count, err := bufferPool.numberOfPages(path)
+	if err != nil {
+		return VacuumResult{}, err
+	}
+
+	result := VacuumResult{}
+	gained := make([]int64, count)
+	kept := int64(0)
+	for number := range count {
+		page, err := bufferPool.fetchPage(PageID{file: path, number: number})
+		if err != nil {
+			return VacuumResult{}, err
+		}
+
+		slots := []int{}
+		for slot := range page.slotCount() {
+			if tuple := page.tuple(slot); tuple != nil && dead(tuple, horizon) {
+				slots = append(slots, slot)
+			}
+		}
+
+		dirty := len(slots) > 0 || page.fragmented()
+		if dirty {
+			free := page.freeEnd() - page.freeStart()
+			lsn := tx.log(LogRecord{kind: vacuumRecord, page: page.id, tuple: vacuumedSlots(slots)})
+			page.vacuum(slots)
+			page.setLSN(lsn)
+
+			gained[number] = int64(page.freeEnd() - page.freeStart() - free)
+			result.removedTuples += int64(len(slots))
+		}
+
+		if page.slotCount() > 0 {
+			kept = number + 1
+		}
+		bufferPool.unpinPage(page.id, dirty)
+	}
+
+	for _, gain := range gained[:kept] {
+		result.reclaimedBytes += gain
+	}
+
+	if kept == count {
+		return result, nil
+	}
+
+	// truncation can't be undone so it's logged first
+	lsn := tx.log(LogRecord{kind: truncateRecord, page: PageID{file: path, number: kept}})
+	if err := writeAheadLog.flush(lsn); err != nil {
+		return VacuumResult{}, err
+	}
+
+	if err := bufferPool.truncateFile(path, kept); err != nil {
+		return VacuumResult{}, err
+	}
+	result.reclaimedBytes += (count - kept) * pageSize
+
+	return result, nil
+}
+
+// rewriteTable writes tuples which aren't dead into compacted copy of table file
+// replacing the original one, indexes are rebuilt for new row locations. Tuples
+// move to other slots so table has to be locked exclusively. Reclaimed bytes
+// are the difference of table file sizes
+func rewriteTable(tx *Transaction, table Table) (VacuumResult, error) {
+	path := getTableDiskPath(table.schemaTable)
+	horizon := writeAheadLog.horizon()
+
+	count, err := bufferPool.numberOfPages(path)
+	if err != nil {
+		return VacuumResult{}, err
+	}
+
+	result := VacuumResult{}
+	pages := []*Page{}
+	keys := make([][][]byte, len(table.indexes))
+	for number := range count {
+		page, err := bufferPool.fetchPage(PageID{file: path, number: number})
+		if err != nil {
+			return VacuumResult{}, err
+		}
+
+		for slot := range page.slotCount() {
+			tuple := page.tuple(slot)
+			if tuple == nil {
+				continue
+			}
+
+			if dead(tuple, horizon) {
+				result.removedTuples++
+				continue
+			}
+
+			// tuple is copied with its header
+			var copied *Page
+			at, ok := 0, false
+			if len(pages) > 0 {
+				copied = pages[len(pages)-1]
+				at, ok = copied.insertTuple(tuple)
+			}
+
+			if !ok {
+				copied = &Page{id: PageID{file: path, number: int64(len(pages))}}
+				copied.init()
+				at, _ = copied.insertTuple(tuple)
+				pages = append(pages, copied)
+			}
+
+			for i, index := range table.indexes {
+				keys[i] = append(keys[i], indexKey(table, index, tuple, RowID{page: copied.id, slot: at}))
+			}
+		}
+
+		bufferPool.unpinPage(page.id, false)
+	}
+
+	files := map[string][]*Page{path: pages}
+	for i, index := range table.indexes {
+		slices.SortFunc(keys[i], bytes.Compare)
+		files[index.file(table)] = index.build(table, keys[i])
+	}
+
+	if err := replaceFiles(tx, files); err != nil {
+		return VacuumResult{}, err
+	}
+	result.reclaimedBytes = (count - max(int64(len(pages)), 1)) * pageSize
+
+	return result, nil
+}
+
+// replaceFiles replaces files by pages written aside, each copy is renamed after
+// rewrite record of its file reaches the log so crash leaves either original
+// file or the copy which is renamed by recovery. Files keep at least one page
+// as recovery recognizes the copy by LSN of its first page
+func replaceFiles(tx *Transaction, files map[string][]*Page) error {
+	paths := slices.Sorted(maps.Keys(files))
+	for _, path := range paths {
+		pages := files[path]
+		if len(pages) == 0 {
+			page := &Page{id: PageID{file: path}}
+			page.init()
+			pages = append(pages, page)
+		}
+
+		lsn := tx.log(LogRecord{kind: rewriteRecord, page: PageID{file: path}})
+		tmp, err := os.Create(path + ".tmp")
+		if err != nil {
+			return err
+		}
+
+		for _, page := range pages {
+			page.setLSN(lsn)
+			if err := physicalWrite(tmp, "page", page.data[:], page.id.number*pageSize); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+
+		if err := errors.Join(tmp.Sync(), tmp.Close()); err != nil {
+			return err
+		}
+	}
+
+	if err := writeAheadLog.flush(tx.lastLSN); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := bufferPool.dropFile(path); err != nil {
+			return err
+		}
+
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// autovacuum vacuums all tables which can be locked without waiting, only
+// vacuums which reclaimed some space are recorded
+func autovacuum() error {
+	statementLock.Lock()
+	defer statementLock.Unlock()
+
+	var sources []SchemaTable[string, string]
+	err := autocommit(func(tx *Transaction) error {
+		var err error
+		sources, err = listTables(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		err := autocommit(func(tx *Transaction) error {
+			table, err := getTable(tx, source)
+			if err != nil {
+				return err
+			}
+
+			if !lockManager.tryLock(tx, tableLockTag(table), intentionExclusive) {
+				return nil
+			}
+
+			result, err := vacuumTable(tx, table)
+			if err != nil || result == (VacuumResult{}) {
+				return err
+			}
+
+			return recordVacuum(tx, table, "autovacuum", result)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startAutovacuum starts worker running autovacuum every autovacuum_naptime
+// seconds while autovacuum setting is on, returned function stops the worker
+func startAutovacuum() func() {
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			statementLock.Lock()
+			naptime := time.Duration(getIntSetting("autovacuum_naptime")) * time.Second
+			statementLock.Unlock()
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(naptime):
+			}
+
+			statementLock.Lock()
+			enabled, _ := getSetting("autovacuum")
+			statementLock.Unlock()
+
+			if enabled != "on" {
+				continue
+			}
+
+			if err := autovacuum(); err != nil {
+				logger.Load().Printf("ERROR: autovacuum failed %v\n", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
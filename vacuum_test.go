@@ -0,0 +1,275 @@
+package auralis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fillItems inserts rows with ids from zero, 215 rows fit into a page
+func fillItems(t *testing.T, count int) {
+	t.Helper()
+
+	err := autocommit(func(tx *Transaction) error {
+		table, err := getTable(tx, SchemaTable[string, string]{defaultScheme, "items"})
+		if err != nil {
+			return err
+		}
+
+		// columns of created table are in any order
+		rows := []Row{}
+		for i := range count {
+			row := Row{}
+			for _, cd := range table.columns {
+				if cd.name == "id" {
+					row.cells = append(row.cells, int16(i))
+				} else {
+					row.cells = append(row.cells, fmt.Sprintf("item %d", i))
+				}
+			}
+			rows = append(rows, row)
+		}
+
+		return writeIntoTable(tx, table, DataSet{columns: table.columns, rows: rows})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func queryCells(t *testing.T, query string) [][]any {
+	t.Helper()
+
+	dataSet, err := ExecuteQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resultCells(dataSet)
+}
+
+func itemPages(t *testing.T) int64 {
+	t.Helper()
+
+	count, err := bufferPool.numberOfPages(getTableDiskPath(SchemaTable[string, string]{defaultScheme, "items"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+const vacuumStatisticsQuery = "SELECT operation, removed_tuples, reclaimed_bytes FROM auralis.vacuum_stats " +
+	"WHERE table_name = 'items'"
+
+func TestVacuum(t *testing.T) {
+	testCases := map[string]struct {
+		queries    []string
+		statistics [][]any
+		rows       int64
+		pages      int64
+	}{
+		"deleted rows are removed": {
+			queries:    []string{"DELETE FROM items WHERE id < 100", "VACUUM items"},
+			statistics: [][]any{{"vacuum", int64(100), int64(100 * 35)}},
+			rows:       400,
+			pages:      3,
+		},
+		"empty pages at the end are truncated": {
+			queries: []string{"DELETE FROM items WHERE id >= 200", "VACUUM"},
+			// slots of the last rows of the first page are released
+			statistics: [][]any{{"vacuum", int64(300), int64(9*39 + 2*pageSize)}},
+			rows:       200,
+			pages:      1,
+		},
+		"updated rows leave old versions": {
+			queries:    []string{"UPDATE items SET name = 'x' WHERE id < 10", "VACUUM items"},
+			statistics: [][]any{{"vacuum", int64(10), int64(10 * 35)}},
+			rows:       500,
+			pages:      3,
+		},
+		"nothing to reclaim": {
+			queries:    []string{"VACUUM items"},
+			statistics: [][]any{{"vacuum", int64(0), int64(0)}},
+			rows:       500,
+			pages:      3,
+		},
+		"vacuum full compacts pages": {
+			queries:    []string{"DELETE FROM items WHERE id < 300", "VACUUM FULL items"},
+			statistics: [][]any{{"vacuum full", int64(300), int64(2 * pageSize)}},
+			rows:       200,
+			pages:      1,
+		},
+	}
+	for test, tC := range testCases {
+		t.Run(test, func(t *testing.T) {
+			setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+			fillItems(t, 500)
+
+			for _, query := range tC.queries {
+				if _, err := ExecuteQuery(query); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if statistics := queryCells(t, vacuumStatisticsQuery); !reflect.DeepEqual(statistics, tC.statistics) {
+				t.Errorf("\nexp %+v\ngot %+v", tC.statistics, statistics)
+			}
+
+			if rows := queryCells(t, "SELECT count(*) FROM items"); rows[0][0] != tC.rows {
+				t.Errorf("\nexp %+v\ngot %+v", tC.rows, rows[0][0])
+			}
+
+			if pages := itemPages(t); pages != tC.pages {
+				t.Errorf("\nexp %+v\ngot %+v", tC.pages, pages)
+			}
+
+			// vacuumed table accepts changes
+			if _, err := ExecuteQuery("UPDATE items SET name = 'y'; INSERT INTO items (id, name) VALUES ('500', 'z')"); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := [][]any{{"y", tC.rows}, {"z", int64(1)}}
+			if rows := queryCells(t, "SELECT name, count(*) FROM items GROUP BY name ORDER BY name"); !reflect.DeepEqual(rows, expected) {
+				t.Errorf("\nexp %+v\ngot %+v", expected, rows)
+			}
+		})
+	}
+}
+
+func TestVacuumKeepsVersionsVisibleToSnapshots(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+	fillItems(t, 500)
+
+	reader := newSession()
+	defer reader.close()
+
+	if _, err := reader.executeQuery("BEGIN ISOLATION LEVEL REPEATABLE READ; SELECT count(*) FROM items"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ExecuteQuery("DELETE FROM items WHERE id < 100; VACUUM items"); err != nil {
+		t.Fatal(err)
+	}
+
+	dataSet, err := reader.executeQuery("SELECT count(*) FROM items")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := resultCells(dataSet)[0][0]; count != int64(500) {
+		t.Fatalf("\nexp %+v\ngot %+v", 500, count)
+	}
+
+	if _, err := reader.executeQuery("VACUUM items"); err != ErrVacuumInTransaction {
+		t.Fatalf("\nexp %+v\ngot %+v", ErrVacuumInTransaction, err)
+	}
+
+	if _, err := reader.executeQuery("COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ExecuteQuery("VACUUM items"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]any{{"vacuum", int64(0), int64(0)}, {"vacuum", int64(100), int64(100 * 35)}}
+	if statistics := queryCells(t, vacuumStatisticsQuery); !reflect.DeepEqual(statistics, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, statistics)
+	}
+}
+
+func TestAutovacuum(t *testing.T) {
+	setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+	fillItems(t, 500)
+
+	if _, err := ExecuteQuery("DELETE FROM items WHERE id >= 400"); err != nil {
+		t.Fatal(err)
+	}
+
+	// table locked by other transaction is skipped
+	locker := newSession()
+	defer locker.close()
+	if _, err := locker.executeQuery("BEGIN; LOCK TABLE items IN SHARE MODE"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := autovacuum(); err != nil {
+		t.Fatal(err)
+	}
+
+	if statistics := queryCells(t, vacuumStatisticsQuery); len(statistics) != 0 {
+		t.Fatalf("\nexp %+v\ngot %+v", [][]any{}, statistics)
+	}
+
+	if _, err := locker.executeQuery("COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := autovacuum(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the last page is truncated
+	expected := [][]any{{"autovacuum", int64(100), int64(18*39 + pageSize)}}
+	if statistics := queryCells(t, vacuumStatisticsQuery); !reflect.DeepEqual(statistics, expected) {
+		t.Errorf("\nexp %+v\ngot %+v", expected, statistics)
+	}
+}
+
+func TestVacuumCrashRecovery(t *testing.T) {
+	const poolSize = 3
+
+	for _, query := range []string{"VACUUM items", "VACUUM FULL items"} {
+		for crashAt := 1; ; crashAt++ {
+			crashed := false
+			t.Run(fmt.Sprintf("%s crash at write %d", query, crashAt), func(t *testing.T) {
+				setupTestDatabase(t, "CREATE TABLE items (id smallint, name varchar)")
+				defer func() { crashHook = nil }()
+
+				bufferPool.close()
+				bufferPool = newBufferPool(poolSize)
+
+				fillItems(t, 500)
+				if _, err := ExecuteQuery("DELETE FROM items WHERE id < 100; DELETE FROM items WHERE id >= 300"); err != nil {
+					t.Fatal(err)
+				}
+
+				flag := simulateCrashAt(crashAt)
+				if _, err := ExecuteQuery(query); err != nil && !errors.Is(err, errSimulatedCrash) {
+					t.Fatal(err)
+				}
+				crashed = *flag
+
+				crashHook = nil
+				if err := restartDatabase(poolSize); err != nil {
+					t.Fatal(err)
+				}
+
+				// vacuum changes only layout of table
+				expected := [][]any{{int64(200), int16(100), int16(299)}}
+				if rows := queryCells(t, "SELECT count(*), min(id), max(id) FROM items"); !reflect.DeepEqual(rows, expected) {
+					t.Fatalf("\nexp %+v\ngot %+v", expected, rows)
+				}
+
+				if _, err := ExecuteQuery("DELETE FROM items WHERE id = 100; " + query); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := restartDatabase(poolSize); err != nil {
+					t.Fatal(err)
+				}
+
+				expected = [][]any{{int64(199), int16(101), int16(299)}}
+				if rows := queryCells(t, "SELECT count(*), min(id), max(id) FROM items"); !reflect.DeepEqual(rows, expected) {
+					t.Fatalf("\nexp %+v\ngot %+v", expected, rows)
+				}
+			})
+
+			if !crashed {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package auralis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type LogRecordKind uint8
+
+const (
+	beginRecord LogRecordKind = iota + 1
+	insertRecord
+	// deleteRecord sets xmax of tuple, tuple holds previous and new xmax
+	deleteRecord
+	commitRecord
+	abortRecord
+	// compensationRecord logs undo of insert or delete, tuple is removed from the page
+	// when record has no tuple, otherwise the tuple is overwritten by logged one
+	compensationRecord
+	// checkpointRecord starts log, all changes before it are already on disk
+	checkpointRecord
+	// vacuumRecord removes dead tuples and compacts page, tuple holds removed slots.
+	// Vacuum records are not undone
+	vacuumRecord
+	// truncateRecord shortens file to number of pages of the record page
+	truncateRecord
+	// rewriteRecord replaces file by its copy written aside, earlier changes of
+	// the file are not redone. Copy left by crash is renamed by recovery
+	rewriteRecord
+	// indexRecord holds images of changed B+tree nodes, index changes are not undone
+	indexRecord
+)
+
+// LogRecord describes single change, page changes are logged physiologically,
+// by page and slot within the page
+type LogRecord struct {
+	lsn      uint64
+	prevLSN  uint64 // previous record of the same transaction
+	txID     uint64 // next transaction id for checkpoint records
+	kind     LogRecordKind
+	undoNext uint64 // next record of transaction to undo, used by compensation records
+	page     PageID
+	slot     int
+	tuple    []byte
+}
+
+// WAL is write-ahead log, records are buffered in memory and written to disk
+// when flushed. Pages can't be written to disk before log records describing
+// their changes
+type WAL struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	size       int64  // bytes written to file
+	buffer     []byte // encoded records not written yet
+	nextLSN    uint64
+	flushedLSN uint64
+	nextTxID   uint64
+	active     map[uint64]bool   // running transactions
+	xmins      map[uint64]uint64 // oldest transaction not seen committed by snapshot of transaction
+}
+
+const (
+	walDirectory = "wal"
+	walFile      = "log"
+
+	// log is truncated by checkpoint after it grows over the threshold
+	walCheckpointThreshold = 16 << 20
+	// record header layout: payload length (4B), payload crc32 (4B)
+	logRecordHeaderSize = 8
+)
+
+var (
+	ErrLogCorrupted = AuraError{Code: "LOG_CORRUPTED", Message: "write-ahead log doesn't match data pages"}
+
+	writeAheadLog *WAL
+)
+
+// crashHook allows tests to simulate crash before physical write of data to target
+// file, it returns part of data which reaches the disk and error aborting the write
+var crashHook func(target string, data []byte) ([]byte, error)
+
+func physicalWrite(f *os.File, target string, data []byte, offset int64) error {
+	if crashHook != nil {
+		written, err := crashHook(target, data)
+		if err != nil {
+			f.WriteAt(written, offset)
+			return err
+		}
+	}
+
+	_, err := f.WriteAt(data, offset)
+	return err
+}
+
+func getWALDiskPath() string {
+	return filepath.Join(dataPath, walDirectory, walFile)
+}
+
+// openWAL opens log and returns its valid records, log is truncated after the
+// last complete record so torn writes are discarded
+func openWAL(path string) (*WAL, []LogRecord, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	wal := &WAL{path: path, file: f, nextLSN: 1, nextTxID: 1, active: map[uint64]bool{}, xmins: map[uint64]uint64{}}
+	records := []LogRecord{}
+	for {
+		record, n, ok := decodeLogRecord(data[wal.size:])
+		if !ok {
+			break
+		}
+
+		records = append(records, record)
+		wal.size += int64(n)
+		wal.nextLSN = record.lsn + 1
+		if record.kind == checkpointRecord {
+			wal.nextTxID = max(wal.nextTxID, record.txID)
+		} else {
+			wal.nextTxID = max(wal.nextTxID, record.txID+1)
+		}
+	}
+	wal.flushedLSN = wal.nextLSN - 1
+
+	if err := f.Truncate(wal.size); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return wal, records, nil
+}
+
+// append assigns LSN to record and buffers it
+func (w *WAL) append(record *LogRecord) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record.lsn = w.nextLSN
+	w.nextLSN++
+	w.buffer = append(w.buffer, encodeLogRecord(*record)...)
+
+	return record.lsn
+}
+
+// flush writes and syncs buffered records when record with lsn is not on disk yet
+func (w *WAL) flush(lsn uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if lsn <= w.flushedLSN || len(w.buffer) == 0 {
+		return nil
+	}
+
+	if err := physicalWrite(w.file, "wal", w.buffer, w.size); err != nil {
+		return err
+	}
+
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+
+	w.size += int64(len(w.buffer))
+	w.buffer = w.buffer[:0]
+	w.flushedLSN = w.nextLSN - 1
+
+	return nil
+}
+
+func (w *WAL) newTransactionID() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextTxID
+	w.nextTxID++
+	w.active[id] = true
+
+	return id
+}
+
+// resumeTransaction registers transaction found in log as running
+func (w *WAL) resumeTransaction(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.active[id] = true
+}
+
+func (w *WAL) endTransaction(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.active, id)
+	delete(w.xmins, id)
+}
+
+// snapshot returns snapshot of transactions committed so far taken by transaction
+func (w *WAL) snapshot(id uint64) Snapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	xmin := w.nextTxID
+	active := make(map[uint64]bool, len(w.active))
+	for other := range w.active {
+		active[other] = true
+		xmin = min(xmin, other)
+	}
+	w.xmins[id] = xmin
+
+	return Snapshot{xmax: w.nextTxID, active: active}
+}
+
+// latestSnapshot returns snapshot of transactions committed so far, it doesn't
+// hold back the horizon as it's used only by the current statement
+func (w *WAL) latestSnapshot() Snapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	active := make(map[uint64]bool, len(w.active))
+	for id := range w.active {
+		active[id] = true
+	}
+
+	return Snapshot{xmax: w.nextTxID, active: active}
+}
+
+// running reports whether transaction hasn't finished yet
+func (w *WAL) running(id uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.active[id]
+}
+
+// horizon returns the oldest transaction which may not be seen committed by
+// current or future snapshots, tuples deleted before it are visible to nobody
+func (w *WAL) horizon() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	horizon := w.nextTxID
+	for id := range w.active {
+		if xmin, ok := w.xmins[id]; ok {
+			id = xmin
+		}
+		horizon = min(horizon, id)
+	}
+
+	return horizon
+}
+
+// checkpoint writes all dirty pages to disk and replaces log with a single
+// checkpoint record, it can run only without active transactions
+func (w *WAL) checkpoint() error {
+	if err := bufferPool.sync(); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.active) > 0 {
+		return nil
+	}
+
+	// new log is written aside and renamed so crash leaves either old or new log
+	record := LogRecord{lsn: w.nextLSN, txID: w.nextTxID, kind: checkpointRecord}
+	data := encodeLogRecord(record)
+
+	tmp, err := os.Create(w.path + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	if err := physicalWrite(tmp, "wal", data, 0); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := errors.Join(tmp.Sync(), tmp.Close()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(w.path+".tmp", w.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.path, os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	w.file.Close()
+	w.file = f
+	w.size = int64(len(data))
+	w.buffer = w.buffer[:0]
+	w.nextLSN++
+	w.flushedLSN = record.lsn
+
+	return nil
+}
+
+func (w *WAL) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+// vacuumedSlots encodes tuple of vacuum record
+func vacuumedSlots(slots []int) []byte {
+	data := []byte{}
+	for _, slot := range slots {
+		data = binary.BigEndian.AppendUint16(data, uint16(slot))
+	}
+
+	return data
+}
+
+func decodeVacuumedSlots(data []byte) []int {
+	slots := []int{}
+	for i := 0; i+2 <= len(data); i += 2 {
+		slots = append(slots, int(binary.BigEndian.Uint16(data[i:i+2])))
+	}
+
+	return slots
+}
+
+// xmaxChange encodes tuple of delete record
+func xmaxChange(previous uint64, xmax uint64) []byte {
+	return binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, previous), xmax)
+}
+
+func decodeXmaxChange(change []byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(change[0:8]), binary.BigEndian.Uint64(change[8:16])
+}
+
+func encodeLogRecord(record LogRecord) []byte {
+	payload := binary.BigEndian.AppendUint64(nil, record.lsn)
+	payload = binary.BigEndian.AppendUint64(payload, record.prevLSN)
+	payload = binary.BigEndian.AppendUint64(payload, record.txID)
+	payload = append(payload, byte(record.kind))
+	payload = binary.BigEndian.AppendUint64(payload, record.undoNext)
+
+	// pages are stored relative to data directory
+	file := ""
+	if record.page.file != "" {
+		file = filepath.Base(record.page.file)
+	}
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(file)))
+	payload = append(payload, file...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(record.page.number))
+	payload = binary.BigEndian.AppendUint16(payload, uint16(record.slot))
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(record.tuple)))
+	payload = append(payload, record.tuple...)
+
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(payload))
+
+	return append(data, payload...)
+}
+
+// decodeLogRecord returns record and its encoded size, false is returned
+// for incomplete or corrupted record
+func decodeLogRecord(data []byte) (LogRecord, int, bool) {
+	if len(data) < logRecordHeaderSize {
+		return LogRecord{}, 0, false
+	}
+
+	length := int(binary.BigEndian.Uint32(data[0:4]))
+	if length > len(data)-logRecordHeaderSize {
+		return LogRecord{}, 0, false
+	}
+
+	payload := data[logRecordHeaderSize : logRecordHeaderSize+length]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[4:8]) {
+		return LogRecord{}, 0, false
+	}
+
+	r := bytes.NewReader(payload)
+	record := LogRecord{}
+	var kind uint8
+	var fileLength uint16
+	var number uint64
+	var slot uint16
+	var tupleLength uint32
+
+	err := errors.Join(
+		binary.Read(r, binary.BigEndian, &record.lsn),
+		binary.Read(r, binary.BigEndian, &record.prevLSN),
+		binary.Read(r, binary.BigEndian, &record.txID),
+		binary.Read(r, binary.BigEndian, &kind),
+		binary.Read(r, binary.BigEndian, &record.undoNext),
+		binary.Read(r, binary.BigEndian, &fileLength),
+	)
+	if err != nil {
+		return LogRecord{}, 0, false
+	}
+
+	file := make([]byte, fileLength)
+	if _, err := io.ReadFull(r, file); err != nil {
+		return LogRecord{}, 0, false
+	}
+
+	err = errors.Join(
+		binary.Read(r, binary.BigEndian, &number),
+		binary.Read(r, binary.BigEndian, &slot),
+		binary.Read(r, binary.BigEndian, &tupleLength),
+	)
+	if err != nil {
+		return LogRecord{}, 0, false
+	}
+
+	record.tuple = make([]byte, tupleLength)
+	if _, err := io.ReadFull(r, record.tuple); err != nil {
+		return LogRecord{}, 0, false
+	}
+
+	record.kind = LogRecordKind(kind)
+	record.slot = int(slot)
+	if fileLength > 0 {
+		record.page = PageID{file: fmt.Sprintf("%s/%s", dataPath, file), number: int64(number)}
+	}
+
+	return record, logRecordHeaderSize + length, true
+}
@@ -0,0 +1,57 @@
+package auralis
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestOpenWALDiscardsTornRecord(t *testing.T) {
+	previous := dataPath
+	dataPath = t.TempDir()
+	defer func() { dataPath = previous }()
+
+	path := getWALDiskPath()
+	wal, records, err := openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 0 {
+		t.Fatalf("unexpected records of new log %+v", records)
+	}
+
+	insert := LogRecord{
+		txID:  7,
+		kind:  insertRecord,
+		page:  PageID{file: dataPath + "/dbo.users", number: 3},
+		slot:  12,
+		tuple: []byte("tuple"),
+	}
+	wal.append(&LogRecord{txID: 7, kind: beginRecord})
+	wal.append(&insert)
+	if err := wal.flush(insert.lsn); err != nil {
+		t.Fatal(err)
+	}
+
+	// torn record at the end of log
+	commit := encodeLogRecord(LogRecord{lsn: 3, txID: 7, kind: commitRecord})
+	wal.file.WriteAt(commit[:len(commit)-1], wal.size)
+	size := wal.size
+	wal.close()
+
+	wal, records, err = openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.close()
+
+	if len(records) != 2 || !reflect.DeepEqual(records[1], insert) {
+		t.Fatalf("\nexp %+v\ngot %+v", insert, records)
+	}
+
+	if info, _ := os.Stat(path); info.Size() != size || wal.nextLSN != 3 || wal.nextTxID != 8 {
+		t.Errorf("unexpected log state size %d next lsn %d next transaction %d",
+			info.Size(), wal.nextLSN, wal.nextTxID)
+	}
+}
@@ -0,0 +1,319 @@
+package auralis
+
+import (
+	"slices"
+)
+
+var windowFunctions = []string{
+	"row_number",
+	"rank",
+	"dense_rank",
+	"lag",
+	"lead",
+	"first_value",
+}
+
+func isWindowFunction(name string) bool {
+	return slices.Contains(windowFunctions, name)
+}
+
+// windowPartitionRow holds row index within data set with evaluated window keys
+type windowPartitionRow struct {
+	index     int
+	partition []any
+	order     []any
+}
+
+// planWindows returns window functions used in projections and order by and
+// columns of rows extended by their results. Expressions are rewritten to
+// reference these columns
+func planWindows(columns []Column, projections []Expression,
+	orderBy []OrderByItem) ([]FunctionCall, []Column, []Expression, []OrderByItem, error) {
+	calls := collectFunctionCalls(slices.Concat(projections, orderByExpressions(orderBy)),
+		func(call FunctionCall) bool { return call.over != nil })
+
+	extended := slices.Clone(columns)
+	for i, call := range calls {
+		if !isWindowFunction(call.name) && !isAggregateFunction(call.name) {
+			return nil, nil, nil, nil, ErrFunctionNotFound
+		}
+
+		extended = append(extended, Column{
+			name:     hiddenColumnName("window", i),
+			dataType: expressionDataType(call, columns),
+		})
+	}
+
+	replace := func(expr Expression) (Expression, bool, error) {
+		call, ok := expr.(FunctionCall)
+		if !ok || call.over == nil {
+			return nil, false, nil
+		}
+
+		i := slices.IndexFunc(calls, func(c FunctionCall) bool { return sameExpression(c, call, nil) })
+		return ColumnReference{name: hiddenColumnName("window", i)}, true, nil
+	}
+
+	rewritten := make([]Expression, 0, len(projections))
+	for _, expr := range projections {
+		expr, err := rewriteExpression(expr, replace)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		rewritten = append(rewritten, expr)
+	}
+
+	rewrittenOrderBy := make([]OrderByItem, 0, len(orderBy))
+	for _, item := range orderBy {
+		expr, err := rewriteExpression(item.expression, replace)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		rewrittenOrderBy = append(rewrittenOrderBy, OrderByItem{expression: expr, descending: item.descending})
+	}
+
+	return calls, extended, rewritten, rewrittenOrderBy, nil
+}
+
+// windowRows appends results of window functions to rows. Rows are returned
+// sorted by the last window definition
+func windowRows(columns []Column, calls []FunctionCall, rows []Row) ([]Row, error) {
+	result := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, Row{cells: slices.Clone(row.cells)})
+	}
+
+	var order []int
+	for _, call := range calls {
+		values, sorted, err := evaluateWindowFunction(call, columns, rows)
+		if err != nil {
+			return nil, err
+		}
+
+		for r := range result {
+			result[r].cells = append(result[r].cells, values[r])
+		}
+
+		order = sorted
+	}
+
+	if order == nil {
+		return result, nil
+	}
+
+	sortedRows := make([]Row, 0, len(result))
+	for _, i := range order {
+		sortedRows = append(sortedRows, result[i])
+	}
+
+	return sortedRows, nil
+}
+
+// evaluateWindowFunction returns function value for each row and row indexes
+// in order of window partitions
+func evaluateWindowFunction(call FunctionCall, columns []Column, rows []Row) ([]any, []int, error) {
+	window := call.over
+
+	partitionRows := make([]windowPartitionRow, 0, len(rows))
+	for i, row := range rows {
+		pr := windowPartitionRow{index: i}
+		for _, expr := range window.partitionBy {
+			value, err := evaluateExpression(expr, columns, row)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			pr.partition = append(pr.partition, value)
+		}
+
+		for _, item := range window.orderBy {
+			value, err := evaluateExpression(item.expression, columns, row)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			pr.order = append(pr.order, value)
+		}
+
+		partitionRows = append(partitionRows, pr)
+	}
+
+	slices.SortStableFunc(partitionRows, func(a, b windowPartitionRow) int {
+		if c := compareKeys(a.partition, b.partition, nil); c != 0 {
+			return c
+		}
+
+		return compareKeys(a.order, b.order, window.orderBy)
+	})
+
+	values := make([]any, len(rows))
+	order := make([]int, 0, len(rows))
+	for start := 0; start < len(partitionRows); {
+		end := start + 1
+		for end < len(partitionRows) &&
+			compareKeys(partitionRows[start].partition, partitionRows[end].partition, nil) == 0 {
+			end++
+		}
+
+		partition := partitionRows[start:end]
+		for pos := range partition {
+			value, err := evaluateWindowFunctionAt(call, columns, rows, partition, pos)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			values[partition[pos].index] = value
+			order = append(order, partition[pos].index)
+		}
+
+		start = end
+	}
+
+	return values, order, nil
+}
+
+func evaluateWindowFunctionAt(call FunctionCall, columns []Column, rows []Row,
+	partition []windowPartitionRow, pos int) (any, error) {
+	peers := func(a, b int) bool {
+		return compareKeys(partition[a].order, partition[b].order, nil) == 0
+	}
+
+	switch call.name {
+	case "row_number":
+		return int64(pos + 1), nil
+	case "rank":
+		rank := pos
+		for rank > 0 && peers(rank-1, pos) {
+			rank--
+		}
+
+		return int64(rank + 1), nil
+	case "dense_rank":
+		rank := 1
+		for i := 1; i <= pos; i++ {
+			if !peers(i-1, i) {
+				rank++
+			}
+		}
+
+		return int64(rank), nil
+	case "lag", "lead":
+		if len(call.args) == 0 || len(call.args) > 3 {
+			return nil, AuraError{
+				Code:    "INVALID_ARGUMENTS",
+				Message: call.name + " expects value, optional offset and default"}
+		}
+
+		current := rows[partition[pos].index]
+		offset := int64(1)
+		if len(call.args) > 1 {
+			v, err := evaluateExpression(call.args[1], columns, current)
+			if err != nil {
+				return nil, err
+			}
+
+			if !isInteger(v) {
+				return nil, AuraError{Code: "INVALID_ARGUMENTS", Message: call.name + " offset must be integer"}
+			}
+			offset = toInt64(v)
+		}
+
+		if call.name == "lag" {
+			offset = -offset
+		}
+
+		target := int64(pos) + offset
+		if target < 0 || target >= int64(len(partition)) {
+			if len(call.args) > 2 {
+				return evaluateExpression(call.args[2], columns, current)
+			}
+
+			return nil, nil
+		}
+
+		return evaluateExpression(call.args[0], columns, rows[partition[target].index])
+	}
+
+	start, end := frameBounds(call.over, partition, pos, peers)
+	frameRows := []Row{}
+	for i := start; i < end; i++ {
+		frameRows = append(frameRows, rows[partition[i].index])
+	}
+
+	if call.name == "first_value" {
+		if len(call.args) != 1 {
+			return nil, AuraError{Code: "INVALID_ARGUMENTS", Message: "first_value expects single argument"}
+		}
+
+		if len(frameRows) == 0 {
+			return nil, nil
+		}
+
+		return evaluateExpression(call.args[0], columns, frameRows[0])
+	}
+
+	aggregate := call
+	aggregate.over = nil
+	return accumulate(aggregate, columns, frameRows)
+}
+
+// frameBounds returns partition positions range [start, end) of rows within window frame,
+// default frame without ordering covers whole partition, with ordering it ends on last peer
+func frameBounds(window *WindowDefinition, partition []windowPartitionRow, pos int,
+	peers func(a, b int) bool) (int, int) {
+	if window.frame == nil {
+		if len(window.orderBy) == 0 {
+			return 0, len(partition)
+		}
+
+		end := pos + 1
+		for end < len(partition) && peers(pos, end) {
+			end++
+		}
+
+		return 0, end
+	}
+
+	bound := func(b FrameBound, isEnd bool) int {
+		var p int
+		switch b.kind {
+		case unboundedPreceding:
+			return 0
+		case unboundedFollowing:
+			return len(partition)
+		case offsetPreceding:
+			p = pos - b.offset
+		case offsetFollowing:
+			p = pos + b.offset
+		default:
+			p = pos
+		}
+
+		if isEnd {
+			p++
+		}
+
+		return max(0, min(p, len(partition)))
+	}
+
+	return bound(window.frame.start, false), bound(window.frame.end, true)
+}
+
+// compareKeys compares lists of values in order, items define descending order
+// of the values at the same positions
+func compareKeys(a []any, b []any, items []OrderByItem) int {
+	for i := range a {
+		c := compareValues(a[i], b[i])
+		if i < len(items) && items[i].descending {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}